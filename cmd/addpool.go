@@ -70,7 +70,7 @@ func newAddPoolCmd() *cobra.Command {
 	f := addPoolCmd.Flags()
 	f.StringVarP(&apc.location, "location", "l", "", "location the cluster is deployed in")
 	f.StringVarP(&apc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed")
-	f.StringVarP(&apc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file")
+	f.StringVarP(&apc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file")
 	f.StringVarP(&apc.nodePoolPath, "node-pool", "p", "", "path to a JSON file that defines the new node pool spec")
 
 	addAuthFlags(&apc.authArgs, f)
@@ -117,7 +117,7 @@ func (apc *addPoolCmd) load() error {
 	apc.logger = log.NewEntry(log.New())
 	var err error
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), apc.authArgs.retryOptions.GetOperationTimeout())
 	defer cancel()
 
 	if _, err = os.Stat(apc.apiModelPath); os.IsNotExist(err) {
@@ -207,7 +207,7 @@ func (apc *addPoolCmd) run(cmd *cobra.Command, args []string) error {
 	}
 	apCount := len(apc.containerService.Properties.AgentPoolProfiles)
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), apc.authArgs.retryOptions.GetOperationTimeout())
 	defer cancel()
 	orchestratorInfo := apc.containerService.Properties.OrchestratorProfile
 	winPoolIndex := -1
@@ -318,7 +318,7 @@ func (apc *addPoolCmd) saveAPIModel() error {
 
 	apc.containerService.Properties.AgentPoolProfiles = append(apc.containerService.Properties.AgentPoolProfiles, apc.nodePool)
 
-	b, err := apiloader.SerializeContainerService(apc.containerService, apiVersion)
+	b, err := apiloader.SerializeContainerServiceForFile(apc.containerService, apiVersion, apc.apiModelPath)
 
 	if err != nil {
 		return err
@@ -4,8 +4,14 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers/fakearm"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -107,3 +113,31 @@ func TestAddPoolCmdValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestAddPoolCmdFakeARM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stateFile, outputDirectory := deployToFakeARM(t)
+	nodePool := filepath.Join(t.TempDir(), "nodepool.json")
+	g.Expect(os.WriteFile(nodePool, []byte(`{"name": "newpool", "count": 2, "vmSize": "Standard_D2_v2", "distro": "aks-ubuntu-20.04", "availabilityProfile": "AvailabilitySet"}`), 0600)).To(Succeed())
+	apimodel := filepath.Join(outputDirectory, "apimodel.json")
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"addpool", "--fake-arm-state", stateFile, "--api-model", apimodel, "--node-pool", nodePool,
+		"--location", "local", "--resource-group", "fakearm-rg",
+		"--auth-method", "client_secret", "--subscription-id", fakearm.DefaultSubscriptionID, "--azure-env", "AzureStackCloud"})
+	g.Expect(root.Execute()).To(Succeed())
+
+	server, err := fakearm.NewServer(fakearm.WithStateFile(stateFile))
+	g.Expect(err).NotTo(HaveOccurred())
+	var pools []string
+	for _, vm := range server.Resources("fakearm-rg", "Microsoft.Compute/virtualMachines") {
+		pools = append(pools, vm["tags"].(map[string]interface{})["poolName"].(string))
+	}
+	g.Expect(pools).To(ConsistOf("master", "linuxpool", "linuxpool", "newpool", "newpool"))
+
+	cs, _, err := (&api.Apiloader{Translator: &i18n.Translator{}}).LoadContainerServiceFromFile(apimodel, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cs.Properties.AgentPoolProfiles).To(HaveLen(2))
+	g.Expect(cs.Properties.AgentPoolProfiles[1].Name).To(Equal("newpool"))
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	apimodelName             = "apimodel"
+	apimodelShortDescription = "Inspect API models"
+	apimodelLongDescription  = "Inspect and compare the API models (cluster definitions) of clusters"
+
+	apimodelDiffName             = "diff OLD NEW"
+	apimodelDiffShortDescription = "Compare two API models and show how each change is applied"
+	apimodelDiffLongDescription  = "Compare two API models semantically: the agent pools are matched by name, the order of lists of values and the secrets are ignored. " +
+		"The changes are grouped by whether they are applied by scale, by upgrade, by replacing the nodes or only by a new deployment."
+)
+
+// apimodelChangeImpactDescriptions are the headings of the changes of each impact
+var apimodelChangeImpactDescriptions = map[api.APIModelChangeImpact]string{
+	api.ImpactScale:           "Applied by scale or addpool",
+	api.ImpactUpgrade:         "Applied by upgrade",
+	api.ImpactNodeReplacement: "Applied to new nodes, the existing nodes must be replaced",
+	api.ImpactNewDeployment:   "Require a new deployment",
+}
+
+type apimodelDiffCmd struct {
+	output string
+}
+
+func newAPIModelCmd() *cobra.Command {
+	apimodelCmd := &cobra.Command{
+		Use:   apimodelName,
+		Short: apimodelShortDescription,
+		Long:  apimodelLongDescription,
+	}
+	apimodelCmd.AddCommand(newAPIModelDiffCmd())
+	return apimodelCmd
+}
+
+func newAPIModelDiffCmd() *cobra.Command {
+	dc := apimodelDiffCmd{}
+
+	diffCmd := &cobra.Command{
+		Use:   apimodelDiffName,
+		Short: apimodelDiffShortDescription,
+		Long:  apimodelDiffLongDescription,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.validate(args); err != nil {
+				return errors.Wrap(err, "validating apimodelDiffCmd")
+			}
+			return dc.run(cmd.OutOrStdout(), args[0], args[1])
+		},
+	}
+
+	f := diffCmd.Flags()
+	f.StringVarP(&dc.output, "output", "o", "human", fmt.Sprintf("Output format. Allowed values: %s", strings.Join(outputFormatOptions, ", ")))
+
+	return diffCmd
+}
+
+func (dc *apimodelDiffCmd) validate(args []string) error {
+	switch dc.output {
+	case "human", "json":
+	default:
+		return errors.Errorf(`output format "%s" is not supported`, dc.output)
+	}
+	for _, path := range args {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return errors.Errorf("specified api model does not exist (%s)", path)
+		}
+	}
+	return nil
+}
+
+func (dc *apimodelDiffCmd) run(out io.Writer, fromPath, toPath string) error {
+	locale, err := i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+	apiloader := &api.Apiloader{Translator: &i18n.Translator{Locale: locale}}
+	from, _, err := apiloader.LoadContainerServiceFromFile(fromPath, false, true, nil)
+	if err != nil {
+		return errors.Wrapf(err, "loading API model %s", fromPath)
+	}
+	to, _, err := apiloader.LoadContainerServiceFromFile(toPath, false, true, nil)
+	if err != nil {
+		return errors.Wrapf(err, "loading API model %s", toPath)
+	}
+	diffs, err := api.DiffContainerServices(from, to)
+	if err != nil {
+		return errors.Wrap(err, "comparing the API models")
+	}
+
+	if dc.output == "json" {
+		if diffs == nil {
+			diffs = []api.APIModelDifference{}
+		}
+		b, err := helpers.JSONMarshalIndent(diffs, "", "  ", false)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+	return writeAPIModelDifferences(diffs, out)
+}
+
+// writeAPIModelDifferences writes a table of the differences of each impact,
+// from the least to the most disruptive
+func writeAPIModelDifferences(diffs []api.APIModelDifference, out io.Writer) error {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(out, "The API models are equivalent")
+		return err
+	}
+	first := true
+	for _, impact := range api.APIModelChangeImpacts {
+		var changes []api.APIModelDifference
+		for _, d := range diffs {
+			if d.Impact == impact {
+				changes = append(changes, d)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(out)
+		}
+		first = false
+		fmt.Fprintf(out, "%s (%d):\n", apimodelChangeImpactDescriptions[impact], len(changes))
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  PATH\tFROM\tTO")
+		for _, c := range changes {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", c.Path, describeAPIModelValue(c.From, "(unset)"), describeAPIModelValue(c.To, "(removed)"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeAPIModelValue returns the JSON encoding of v shortened to be printed, or none if v is nil
+func describeAPIModelValue(v interface{}, none string) string {
+	if v == nil {
+		return none
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if s := string(b); len(s) <= 60 {
+		return s
+	}
+	return string(b[:57]) + "..."
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIModelCmd_ShouldCreate(t *testing.T) {
+	command := newAPIModelCmd()
+
+	g := NewGomegaWithT(t)
+	g.Expect(command.Use).Should(Equal(apimodelName))
+	g.Expect(command.Short).Should(Equal(apimodelShortDescription))
+	g.Expect(command.Long).Should(Equal(apimodelLongDescription))
+	g.Expect(command.Commands()).To(HaveLen(1))
+
+	diffCmd := command.Commands()[0]
+	g.Expect(diffCmd.Use).Should(Equal(apimodelDiffName))
+	g.Expect(diffCmd.Short).Should(Equal(apimodelDiffShortDescription))
+	g.Expect(diffCmd.Long).Should(Equal(apimodelDiffLongDescription))
+	g.Expect(diffCmd.Flags().Lookup("output")).NotTo(BeNil())
+}
+
+func TestAPIModelDiffCmd_Run(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	apimodel := `apiVersion: vlabs
+location: local
+properties:
+  masterProfile:
+    count: 1
+    dnsPrefix: diff
+    vmSize: Standard_D2_v2
+  agentPoolProfiles:
+  - name: linuxpool
+    count: 3
+    vmSize: Standard_D2_v2
+  linuxProfile:
+    adminUsername: azureuser
+    ssh:
+      publicKeys:
+      - keyData: ssh-rsa PUBLICKEY azureuser@linuxvm
+  servicePrincipalProfile:
+    clientId: ServicePrincipalClientID
+    secret: ServicePrincipalSecret
+`
+	dir := t.TempDir()
+	from := filepath.Join(dir, "old.yaml")
+	g.Expect(os.WriteFile(from, []byte(apimodel), 0600)).To(Succeed())
+	to := filepath.Join(dir, "new.yaml")
+	changed := strings.NewReplacer("count: 3", "count: 5", "location: local", "location: redmond", "ServicePrincipalSecret", "RotatedSecret").Replace(apimodel)
+	g.Expect(os.WriteFile(to, []byte(changed), 0600)).To(Succeed())
+
+	var out bytes.Buffer
+	dc := &apimodelDiffCmd{output: "human"}
+	g.Expect(dc.validate([]string{from, from})).To(Succeed())
+	g.Expect(dc.run(&out, from, from)).To(Succeed())
+	g.Expect(out.String()).To(Equal("The API models are equivalent\n"))
+
+	out.Reset()
+	g.Expect(dc.run(&out, from, to)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("Applied by scale or addpool (1):"))
+	g.Expect(out.String()).To(ContainSubstring("properties.agentPoolProfiles[name=linuxpool].count"))
+	g.Expect(out.String()).To(ContainSubstring("Require a new deployment (1):"))
+	g.Expect(out.String()).NotTo(ContainSubstring("Secret"))
+	g.Expect(strings.Index(out.String(), "scale")).To(BeNumerically("<", strings.Index(out.String(), "new deployment")))
+
+	out.Reset()
+	dc = &apimodelDiffCmd{output: "json"}
+	g.Expect(dc.run(&out, from, to)).To(Succeed())
+	var diffs []api.APIModelDifference
+	g.Expect(json.Unmarshal(out.Bytes(), &diffs)).To(Succeed())
+	g.Expect(diffs).To(HaveLen(2))
+	g.Expect(diffs[0].Path).To(Equal("location"))
+	g.Expect(diffs[0].Impact).To(Equal(api.ImpactNewDeployment))
+	g.Expect(diffs[1].Impact).To(Equal(api.ImpactScale))
+
+	dc = &apimodelDiffCmd{output: "yaml"}
+	g.Expect(dc.validate([]string{from, to})).To(MatchError(`output format "yaml" is not supported`))
+	dc = &apimodelDiffCmd{output: "human"}
+	g.Expect(dc.validate([]string{from, filepath.Join(dir, "missing.json")})).To(HaveOccurred())
+}
@@ -0,0 +1,368 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	configName             = "config"
+	configShortDescription = "Inspect the AKS Engine CLI configuration"
+	configLongDescription  = "Inspect the values AKS Engine CLI commands load from the configuration file and AKSE_* environment variables"
+
+	configViewName             = "view [command]"
+	configViewShortDescription = "Show the resolved flag values and where each value came from"
+	configViewLongDescription  = "Show the resolved flag values of a command and whether each value came from an environment variable, a configuration file profile or the flag default"
+)
+
+const (
+	// configEnvVarPrefix is the prefix of the environment variables bound to command line flags
+	configEnvVarPrefix = "AKSE_"
+	// configFileEnvVar is the environment variable overriding the default configuration file path
+	configFileEnvVar = "AKSE_CONFIG"
+	// configProfileEnvVar is the environment variable selecting the configuration file profile
+	configProfileEnvVar = "AKSE_PROFILE"
+)
+
+// Flag value sources, from highest to lowest precedence
+const (
+	flagSourceCommandLine = "flag"
+	flagSourceEnv         = "env"
+	flagSourceConfig      = "config"
+	flagSourceDefault     = "default"
+)
+
+// flagSourceAnnotation is the annotation recording the source of a flag set by bindFlagSources
+const flagSourceAnnotation = "aks-engine-azurestack/flag-source"
+
+var (
+	configFilePath    string
+	configProfileName string
+)
+
+// cliConfig is the content of the CLI configuration file.
+//
+// Each profile maps flag names to values, for example:
+//
+//	currentProfile: stamp1
+//	profiles:
+//	  stamp1:
+//	    azure-env: AzureStackCloud
+//	    identity-system: adfs
+//	    location: local
+type cliConfig struct {
+	CurrentProfile string                            `json:"currentProfile,omitempty"`
+	Profiles       map[string]map[string]interface{} `json:"profiles,omitempty"`
+}
+
+// flagValueSource describes the resolved value of a flag and where it came from
+type flagValueSource struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// addConfigFlags adds the flags selecting the configuration file and profile
+func addConfigFlags(f *flag.FlagSet) {
+	f.StringVar(&configFilePath, "config", "", fmt.Sprintf("path to the CLI configuration file (default %q, or $%s)", defaultConfigFilePath(), configFileEnvVar))
+	f.StringVar(&configProfileName, "profile", "", fmt.Sprintf("configuration file profile to use (defaults to $%s or the file's currentProfile)", configProfileEnvVar))
+}
+
+func defaultConfigFilePath() string {
+	return filepath.Join(helpers.GetHomeDir(), ".aks-engine-azurestack", "config.yaml")
+}
+
+// getConfigFilePath returns the configuration file path and whether it was explicitly requested
+func getConfigFilePath() (string, bool) {
+	if configFilePath != "" {
+		return configFilePath, true
+	}
+	if p, ok := os.LookupEnv(configFileEnvVar); ok && p != "" {
+		return p, true
+	}
+	return defaultConfigFilePath(), false
+}
+
+// loadCLIConfig reads the configuration file at path.
+// A missing file is not an error unless the path was explicitly requested.
+func loadCLIConfig(path string, required bool) (*cliConfig, error) {
+	cfg := &cliConfig{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return cfg, nil
+		}
+		return nil, errors.Wrapf(err, "reading configuration file %s", path)
+	}
+	if err = yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, errors.Wrapf(err, "parsing configuration file %s", path)
+	}
+	return cfg, nil
+}
+
+// getProfile returns the flag values of the selected profile.
+// The profile is selected by name, then by $AKSE_PROFILE, then by the file's currentProfile.
+func (cfg *cliConfig) getProfile(name string) (map[string]string, string, error) {
+	if name == "" {
+		name = os.Getenv(configProfileEnvVar)
+	}
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	if name == "" {
+		return map[string]string{}, "", nil
+	}
+	values, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, "", errors.Errorf("profile %q not found in configuration file", name)
+	}
+	profile := make(map[string]string, len(values))
+	for k, v := range values {
+		switch t := v.(type) {
+		case []interface{}:
+			items := make([]string, len(t))
+			for i := range t {
+				items[i] = fmt.Sprint(t[i])
+			}
+			profile[k] = strings.Join(items, ",")
+		case nil:
+			profile[k] = ""
+		default:
+			profile[k] = fmt.Sprint(t)
+		}
+	}
+	return profile, name, nil
+}
+
+// flagEnvVarName returns the environment variable bound to a flag, e.g. AKSE_SUBSCRIPTION_ID for --subscription-id
+func flagEnvVarName(flagName string) string {
+	return configEnvVarPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+// isConfigurableFlag returns false for the flags that cannot be loaded from the environment or configuration file
+func isConfigurableFlag(name string) bool {
+	switch name {
+	case "config", "profile", "help":
+		return false
+	}
+	return true
+}
+
+// reservedEnvVars are the AKSE_* environment variables with a meaning of their own,
+// which are not bound to the flags they are named after
+var reservedEnvVars = map[string]bool{
+	configFileEnvVar:                   true,
+	configProfileEnvVar:                true,
+	helpers.EncryptionKeyFileEnvVar:    true,
+	helpers.EncryptionPassphraseEnvVar: true,
+}
+
+// isEnvBoundFlag returns false for the flags whose environment variable is reserved,
+// e.g. --encryption-key-file, as $AKSE_ENCRYPTION_KEY_FILE holds the key decrypting the API model
+func isEnvBoundFlag(name string) bool {
+	return !reservedEnvVars[flagEnvVarName(name)]
+}
+
+// resolveFlagSources returns the value of every configurable flag in fs and its source.
+// Precedence is: command line flag, AKSE_* environment variable, configuration file profile, flag default.
+func resolveFlagSources(fs *flag.FlagSet, profile map[string]string, profileName string, lookupEnv func(string) (string, bool)) []flagValueSource {
+	var sources []flagValueSource
+	fs.VisitAll(func(f *flag.Flag) {
+		if !isConfigurableFlag(f.Name) {
+			return
+		}
+		s := flagValueSource{Name: f.Name, Value: f.DefValue, Source: flagSourceDefault}
+		envVar := flagEnvVarName(f.Name)
+		if bound, ok := f.Annotations[flagSourceAnnotation]; ok {
+			s.Value, s.Source, s.Origin = f.Value.String(), bound[0], bound[1]
+		} else if f.Changed {
+			s.Value, s.Source = f.Value.String(), flagSourceCommandLine
+		} else if v, ok := lookupEnv(envVar); ok && isEnvBoundFlag(f.Name) {
+			s.Value, s.Source, s.Origin = v, flagSourceEnv, envVar
+		} else if v, ok := profile[f.Name]; ok {
+			s.Value, s.Source, s.Origin = v, flagSourceConfig, fmt.Sprintf("profile %q", profileName)
+		}
+		sources = append(sources, s)
+	})
+	return sources
+}
+
+// bindFlagSources sets every flag of cmd that was not given on the command line
+// from its AKSE_* environment variable or from the selected configuration file profile.
+func bindFlagSources(cmd *cobra.Command) error {
+	path, required := getConfigFilePath()
+	cfg, err := loadCLIConfig(path, required)
+	if err != nil {
+		return err
+	}
+	profile, profileName, err := cfg.getProfile(configProfileName)
+	if err != nil {
+		return errors.Wrap(err, path)
+	}
+	if err = checkProfileFlags(cmd.Root(), profile); err != nil {
+		return errors.Wrapf(err, "%s, profile %q", path, profileName)
+	}
+	fs := cmd.Flags()
+	for _, s := range resolveFlagSources(fs, profile, profileName, os.LookupEnv) {
+		if s.Source != flagSourceEnv && s.Source != flagSourceConfig {
+			continue
+		}
+		// set the flag as changed, so that required flags are satisfied,
+		// and record where its value came from
+		if err := fs.Set(s.Name, s.Value); err != nil {
+			return errors.Wrapf(err, "invalid value %q for flag --%s from %s", s.Value, s.Name, s.Origin)
+		}
+		if err := fs.SetAnnotation(s.Name, flagSourceAnnotation, []string{s.Source, s.Origin}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkProfileFlags returns an error listing the profile keys that are not a flag of any command
+func checkProfileFlags(root *cobra.Command, profile map[string]string) error {
+	known := map[string]bool{}
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		for _, fs := range []*flag.FlagSet{c.Flags(), c.PersistentFlags(), c.InheritedFlags()} {
+			fs.VisitAll(func(f *flag.Flag) {
+				known[f.Name] = true
+			})
+		}
+		for _, child := range c.Commands() {
+			visit(child)
+		}
+	}
+	visit(root)
+	var unknown []string
+	for k := range profile {
+		if !known[k] || !isConfigurableFlag(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown flag(s) %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+type configViewCmd struct {
+	showSecrets bool
+}
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   configName,
+		Short: configShortDescription,
+		Long:  configLongDescription,
+	}
+	configCmd.AddCommand(newConfigViewCmd())
+	return configCmd
+}
+
+func newConfigViewCmd() *cobra.Command {
+	vc := configViewCmd{}
+
+	viewCmd := &cobra.Command{
+		Use:   configViewName,
+		Short: configViewShortDescription,
+		Long:  configViewLongDescription,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return vc.run(cmd, args)
+		},
+	}
+
+	f := viewCmd.Flags()
+	f.BoolVar(&vc.showSecrets, "show-secrets", false, "show secret values instead of masking them")
+
+	return viewCmd
+}
+
+func (vc *configViewCmd) run(cmd *cobra.Command, args []string) error {
+	path, required := getConfigFilePath()
+	cfg, err := loadCLIConfig(path, required)
+	if err != nil {
+		return err
+	}
+	profile, profileName, err := cfg.getProfile(configProfileName)
+	if err != nil {
+		return errors.Wrap(err, path)
+	}
+	if err = checkProfileFlags(cmd.Root(), profile); err != nil {
+		return errors.Wrapf(err, "%s, profile %q", path, profileName)
+	}
+
+	var sources []flagValueSource
+	if len(args) == 1 {
+		target, _, err := cmd.Root().Find(args)
+		if err != nil || target == cmd.Root() {
+			return errors.Errorf("unknown command %q", args[0])
+		}
+		sources = resolveFlagSources(target.Flags(), profile, profileName, os.LookupEnv)
+	} else {
+		sources = resolveAllCommandFlagSources(cmd.Root(), profile, profileName)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Configuration file: %s\n", path)
+	if profileName != "" {
+		fmt.Fprintf(out, "Profile: %s\n", profileName)
+	}
+	fmt.Fprintln(out)
+	return vc.writeSources(out, sources)
+}
+
+// resolveAllCommandFlagSources returns the flags of every command set from the environment or the configuration file
+func resolveAllCommandFlagSources(root *cobra.Command, profile map[string]string, profileName string) []flagValueSource {
+	seen := map[string]bool{}
+	var sources []flagValueSource
+	for _, c := range root.Commands() {
+		for _, s := range resolveFlagSources(c.Flags(), profile, profileName, os.LookupEnv) {
+			if seen[s.Name] || s.Source == flagSourceDefault {
+				continue
+			}
+			seen[s.Name] = true
+			sources = append(sources, s)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return sources
+}
+
+func (vc *configViewCmd) writeSources(out io.Writer, sources []flagValueSource) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+	for _, s := range sources {
+		value := s.Value
+		if !vc.showSecrets && isSecretFlag(s.Name) && value != "" {
+			value = "********"
+		}
+		source := s.Source
+		if s.Origin != "" {
+			source = fmt.Sprintf("%s (%s)", s.Source, s.Origin)
+		}
+		fmt.Fprintf(w, "--%s\t%s\t%s\n", s.Name, value, source)
+	}
+	return w.Flush()
+}
+
+func isSecretFlag(name string) bool {
+	return strings.Contains(name, "secret") || strings.Contains(name, "password")
+}
@@ -0,0 +1,227 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	. "github.com/onsi/gomega"
+	flag "github.com/spf13/pflag"
+)
+
+const testConfigFile = `currentProfile: stamp1
+profiles:
+  stamp1:
+    azure-env: AzureStackCloud
+    identity-system: adfs
+    location: local
+    new-node-count: 3
+  stamp2:
+    location: redmond
+`
+
+func writeTestConfigFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCLIConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfg, err := loadCLIConfig(filepath.Join(t.TempDir(), "missing.yaml"), false)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Profiles).To(BeEmpty())
+
+	_, err = loadCLIConfig(filepath.Join(t.TempDir(), "missing.yaml"), true)
+	g.Expect(err).To(HaveOccurred())
+
+	cfg, err = loadCLIConfig(writeTestConfigFile(t), true)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	t.Setenv(configProfileEnvVar, "")
+	profile, name, err := cfg.getProfile("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal("stamp1"))
+	g.Expect(profile["location"]).To(Equal("local"))
+	g.Expect(profile["new-node-count"]).To(Equal("3"))
+
+	t.Setenv(configProfileEnvVar, "stamp2")
+	profile, name, err = cfg.getProfile("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal("stamp2"))
+	g.Expect(profile["location"]).To(Equal("redmond"))
+
+	_, _, err = cfg.getProfile("stamp3")
+	g.Expect(err).To(MatchError(`profile "stamp3" not found in configuration file`))
+
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	g.Expect(os.WriteFile(bad, []byte("profile: {}\n"), 0600)).To(Succeed())
+	_, err = loadCLIConfig(bad, true)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestFlagEnvVarName(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	g.Expect(flagEnvVarName("subscription-id")).To(Equal("AKSE_SUBSCRIPTION_ID"))
+	g.Expect(flagEnvVarName("resource-group")).To(Equal("AKSE_RESOURCE_GROUP"))
+	g.Expect(flagEnvVarName("debug")).To(Equal("AKSE_DEBUG"))
+
+	g.Expect(isEnvBoundFlag("subscription-id")).To(BeTrue())
+	g.Expect(isEnvBoundFlag("encryption-key-file")).To(BeFalse())
+}
+
+func TestResolveFlagSources(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	var location, group, env, sub, keyFile string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVarP(&location, "location", "l", "", "")
+	fs.StringVarP(&group, "resource-group", "g", "", "")
+	fs.StringVar(&env, "azure-env", "AzurePublicCloud", "")
+	fs.StringVar(&sub, "subscription-id", "", "")
+	fs.StringVar(&keyFile, "encryption-key-file", "", "")
+	fs.String("config", "", "")
+	g.Expect(fs.Parse([]string{"-l", "westus"})).To(Succeed())
+
+	profile := map[string]string{"location": "local", "resource-group": "from-config", "subscription-id": "from-config"}
+	lookupEnv := func(key string) (string, bool) {
+		switch key {
+		case "AKSE_SUBSCRIPTION_ID":
+			return "from-env", true
+		case helpers.EncryptionKeyFileEnvVar:
+			return "/path/to/output.key", true
+		}
+		return "", false
+	}
+
+	sources := map[string]flagValueSource{}
+	for _, s := range resolveFlagSources(fs, profile, "stamp1", lookupEnv) {
+		sources[s.Name] = s
+	}
+	g.Expect(sources).NotTo(HaveKey("config"))
+	g.Expect(sources["location"]).To(Equal(flagValueSource{Name: "location", Value: "westus", Source: flagSourceCommandLine}))
+	g.Expect(sources["subscription-id"]).To(Equal(flagValueSource{Name: "subscription-id", Value: "from-env", Source: flagSourceEnv, Origin: "AKSE_SUBSCRIPTION_ID"}))
+	g.Expect(sources["resource-group"]).To(Equal(flagValueSource{Name: "resource-group", Value: "from-config", Source: flagSourceConfig, Origin: `profile "stamp1"`}))
+	g.Expect(sources["azure-env"]).To(Equal(flagValueSource{Name: "azure-env", Value: "AzurePublicCloud", Source: flagSourceDefault}))
+	// $AKSE_ENCRYPTION_KEY_FILE is the key decrypting the API model, not the --encryption-key-file value
+	g.Expect(sources["encryption-key-file"]).To(Equal(flagValueSource{Name: "encryption-key-file", Source: flagSourceDefault}))
+}
+
+func TestBindFlagSources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	configFilePath = writeTestConfigFile(t)
+	defer func() { configFilePath = "" }()
+	t.Setenv(configProfileEnvVar, "")
+	t.Setenv("AKSE_RESOURCE_GROUP", "env-group")
+
+	command := newScaleCmd()
+	g.Expect(command.ParseFlags([]string{"--location", "westus"})).To(Succeed())
+	g.Expect(bindFlagSources(command)).To(Succeed())
+
+	f := command.Flags()
+	g.Expect(f.Lookup("location").Value.String()).To(Equal("westus"))
+	g.Expect(f.Lookup("resource-group").Value.String()).To(Equal("env-group"))
+	g.Expect(f.Lookup("azure-env").Value.String()).To(Equal("AzureStackCloud"))
+	g.Expect(f.Lookup("identity-system").Value.String()).To(Equal("adfs"))
+	g.Expect(f.Lookup("new-node-count").Value.String()).To(Equal("3"))
+
+	sources := map[string]flagValueSource{}
+	for _, s := range resolveFlagSources(f, nil, "", func(string) (string, bool) { return "", false }) {
+		sources[s.Name] = s
+	}
+	g.Expect(sources["location"].Source).To(Equal(flagSourceCommandLine))
+	g.Expect(sources["resource-group"]).To(Equal(flagValueSource{Name: "resource-group", Value: "env-group", Source: flagSourceEnv, Origin: "AKSE_RESOURCE_GROUP"}))
+	g.Expect(sources["azure-env"]).To(Equal(flagValueSource{Name: "azure-env", Value: "AzureStackCloud", Source: flagSourceConfig, Origin: `profile "stamp1"`}))
+
+	t.Setenv("AKSE_NEW_NODE_COUNT", "three")
+	command = newScaleCmd()
+	err := bindFlagSources(command)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("--new-node-count from AKSE_NEW_NODE_COUNT"))
+}
+
+func TestBindFlagSourcesRequiredFlags(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `currentProfile: stamp1
+profiles:
+  stamp1:
+    location: local
+    resource-group: from-config
+    ssh-host: master.local.cloudapp.azurestack.external
+    linux-ssh-private-key: ~/.ssh/id_rsa
+`
+	g.Expect(os.WriteFile(path, []byte(config), 0600)).To(Succeed())
+	t.Setenv(configProfileEnvVar, "")
+	t.Setenv("AKSE_API_MODEL", "./random/apimodel.json")
+
+	root := NewRootCmd()
+	root.SetOutput(&bytes.Buffer{})
+	root.SetArgs([]string{"rotate-certs", "--config", path})
+	err := root.Execute()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).NotTo(ContainSubstring("required flag"))
+	g.Expect(err.Error()).To(ContainSubstring("validating rotate-certs args: specified --linux-ssh-private-key does not exist (~/.ssh/id_rsa)"))
+}
+
+func TestBindFlagSourcesUnknownProfileFlags(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `currentProfile: stamp1
+profiles:
+  stamp1:
+    location: local
+    resourcegroup: typo
+    profile: stamp2
+`
+	g.Expect(os.WriteFile(path, []byte(config), 0600)).To(Succeed())
+	t.Setenv(configProfileEnvVar, "")
+
+	root := NewRootCmd()
+	root.SetOutput(&bytes.Buffer{})
+	root.SetArgs([]string{"scale", "--config", path})
+	err := root.Execute()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal(path + `, profile "stamp1": unknown flag(s) profile, resourcegroup`))
+}
+
+func TestConfigView(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := writeTestConfigFile(t)
+	t.Setenv(configProfileEnvVar, "")
+	t.Setenv("AKSE_CLIENT_SECRET", "supersecret")
+
+	root := NewRootCmd()
+	out := &bytes.Buffer{}
+	root.SetOutput(out)
+	root.SetArgs([]string{"config", "view", "scale", "--config", path})
+	g.Expect(root.Execute()).To(Succeed())
+
+	lines := strings.Split(out.String(), "\n")
+	g.Expect(lines[1]).To(Equal("Profile: stamp1"))
+	g.Expect(out.String()).NotTo(ContainSubstring("supersecret"))
+	g.Expect(out.String()).To(MatchRegexp(`--client-secret\s+\*+\s+env \(AKSE_CLIENT_SECRET\)`))
+	g.Expect(out.String()).To(MatchRegexp(`--location\s+local\s+config \(profile "stamp1"\)`))
+	g.Expect(out.String()).To(MatchRegexp(`--resource-group\s+default`))
+
+	root = NewRootCmd()
+	root.SetArgs([]string{"config", "view", "not-a-command", "--config", path})
+	root.SetOutput(&bytes.Buffer{})
+	g.Expect(root.Execute()).To(MatchError(`unknown command "not-a-command"`))
+}
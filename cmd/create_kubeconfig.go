@@ -0,0 +1,291 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/engine"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/Azure/aks-engine-azurestack/pkg/kubernetes"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	createKubeConfigName             = "create-kubeconfig"
+	createKubeConfigShortDescription = "Create a kubeconfig for a user, authenticated by a client certificate signed by the cluster CA."
+	createKubeConfigLongDescription  = "Create a kubeconfig for a user and its groups, authenticated by a short-lived client certificate signed by the CA of the API model. The serial number of each issued certificate is recorded, and a role binding granting the user a role can be created."
+)
+
+const (
+	createKubeConfigDefaultTTL       = 24 * time.Hour
+	createKubeConfigDefaultNamespace = "default"
+	createKubeConfigIssuedFile       = "issued-client-certificates.json"
+)
+
+var invalidFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+var invalidObjectNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+type createKubeConfigCmd struct {
+	// user input
+	apiModelPath string
+	location     string
+	user         string
+	groups       []string
+	// allowSystemGroups allows groups reserved for Kubernetes system components, like system:masters
+	allowSystemGroups bool
+	ttl               time.Duration
+	outputFile        string
+	namespace         string
+	role              string
+	clusterRole       string
+
+	// computed
+	cs                     *api.ContainerService
+	locale                 *gotext.Locale
+	issuedCertificatesPath string
+	// encryptionKey encrypts the kubeconfig if the api-model is encrypted
+	encryptionKey *helpers.EncryptionKey
+	now           time.Time
+}
+
+// issuedClientCertificate is a client certificate issued by create-kubeconfig,
+// recorded so that the certificates to revoke can be identified
+type issuedClientCertificate struct {
+	SerialNumber      string    `json:"serialNumber"`
+	User              string    `json:"user"`
+	Groups            []string  `json:"groups,omitempty"`
+	IssuedAt          time.Time `json:"issuedAt"`
+	NotAfter          time.Time `json:"notAfter"`
+	SHA256Fingerprint string    `json:"sha256Fingerprint"`
+}
+
+func newCreateKubeConfigCmd() *cobra.Command {
+	ckc := createKubeConfigCmd{}
+	command := &cobra.Command{
+		Use:   createKubeConfigName,
+		Short: createKubeConfigShortDescription,
+		Long:  createKubeConfigLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ckc.validateArgs(); err != nil {
+				return errors.Wrap(err, "validating create-kubeconfig args")
+			}
+			if err := ckc.loadAPIModel(); err != nil {
+				return errors.Wrap(err, "loading API model")
+			}
+			ckc.init()
+			cmd.SilenceUsage = true
+			return ckc.run()
+		},
+	}
+	f := command.Flags()
+	f.StringVarP(&ckc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file (required)")
+	f.StringVarP(&ckc.location, "location", "l", "", "Azure location where the cluster is deployed, defaults to the api-model location")
+	f.StringVar(&ckc.user, "user", "", "Kubernetes user name, the common name of the client certificate (required)")
+	f.StringSliceVar(&ckc.groups, "group", nil, "Kubernetes groups of the user, the organizations of the client certificate (comma-separated or repeated)")
+	f.BoolVar(&ckc.allowSystemGroups, "allow-system-groups", false, "allow --group values starting with system:, like system:masters which bypasses authorization")
+	f.DurationVar(&ckc.ttl, "ttl", createKubeConfigDefaultTTL, "duration the client certificate is valid")
+	f.StringVar(&ckc.outputFile, "output-file", "", "path of the kubeconfig file, defaults to kubeconfig/kubeconfig.<user>.json in the api-model directory")
+	f.StringVar(&ckc.role, "role", "", "name of the Role bound to the user in --namespace")
+	f.StringVar(&ckc.clusterRole, "cluster-role", "", "name of the ClusterRole bound to the user in --namespace")
+	f.StringVarP(&ckc.namespace, "namespace", "n", "", fmt.Sprintf("namespace of the role binding, defaults to %s", createKubeConfigDefaultNamespace))
+	_ = command.MarkFlagRequired("api-model")
+	_ = command.MarkFlagRequired("user")
+	return command
+}
+
+func (ckc *createKubeConfigCmd) validateArgs() (err error) {
+	if ckc.locale, err = i18n.LoadTranslations(); err != nil {
+		return errors.Wrap(err, "loading translation files")
+	}
+	ckc.location = helpers.NormalizeAzureRegion(ckc.location)
+	if ckc.apiModelPath == "" {
+		return errors.New("--api-model must be specified")
+	} else if _, err := os.Stat(ckc.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified --api-model does not exist (%s)", ckc.apiModelPath)
+	}
+	if ckc.user == "" {
+		return errors.New("--user must be specified")
+	}
+	if strings.HasPrefix(ckc.user, "system:") {
+		return errors.New("--user cannot start with system:, the prefix is reserved for Kubernetes system components")
+	}
+	for _, group := range ckc.groups {
+		if strings.HasPrefix(group, "system:") && !ckc.allowSystemGroups {
+			return errors.Errorf("--group %s starts with system:, the prefix is reserved for Kubernetes system components, set --allow-system-groups to allow it", group)
+		}
+	}
+	if ckc.ttl <= 0 {
+		return errors.New("--ttl must be a positive duration")
+	}
+	if ckc.role != "" && ckc.clusterRole != "" {
+		return errors.New("--role and --cluster-role are mutually exclusive")
+	}
+	if ckc.namespace != "" && ckc.role == "" && ckc.clusterRole == "" {
+		return errors.New("--namespace requires --role or --cluster-role")
+	}
+	if ckc.namespace == "" {
+		ckc.namespace = createKubeConfigDefaultNamespace
+	}
+	return nil
+}
+
+func (ckc *createKubeConfigCmd) loadAPIModel() (err error) {
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: ckc.locale,
+		},
+	}
+	if ckc.cs, _, err = apiloader.LoadContainerServiceFromFile(ckc.apiModelPath, false, false, nil); err != nil {
+		return errors.Wrap(err, "error parsing api-model")
+	}
+	// the kubeconfig of an encrypted api-model is encrypted too
+	if encrypted, _ := helpers.IsEncryptedFile(ckc.apiModelPath); encrypted {
+		if ckc.encryptionKey, err = helpers.EncryptionKeyFromEnvironment(); err != nil {
+			return errors.Wrap(err, "loading the encryption key")
+		}
+	}
+	if ckc.cs.Properties.CertificateProfile == nil || ckc.cs.Properties.CertificateProfile.CaPrivateKey == "" {
+		return errors.New("the api-model has no CA private key to sign the client certificate")
+	}
+	if ckc.cs.Location == "" {
+		if ckc.location == "" {
+			return errors.New("--location must be specified")
+		}
+		ckc.cs.Location = ckc.location
+	} else if ckc.location != "" && ckc.cs.Location != ckc.location {
+		return errors.New("--location flag does not match api-model location")
+	}
+	return nil
+}
+
+func (ckc *createKubeConfigCmd) init() {
+	ckc.now = time.Now()
+	dir := filepath.Join(filepath.Dir(ckc.apiModelPath), "kubeconfig")
+	if ckc.outputFile == "" {
+		ckc.outputFile = filepath.Join(dir, fmt.Sprintf("kubeconfig.%s.json", invalidFileNameChars.ReplaceAllString(ckc.user, "_")))
+	}
+	ckc.issuedCertificatesPath = filepath.Join(dir, createKubeConfigIssuedFile)
+}
+
+func (ckc *createKubeConfigCmd) run() error {
+	p := ckc.cs.Properties.CertificateProfile
+	pair, err := helpers.CreateClientCertificate(helpers.PkiClientCertificateParams{
+		CommonName:         ckc.user,
+		Organizations:      ckc.groups,
+		CaPair:             &helpers.PkiKeyCertPair{CertificatePem: p.CaCertificate, PrivateKeyPem: p.CaPrivateKey},
+		CaCertificateChain: p.CaCertificateChain,
+		PkiKeySize:         helpers.DefaultPkiKeySize,
+		PkiKeyAlgorithm:    p.KeyAlgorithm,
+		ValidityDuration:   ckc.ttl,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating client certificate")
+	}
+	details, err := helpers.GetCertificateDetails(pair.CertificatePem)
+	if err != nil {
+		return errors.Wrap(err, "reading client certificate")
+	}
+	kubeconfig, err := engine.GenerateClientKubeConfig(ckc.cs.Properties, ckc.cs.Location, ckc.user, pair.CertificatePem, pair.PrivateKeyPem)
+	if err != nil {
+		return errors.Wrap(err, "generating kubeconfig")
+	}
+
+	// the certificate is recorded before the kubeconfig is written, so that no certificate escapes revocation
+	issued := issuedClientCertificate{
+		SerialNumber:      details.SerialNumber,
+		User:              ckc.user,
+		Groups:            ckc.groups,
+		IssuedAt:          ckc.now.UTC(),
+		NotAfter:          details.NotAfter,
+		SHA256Fingerprint: details.SHA256Fingerprint,
+	}
+	if err = recordIssuedCertificate(ckc.issuedCertificatesPath, issued); err != nil {
+		return errors.Wrap(err, "recording the issued client certificate")
+	}
+	log.Infof("Client certificate serial number %s recorded in %s", issued.SerialNumber, ckc.issuedCertificatesPath)
+
+	saver := &helpers.FileSaver{
+		Translator:    &i18n.Translator{Locale: ckc.locale},
+		EncryptionKey: ckc.encryptionKey,
+	}
+	if err = saver.SaveFileString(filepath.Dir(ckc.outputFile), filepath.Base(ckc.outputFile), kubeconfig); err != nil {
+		return errors.Wrapf(err, "writing kubeconfig %s", ckc.outputFile)
+	}
+	log.Infof("Kubeconfig of user %s written to %s, valid until %s", ckc.user, ckc.outputFile, details.NotAfter.Format(time.RFC3339))
+
+	if ckc.role == "" && ckc.clusterRole == "" {
+		return nil
+	}
+	adminKubeConfig, err := engine.GenerateKubeConfig(ckc.cs.Properties, ckc.cs.Location)
+	if err != nil {
+		return errors.Wrap(err, "generating admin kubeconfig")
+	}
+	client, err := kubernetes.NewClient("", adminKubeConfig, 10*time.Second, 10*time.Minute)
+	if err != nil {
+		return errors.Wrap(err, "creating Kubernetes client")
+	}
+	return ckc.createRoleBinding(client)
+}
+
+// createRoleBinding binds the role, or the cluster role, to the user in the namespace
+func (ckc *createKubeConfigCmd) createRoleBinding(client kubernetes.RoleBindingCreator) error {
+	roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: ckc.role}
+	if ckc.clusterRole != "" {
+		roleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: ckc.clusterRole}
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      invalidObjectNameChars.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s", ckc.user, roleRef.Name)), "-"),
+			Namespace: ckc.namespace,
+		},
+		Subjects: []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: ckc.user}},
+		RoleRef:  roleRef,
+	}
+	if _, err := client.CreateRoleBinding(binding); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "creating role binding %s/%s", binding.Namespace, binding.Name)
+		}
+		log.Infof("Role binding %s/%s already exists", binding.Namespace, binding.Name)
+		return nil
+	}
+	log.Infof("%s %s bound to user %s by role binding %s/%s", roleRef.Kind, roleRef.Name, ckc.user, binding.Namespace, binding.Name)
+	return nil
+}
+
+// recordIssuedCertificate appends the issued client certificate to the list persisted to path
+func recordIssuedCertificate(path string, issued issuedClientCertificate) error {
+	var list []issuedClientCertificate
+	b, err := os.ReadFile(path)
+	if err == nil {
+		if err = json.Unmarshal(b, &list); err != nil {
+			return errors.Wrapf(err, "parsing %s", path)
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "reading %s", path)
+	}
+	list = append(list, issued)
+	out, err := helpers.JSONMarshalIndent(list, "", "  ", false)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "creating directory %s", filepath.Dir(path))
+	}
+	return os.WriteFile(path, out, 0600)
+}
@@ -0,0 +1,208 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestCreateKubeConfigCmd(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	command := newCreateKubeConfigCmd()
+	g.Expect(command.Use).Should(Equal(createKubeConfigName))
+	g.Expect(command.Short).Should(Equal(createKubeConfigShortDescription))
+	g.Expect(command.Long).Should(Equal(createKubeConfigLongDescription))
+	g.Expect(command.Flags().Lookup("ttl").DefValue).Should(Equal("24h0m0s"))
+
+	command.SetArgs([]string{})
+	err := command.Execute()
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCreateKubeConfigCmdValidateArgs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	existingFile := "../examples/kubernetes.json"
+	missingFile := "./random/file"
+
+	cases := []struct {
+		ckc         *createKubeConfigCmd
+		expectedErr error
+		name        string
+	}{
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: missingFile, user: "alice", ttl: time.Hour},
+			expectedErr: errors.Errorf("specified --api-model does not exist (%s)", missingFile),
+			name:        "BadAPIModel",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, ttl: time.Hour},
+			expectedErr: errors.New("--user must be specified"),
+			name:        "NeedsUser",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "system:kube-proxy", ttl: time.Hour},
+			expectedErr: errors.New("--user cannot start with system:, the prefix is reserved for Kubernetes system components"),
+			name:        "SystemUser",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice", groups: []string{"dev-team", "system:masters"}, ttl: time.Hour},
+			expectedErr: errors.New("--group system:masters starts with system:, the prefix is reserved for Kubernetes system components, set --allow-system-groups to allow it"),
+			name:        "SystemGroup",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice", groups: []string{"system:masters"}, allowSystemGroups: true, ttl: time.Hour},
+			expectedErr: nil,
+			name:        "AllowedSystemGroup",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice"},
+			expectedErr: errors.New("--ttl must be a positive duration"),
+			name:        "NeedsTTL",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice", ttl: time.Hour, role: "dev", clusterRole: "view"},
+			expectedErr: errors.New("--role and --cluster-role are mutually exclusive"),
+			name:        "Role+ClusterRole",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice", ttl: time.Hour, namespace: "dev"},
+			expectedErr: errors.New("--namespace requires --role or --cluster-role"),
+			name:        "NamespaceWithoutRole",
+		},
+		{
+			ckc:         &createKubeConfigCmd{apiModelPath: existingFile, user: "alice", groups: []string{"dev-team"}, ttl: 720 * time.Hour, clusterRole: "view"},
+			expectedErr: nil,
+			name:        "IsValid",
+		},
+	}
+	for _, tc := range cases {
+		c := tc
+		t.Run(c.name, func(t *testing.T) {
+			err := c.ckc.validateArgs()
+			if c.expectedErr != nil {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(c.ckc.namespace).To(Equal(createKubeConfigDefaultNamespace))
+			}
+		})
+	}
+}
+
+func TestCreateKubeConfigRun(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	cs := api.CreateMockContainerService("testcluster", "", 1, 1, false)
+	cs.Location = "westus2"
+	cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+	cs.Properties.CertificateProfile.KeyAlgorithm = helpers.PkiKeyAlgorithmECDSAP256
+	_, _, err := cs.SetDefaultCerts(api.DefaultCertParams{PkiKeySize: 2048})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dir := t.TempDir()
+	ckc := &createKubeConfigCmd{
+		apiModelPath: filepath.Join(dir, "apimodel.json"),
+		user:         "alice@contoso.com",
+		groups:       []string{"dev-team"},
+		ttl:          720 * time.Hour,
+		cs:           cs,
+	}
+	ckc.init()
+	g.Expect(ckc.outputFile).To(Equal(filepath.Join(dir, "kubeconfig", "kubeconfig.alice_contoso.com.json")))
+	g.Expect(ckc.run()).To(Succeed())
+	g.Expect(ckc.run()).To(Succeed())
+
+	b, err := os.ReadFile(ckc.outputFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	var kubeconfig struct {
+		Users []struct {
+			User struct {
+				ClientCertificateData string `json:"client-certificate-data"`
+			} `json:"user"`
+		} `json:"users"`
+	}
+	g.Expect(json.Unmarshal(b, &kubeconfig)).To(Succeed())
+	g.Expect(kubeconfig.Users).To(HaveLen(1))
+	certificate, err := base64.StdEncoding.DecodeString(kubeconfig.Users[0].User.ClientCertificateData)
+	g.Expect(err).ToNot(HaveOccurred())
+	details, err := helpers.GetCertificateDetails(string(certificate))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(details.Subject).To(Equal("CN=alice@contoso.com,O=dev-team"))
+	g.Expect(details.NotAfter).To(BeTemporally("~", time.Now().Add(720*time.Hour), time.Minute))
+
+	b, err = os.ReadFile(ckc.issuedCertificatesPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	var issued []issuedClientCertificate
+	g.Expect(json.Unmarshal(b, &issued)).To(Succeed())
+	g.Expect(issued).To(HaveLen(2))
+	g.Expect(issued[1].SerialNumber).To(Equal(details.SerialNumber))
+	g.Expect(issued[1].SHA256Fingerprint).To(Equal(details.SHA256Fingerprint))
+	g.Expect(issued[1].User).To(Equal("alice@contoso.com"))
+	g.Expect(issued[1].Groups).To(Equal([]string{"dev-team"}))
+	g.Expect(issued[0].SerialNumber).ToNot(Equal(issued[1].SerialNumber))
+
+	// the kubeconfig of an encrypted api-model is encrypted
+	ckc.encryptionKey, err = helpers.NewPassphraseEncryptionKey("correct horse battery staple")
+	g.Expect(err).ToNot(HaveOccurred())
+	ckc.outputFile = filepath.Join(dir, "kubeconfig", "encrypted.json")
+	g.Expect(ckc.run()).To(Succeed())
+	b, err = os.ReadFile(ckc.outputFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(helpers.IsEncrypted(b)).To(BeTrue())
+	b, err = ckc.encryptionKey.Decrypt(b)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(json.Unmarshal(b, &kubeconfig)).To(Succeed())
+}
+
+type mockRoleBindingCreator struct {
+	bindings []*rbacv1.RoleBinding
+}
+
+func (m *mockRoleBindingCreator) CreateRoleBinding(binding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+	for _, b := range m.bindings {
+		if b.Namespace == binding.Namespace && b.Name == binding.Name {
+			return nil, apierrors.NewAlreadyExists(rbacv1.Resource("rolebindings"), binding.Name)
+		}
+	}
+	m.bindings = append(m.bindings, binding)
+	return binding, nil
+}
+
+func TestCreateKubeConfigCreateRoleBinding(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	client := &mockRoleBindingCreator{}
+	ckc := &createKubeConfigCmd{user: "Alice@contoso.com", clusterRole: "view", namespace: "dev"}
+	g.Expect(ckc.createRoleBinding(client)).To(Succeed())
+	ckc = &createKubeConfigCmd{user: "bob", role: "deployer", namespace: "default"}
+	g.Expect(ckc.createRoleBinding(client)).To(Succeed())
+	// the role binding of a user issued a new kubeconfig already exists
+	g.Expect(ckc.createRoleBinding(client)).To(Succeed())
+
+	g.Expect(client.bindings).To(HaveLen(2))
+	g.Expect(client.bindings[0].Name).To(Equal("alice-contoso.com-view"))
+	g.Expect(client.bindings[0].Namespace).To(Equal("dev"))
+	g.Expect(client.bindings[0].RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"}))
+	g.Expect(client.bindings[0].Subjects).To(Equal([]rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "Alice@contoso.com"}}))
+	g.Expect(client.bindings[1].RoleRef.Kind).To(Equal("Role"))
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	decryptName             = "decrypt"
+	decryptShortDescription = "Decrypt the files of an output directory encrypted with --encrypt-output"
+	decryptLongDescription  = "Decrypt an API model, parameters file, kubeconfig, certificate or key encrypted with --encrypt-output and print it, " +
+		"or decrypt files and output directories in place with --in-place. The key is read from --encryption-key-file, " +
+		"$" + helpers.EncryptionKeyFileEnvVar + " or derived from $" + helpers.EncryptionPassphraseEnvVar + "."
+)
+
+type decryptCmd struct {
+	encryptionKeyFile string
+	inPlace           bool
+	outputFile        string
+
+	// derived
+	encryptionKey *helpers.EncryptionKey
+}
+
+func newDecryptCmd() *cobra.Command {
+	dc := decryptCmd{}
+
+	command := &cobra.Command{
+		Use:   decryptName + " FILE...",
+		Short: decryptShortDescription,
+		Long:  decryptLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dc.validate(args); err != nil {
+				return errors.Wrap(err, "validating decryptCmd")
+			}
+			return dc.run(args, cmd.OutOrStdout())
+		},
+	}
+
+	f := command.Flags()
+	f.StringVar(&dc.encryptionKeyFile, "encryption-key-file", "", fmt.Sprintf("path to the key file the files were encrypted with (defaults to $%s, or the key derived from $%s)", helpers.EncryptionKeyFileEnvVar, helpers.EncryptionPassphraseEnvVar))
+	f.BoolVar(&dc.inPlace, "in-place", false, "replace the encrypted files, and the encrypted files of the directories, by their decrypted content")
+	f.StringVarP(&dc.outputFile, "output", "o", "", "file the decrypted file is written to (defaults to standard output)")
+
+	return command
+}
+
+func (dc *decryptCmd) validate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("a file to decrypt must be specified")
+	}
+	if !dc.inPlace && len(args) > 1 {
+		return errors.New("only one file can be decrypted without --in-place")
+	}
+	if dc.inPlace && dc.outputFile != "" {
+		return errors.New("--output and --in-place cannot be specified together")
+	}
+	var err error
+	if dc.encryptionKeyFile != "" {
+		dc.encryptionKey, err = helpers.LoadEncryptionKeyFile(dc.encryptionKeyFile)
+	} else {
+		dc.encryptionKey, err = helpers.EncryptionKeyFromEnvironment()
+	}
+	if err != nil {
+		return errors.Wrap(err, "loading the encryption key")
+	}
+	if dc.encryptionKey == nil {
+		return errors.Errorf("--encryption-key-file, $%s or $%s must be specified", helpers.EncryptionKeyFileEnvVar, helpers.EncryptionPassphraseEnvVar)
+	}
+	return nil
+}
+
+func (dc *decryptCmd) run(args []string, out io.Writer) error {
+	if !dc.inPlace {
+		b, err := dc.decryptFile(args[0])
+		if err != nil {
+			return err
+		}
+		if dc.outputFile == "" {
+			_, err = out.Write(b)
+			return err
+		}
+		if err = os.WriteFile(dc.outputFile, b, 0600); err != nil {
+			return errors.Wrapf(err, "writing the decrypted file to %s", dc.outputFile)
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			encrypted, err := helpers.IsEncryptedFile(path)
+			if err != nil || !encrypted {
+				return err
+			}
+			b, err := dc.decryptFile(path)
+			if err != nil {
+				return err
+			}
+			if err = os.WriteFile(path, b, info.Mode().Perm()); err != nil {
+				return errors.Wrapf(err, "writing %s", path)
+			}
+			log.Infof("decrypted %s", path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptFile returns the decrypted content of the file at path
+func (dc *decryptCmd) decryptFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	if !helpers.IsEncrypted(b) {
+		return nil, errors.Errorf("%s is not encrypted", path)
+	}
+	if b, err = dc.encryptionKey.Decrypt(b); err != nil {
+		return nil, errors.Wrapf(err, "decrypting %s", path)
+	}
+	return b, nil
+}
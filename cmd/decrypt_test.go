@@ -0,0 +1,105 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/engine"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	. "github.com/onsi/gomega"
+)
+
+func TestDecryptCmd_ShouldCreate(t *testing.T) {
+	command := newDecryptCmd()
+
+	g := NewGomegaWithT(t)
+	g.Expect(command.Name()).Should(Equal(decryptName))
+	g.Expect(command.Short).Should(Equal(decryptShortDescription))
+	g.Expect(command.Long).Should(Equal(decryptLongDescription))
+	for _, f := range []string{"encryption-key-file", "in-place", "output"} {
+		g.Expect(command.Flags().Lookup(f)).NotTo(BeNil(), f)
+	}
+}
+
+func TestDecryptCmd_Validate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv(helpers.EncryptionKeyFileEnvVar, "")
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "")
+
+	dc := &decryptCmd{}
+	g.Expect(dc.validate(nil)).To(MatchError("a file to decrypt must be specified"))
+	g.Expect(dc.validate([]string{"a", "b"})).To(MatchError("only one file can be decrypted without --in-place"))
+	dc = &decryptCmd{inPlace: true, outputFile: "out"}
+	g.Expect(dc.validate([]string{"a"})).To(MatchError("--output and --in-place cannot be specified together"))
+	dc = &decryptCmd{}
+	g.Expect(dc.validate([]string{"a"})).To(MatchError("--encryption-key-file, $AKSE_ENCRYPTION_KEY_FILE or $AKSE_ENCRYPTION_PASSPHRASE must be specified"))
+
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "passphrase")
+	g.Expect(dc.validate([]string{"a"})).To(Succeed())
+	g.Expect(dc.encryptionKey).NotTo(BeNil())
+}
+
+func TestDecryptCmd_Run(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "output.key")
+	g.Expect(os.WriteFile(keyPath, bytes.Repeat([]byte{42}, 32), 0600)).To(Succeed())
+	key, err := helpers.LoadEncryptionKeyFile(keyPath)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cs := api.CreateMockContainerService("testcluster", "", 3, 2, false)
+	_, err = cs.SetPropertiesDefaults(api.PropertiesDefaultsParams{
+		IsScale:    false,
+		IsUpgrade:  false,
+		PkiKeySize: helpers.DefaultPkiKeySize,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	outdir := filepath.Join(dir, "_output")
+	w := &engine.ArtifactWriter{Translator: &i18n.Translator{}, EncryptionKey: key}
+	g.Expect(w.WriteTLSArtifacts(cs, "vlabs", "{}", "{}", outdir, true, false)).To(Succeed())
+
+	for _, file := range []string{"apimodel.json", "azuredeploy.parameters.json", "ca.key", "etcdpeer0.crt", "kubeconfig/kubeconfig.eastus.json"} {
+		encrypted, err := helpers.IsEncryptedFile(filepath.Join(outdir, file))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(encrypted).To(BeTrue(), file)
+	}
+	encrypted, err := helpers.IsEncryptedFile(filepath.Join(outdir, "azuredeploy.json"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(encrypted).To(BeFalse())
+
+	// the API model is decrypted transparently with the key set in the environment
+	apiloader := &api.Apiloader{Translator: &i18n.Translator{}}
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "")
+	t.Setenv(helpers.EncryptionKeyFileEnvVar, "")
+	_, _, err = apiloader.LoadContainerServiceFromFile(filepath.Join(outdir, "apimodel.json"), false, false, nil)
+	g.Expect(err).To(HaveOccurred())
+	t.Setenv(helpers.EncryptionKeyFileEnvVar, keyPath)
+	loaded, _, err := apiloader.LoadContainerServiceFromFile(filepath.Join(outdir, "apimodel.json"), false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(loaded.Properties.CertificateProfile.CaPrivateKey).To(Equal(cs.Properties.CertificateProfile.CaPrivateKey))
+
+	var out bytes.Buffer
+	dc := &decryptCmd{encryptionKeyFile: keyPath}
+	g.Expect(dc.validate([]string{filepath.Join(outdir, "ca.key")})).To(Succeed())
+	g.Expect(dc.run([]string{filepath.Join(outdir, "ca.key")}, &out)).To(Succeed())
+	g.Expect(out.String()).To(Equal(cs.Properties.CertificateProfile.CaPrivateKey))
+	g.Expect(dc.run([]string{filepath.Join(outdir, "azuredeploy.json")}, &out)).To(HaveOccurred())
+
+	dc = &decryptCmd{encryptionKeyFile: keyPath, inPlace: true}
+	g.Expect(dc.validate([]string{outdir})).To(Succeed())
+	g.Expect(dc.run([]string{outdir}, &out)).To(Succeed())
+	b, err := os.ReadFile(filepath.Join(outdir, "ca.key"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(Equal(cs.Properties.CertificateProfile.CaPrivateKey))
+	encrypted, err = helpers.IsEncryptedFile(filepath.Join(outdir, "kubeconfig", "kubeconfig.eastus.json"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(encrypted).To(BeFalse())
+}
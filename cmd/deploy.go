@@ -36,6 +36,7 @@ const (
 type deployCmd struct {
 	authProvider
 	apimodelPath      string
+	apimodelPaths     []string
 	dnsPrefix         string
 	autoSuffix        bool
 	outputDirectory   string // can be auto-determined from clusterDefinition
@@ -44,11 +45,20 @@ type deployCmd struct {
 	caPrivateKeyPath  string
 	parametersOnly    bool
 	set               []string
+	setJSON           []string
+	patchFiles        []string
+	apimodelFormat    string
+	validationOutput  string
+	encryptOutput     bool
+	encryptionKeyFile string
 
 	// derived
 	containerService *api.ContainerService
 	apiVersion       string
 	locale           *gotext.Locale
+	encryptionKey    *helpers.EncryptionKey
+	// apimodelSourcePath is the --api-model file, apimodelPath is replaced by a temporary file when overlays, --set, --set-json or --patch-file are used
+	apimodelSourcePath string
 
 	client        armhelpers.AKSEngineClient
 	resourceGroup string
@@ -72,6 +82,7 @@ func newDeployCmd() *cobra.Command {
 			if err := dc.mergeAPIModel(); err != nil {
 				return errors.Wrap(err, "merging API model in deployCmd")
 			}
+			defer dc.removeMergedAPIModel()
 			if err := dc.loadAPIModel(); err != nil {
 				return errors.Wrap(err, "loading API model")
 			}
@@ -87,16 +98,21 @@ func newDeployCmd() *cobra.Command {
 	}
 
 	f := deployCmd.Flags()
-	f.StringVarP(&dc.apimodelPath, "api-model", "m", "", "path to your cluster definition file")
+	f.StringArrayVarP(&dc.apimodelPaths, "api-model", "m", []string{}, "path to your cluster definition file, repeat to deep-merge overlays into it in order")
 	f.StringVarP(&dc.dnsPrefix, "dns-prefix", "p", "", "dns prefix (unique name for the cluster)")
 	f.BoolVar(&dc.autoSuffix, "auto-suffix", false, "automatically append a compressed timestamp to the dnsPrefix to ensure cluster name uniqueness")
 	f.StringVarP(&dc.outputDirectory, "output-directory", "o", "", "output directory (derived from FQDN if absent)")
-	f.StringVar(&dc.caCertificatePath, "ca-certificate-path", "", "path to the CA certificate to use for Kubernetes PKI assets")
+	f.StringVar(&dc.caCertificatePath, "ca-certificate-path", "", "path to the CA certificate to use for Kubernetes PKI assets, followed by its chain if it is an intermediate CA")
 	f.StringVar(&dc.caPrivateKeyPath, "ca-private-key-path", "", "path to the CA private key to use for Kubernetes PKI assets")
 	f.StringVarP(&dc.resourceGroup, "resource-group", "g", "", "resource group to deploy to (will use the DNS prefix from the apimodel if not specified)")
 	f.StringVarP(&dc.location, "location", "l", "", "location to deploy to (required)")
 	f.BoolVarP(&dc.forceOverwrite, "force-overwrite", "f", false, "automatically overwrite existing files in the output directory")
 	f.StringArrayVar(&dc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	f.StringArrayVar(&dc.setJSON, "set-json", []string{}, "set JSON values on the command line (can specify multiple: key1='{\"a\":1}' --set-json key2='[1,2]')")
+	f.StringArrayVar(&dc.patchFiles, "patch-file", []string{}, "path to a JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7386) file to apply to the api model before the --set values (can specify multiple)")
+	addAPIModelFormatFlag(f, &dc.apimodelFormat)
+	addValidationOutputFlag(f, &dc.validationOutput)
+	addEncryptionFlags(f, &dc.encryptOutput, &dc.encryptionKeyFile)
 
 	addAuthFlags(dc.getAuthArgs(), f)
 
@@ -111,6 +127,9 @@ func (dc *deployCmd) validateArgs(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "loading translation files")
 	}
 
+	if len(dc.apimodelPaths) > 0 {
+		dc.apimodelPath = dc.apimodelPaths[0]
+	}
 	if dc.apimodelPath == "" {
 		if len(args) == 1 {
 			dc.apimodelPath = args[0]
@@ -121,8 +140,10 @@ func (dc *deployCmd) validateArgs(cmd *cobra.Command, args []string) error {
 	}
 
 	if dc.apimodelPath != "" {
-		if _, err := os.Stat(dc.apimodelPath); os.IsNotExist(err) {
-			return errors.Errorf("specified api model does not exist (%s)", dc.apimodelPath)
+		for _, apimodelPath := range append([]string{dc.apimodelPath}, dc.apimodelPaths...) {
+			if _, err := os.Stat(apimodelPath); os.IsNotExist(err) {
+				return errors.Errorf("specified api model does not exist (%s)", apimodelPath)
+			}
 		}
 	}
 
@@ -131,6 +152,10 @@ func (dc *deployCmd) validateArgs(cmd *cobra.Command, args []string) error {
 	}
 	dc.location = helpers.NormalizeAzureRegion(dc.location)
 
+	if dc.encryptionKey, err = loadEncryptionKey(dc.encryptOutput, dc.encryptionKeyFile); err != nil {
+		return errors.Wrap(err, "loading the encryption key")
+	}
+
 	return nil
 }
 
@@ -153,23 +178,32 @@ func (dc *deployCmd) mergeAPIModel() error {
 		dc.apimodelPath = f.Name()
 	}
 
-	// if --set flag has been used
-	if len(dc.set) > 0 {
-		m := make(map[string]transform.APIModelValue)
-		transform.MapValues(m, dc.set)
-
+	// if api model overlays, --set, --set-json or --patch-file flags have been used
+	layers := []string{dc.apimodelPath}
+	if len(dc.apimodelPaths) > 1 {
+		layers = append(layers, dc.apimodelPaths[1:]...)
+	}
+	merged, err := mergeAPIModelInputs(layers, dc.patchFiles, dc.setJSON, dc.set)
+	if err != nil {
+		return errors.Wrapf(err, "error merging the api model overlays, patch files and --set values with the api model: %s", dc.apimodelPath)
+	}
+	if merged != "" {
 		// overrides the api model and generates a new file
-		dc.apimodelPath, err = transform.MergeValuesWithAPIModel(dc.apimodelPath, m)
-		if err != nil {
-			return errors.Wrapf(err, "error merging --set values with the api model: %s", dc.apimodelPath)
-		}
-
+		dc.apimodelSourcePath = dc.apimodelPath
+		dc.apimodelPath = merged
 		log.Infoln(fmt.Sprintf("new API model file has been generated during merge: %s", dc.apimodelPath))
 	}
 
 	return nil
 }
 
+// removeMergedAPIModel removes the temporary file holding the decrypted merged API model, if any
+func (dc *deployCmd) removeMergedAPIModel() {
+	if dc.apimodelSourcePath != "" {
+		_ = os.Remove(dc.apimodelPath)
+	}
+}
+
 func (dc *deployCmd) loadAPIModel() error {
 	var caCertificateBytes []byte
 	var caKeyBytes []byte
@@ -180,6 +214,10 @@ func (dc *deployCmd) loadAPIModel() error {
 			Locale: dc.locale,
 		},
 	}
+	if dc.apimodelSourcePath != "" {
+		// the merged api model is a temporary file, its file: secret references are relative to the --api-model file
+		apiloader.APIModelDir = filepath.Dir(dc.apimodelSourcePath)
+	}
 
 	// do not validate when initially loading the apimodel, validation is done later after autofilling values
 	dc.containerService, dc.apiVersion, err = apiloader.LoadContainerServiceFromFile(dc.apimodelPath, false, false, nil)
@@ -204,11 +242,18 @@ func (dc *deployCmd) loadAPIModel() error {
 			return errors.Wrap(err, "failed to read CA private key file")
 		}
 
+		// the CA certificate file may hold the chain of an intermediate CA after the CA certificate
+		caCertificate, caCertificateChain, err := helpers.SplitCertificateChain(string(caCertificateBytes))
+		if err != nil {
+			return errors.Wrap(err, "failed to parse CA certificate file")
+		}
+
 		prop := dc.containerService.Properties
 		if prop.CertificateProfile == nil {
 			prop.CertificateProfile = &api.CertificateProfile{}
 		}
-		prop.CertificateProfile.CaCertificate = string(caCertificateBytes)
+		prop.CertificateProfile.CaCertificate = caCertificate
+		prop.CertificateProfile.CaCertificateChain = caCertificateChain
 		prop.CertificateProfile.CaPrivateKey = string(caKeyBytes)
 	}
 
@@ -334,7 +379,7 @@ func autofillApimodel(dc *deployCmd) error {
 		dc.containerService.Properties.LinuxProfile.SSH.PublicKeys = []api.PublicKey{{KeyData: publicKey}}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), dc.client.OperationTimeout())
 	defer cancel()
 	_, err := dc.client.EnsureResourceGroup(ctx, dc.resourceGroup, dc.location, nil)
 	if err != nil {
@@ -362,7 +407,7 @@ func autofillApimodel(dc *deployCmd) error {
 
 // validateAPIModelAsVLabs converts the ContainerService object to a vlabs ContainerService object and validates it
 func (dc *deployCmd) validateAPIModelAsVLabs() error {
-	return api.ConvertContainerServiceToVLabs(dc.containerService).Validate(false)
+	return validateAPIModelAsVLabs(dc.containerService, dc.validationOutput, os.Stdout)
 }
 
 func (dc *deployCmd) run() error {
@@ -405,11 +450,15 @@ func (dc *deployCmd) run() error {
 		return errors.Wrap(err, "pretty-printing template parameters")
 	}
 
-	writer := &engine.ArtifactWriter{
-		Translator: &i18n.Translator{
-			Locale: dc.locale,
-		},
+	apimodelSourcePath := dc.apimodelSourcePath
+	if apimodelSourcePath == "" {
+		apimodelSourcePath = dc.apimodelPath
+	}
+	writer, err := newArtifactWriter(&i18n.Translator{Locale: dc.locale}, dc.apimodelFormat, apimodelSourcePath)
+	if err != nil {
+		return errors.Wrap(err, "writing artifacts")
 	}
+	writer.EncryptionKey = dc.encryptionKey
 	if err = writer.WriteTLSArtifacts(dc.containerService, dc.apiVersion, template, parametersFile, dc.outputDirectory, certsgenerated, dc.parametersOnly); err != nil {
 		return errors.Wrap(err, "writing artifacts")
 	}
@@ -425,7 +474,7 @@ func (dc *deployCmd) run() error {
 		return err
 	}
 
-	cx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	cx, cancel := context.WithTimeout(context.Background(), dc.client.OperationTimeout())
 	defer cancel()
 
 	deploymentSuffix := dc.random.Int31()
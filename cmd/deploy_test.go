@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"testing"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
 	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers/fakearm"
 	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -98,7 +101,7 @@ func TestNewDeployCmd(t *testing.T) {
 		t.Fatalf("deploy command should have use %s equal %s, short %s equal %s and long %s equal to %s", command.Use, deployName, command.Short, deployShortDescription, command.Long, versionLongDescription)
 	}
 
-	expectedFlags := []string{"api-model", "dns-prefix", "auto-suffix", "output-directory", "ca-private-key-path", "resource-group", "location", "force-overwrite"}
+	expectedFlags := []string{"api-model", "dns-prefix", "auto-suffix", "output-directory", "ca-private-key-path", "resource-group", "location", "force-overwrite", "set", "set-json", "patch-file", "encrypt-output", "encryption-key-file"}
 	for _, f := range expectedFlags {
 		if command.Flags().Lookup(f) == nil {
 			t.Fatalf("deploy command should have flag %s", f)
@@ -524,6 +527,19 @@ func TestDeployCmdMergeAPIModel(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error calling mergeAPIModel with one --set flag to override an array property: %s", err.Error())
 	}
+
+	patch := filepath.Join(t.TempDir(), "patch.json")
+	if err = os.WriteFile(patch, []byte(`{"properties":{"masterProfile":{"count":3}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d = &deployCmd{}
+	d.apimodelPath = "../pkg/engine/testdata/simple/kubernetes.json"
+	d.patchFiles = []string{patch}
+	d.setJSON = []string{`linuxProfile.ssh.publicKeys[-]={"keyData":"ssh-rsa PUBLICKEY2"}`}
+	err = d.mergeAPIModel()
+	if err != nil {
+		t.Fatalf("unexpected error calling mergeAPIModel with --patch-file and --set-json flags: %s", err.Error())
+	}
 }
 
 func TestDeployCmdRun(t *testing.T) {
@@ -778,3 +794,73 @@ func makeTmpFile(t *testing.T, name string) (string, func()) {
 		defer os.Remove(tmpF.Name())
 	}
 }
+
+const fakeARMAPIModel = `{
+  "apiVersion": "vlabs",
+  "location": "local",
+  "properties": {
+    "orchestratorProfile": { "kubernetesConfig": { "useCloudControllerManager": true } },
+    "customCloudProfile": {
+      "identitySystem": "azure_ad",
+      "authenticationMethod": "client_secret",
+      "portalURL": "https://portal.local.azurestack.external/",
+      "environment": {
+        "name": "AzureStackCloud",
+        "serviceManagementEndpoint": "https://management.azurestack.onmicrosoft.com/36f71706-54df-4305-9847-5b038a4cf189",
+        "resourceManagerEndpoint": "https://management.local.azurestack.external/",
+        "activeDirectoryEndpoint": "https://login.windows.net/",
+        "graphEndpoint": "https://graph.windows.net/",
+        "storageEndpointSuffix": "local.azurestack.external",
+        "keyVaultDNSSuffix": "vault.local.azurestack.external",
+        "resourceManagerVMDNSSuffix": "cloudapp.azurestack.external"
+      }
+    },
+    "masterProfile": { "dnsPrefix": "fakearm", "distro": "aks-ubuntu-20.04", "count": 1, "vmSize": "Standard_D2_v2" },
+    "agentPoolProfiles": [
+      { "name": "linuxpool", "count": 2, "vmSize": "Standard_D2_v2", "distro": "aks-ubuntu-20.04", "availabilityProfile": "AvailabilitySet" }
+    ],
+    "linuxProfile": { "adminUsername": "azureuser", "ssh": { "publicKeys": [ { "keyData": "ssh-rsa publickey azure@linux" } ] } },
+    "servicePrincipalProfile": { "clientId": "b829b379-ca1f-4f1d-91a2-0d26b244680d", "secret": "secret" }
+  }
+}`
+
+// deployToFakeARM runs the deploy command against the ARM emulator and returns its state file and output directory
+func deployToFakeARM(t *testing.T) (string, string) {
+	t.Helper()
+	g := NewGomegaWithT(t)
+	t.Setenv("AZURE_ENVIRONMENT_FILEPATH", "")
+	t.Cleanup(func() { fakeARMStateFile = "" })
+
+	dir := t.TempDir()
+	apimodel := filepath.Join(dir, "kubernetes.json")
+	g.Expect(os.WriteFile(apimodel, []byte(fakeARMAPIModel), 0600)).To(Succeed())
+	stateFile := filepath.Join(dir, "arm-state.json")
+	outputDirectory := filepath.Join(dir, "_output")
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"deploy", "--fake-arm-state", stateFile, "--api-model", apimodel, "--location", "local",
+		"--resource-group", "fakearm-rg", "--output-directory", outputDirectory, "--auth-method", "client_secret",
+		"--subscription-id", fakearm.DefaultSubscriptionID, "--azure-env", "AzureStackCloud"})
+	g.Expect(root.Execute()).To(Succeed())
+	return stateFile, outputDirectory
+}
+
+func TestDeployCmdFakeARM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stateFile, outputDirectory := deployToFakeARM(t)
+	g.Expect(filepath.Join(outputDirectory, "apimodel.json")).To(BeAnExistingFile())
+
+	server, err := fakearm.NewServer(fakearm.WithStateFile(stateFile))
+	g.Expect(err).NotTo(HaveOccurred())
+	var names []string
+	for _, vm := range server.Resources("fakearm-rg", "Microsoft.Compute/virtualMachines") {
+		names = append(names, vm["name"].(string))
+	}
+	g.Expect(names).To(HaveLen(3))
+	g.Expect(names).To(ContainElement(HavePrefix("k8s-master-")))
+	g.Expect(names).To(ContainElement(HavePrefix("k8s-linuxpool-")))
+	// one OS disk per VM, plus the etcd data disk of the master
+	g.Expect(server.Resources("fakearm-rg", "Microsoft.Compute/disks")).To(HaveLen(4))
+	g.Expect(server.Resources("fakearm-rg", "Microsoft.Network/networkInterfaces")).To(HaveLen(3))
+}
@@ -4,9 +4,13 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"text/tabwriter"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
 	"github.com/Azure/aks-engine-azurestack/pkg/engine"
@@ -25,21 +29,33 @@ const (
 	generateName             = "generate"
 	generateShortDescription = "Generate an Azure Resource Manager template"
 	generateLongDescription  = "Generates an Azure Resource Manager template, parameters file and other assets for a cluster"
+	defaultsReportFilename   = "defaults-report.txt"
 )
 
 type generateCmd struct {
 	apimodelPath      string
+	apimodelPaths     []string
 	outputDirectory   string // can be auto-determined from clusterDefinition
 	caCertificatePath string
 	caPrivateKeyPath  string
 	noPrettyPrint     bool
 	parametersOnly    bool
 	set               []string
+	setJSON           []string
+	patchFiles        []string
+	apimodelFormat    string
+	validationOutput  string
+	explainDefaults   bool
+	encryptOutput     bool
+	encryptionKeyFile string
 
 	// derived
 	containerService *api.ContainerService
 	apiVersion       string
 	locale           *gotext.Locale
+	encryptionKey    *helpers.EncryptionKey
+	// apimodelSourcePath is the --api-model file, apimodelPath is replaced by a temporary file when overlays, --set, --set-json or --patch-file are used
+	apimodelSourcePath string
 
 	rawClientID string
 
@@ -62,6 +78,7 @@ func newGenerateCmd() *cobra.Command {
 			if err := gc.mergeAPIModel(); err != nil {
 				return errors.Wrap(err, "merging API model in generateCmd")
 			}
+			defer gc.removeMergedAPIModel()
 
 			if err := gc.loadAPIModel(); err != nil {
 				return errors.Wrap(err, "loading API model in generateCmd")
@@ -78,13 +95,19 @@ func newGenerateCmd() *cobra.Command {
 	}
 
 	f := generateCmd.Flags()
-	f.StringVarP(&gc.apimodelPath, "api-model", "m", "", "path to your cluster definition file")
+	f.StringArrayVarP(&gc.apimodelPaths, "api-model", "m", []string{}, "path to your cluster definition file, repeat to deep-merge overlays into it in order")
 	f.StringVarP(&gc.outputDirectory, "output-directory", "o", "", "output directory (derived from FQDN if absent)")
-	f.StringVar(&gc.caCertificatePath, "ca-certificate-path", "", "path to the CA certificate to use for Kubernetes PKI assets")
+	f.StringVar(&gc.caCertificatePath, "ca-certificate-path", "", "path to the CA certificate to use for Kubernetes PKI assets, followed by its chain if it is an intermediate CA")
 	f.StringVar(&gc.caPrivateKeyPath, "ca-private-key-path", "", "path to the CA private key to use for Kubernetes PKI assets")
 	f.StringArrayVar(&gc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	f.StringArrayVar(&gc.setJSON, "set-json", []string{}, "set JSON values on the command line (can specify multiple: key1='{\"a\":1}' --set-json key2='[1,2]')")
+	f.StringArrayVar(&gc.patchFiles, "patch-file", []string{}, "path to a JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7386) file to apply to the api model before the --set values (can specify multiple)")
+	addAPIModelFormatFlag(f, &gc.apimodelFormat)
+	addValidationOutputFlag(f, &gc.validationOutput)
+	addEncryptionFlags(f, &gc.encryptOutput, &gc.encryptionKeyFile)
 	f.BoolVar(&gc.noPrettyPrint, "no-pretty-print", false, "skip pretty printing the output")
 	f.BoolVar(&gc.parametersOnly, "parameters-only", false, "only output parameters files")
+	f.BoolVar(&gc.explainDefaults, "explain-defaults", false, "write defaults-report.txt to the output directory, explaining whether every value of the API model was set in the cluster definition or by the defaults, and which rule chose it")
 	f.StringVar(&gc.rawClientID, "client-id", "", "client id")
 	f.StringVar(&gc.ClientSecret, "client-secret", "", "client secret")
 	return generateCmd
@@ -98,6 +121,9 @@ func (gc *generateCmd) validate(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "error loading translation files")
 	}
 
+	if len(gc.apimodelPaths) > 0 {
+		gc.apimodelPath = gc.apimodelPaths[0]
+	}
 	if gc.apimodelPath == "" {
 		if len(args) == 1 {
 			gc.apimodelPath = args[0]
@@ -110,8 +136,14 @@ func (gc *generateCmd) validate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if _, err := os.Stat(gc.apimodelPath); os.IsNotExist(err) {
-		return errors.Errorf("specified api model does not exist (%s)", gc.apimodelPath)
+	for _, apimodelPath := range append([]string{gc.apimodelPath}, gc.apimodelPaths...) {
+		if _, err := os.Stat(apimodelPath); os.IsNotExist(err) {
+			return errors.Errorf("specified api model does not exist (%s)", apimodelPath)
+		}
+	}
+
+	if gc.encryptionKey, err = loadEncryptionKey(gc.encryptOutput, gc.encryptionKeyFile); err != nil {
+		return errors.Wrap(err, "loading the encryption key")
 	}
 
 	gc.ClientID, _ = uuid.Parse(gc.rawClientID)
@@ -120,24 +152,32 @@ func (gc *generateCmd) validate(cmd *cobra.Command, args []string) error {
 }
 
 func (gc *generateCmd) mergeAPIModel() error {
-	var err error
-	// if --set flag has been used
-	if len(gc.set) > 0 {
-		m := make(map[string]transform.APIModelValue)
-		transform.MapValues(m, gc.set)
-
+	// if api model overlays, --set, --set-json or --patch-file flags have been used
+	layers := []string{gc.apimodelPath}
+	if len(gc.apimodelPaths) > 1 {
+		layers = append(layers, gc.apimodelPaths[1:]...)
+	}
+	merged, err := mergeAPIModelInputs(layers, gc.patchFiles, gc.setJSON, gc.set)
+	if err != nil {
+		return errors.Wrap(err, "error merging the api model overlays, patch files and --set values")
+	}
+	if merged != "" {
 		// overrides the api model and generates a new file
-		gc.apimodelPath, err = transform.MergeValuesWithAPIModel(gc.apimodelPath, m)
-		if err != nil {
-			return errors.Wrap(err, "error merging --set values with the api model")
-		}
-
+		gc.apimodelSourcePath = gc.apimodelPath
+		gc.apimodelPath = merged
 		log.Infoln(fmt.Sprintf("new API model file has been generated during merge: %s", gc.apimodelPath))
 	}
 
 	return nil
 }
 
+// removeMergedAPIModel removes the temporary file holding the decrypted merged API model, if any
+func (gc *generateCmd) removeMergedAPIModel() {
+	if gc.apimodelSourcePath != "" {
+		_ = os.Remove(gc.apimodelPath)
+	}
+}
+
 func (gc *generateCmd) loadAPIModel() error {
 	var caCertificateBytes []byte
 	var caKeyBytes []byte
@@ -148,6 +188,10 @@ func (gc *generateCmd) loadAPIModel() error {
 			Locale: gc.locale,
 		},
 	}
+	if gc.apimodelSourcePath != "" {
+		// the merged api model is a temporary file, its file: secret references are relative to the --api-model file
+		apiloader.APIModelDir = filepath.Dir(gc.apimodelSourcePath)
+	}
 	gc.containerService, gc.apiVersion, err = apiloader.LoadContainerServiceFromFile(gc.apimodelPath, false, false, nil)
 	if err != nil {
 		return errors.Wrap(err, "error parsing the api model")
@@ -174,11 +218,18 @@ func (gc *generateCmd) loadAPIModel() error {
 			return errors.Wrap(err, "failed to read CA private key file")
 		}
 
+		// the CA certificate file may hold the chain of an intermediate CA after the CA certificate
+		caCertificate, caCertificateChain, err := helpers.SplitCertificateChain(string(caCertificateBytes))
+		if err != nil {
+			return errors.Wrap(err, "failed to parse CA certificate file")
+		}
+
 		prop := gc.containerService.Properties
 		if prop.CertificateProfile == nil {
 			prop.CertificateProfile = &api.CertificateProfile{}
 		}
-		prop.CertificateProfile.CaCertificate = string(caCertificateBytes)
+		prop.CertificateProfile.CaCertificate = caCertificate
+		prop.CertificateProfile.CaCertificateChain = caCertificateChain
 		prop.CertificateProfile.CaPrivateKey = string(caKeyBytes)
 	}
 
@@ -225,7 +276,31 @@ func (gc *generateCmd) autofillApimodel() error {
 
 // validateAPIModelAsVLabs converts the ContainerService object to a vlabs ContainerService object and validates it
 func (gc *generateCmd) validateAPIModelAsVLabs() error {
-	return api.ConvertContainerServiceToVLabs(gc.containerService).Validate(false)
+	return validateAPIModelAsVLabs(gc.containerService, gc.validationOutput, os.Stdout)
+}
+
+// explainAPIModelDefaults explains the values of the API model once its defaults are set with params
+func (gc *generateCmd) explainAPIModelDefaults(params api.PropertiesDefaultsParams) (api.DefaultsReport, error) {
+	apimodel, err := helpers.ReadDecryptedFile(gc.apimodelPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading API model file %s", gc.apimodelPath)
+	}
+	report, err := api.ExplainDefaults(apimodel, gc.containerService, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "explaining the API model defaults")
+	}
+	return report, nil
+}
+
+// writeDefaultsReport writes report as a table: the path of every value of the API model, the value,
+// whether it was set in the cluster definition, and the defaults that chose the other values
+func writeDefaultsReport(report api.DefaultsReport, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tVALUE\tSOURCE\tRULE")
+	for _, v := range report {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Path, v.Value, v.Source, v.Rule)
+	}
+	return w.Flush()
 }
 
 func (gc *generateCmd) run() error {
@@ -241,14 +316,21 @@ func (gc *generateCmd) run() error {
 		return errors.Wrap(err, "initializing template generator")
 	}
 
-	certsGenerated, err := gc.containerService.SetPropertiesDefaults(api.PropertiesDefaultsParams{
+	defaultsParams := api.PropertiesDefaultsParams{
 		IsScale:    false,
 		IsUpgrade:  false,
 		PkiKeySize: helpers.DefaultPkiKeySize,
-	})
+	}
+	certsGenerated, err := gc.containerService.SetPropertiesDefaults(defaultsParams)
 	if err != nil {
 		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", gc.apimodelPath)
 	}
+	var defaultsReport api.DefaultsReport
+	if gc.explainDefaults {
+		if defaultsReport, err = gc.explainAPIModelDefaults(defaultsParams); err != nil {
+			return err
+		}
+	}
 
 	//TODO remove these debug statements when we're new template generation implementation is enabled!
 	//bts, _ := json.Marshal(gc.containerService)
@@ -268,14 +350,36 @@ func (gc *generateCmd) run() error {
 		}
 	}
 
-	writer := &engine.ArtifactWriter{
-		Translator: &i18n.Translator{
-			Locale: gc.locale,
-		},
+	apimodelSourcePath := gc.apimodelSourcePath
+	if apimodelSourcePath == "" {
+		apimodelSourcePath = gc.apimodelPath
+	}
+	writer, err := newArtifactWriter(&i18n.Translator{Locale: gc.locale}, gc.apimodelFormat, apimodelSourcePath)
+	if err != nil {
+		return errors.Wrap(err, "writing artifacts")
 	}
+	writer.EncryptionKey = gc.encryptionKey
 	if err = writer.WriteTLSArtifacts(gc.containerService, gc.apiVersion, template, parameters, gc.outputDirectory, certsGenerated, gc.parametersOnly); err != nil {
 		return errors.Wrap(err, "writing artifacts")
 	}
 
+	if gc.explainDefaults {
+		var b bytes.Buffer
+		if err = writeDefaultsReport(defaultsReport, &b); err != nil {
+			return errors.Wrap(err, "rendering the explanation of the API model defaults")
+		}
+		report := b.Bytes()
+		if gc.encryptionKey != nil {
+			// the report has the secrets of the API model
+			if report, err = gc.encryptionKey.Encrypt(report); err != nil {
+				return errors.Wrap(err, "encrypting the explanation of the API model defaults")
+			}
+		}
+		reportPath := path.Join(gc.outputDirectory, defaultsReportFilename)
+		if err = os.WriteFile(reportPath, report, 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", reportPath)
+		}
+		log.Infof("The explanation of the API model defaults was written to %s", reportPath)
+	}
 	return nil
 }
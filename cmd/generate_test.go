@@ -4,13 +4,19 @@
 package cmd
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
 )
 
 func TestNewGenerateCmd(t *testing.T) {
@@ -21,7 +27,7 @@ func TestNewGenerateCmd(t *testing.T) {
 		t.Fatalf("generate command should have use %s equal %s, short %s equal %s and long %s equal to %s", command.Use, generateName, command.Short, generateShortDescription, command.Long, generateLongDescription)
 	}
 
-	expectedFlags := []string{"api-model", "output-directory", "ca-certificate-path", "ca-private-key-path", "set", "no-pretty-print", "parameters-only", "client-id", "client-secret"}
+	expectedFlags := []string{"api-model", "output-directory", "ca-certificate-path", "ca-private-key-path", "set", "set-json", "patch-file", "no-pretty-print", "parameters-only", "client-id", "client-secret", "validation-output", "explain-defaults", "encrypt-output", "encryption-key-file"}
 	for _, f := range expectedFlags {
 		if command.Flags().Lookup(f) == nil {
 			t.Fatalf("generate command should have flag %s", f)
@@ -141,6 +147,71 @@ func TestGenerateCmdMergeAPIModel(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "PatchFileAndSetJSONFlagSet",
+			test: func(t *testing.T) {
+				patch := filepath.Join(t.TempDir(), "patch.yaml")
+				if err := os.WriteFile(patch, []byte("- op: replace\n  path: /properties/masterProfile/count\n  value: 3\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				g := new(generateCmd)
+				g.apimodelPath = "../pkg/engine/testdata/simple/kubernetes.json"
+				g.patchFiles = []string{patch}
+				g.setJSON = []string{`agentPoolProfiles[-]={"name":"agentpool3","count":1,"vmSize":"Standard_D2_v2"}`}
+				g.set = []string{`agentPoolProfiles[2].kubernetesConfig.kubeletConfig["--max-pods"]=50`}
+				err := g.mergeAPIModel()
+				if err != nil {
+					t.Fatalf("unexpected error calling mergeAPIModel with --patch-file and --set-json flags: %s", err.Error())
+				}
+				b, err := os.ReadFile(g.apimodelPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, expected := range []string{`"count":3`, `{"count":1,"kubernetesConfig":{"kubeletConfig":{"--max-pods":"50"}},"name":"agentpool3","vmSize":"Standard_D2_v2"}`} {
+					if !strings.Contains(string(b), expected) {
+						t.Fatalf("expected the merged api model to contain %s, got %s", expected, b)
+					}
+				}
+			},
+		},
+		{
+			name: "OverlaysSet",
+			test: func(t *testing.T) {
+				overlay := filepath.Join(t.TempDir(), "stamp.yaml")
+				if err := os.WriteFile(overlay, []byte("properties:\n  agentPoolProfiles:\n  - name: agentpool2\n    count: 1\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				g := new(generateCmd)
+				g.apimodelPaths = []string{"../pkg/engine/testdata/simple/kubernetes.json", overlay}
+				if err := g.validate(&cobra.Command{}, nil); err != nil {
+					t.Fatalf("unexpected error validating api model overlays: %s", err.Error())
+				}
+				if err := g.mergeAPIModel(); err != nil {
+					t.Fatalf("unexpected error calling mergeAPIModel with api model overlays: %s", err.Error())
+				}
+				if g.apimodelSourcePath != "../pkg/engine/testdata/simple/kubernetes.json" {
+					t.Fatalf("expected the base api model to be the source api model, got %s", g.apimodelSourcePath)
+				}
+				b, err := os.ReadFile(g.apimodelPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(b), `{"availabilityProfile":"AvailabilitySet","count":1,"name":"agentpool2","vmSize":"Standard_D2_v2"}`) {
+					t.Fatalf("expected the agent pool to be merged by name, got %s", b)
+				}
+			},
+		},
+		{
+			name: "InvalidSetJSONFlagSet",
+			test: func(t *testing.T) {
+				g := new(generateCmd)
+				g.apimodelPath = "../pkg/engine/testdata/simple/kubernetes.json"
+				g.setJSON = []string{`masterProfile={"count":`}
+				if err := g.mergeAPIModel(); err == nil {
+					t.Fatal("expected an error calling mergeAPIModel with invalid --set-json JSON")
+				}
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -864,3 +935,48 @@ func TestExampleAPIModels(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteDefaultsReport(t *testing.T) {
+	report := api.DefaultsReport{
+		{Path: "location", Value: "local", Source: api.ValueSourceUser},
+		{Path: "properties.masterProfile.distro", Value: "aks-ubuntu-22.04", Source: api.ValueSourceDefault, Rule: "master profile defaults (Azure Stack-specific)"},
+	}
+	var out bytes.Buffer
+	if err := writeDefaultsReport(report, &out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "PATH                             VALUE             SOURCE   RULE\n" +
+		"location                         local             user     \n" +
+		"properties.masterProfile.distro  aks-ubuntu-22.04  default  master profile defaults (Azure Stack-specific)\n"
+	if out.String() != expected {
+		t.Errorf("expected report\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestGenerateCmdEncryptedAPIModel(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv(configProfileEnvVar, "")
+	t.Setenv(helpers.EncryptionKeyFileEnvVar, "")
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "")
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "output.key")
+	g.Expect(os.WriteFile(keyPath, bytes.Repeat([]byte{42}, 32), 0600)).To(Succeed())
+	encryptedDir := filepath.Join(dir, "encrypted")
+	root := NewRootCmd()
+	root.SetArgs([]string{"generate", "-m", "../pkg/engine/testdata/simple/kubernetes.json", "-o", encryptedDir, "--encrypt-output", "--encryption-key-file", keyPath})
+	g.Expect(root.Execute()).To(Succeed())
+	encrypted, err := helpers.IsEncryptedFile(filepath.Join(encryptedDir, "apimodel.json"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(encrypted).To(BeTrue())
+
+	// $AKSE_ENCRYPTION_KEY_FILE decrypts the API model, it is not the --encryption-key-file of the new output directory
+	t.Setenv(helpers.EncryptionKeyFileEnvVar, keyPath)
+	decryptedDir := filepath.Join(dir, "decrypted")
+	root = NewRootCmd()
+	root.SetArgs([]string{"generate", "-m", filepath.Join(encryptedDir, "apimodel.json"), "-o", decryptedDir})
+	g.Expect(root.Execute()).To(Succeed())
+	encrypted, err = helpers.IsEncryptedFile(filepath.Join(decryptedDir, "apimodel.json"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(encrypted).To(BeFalse())
+}
@@ -0,0 +1,459 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/ssh"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/Azure/aks-engine-azurestack/pkg/kubernetes"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	getCertsName             = "get-certs"
+	getCertsShortDescription = "Inspect the cluster certificates and their expiration dates."
+	getCertsLongDescription  = "Inspect the certificates of the API model and, if --ssh-host is set, the certificates on the cluster nodes. Reports when each certificate expires and flags the node certificates that differ from the API model."
+)
+
+const (
+	getCertsSourceAPIModel     = "apimodel"
+	getCertsFileMarker         = "### "
+	getCertsDefaultExpiryDays  = 30
+	getCertsKubeletPkiDir      = "/var/lib/kubelet/pki"
+	getCertsKubernetesCertsDir = "/etc/kubernetes/certs"
+)
+
+type getCertsCmd struct {
+	// user input
+	location               string
+	apiModelPath           string
+	sshHostURI             string
+	linuxSSHPrivateKeyPath string
+	controlPlaneOnly       bool
+	nodeNames              []string
+	output                 string
+	expiryThresholdDays    int
+	// computed
+	cs              *api.ContainerService
+	locale          *gotext.Locale
+	linuxAuthConfig *ssh.AuthConfig
+	jumpbox         *ssh.JumpBox
+	now             time.Time
+}
+
+// certificateReport describes a certificate of the API model or of a cluster node
+type certificateReport struct {
+	// Source is "apimodel" or the name of the node the certificate was read from
+	Source string `json:"source"`
+	// Name is the API model property or the node file path of the certificate
+	Name string `json:"name"`
+	*helpers.CertificateDetails
+	DaysRemaining int  `json:"daysRemaining"`
+	ExpiresSoon   bool `json:"expiresSoon"`
+	// Mismatch explains how a node certificate differs from the API model
+	Mismatch string `json:"mismatch,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// nodeCertificateFile is a certificate file of a node and the API model property it is created from,
+// property is empty if the certificate is created on the node
+type nodeCertificateFile struct {
+	path     string
+	property string
+	// optional files are not reported if missing
+	optional bool
+}
+
+func newGetCertsCmd() *cobra.Command {
+	gcc := getCertsCmd{}
+	command := &cobra.Command{
+		Use:   getCertsName,
+		Short: getCertsShortDescription,
+		Long:  getCertsLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := gcc.validateArgs(); err != nil {
+				return errors.Wrap(err, "validating get-certs args")
+			}
+			if err := gcc.loadAPIModel(); err != nil {
+				return errors.Wrap(err, "loading API model")
+			}
+			gcc.init()
+			cmd.SilenceUsage = true
+			return gcc.run(cmd.OutOrStdout())
+		},
+	}
+	command.Flags().StringVarP(&gcc.location, "location", "l", "", "Azure location where the cluster is deployed, defaults to the api-model location")
+	command.Flags().StringVarP(&gcc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file (required)")
+	command.Flags().StringVar(&gcc.sshHostURI, "ssh-host", "", "FQDN, or IP address, of an SSH listener that can reach all nodes in the cluster, only the api-model certificates are inspected if missing")
+	command.Flags().StringVar(&gcc.linuxSSHPrivateKeyPath, "linux-ssh-private-key", "", "path to a valid private SSH key to access the cluster's Linux nodes (required if --ssh-host is set)")
+	command.Flags().BoolVarP(&gcc.controlPlaneOnly, "control-plane-only", "", false, "inspect the certificates of the control plane VMs only")
+	command.Flags().StringSliceVar(&gcc.nodeNames, "vm-names", nil, "inspect the certificates of the VM name list only (comma-separated names)")
+	command.Flags().IntVar(&gcc.expiryThresholdDays, "expiry-threshold-days", getCertsDefaultExpiryDays, "flag the certificates that expire in less than this number of days")
+	command.Flags().StringVarP(&gcc.output, "output", "o", "human", fmt.Sprintf("Output format. Allowed values: %s", strings.Join(outputFormatOptions, ", ")))
+	_ = command.MarkFlagRequired("api-model")
+	return command
+}
+
+func (gcc *getCertsCmd) validateArgs() (err error) {
+	if gcc.locale, err = i18n.LoadTranslations(); err != nil {
+		return errors.Wrap(err, "loading translation files")
+	}
+	gcc.location = helpers.NormalizeAzureRegion(gcc.location)
+	if gcc.apiModelPath == "" {
+		return errors.New("--api-model must be specified")
+	} else if _, err := os.Stat(gcc.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified --api-model does not exist (%s)", gcc.apiModelPath)
+	}
+	if gcc.sshHostURI != "" {
+		if gcc.linuxSSHPrivateKeyPath == "" {
+			return errors.New("--linux-ssh-private-key must be specified")
+		} else if _, err := os.Stat(gcc.linuxSSHPrivateKeyPath); os.IsNotExist(err) {
+			return errors.Errorf("specified --linux-ssh-private-key does not exist (%s)", gcc.linuxSSHPrivateKeyPath)
+		}
+	} else if gcc.controlPlaneOnly || gcc.nodeNames != nil {
+		return errors.New("--ssh-host must be specified to inspect the node certificates")
+	}
+	if gcc.nodeNames != nil && len(gcc.nodeNames) == 0 {
+		return errors.New("--vm-names cannot be empty")
+	}
+	if gcc.nodeNames != nil && gcc.controlPlaneOnly {
+		return errors.New("--control-plane-only and --vm-names are mutually exclusive")
+	}
+	if gcc.expiryThresholdDays < 0 {
+		return errors.New("--expiry-threshold-days cannot be negative")
+	}
+	switch gcc.output {
+	case "human", "json":
+	default:
+		return errors.Errorf(`output format "%s" is not supported`, gcc.output)
+	}
+	return nil
+}
+
+func (gcc *getCertsCmd) loadAPIModel() (err error) {
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: gcc.locale,
+		},
+	}
+	if gcc.cs, _, err = apiloader.LoadContainerServiceFromFile(gcc.apiModelPath, false, false, nil); err != nil {
+		return errors.Wrap(err, "error parsing api-model")
+	}
+	if gcc.cs.Properties.CertificateProfile == nil {
+		return errors.New("the api-model has no certificateProfile")
+	}
+	if gcc.sshHostURI != "" && gcc.cs.Properties.IsCustomCloudProfile() {
+		if err = writeCustomCloudProfile(gcc.cs); err != nil {
+			return errors.Wrap(err, "error writing custom cloud profile")
+		}
+		if err = gcc.cs.Properties.SetCustomCloudSpec(api.AzureCustomCloudSpecParams{IsUpgrade: false, IsScale: true}); err != nil {
+			return errors.Wrap(err, "error parsing the api model")
+		}
+	}
+	if gcc.cs.Location == "" {
+		if gcc.location == "" && gcc.sshHostURI != "" {
+			return errors.New("--location must be specified")
+		}
+		gcc.cs.Location = gcc.location
+	} else if gcc.location != "" && gcc.cs.Location != gcc.location {
+		return errors.New("--location flag does not match api-model location")
+	}
+	return
+}
+
+func (gcc *getCertsCmd) init() {
+	gcc.now = time.Now()
+	if gcc.sshHostURI == "" {
+		return
+	}
+	gcc.linuxAuthConfig = &ssh.AuthConfig{
+		User:           gcc.cs.Properties.LinuxProfile.AdminUsername,
+		PrivateKeyPath: gcc.linuxSSHPrivateKeyPath,
+	}
+	gcc.jumpbox = &ssh.JumpBox{
+		URI: gcc.sshHostURI, Port: 22, OperatingSystem: api.Linux, AuthConfig: gcc.linuxAuthConfig}
+}
+
+func (gcc *getCertsCmd) run(out io.Writer) error {
+	reports := gcc.apimodelCertificates()
+	if gcc.sshHostURI != "" {
+		var kubeClient kubernetes.NodeLister
+		if gcc.nodeNames == nil && !gcc.controlPlaneOnly {
+			client, err := getKubeClient(gcc.cs, 10*time.Second, 10*time.Minute)
+			if err != nil {
+				log.Warnf("Error creating Kubernetes client: %s", err)
+			} else {
+				kubeClient = client
+			}
+		}
+		for _, node := range gcc.getNodes(kubeClient) {
+			reports = append(reports, gcc.nodeCertificates(node)...)
+		}
+	}
+
+	if gcc.output == "json" {
+		b, err := helpers.JSONMarshalIndent(reports, "", "  ", false)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+	return writeCertificateReports(reports, out)
+}
+
+// apimodelCertificates reports the certificates of the API model certificate profile
+func (gcc *getCertsCmd) apimodelCertificates() []certificateReport {
+	var reports []certificateReport
+	for _, c := range certificateProfileProperties(gcc.cs.Properties.CertificateProfile) {
+		if c.pem == "" {
+			continue
+		}
+		reports = append(reports, gcc.newCertificateReport(getCertsSourceAPIModel, c.name, c.pem))
+	}
+	return reports
+}
+
+// getNodes returns the Linux nodes to inspect,
+// Windows nodes are skipped as their certificates are not provisioned from the API model
+func (gcc *getCertsCmd) getNodes(kubeClient kubernetes.NodeLister) (nodes []*ssh.RemoteHost) {
+	newNode := func(name string) *ssh.RemoteHost {
+		return &ssh.RemoteHost{
+			URI: name, Port: 22, OperatingSystem: api.Linux, AuthConfig: gcc.linuxAuthConfig, Jumpbox: gcc.jumpbox}
+	}
+	if gcc.nodeNames != nil {
+		for _, name := range gcc.nodeNames {
+			if !strings.HasPrefix(name, api.DefaultOrchestratorName) {
+				log.Infof("Skipping node %s, only the certificates of Linux nodes are inspected", name)
+				continue
+			}
+			nodes = append(nodes, newNode(name))
+		}
+		return nodes
+	}
+	if kubeClient != nil {
+		nodeList, err := kubeClient.ListNodes()
+		if err == nil {
+			for _, node := range nodeList.Items {
+				if !strings.EqualFold(node.Status.NodeInfo.OperatingSystem, string(api.Linux)) {
+					log.Infof("Skipping node %s, only the certificates of Linux nodes are inspected", node.Name)
+					continue
+				}
+				nodes = append(nodes, newNode(node.Name))
+			}
+			return nodes
+		}
+		log.Warnf("Error retrieving node list from apiserver: %s", err)
+		log.Info("Inspecting the certificates of the control plane nodes only")
+	}
+	for _, name := range gcc.cs.Properties.GetMasterVMNameList() {
+		nodes = append(nodes, newNode(name))
+	}
+	return nodes
+}
+
+// nodeCertificates reads the certificate files of a node and compares them with the API model
+func (gcc *getCertsCmd) nodeCertificates(node *ssh.RemoteHost) []certificateReport {
+	log.Infof("Inspecting the certificates of node %s", node.URI)
+	files := gcc.nodeCertificateFiles(node.URI)
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	out, err := ssh.ExecuteRemote(ctx, node, readCertificateFilesScript(paths))
+	if err != nil {
+		log.Debugf("Remote command output: %s", out)
+		return []certificateReport{{Source: node.URI, Error: fmt.Sprintf("reading the certificate files: %s", err)}}
+	}
+	return gcc.compareNodeCertificates(node.URI, files, parseCertificateFiles(out))
+}
+
+// compareNodeCertificates reports the certificate files read from a node
+// and flags the ones which differ from the API model
+func (gcc *getCertsCmd) compareNodeCertificates(nodeName string, files []nodeCertificateFile, contents map[string]string) []certificateReport {
+	properties := map[string]string{}
+	for _, c := range certificateProfileProperties(gcc.cs.Properties.CertificateProfile) {
+		properties[c.name] = c.pem
+	}
+	var reports []certificateReport
+	for _, f := range files {
+		content, ok := contents[f.path]
+		if !ok {
+			if !f.optional {
+				reports = append(reports, certificateReport{Source: nodeName, Name: f.path, Mismatch: "the file is missing on the node"})
+			}
+			continue
+		}
+		r := gcc.newCertificateReport(nodeName, f.path, content)
+		if f.property != "" && r.CertificateDetails != nil {
+			if expected, err := helpers.GetCertificateDetails(properties[f.property]); err != nil {
+				r.Mismatch = fmt.Sprintf("the api-model property %s is not a valid certificate", f.property)
+			} else if expected.SHA256Fingerprint != r.SHA256Fingerprint {
+				r.Mismatch = fmt.Sprintf("differs from the api-model property %s", f.property)
+			}
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// nodeCertificateFiles returns the certificate files to inspect on a node
+func (gcc *getCertsCmd) nodeCertificateFiles(nodeName string) []nodeCertificateFile {
+	profile := gcc.cs.Properties.CertificateProfile
+	certsFile := func(name string) string {
+		return fmt.Sprintf("%s/%s", getCertsKubernetesCertsDir, name)
+	}
+	files := []nodeCertificateFile{
+		{path: certsFile("ca.crt"), property: "caCertificate"},
+		{path: certsFile("client.crt"), property: "clientCertificate"},
+		{path: certsFile("kubeletserver.crt")},
+	}
+	if isMasterNode(nodeName, gcc.cs.Properties.GetMasterVMPrefix()) {
+		files = append(files,
+			nodeCertificateFile{path: certsFile("apiserver.crt"), property: "apiServerCertificate"},
+			nodeCertificateFile{path: certsFile("etcdserver.crt"), property: "etcdServerCertificate"},
+			nodeCertificateFile{path: certsFile("etcdclient.crt"), property: "etcdClientCertificate"})
+		if index := masterNodeIndex(nodeName); index >= 0 {
+			peer := nodeCertificateFile{path: certsFile(fmt.Sprintf("etcdpeer%d.crt", index))}
+			if index < len(profile.EtcdPeerCertificates) {
+				peer.property = fmt.Sprintf("etcdPeerCertificates[%d]", index)
+			}
+			files = append(files, peer)
+		}
+		if profile.HasSeparateCAs() {
+			files = append(files,
+				nodeCertificateFile{path: certsFile("etcd-ca.crt"), property: "etcdCaCertificate"},
+				nodeCertificateFile{path: certsFile("proxy-ca.crt"), property: "frontProxyCaCertificate"},
+				nodeCertificateFile{path: certsFile("proxy.crt"), property: "frontProxyClientCertificate"})
+		} else {
+			files = append(files,
+				nodeCertificateFile{path: certsFile("proxy-ca.crt"), optional: true},
+				nodeCertificateFile{path: certsFile("proxy.crt"), optional: true})
+		}
+	}
+	return append(files,
+		nodeCertificateFile{path: getCertsKubeletPkiDir + "/kubelet-client-current.pem", optional: true},
+		nodeCertificateFile{path: getCertsKubeletPkiDir + "/kubelet-server-current.pem", optional: true})
+}
+
+func (gcc *getCertsCmd) newCertificateReport(source, name, content string) certificateReport {
+	r := certificateReport{Source: source, Name: name}
+	details, err := helpers.GetCertificateDetails(content)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.CertificateDetails = details
+	r.DaysRemaining = int(math.Floor(details.NotAfter.Sub(gcc.now).Hours() / 24))
+	r.ExpiresSoon = details.NotAfter.Before(gcc.now.AddDate(0, 0, gcc.expiryThresholdDays))
+	return r
+}
+
+type certificateProperty struct {
+	name string
+	pem  string
+}
+
+// certificateProfileProperties returns the certificates of the certificate profile keyed by their JSON property name
+func certificateProfileProperties(p *api.CertificateProfile) []certificateProperty {
+	properties := []certificateProperty{
+		{"caCertificate", p.CaCertificate},
+		{"apiServerCertificate", p.APIServerCertificate},
+		{"clientCertificate", p.ClientCertificate},
+		{"kubeConfigCertificate", p.KubeConfigCertificate},
+		{"etcdServerCertificate", p.EtcdServerCertificate},
+		{"etcdClientCertificate", p.EtcdClientCertificate},
+	}
+	for i, c := range p.EtcdPeerCertificates {
+		properties = append(properties, certificateProperty{fmt.Sprintf("etcdPeerCertificates[%d]", i), c})
+	}
+	return append(properties,
+		certificateProperty{"etcdCaCertificate", p.EtcdCaCertificate},
+		certificateProperty{"frontProxyCaCertificate", p.FrontProxyCaCertificate},
+		certificateProperty{"frontProxyClientCertificate", p.FrontProxyClientCertificate})
+}
+
+var masterNodeIndexRegexp = regexp.MustCompile(`(\d+)$`)
+
+// masterNodeIndex returns the index of a control plane VM, or -1 if the name does not end with one
+func masterNodeIndex(nodeName string) int {
+	m := masterNodeIndexRegexp.FindString(nodeName)
+	if m == "" {
+		return -1
+	}
+	var index int
+	if _, err := fmt.Sscanf(m, "%d", &index); err != nil {
+		return -1
+	}
+	return index
+}
+
+// readCertificateFilesScript prints each existing file after a marker line with its path
+func readCertificateFilesScript(paths []string) string {
+	return fmt.Sprintf(`sudo bash -c 'for f in %s; do if [ -f "$f" ]; then echo "%s$f"; cat "$f"; echo; fi; done'`,
+		strings.Join(paths, " "), getCertsFileMarker)
+}
+
+// parseCertificateFiles maps the file paths printed by readCertificateFilesScript with their content
+func parseCertificateFiles(out string) map[string]string {
+	contents := map[string]string{}
+	var current string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, getCertsFileMarker) {
+			current = strings.TrimSpace(strings.TrimPrefix(line, getCertsFileMarker))
+			contents[current] = ""
+			continue
+		}
+		if current != "" {
+			contents[current] += line + "\n"
+		}
+	}
+	return contents
+}
+
+// writeCertificateReports writes a table of the certificate reports
+func writeCertificateReports(reports []certificateReport, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tNAME\tSUBJECT\tSANS\tISSUER\tSERIAL\tNOT AFTER\tDAYS LEFT\tSTATUS")
+	for _, r := range reports {
+		if r.CertificateDetails == nil {
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t\t\t\t%s\n", r.Source, r.Name, r.status())
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", r.Source, r.Name, r.Subject, strings.Join(r.SANs, ","),
+			r.Issuer, r.SerialNumber, r.NotAfter.Format(time.RFC3339), r.DaysRemaining, r.status())
+	}
+	return w.Flush()
+}
+
+func (r certificateReport) status() string {
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("ERROR: %s", r.Error)
+	case r.Mismatch != "":
+		return fmt.Sprintf("MISMATCH: %s", r.Mismatch)
+	case r.DaysRemaining < 0:
+		return "EXPIRED"
+	case r.ExpiresSoon:
+		return "EXPIRES SOON"
+	}
+	return "OK"
+}
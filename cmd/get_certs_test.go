@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/kubernetes"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestGetCertsCmd(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	command := newGetCertsCmd()
+	g.Expect(command.Use).Should(Equal(getCertsName))
+	g.Expect(command.Short).Should(Equal(getCertsShortDescription))
+	g.Expect(command.Long).Should(Equal(getCertsLongDescription))
+	g.Expect(command.Flags().Lookup("expiry-threshold-days").DefValue).Should(Equal("30"))
+
+	command.SetArgs([]string{})
+	err := command.Execute()
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetCertsCmdValidateArgs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	existingFile := "../examples/kubernetes.json"
+	missingFile := "./random/file"
+
+	cases := []struct {
+		gcc         *getCertsCmd
+		expectedErr error
+		name        string
+	}{
+		{
+			gcc:         &getCertsCmd{apiModelPath: missingFile, output: "human"},
+			expectedErr: errors.Errorf("specified --api-model does not exist (%s)", missingFile),
+			name:        "BadAPIModel",
+		},
+		{
+			gcc:         &getCertsCmd{apiModelPath: existingFile, sshHostURI: "server.example.com", output: "human"},
+			expectedErr: errors.New("--linux-ssh-private-key must be specified"),
+			name:        "NeedsLinuxSSHPrivateKey",
+		},
+		{
+			gcc:         &getCertsCmd{apiModelPath: existingFile, controlPlaneOnly: true, output: "human"},
+			expectedErr: errors.New("--ssh-host must be specified to inspect the node certificates"),
+			name:        "NeedsSSHHost",
+		},
+		{
+			gcc: &getCertsCmd{apiModelPath: existingFile, sshHostURI: "server.example.com", linuxSSHPrivateKeyPath: existingFile,
+				nodeNames: []string{"vm1"}, controlPlaneOnly: true, output: "human"},
+			expectedErr: errors.New("--control-plane-only and --vm-names are mutually exclusive"),
+			name:        "ControlPlane+VMNames",
+		},
+		{
+			gcc:         &getCertsCmd{apiModelPath: existingFile, expiryThresholdDays: -1, output: "human"},
+			expectedErr: errors.New("--expiry-threshold-days cannot be negative"),
+			name:        "NegativeThreshold",
+		},
+		{
+			gcc:         &getCertsCmd{apiModelPath: existingFile, output: "yaml"},
+			expectedErr: errors.New(`output format "yaml" is not supported`),
+			name:        "BadOutput",
+		},
+		{
+			gcc:         &getCertsCmd{apiModelPath: existingFile, output: "json"},
+			expectedErr: nil,
+			name:        "APIModelOnly",
+		},
+		{
+			gcc: &getCertsCmd{apiModelPath: existingFile, sshHostURI: "server.example.com", linuxSSHPrivateKeyPath: existingFile,
+				nodeNames: []string{"vm1"}, output: "human"},
+			expectedErr: nil,
+			name:        "IsValid",
+		},
+	}
+	for _, tc := range cases {
+		c := tc
+		t.Run(c.name, func(t *testing.T) {
+			err := c.gcc.validateArgs()
+			if c.expectedErr != nil {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestGetCertsGetNodes(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	gcc := &getCertsCmd{cs: api.CreateMockContainerService("test", "", 1, 1, false)}
+	master := gcc.cs.Properties.GetMasterVMNameList()[0]
+	nodeNames := func(kubeClient kubernetes.NodeLister) []string {
+		var names []string
+		for _, n := range gcc.getNodes(kubeClient) {
+			names = append(names, n.URI)
+		}
+		return names
+	}
+
+	nodeList := []string{master, "k8s-agentpool1-22998975-0", "windows10"}
+	g.Expect(nodeNames(&mockNodeLister{nodeNameList: nodeList})).To(Equal([]string{master, "k8s-agentpool1-22998975-0"}))
+	g.Expect(nodeNames(&mockNodeLister{nodeNameList: nodeList, failListNodes: true})).To(Equal([]string{master}))
+	g.Expect(nodeNames(nil)).To(Equal([]string{master}))
+
+	gcc.nodeNames = []string{"k8s-agentpool1-22998975-1", "windows10"}
+	g.Expect(nodeNames(nil)).To(Equal([]string{"k8s-agentpool1-22998975-1"}))
+}
+
+func TestGetCertsCompareNodeCertificates(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	newPair := func(commonName string) *helpers.PkiKeyCertPair {
+		pair, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{CommonName: commonName, PkiKeyAlgorithm: helpers.PkiKeyAlgorithmECDSAP256})
+		g.Expect(err).ToNot(HaveOccurred())
+		return pair
+	}
+	ca, apiServer, client, otherAPIServer := newPair("ca"), newPair("apiserver"), newPair("client"), newPair("apiserver")
+
+	gcc := &getCertsCmd{cs: api.CreateMockContainerService("test", "", 1, 1, false), now: time.Now(), expiryThresholdDays: 30}
+	gcc.cs.Properties.CertificateProfile = &api.CertificateProfile{
+		CaCertificate:        ca.CertificatePem,
+		APIServerCertificate: apiServer.CertificatePem,
+		ClientCertificate:    client.CertificatePem,
+		EtcdPeerCertificates: []string{apiServer.CertificatePem},
+	}
+	master := gcc.cs.Properties.GetMasterVMNameList()[0]
+
+	out := strings.Join([]string{
+		"sudo: unable to resolve host",
+		getCertsFileMarker + "/etc/kubernetes/certs/ca.crt", ca.CertificatePem,
+		getCertsFileMarker + "/etc/kubernetes/certs/apiserver.crt", otherAPIServer.CertificatePem,
+		getCertsFileMarker + "/etc/kubernetes/certs/kubeletserver.crt", newPair(master).CertificatePem,
+	}, "\n")
+	contents := parseCertificateFiles(out)
+	g.Expect(contents).To(HaveLen(3))
+	g.Expect(contents["/etc/kubernetes/certs/ca.crt"]).To(ContainSubstring(strings.TrimSpace(ca.CertificatePem)))
+
+	files := gcc.nodeCertificateFiles(master)
+	g.Expect(files).To(ContainElement(nodeCertificateFile{path: "/etc/kubernetes/certs/etcdpeer0.crt", property: "etcdPeerCertificates[0]"}))
+	g.Expect(files).ToNot(ContainElement(nodeCertificateFile{path: "/etc/kubernetes/certs/etcd-ca.crt", property: "etcdCaCertificate"}))
+
+	reports := map[string]certificateReport{}
+	for _, r := range gcc.compareNodeCertificates(master, files, contents) {
+		g.Expect(r.Source).To(Equal(master))
+		reports[r.Name] = r
+	}
+	g.Expect(reports["/etc/kubernetes/certs/ca.crt"].status()).To(Equal("OK"))
+	g.Expect(reports["/etc/kubernetes/certs/ca.crt"].Subject).To(Equal("CN=ca"))
+	g.Expect(reports["/etc/kubernetes/certs/apiserver.crt"].Mismatch).To(Equal("differs from the api-model property apiServerCertificate"))
+	g.Expect(reports["/etc/kubernetes/certs/client.crt"].Mismatch).To(Equal("the file is missing on the node"))
+	g.Expect(reports["/etc/kubernetes/certs/kubeletserver.crt"].status()).To(Equal("OK"))
+	g.Expect(reports).ToNot(HaveKey("/etc/kubernetes/certs/proxy.crt"))
+	g.Expect(reports).ToNot(HaveKey(getCertsKubeletPkiDir + "/kubelet-server-current.pem"))
+
+	agentFiles := gcc.nodeCertificateFiles("k8s-agentpool1-22998975-0")
+	g.Expect(agentFiles).ToNot(ContainElement(nodeCertificateFile{path: "/etc/kubernetes/certs/apiserver.crt", property: "apiServerCertificate"}))
+}
+
+func TestGetCertsRun(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	expiring, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{
+		CommonName:      "ca",
+		PkiKeyAlgorithm: helpers.PkiKeyAlgorithmECDSAP256,
+		Settings:        helpers.PkiCertificateSettings{ValidityDuration: 10 * 24 * time.Hour},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	valid, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{CommonName: "apiserver", PkiKeyAlgorithm: helpers.PkiKeyAlgorithmECDSAP256})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	gcc := &getCertsCmd{cs: api.CreateMockContainerService("test", "", 1, 1, false), now: time.Now(), expiryThresholdDays: 30, output: "json"}
+	gcc.cs.Properties.CertificateProfile = &api.CertificateProfile{
+		CaCertificate:        expiring.CertificatePem,
+		APIServerCertificate: valid.CertificatePem,
+		ClientCertificate:    "invalid",
+	}
+
+	out := &bytes.Buffer{}
+	g.Expect(gcc.run(out)).To(Succeed())
+	var reports []map[string]interface{}
+	g.Expect(json.Unmarshal(out.Bytes(), &reports)).To(Succeed())
+	g.Expect(reports).To(HaveLen(3))
+	g.Expect(reports[0]["name"]).To(Equal("caCertificate"))
+	g.Expect(reports[0]["source"]).To(Equal(getCertsSourceAPIModel))
+	g.Expect(reports[0]["expiresSoon"]).To(BeTrue())
+	g.Expect(reports[0]["daysRemaining"]).To(BeNumerically("==", 9))
+	g.Expect(reports[1]["expiresSoon"]).To(BeFalse())
+	g.Expect(reports[1]["subject"]).To(Equal("CN=apiserver"))
+	g.Expect(reports[2]["error"]).ToNot(BeEmpty())
+
+	gcc.output = "human"
+	out.Reset()
+	g.Expect(gcc.run(out)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("EXPIRES SOON"))
+	g.Expect(out.String()).To(ContainSubstring("ERROR: "))
+}
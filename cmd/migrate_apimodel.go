@@ -0,0 +1,163 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/engine/transform"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	migrateAPIModelName             = "migrate-apimodel"
+	migrateAPIModelShortDescription = "Migrate the deprecated fields, distros and addons of an API model"
+	migrateAPIModelLongDescription  = "Rewrite the deprecated fields, distros and addons of an API model to their current equivalents and print every change. " +
+		"The changes that require replacing the nodes, such as moving off an End of Life distro, are skipped unless --allow-node-replacement is set."
+)
+
+type migrateAPIModelCmd struct {
+	apimodelPath         string
+	outputFile           string
+	dryRun               bool
+	allowNodeReplacement bool
+}
+
+func newMigrateAPIModelCmd() *cobra.Command {
+	mc := migrateAPIModelCmd{}
+
+	command := &cobra.Command{
+		Use:   migrateAPIModelName,
+		Short: migrateAPIModelShortDescription,
+		Long:  migrateAPIModelLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := mc.validate(); err != nil {
+				return errors.Wrap(err, "validating migrateAPIModelCmd")
+			}
+			return mc.run(cmd.OutOrStdout())
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&mc.apimodelPath, "api-model", "m", "", "path to the API model to migrate, e.g. _output/<dnsPrefix>/apimodel.json")
+	f.StringVarP(&mc.outputFile, "output", "o", "", "file the migrated API model is written to (defaults to --api-model)")
+	f.BoolVar(&mc.dryRun, "dry-run", false, "print the changes without writing the migrated API model")
+	f.BoolVar(&mc.allowNodeReplacement, "allow-node-replacement", false, "also make the changes that require replacing the nodes of the cluster")
+	_ = command.MarkFlagRequired("api-model")
+
+	return command
+}
+
+func (mc *migrateAPIModelCmd) validate() error {
+	if mc.apimodelPath == "" {
+		return errors.New("--api-model must be specified")
+	}
+	if _, err := os.Stat(mc.apimodelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", mc.apimodelPath)
+	}
+	if mc.outputFile == "" {
+		mc.outputFile = mc.apimodelPath
+	}
+	return nil
+}
+
+func (mc *migrateAPIModelCmd) run(out io.Writer) error {
+	original, err := helpers.ReadDecryptedFile(mc.apimodelPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading API model file %s", mc.apimodelPath)
+	}
+	migrated, changes, err := transform.MigrateAPIModel(original, mc.allowNodeReplacement)
+	if err != nil {
+		return errors.Wrap(err, "migrating the API model")
+	}
+	if err = writeAPIModelChanges(changes, out); err != nil {
+		return err
+	}
+	skipped := 0
+	for _, c := range changes {
+		if c.Skipped {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		log.Warnf("%d change(s) require replacing the nodes and were skipped, set --allow-node-replacement to make them", skipped)
+	}
+	if len(changes) == skipped || mc.dryRun {
+		return nil
+	}
+
+	locale, err := i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+	translator := &i18n.Translator{Locale: locale}
+	apiloader := &api.Apiloader{Translator: translator}
+	if _, _, err = apiloader.DeserializeContainerService(migrated, false, true, nil); err != nil {
+		return errors.Wrap(err, "loading the migrated API model")
+	}
+
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(migrated))
+	d.UseNumber()
+	if err = d.Decode(&doc); err != nil {
+		return errors.Wrap(err, "parsing the migrated API model")
+	}
+	b, err := helpers.JSONMarshalIndent(doc, "", "  ", false)
+	if err != nil {
+		return errors.Wrap(err, "serializing the migrated API model")
+	}
+	if api.IsYAMLFile(mc.outputFile) {
+		// the comments of a YAML API model are preserved where possible
+		var comments []byte
+		if api.IsYAMLFile(mc.apimodelPath) {
+			comments = original
+		}
+		if b, err = api.ConvertJSONToYAML(b, comments); err != nil {
+			return err
+		}
+	}
+	f := helpers.FileSaver{Translator: translator}
+	if err = f.SaveFile(filepath.Dir(mc.outputFile), filepath.Base(mc.outputFile), b); err != nil {
+		return errors.Wrapf(err, "writing the migrated API model to %s", mc.outputFile)
+	}
+	log.Infof("migrated API model written to %s", mc.outputFile)
+	return nil
+}
+
+// writeAPIModelChanges writes changes as a table: the path of every changed value,
+// the value before and after the change and why it was changed or skipped
+func writeAPIModelChanges(changes []transform.APIModelChange, out io.Writer) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(out, "The API model has no deprecated value to migrate")
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tFROM\tTO\tDESCRIPTION")
+	for _, c := range changes {
+		from, to := c.From, c.To
+		if from == "" {
+			from = "(unset)"
+		}
+		if to == "" {
+			to = "(removed)"
+		}
+		description := c.Description
+		if c.Skipped {
+			description = "SKIPPED, requires node replacement: " + description
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Path, from, to, description)
+	}
+	return w.Flush()
+}
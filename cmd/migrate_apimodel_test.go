@@ -0,0 +1,98 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMigrateAPIModelCmd_ShouldCreate(t *testing.T) {
+	command := newMigrateAPIModelCmd()
+
+	g := NewGomegaWithT(t)
+	g.Expect(command.Use).Should(Equal(migrateAPIModelName))
+	g.Expect(command.Short).Should(Equal(migrateAPIModelShortDescription))
+	g.Expect(command.Long).Should(Equal(migrateAPIModelLongDescription))
+	for _, f := range []string{"api-model", "output", "dry-run", "allow-node-replacement"} {
+		g.Expect(command.Flags().Lookup(f)).NotTo(BeNil(), f)
+	}
+}
+
+func TestMigrateAPIModelCmd_Run(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	apimodel := `apiVersion: vlabs
+properties:
+  orchestratorProfile:
+    kubernetesConfig:
+      dockerEngineVersion: 17.05.* # unused
+  masterProfile:
+    count: 1
+    dnsPrefix: migrate
+    vmSize: Standard_D2_v2
+    distro: aks-1804 # the master distro
+  agentPoolProfiles:
+  - name: linuxpool
+    count: 3
+    vmSize: Standard_D2_v2
+    distro: ubuntu
+`
+	path := filepath.Join(t.TempDir(), "kubernetes.yaml")
+	g.Expect(os.WriteFile(path, []byte(apimodel), 0600)).To(Succeed())
+
+	var out bytes.Buffer
+	mc := &migrateAPIModelCmd{apimodelPath: path, dryRun: true}
+	g.Expect(mc.validate()).To(Succeed())
+	g.Expect(mc.outputFile).To(Equal(path))
+	g.Expect(mc.run(&out)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("properties.masterProfile.distro"))
+	g.Expect(out.String()).To(ContainSubstring("SKIPPED, requires node replacement"))
+	b, err := os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(Equal(apimodel))
+
+	out.Reset()
+	mc = &migrateAPIModelCmd{apimodelPath: path}
+	g.Expect(mc.validate()).To(Succeed())
+	g.Expect(mc.run(&out)).To(Succeed())
+	b, err = os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(Equal(`apiVersion: vlabs
+properties:
+  agentPoolProfiles:
+    - count: 3
+      distro: ubuntu
+      name: linuxpool
+      vmSize: Standard_D2_v2
+  masterProfile:
+    count: 1
+    distro: aks-ubuntu-18.04 # the master distro
+    dnsPrefix: migrate
+    vmSize: Standard_D2_v2
+  orchestratorProfile:
+    kubernetesConfig: {}
+`))
+
+	out.Reset()
+	output := filepath.Join(t.TempDir(), "apimodel.json")
+	mc = &migrateAPIModelCmd{apimodelPath: path, outputFile: output, allowNodeReplacement: true}
+	g.Expect(mc.validate()).To(Succeed())
+	g.Expect(mc.run(&out)).To(Succeed())
+	g.Expect(out.String()).NotTo(ContainSubstring("SKIPPED"))
+	b, err = os.ReadFile(output)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring(`"distro": "ubuntu-18.04"`))
+
+	out.Reset()
+	mc = &migrateAPIModelCmd{apimodelPath: output}
+	g.Expect(mc.validate()).To(Succeed())
+	g.Expect(mc.run(&out)).To(Succeed())
+	g.Expect(out.String()).To(Equal("The API model has no deprecated value to migrate\n"))
+}
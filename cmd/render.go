@@ -0,0 +1,131 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/engine"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	renderName             = "render"
+	renderShortDescription = "Print the cluster definition merged from a base and its overlays"
+	renderLongDescription  = "Deep-merge the cluster definition overlays into the base cluster definition in order, " +
+		"apply the patch files and the --set values, and print the cluster definition generate and deploy would use."
+)
+
+type renderCmd struct {
+	apimodelPaths  []string
+	set            []string
+	setJSON        []string
+	patchFiles     []string
+	apimodelFormat string
+	outputFile     string
+}
+
+func newRenderCmd() *cobra.Command {
+	rc := renderCmd{}
+
+	command := &cobra.Command{
+		Use:   renderName,
+		Short: renderShortDescription,
+		Long:  renderLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := rc.validate(); err != nil {
+				return errors.Wrap(err, "validating renderCmd")
+			}
+			return rc.run(cmd.OutOrStdout())
+		},
+	}
+
+	f := command.Flags()
+	f.StringArrayVarP(&rc.apimodelPaths, "api-model", "m", []string{}, "path to your cluster definition file, repeat to deep-merge overlays into it in order")
+	f.StringArrayVar(&rc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	f.StringArrayVar(&rc.setJSON, "set-json", []string{}, "set JSON values on the command line (can specify multiple: key1='{\"a\":1}' --set-json key2='[1,2]')")
+	f.StringArrayVar(&rc.patchFiles, "patch-file", []string{}, "path to a JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7386) file to apply to the api model before the --set values (can specify multiple)")
+	addAPIModelFormatFlag(f, &rc.apimodelFormat)
+	f.StringVarP(&rc.outputFile, "output", "o", "", "file the merged cluster definition is written to (defaults to standard output)")
+	_ = command.MarkFlagRequired("api-model")
+
+	return command
+}
+
+func (rc *renderCmd) validate() error {
+	if len(rc.apimodelPaths) == 0 {
+		return errors.New("--api-model must be specified")
+	}
+	for _, apimodelPath := range rc.apimodelPaths {
+		if _, err := os.Stat(apimodelPath); os.IsNotExist(err) {
+			return errors.Errorf("specified api model does not exist (%s)", apimodelPath)
+		}
+	}
+	return nil
+}
+
+func (rc *renderCmd) run(out io.Writer) error {
+	apimodelPath, err := mergeAPIModelInputs(rc.apimodelPaths, rc.patchFiles, rc.setJSON, rc.set)
+	if err != nil {
+		return errors.Wrap(err, "error merging the api model overlays, patch files and --set values")
+	}
+	if apimodelPath == "" {
+		apimodelPath = rc.apimodelPaths[0]
+	} else {
+		defer os.Remove(apimodelPath)
+	}
+	contents, err := helpers.ReadDecryptedFile(apimodelPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading API model file %s", apimodelPath)
+	}
+	if contents, err = api.ConvertYAMLToJSON(contents); err != nil {
+		return err
+	}
+
+	locale, err := i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+	translator := &i18n.Translator{Locale: locale}
+	apiloader := &api.Apiloader{Translator: translator}
+	if _, _, err = apiloader.DeserializeContainerService(contents, false, false, nil); err != nil {
+		return errors.Wrap(err, "loading the merged API model")
+	}
+
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(contents))
+	d.UseNumber()
+	if err = d.Decode(&doc); err != nil {
+		return errors.Wrap(err, "parsing the merged API model")
+	}
+	b, err := helpers.JSONMarshalIndent(doc, "", "  ", false)
+	if err != nil {
+		return errors.Wrap(err, "serializing the merged API model")
+	}
+	w, err := newArtifactWriter(translator, rc.apimodelFormat, rc.apimodelPaths[0])
+	if err != nil {
+		return err
+	}
+	if w.APIModelFormat == engine.APIModelFormatYAML {
+		if b, err = api.ConvertJSONToYAML(b, w.OriginalAPIModel); err != nil {
+			return err
+		}
+	}
+
+	if rc.outputFile == "" {
+		_, err = out.Write(b)
+		return err
+	}
+	if err = os.WriteFile(rc.outputFile, b, 0600); err != nil {
+		return errors.Wrapf(err, "writing the merged API model to %s", rc.outputFile)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRenderCmd_ShouldCreate(t *testing.T) {
+	command := newRenderCmd()
+
+	g := NewGomegaWithT(t)
+	g.Expect(command.Use).Should(Equal(renderName))
+	g.Expect(command.Short).Should(Equal(renderShortDescription))
+	g.Expect(command.Long).Should(Equal(renderLongDescription))
+	for _, f := range []string{"api-model", "set", "set-json", "patch-file", "apimodel-format", "output"} {
+		g.Expect(command.Flags().Lookup(f)).NotTo(BeNil(), f)
+	}
+}
+
+func TestRenderCmd_Run(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	g.Expect(os.WriteFile(base, []byte(`apiVersion: vlabs
+location: local
+properties:
+  masterProfile:
+    count: 1 # a single master
+    dnsPrefix: base
+    vmSize: Standard_D2_v2
+  agentPoolProfiles:
+  - name: linuxpool
+    count: 3
+    vmSize: Standard_D2_v2
+`), 0600)).To(Succeed())
+	stamp := filepath.Join(dir, "stamp3.json")
+	g.Expect(os.WriteFile(stamp, []byte(`{"location":"stamp3","properties":{"agentPoolProfiles":[{"name":"linuxpool","count":5}]}}`), 0600)).To(Succeed())
+
+	var out bytes.Buffer
+	rc := &renderCmd{apimodelPaths: []string{base, stamp}, set: []string{"masterProfile.dnsPrefix=stamp3"}}
+	g.Expect(rc.validate()).To(Succeed())
+	g.Expect(rc.run(&out)).To(Succeed())
+	g.Expect(out.String()).To(Equal(`apiVersion: vlabs
+location: stamp3
+properties:
+  agentPoolProfiles:
+    - count: 5
+      name: linuxpool
+      vmSize: Standard_D2_v2
+  masterProfile:
+    count: 1 # a single master
+    dnsPrefix: stamp3
+    vmSize: Standard_D2_v2
+`))
+
+	out.Reset()
+	rc = &renderCmd{apimodelPaths: []string{base, stamp}, apimodelFormat: "json", outputFile: filepath.Join(dir, "rendered.json")}
+	g.Expect(rc.run(&out)).To(Succeed())
+	g.Expect(out.String()).To(BeEmpty())
+	b, err := os.ReadFile(rc.outputFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b).To(MatchJSON(`{"apiVersion":"vlabs","location":"stamp3","properties":{"masterProfile":{"count":1,"dnsPrefix":"base","vmSize":"Standard_D2_v2"},"agentPoolProfiles":[{"name":"linuxpool","count":5,"vmSize":"Standard_D2_v2"}]}}`))
+
+	typo := filepath.Join(dir, "typo.json")
+	g.Expect(os.WriteFile(typo, []byte(`{"properties":{"masterProfile":{"dnsPrefx":"typo"}}}`), 0600)).To(Succeed())
+	rc = &renderCmd{apimodelPaths: []string{base, typo}}
+	g.Expect(rc.run(&out)).To(MatchError(ContainSubstring(`properties.masterProfile.dnsPrefx, did you mean "dnsPrefix"?`)))
+
+	rc = &renderCmd{apimodelPaths: []string{base, filepath.Join(dir, "missing.json")}}
+	g.Expect(rc.validate()).To(MatchError(ContainSubstring("specified api model does not exist")))
+}
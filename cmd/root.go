@@ -15,6 +15,7 @@ import (
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
 	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
 	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers/fakearm"
 	"github.com/Azure/aks-engine-azurestack/pkg/engine"
 	"github.com/Azure/aks-engine-azurestack/pkg/engine/transform"
 	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
@@ -23,6 +24,8 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -40,6 +43,9 @@ const (
 var (
 	debug            bool
 	dumpDefaultModel bool
+	recordARMDir     string
+	replayARMDir     string
+	fakeARMStateFile string
 )
 
 // NewRootCmd returns the root command for AKS Engine.
@@ -48,10 +54,20 @@ func NewRootCmd() *cobra.Command {
 		Use:   rootName,
 		Short: rootShortDescription,
 		Long:  rootLongDescription,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindFlagSources(cmd); err != nil {
+				return err
+			}
+			if recordARMDir != "" && replayARMDir != "" {
+				return errors.New("--record-arm and --replay-arm are mutually exclusive")
+			}
+			if fakeARMStateFile != "" && replayARMDir != "" {
+				return errors.New("--fake-arm-state and --replay-arm are mutually exclusive")
+			}
 			if debug {
 				log.SetLevel(log.DebugLevel)
 			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if dumpDefaultModel {
@@ -63,6 +79,11 @@ func NewRootCmd() *cobra.Command {
 
 	p := rootCmd.PersistentFlags()
 	p.BoolVar(&debug, "debug", false, "enable verbose debug logs")
+	p.StringVar(&recordARMDir, "record-arm", "", "record every ARM request and response, with secrets scrubbed, to this directory")
+	p.StringVar(&replayARMDir, "replay-arm", "", "serve ARM requests from the responses recorded in this directory with --record-arm")
+	p.StringVar(&fakeARMStateFile, "fake-arm-state", "", "serve ARM requests from an in-process emulator persisting its state to this file, for offline testing")
+	_ = p.MarkHidden("fake-arm-state")
+	addConfigFlags(p)
 
 	f := rootCmd.Flags()
 	f.BoolVar(&dumpDefaultModel, "show-default-model", false, "Dump the default API model to stdout")
@@ -71,12 +92,20 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newGenerateCmd())
 	rootCmd.AddCommand(newDeployCmd())
 	rootCmd.AddCommand(newGetLogsCmd())
+	rootCmd.AddCommand(newGetCertsCmd())
+	rootCmd.AddCommand(newCreateKubeConfigCmd())
 	rootCmd.AddCommand(newGetVersionsCmd())
 	rootCmd.AddCommand(newOrchestratorsCmd())
 	rootCmd.AddCommand(newUpgradeCmd())
 	rootCmd.AddCommand(newScaleCmd())
 	rootCmd.AddCommand(newRotateCertsCmd())
 	rootCmd.AddCommand(newAddPoolCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newRenderCmd())
+	rootCmd.AddCommand(newDecryptCmd())
+	rootCmd.AddCommand(newMigrateAPIModelCmd())
+	rootCmd.AddCommand(newAPIModelCmd())
 	rootCmd.AddCommand(getCompletionCmd(rootCmd))
 
 	return rootCmd
@@ -112,24 +141,46 @@ type authArgs struct {
 	AuthMethod          string
 	rawClientID         string
 
-	ClientID        uuid.UUID
-	ClientSecret    string
-	CertificatePath string
-	PrivateKeyPath  string
-	IdentitySystem  string
-	language        string
+	ClientID            uuid.UUID
+	ClientSecret        string
+	CertificatePath     string
+	PrivateKeyPath      string
+	CertificatePassword string
+	IdentitySystem      string
+	language            string
+	retryOptions        armhelpers.RetryOptions
 }
 
+const (
+	clientSecretAuthMethod      = "client_secret"
+	clientCertificateAuthMethod = "client_certificate"
+	deviceCodeAuthMethod        = "device_code"
+	azureCLIAuthMethod          = "azure_cli"
+)
+
 func addAuthFlags(authArgs *authArgs, f *flag.FlagSet) {
 	f.StringVar(&authArgs.RawAzureEnvironment, "azure-env", "AzurePublicCloud", "the target Azure cloud")
 	f.StringVarP(&authArgs.rawSubscriptionID, "subscription-id", "s", "", "azure subscription id (required)")
-	f.StringVar(&authArgs.AuthMethod, "auth-method", "client_secret", "auth method (default:`client_secret`, `client_certificate`)")
-	f.StringVar(&authArgs.rawClientID, "client-id", "", "client id (used with --auth-method=[client_secret|client_certificate])")
+	f.StringVar(&authArgs.AuthMethod, "auth-method", clientSecretAuthMethod, "auth method (default:`client_secret`, `client_certificate`, `device_code`, `azure_cli`)")
+	f.StringVar(&authArgs.rawClientID, "client-id", "", "client id (used with --auth-method=[client_secret|client_certificate|device_code])")
 	f.StringVar(&authArgs.ClientSecret, "client-secret", "", "client secret (used with --auth-method=client_secret)")
-	f.StringVar(&authArgs.CertificatePath, "certificate-path", "", "path to client certificate (used with --auth-method=client_certificate)")
-	f.StringVar(&authArgs.PrivateKeyPath, "private-key-path", "", "path to private key (used with --auth-method=client_certificate)")
+	f.StringVar(&authArgs.CertificatePath, "certificate-path", "", "path to client certificate, PEM or PKCS#12 (used with --auth-method=client_certificate)")
+	f.StringVar(&authArgs.PrivateKeyPath, "private-key-path", "", "path to private key, omit if --certificate-path is a PKCS#12 file (used with --auth-method=client_certificate)")
+	f.StringVar(&authArgs.CertificatePassword, "certificate-password", "", "password of the PKCS#12 client certificate (used with --auth-method=client_certificate)")
 	f.StringVar(&authArgs.IdentitySystem, "identity-system", "azure_ad", "identity system (default:`azure_ad`, `adfs`)")
 	f.StringVar(&authArgs.language, "language", "en-us", "language to return error messages in")
+	addARMRetryFlags(&authArgs.retryOptions, f)
+}
+
+func addARMRetryFlags(o *armhelpers.RetryOptions, f *flag.FlagSet) {
+	d := armhelpers.DefaultRetryOptions()
+	f.Int32Var(&o.MaxRetries, "arm-max-retries", d.MaxRetries, "number of times a throttled, timed out or failed ARM request is retried")
+	f.DurationVar(&o.RetryDelay, "arm-retry-delay", d.RetryDelay, "delay before the first retry of an ARM request, doubled on every retry unless ARM sends a Retry-After header")
+	f.DurationVar(&o.MaxRetryDelay, "arm-max-retry-delay", d.MaxRetryDelay, "maximum delay between retries of an ARM request, including delays requested by Retry-After headers")
+	f.DurationVar(&o.TryTimeout, "arm-try-timeout", d.TryTimeout, "timeout of each try of an ARM request (0 disables the timeout)")
+	f.DurationVar(&o.OperationTimeout, "arm-operation-timeout", d.OperationTimeout, "timeout of an ARM operation, including the polling of long-running operations")
+	f.IntVar(&o.CircuitBreakerThreshold, "arm-circuit-breaker-threshold", d.CircuitBreakerThreshold, "number of consecutive ARM server errors after which ARM requests are suspended (0 disables the circuit breaker)")
+	f.DurationVar(&o.CircuitBreakerCooldown, "arm-circuit-breaker-cooldown", d.CircuitBreakerCooldown, "how long ARM requests are suspended once the circuit breaker opens")
 }
 
 func (authArgs *authArgs) getAuthArgs() *authArgs {
@@ -140,6 +191,15 @@ func (authArgs *authArgs) isAzureStackCloud() bool {
 	return strings.EqualFold(authArgs.RawAzureEnvironment, api.AzureStackCloud)
 }
 
+// isPFXCertificate returns true if the client certificate is a PKCS#12 file holding its own private key
+func (authArgs *authArgs) isPFXCertificate() bool {
+	if authArgs.PrivateKeyPath != "" {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(authArgs.CertificatePath))
+	return ext == ".pfx" || ext == ".p12"
+}
+
 func (authArgs *authArgs) validateAuthArgs() error {
 	var err error
 
@@ -147,22 +207,17 @@ func (authArgs *authArgs) validateAuthArgs() error {
 		return errors.New("--auth-method is a required parameter")
 	}
 
-	if authArgs.AuthMethod == "client_secret" || authArgs.AuthMethod == "client_certificate" {
-		authArgs.ClientID, err = uuid.Parse(authArgs.rawClientID)
-		if err != nil {
-			return errors.Wrap(err, "parsing --client-id")
-		}
-		if authArgs.AuthMethod == "client_secret" {
-			if authArgs.ClientSecret == "" {
-				return errors.New(`--client-secret must be specified when --auth-method="client_secret"`)
-			}
-		} else if authArgs.AuthMethod == "client_certificate" {
-			if authArgs.CertificatePath == "" || authArgs.PrivateKeyPath == "" {
-				return errors.New(`--certificate-path and --private-key-path must be specified when --auth-method="client_certificate"`)
-			}
+	// replayed and emulated ARM responses do not need credentials
+	if !usesFakeCredentials() {
+		if err = authArgs.validateCredentialArgs(); err != nil {
+			return err
 		}
 	}
 
+	if err = authArgs.retryOptions.Validate(); err != nil {
+		return err
+	}
+
 	authArgs.SubscriptionID, _ = uuid.Parse(authArgs.rawSubscriptionID)
 	if authArgs.SubscriptionID.String() == "00000000-0000-0000-0000-000000000000" {
 		var subID uuid.UUID
@@ -190,6 +245,31 @@ func (authArgs *authArgs) validateAuthArgs() error {
 	return nil
 }
 
+func (authArgs *authArgs) validateCredentialArgs() error {
+	var err error
+	if authArgs.AuthMethod == clientSecretAuthMethod || authArgs.AuthMethod == clientCertificateAuthMethod {
+		authArgs.ClientID, err = uuid.Parse(authArgs.rawClientID)
+		if err != nil {
+			return errors.Wrap(err, "parsing --client-id")
+		}
+		if authArgs.AuthMethod == clientSecretAuthMethod {
+			if authArgs.ClientSecret == "" {
+				return errors.New(`--client-secret must be specified when --auth-method="client_secret"`)
+			}
+		} else if authArgs.AuthMethod == clientCertificateAuthMethod {
+			if authArgs.CertificatePath == "" || (authArgs.PrivateKeyPath == "" && !authArgs.isPFXCertificate()) {
+				return errors.New(`--certificate-path and --private-key-path must be specified when --auth-method="client_certificate"`)
+			}
+		}
+	} else if authArgs.AuthMethod == deviceCodeAuthMethod && authArgs.rawClientID != "" {
+		authArgs.ClientID, err = uuid.Parse(authArgs.rawClientID)
+		if err != nil {
+			return errors.Wrap(err, "parsing --client-id")
+		}
+	}
+	return nil
+}
+
 func getSubFromAzDir(root string) (uuid.UUID, error) {
 	subConfig, err := ini.Load(filepath.Join(root, "clouds.config"))
 	if err != nil {
@@ -261,11 +341,14 @@ func (authArgs *authArgs) getClient(env *api.Environment) (armhelpers.AKSEngineC
 }
 
 func (authArgs *authArgs) getCredentials(env cloud.Configuration) (azcore.TokenCredential, error) {
+	if usesFakeCredentials() {
+		return &fake.TokenCredential{}, nil
+	}
 	if !authArgs.isAzureStackCloud() {
 		return armhelpers.NewDefaultCredential(env, authArgs.SubscriptionID.String())
 	}
 	switch authArgs.AuthMethod {
-	case "client_secret":
+	case clientSecretAuthMethod:
 		if authArgs.IdentitySystem == "azure_ad" {
 			return armhelpers.NewClientSecretCredential(env, authArgs.SubscriptionID.String(), authArgs.ClientID.String(), authArgs.ClientSecret)
 		} else if authArgs.IdentitySystem == "adfs" {
@@ -273,12 +356,36 @@ func (authArgs *authArgs) getCredentials(env cloud.Configuration) (azcore.TokenC
 		} else {
 			return nil, errors.Errorf("--auth-method: ERROR: method unsupported. method=%q identitysystem=%q", authArgs.AuthMethod, authArgs.IdentitySystem)
 		}
-	case "client_certificate":
-		if authArgs.IdentitySystem == "azure_ad" {
+	case clientCertificateAuthMethod:
+		if authArgs.isPFXCertificate() {
+			if authArgs.IdentitySystem == "azure_ad" {
+				return armhelpers.NewClientCertificateCredentialFromPFX(env, authArgs.SubscriptionID.String(), authArgs.ClientID.String(), authArgs.CertificatePath, authArgs.CertificatePassword)
+			} else if authArgs.IdentitySystem == "adfs" {
+				return armhelpers.NewClientCertificateCredentialFromPFXExternalTenant(env, authArgs.SubscriptionID.String(), authArgs.ClientID.String(), authArgs.CertificatePath, authArgs.CertificatePassword)
+			}
+		} else if authArgs.IdentitySystem == "azure_ad" {
 			return armhelpers.NewClientCertificateCredential(env, authArgs.SubscriptionID.String(), authArgs.ClientID.String(), authArgs.CertificatePath, authArgs.PrivateKeyPath)
 		} else if authArgs.IdentitySystem == "adfs" {
 			return armhelpers.NewClientCertificateCredentialExternalTenant(env, authArgs.SubscriptionID.String(), authArgs.ClientID.String(), authArgs.CertificatePath, authArgs.PrivateKeyPath)
 		}
+		return nil, errors.Errorf("--auth-method: ERROR: method unsupported. method=%q identitysystem=%q", authArgs.AuthMethod, authArgs.IdentitySystem)
+	case deviceCodeAuthMethod:
+		var clientID string
+		if authArgs.rawClientID != "" {
+			clientID = authArgs.ClientID.String()
+		}
+		if authArgs.IdentitySystem == "azure_ad" {
+			return armhelpers.NewDeviceCodeCredential(env, authArgs.SubscriptionID.String(), clientID)
+		} else if authArgs.IdentitySystem == "adfs" {
+			return armhelpers.NewDeviceCodeCredentialExternalTenant(env, clientID)
+		}
+		return nil, errors.Errorf("--auth-method: ERROR: method unsupported. method=%q identitysystem=%q", authArgs.AuthMethod, authArgs.IdentitySystem)
+	case azureCLIAuthMethod:
+		if authArgs.IdentitySystem == "azure_ad" {
+			return armhelpers.NewAzureCLICredential(env, authArgs.SubscriptionID.String())
+		} else if authArgs.IdentitySystem == "adfs" {
+			return armhelpers.NewAzureCLICredentialExternalTenant(authArgs.SubscriptionID.String())
+		}
 		fallthrough
 	default:
 		return nil, errors.Errorf("--auth-method: ERROR: method unsupported. method=%q identitysystem=%q", authArgs.AuthMethod, authArgs.IdentitySystem)
@@ -286,14 +393,22 @@ func (authArgs *authArgs) getCredentials(env cloud.Configuration) (azcore.TokenC
 }
 
 func (authArgs *authArgs) getAzureClient(credential azcore.TokenCredential, env cloud.Configuration) (armhelpers.AKSEngineClient, error) {
-	client, err := armhelpers.NewAzureClient(authArgs.SubscriptionID.String(), credential, &arm.ClientOptions{
+	transport, err := getARMTransport(authArgs.SubscriptionID.String())
+	if err != nil {
+		return nil, err
+	}
+	options := &arm.ClientOptions{
 		ClientOptions: azcore.ClientOptions{
-			Cloud: env,
+			Cloud:     env,
+			Transport: transport,
 		},
-	})
+	}
+	authArgs.retryOptions.Apply(&options.ClientOptions)
+	client, err := armhelpers.NewAzureClient(authArgs.SubscriptionID.String(), credential, options)
 	if err != nil {
 		return nil, err
 	}
+	client.SetOperationTimeout(authArgs.retryOptions.GetOperationTimeout())
 	err = client.EnsureProvidersRegistered(authArgs.SubscriptionID.String())
 	if err != nil {
 		return nil, err
@@ -302,6 +417,34 @@ func (authArgs *authArgs) getAzureClient(credential azcore.TokenCredential, env
 	return client, nil
 }
 
+// getARMTransport returns the ARM transport selected by --record-arm, --replay-arm or --fake-arm-state,
+// or nil to send requests to the ARM endpoint
+func getARMTransport(subscriptionID string) (policy.Transporter, error) {
+	if replayARMDir != "" {
+		log.Infof("Replaying ARM responses recorded in %s", replayARMDir)
+		return armhelpers.NewReplayTransport(replayARMDir)
+	}
+	var transport policy.Transporter
+	if fakeARMStateFile != "" {
+		log.Infof("Serving ARM requests from an emulator with state file %s", fakeARMStateFile)
+		server, err := fakearm.NewServer(fakearm.WithSubscriptionID(subscriptionID), fakearm.WithStateFile(fakeARMStateFile))
+		if err != nil {
+			return nil, err
+		}
+		transport = server.Transport()
+	}
+	if recordARMDir != "" {
+		log.Infof("Recording ARM requests and responses to %s", recordARMDir)
+		return armhelpers.NewRecordingTransport(recordARMDir, transport)
+	}
+	return transport, nil
+}
+
+// usesFakeCredentials returns true if ARM requests are served without reaching an ARM endpoint
+func usesFakeCredentials() bool {
+	return replayARMDir != "" || fakeARMStateFile != ""
+}
+
 func getCompletionCmd(root *cobra.Command) *cobra.Command {
 	var completionCmd = &cobra.Command{
 		Use:   "completion",
@@ -358,7 +501,9 @@ func getKubeClient(cs *api.ContainerService, interval, timeout time.Duration) (k
 	return client, nil
 }
 
-func writeArtifacts(outputDirectory string, cs *api.ContainerService, apiVersion string, translator *i18n.Translator) error {
+// writeArtifacts generates the cluster artifacts to outputDirectory,
+// writing the API model in the same format as the API model file at apiModelPath
+func writeArtifacts(outputDirectory string, cs *api.ContainerService, apiVersion, apiModelPath string, translator *i18n.Translator) error {
 	ctx := engine.Context{Translator: translator}
 	tplgen, err := engine.InitializeTemplateGenerator(ctx)
 	if err != nil {
@@ -374,6 +519,146 @@ func writeArtifacts(outputDirectory string, cs *api.ContainerService, apiVersion
 	if params, err = transform.BuildAzureParametersFile(params); err != nil {
 		return errors.Wrap(err, "pretty-printing template parameters")
 	}
-	w := &engine.ArtifactWriter{Translator: translator}
+	w, err := newArtifactWriter(translator, "", apiModelPath)
+	if err != nil {
+		return err
+	}
+	// the artifacts of an encrypted API model, e.g. a backup, are encrypted too
+	if encrypted, _ := helpers.IsEncryptedFile(apiModelPath); encrypted {
+		if w.EncryptionKey, err = helpers.EncryptionKeyFromEnvironment(); err != nil {
+			return errors.Wrap(err, "loading the encryption key")
+		}
+	}
 	return w.WriteTLSArtifacts(cs, apiVersion, tpl, params, outputDirectory, true, false)
 }
+
+// apiModelFileInDir returns the path of the API model in a deployment directory,
+// apimodel.yaml if it exists and apimodel.json otherwise
+func apiModelFileInDir(dir string) string {
+	yamlPath := filepath.Join(dir, apiModelYAMLFilename)
+	if _, err := os.Stat(yamlPath); err == nil {
+		return yamlPath
+	}
+	return filepath.Join(dir, apiModelFilename)
+}
+
+// addEncryptionFlags adds the flags encrypting the artifacts at rest
+func addEncryptionFlags(f *flag.FlagSet, encryptOutput *bool, keyFile *string) {
+	f.BoolVar(encryptOutput, "encrypt-output", false, fmt.Sprintf("encrypt the API model, the parameters, the kubeconfigs and the certificates and keys in the output directory with --encryption-key-file or $%s", helpers.EncryptionPassphraseEnvVar))
+	f.StringVar(keyFile, "encryption-key-file", "", "path to the 32 bytes key file, raw or base64-encoded, encrypting the output directory with --encrypt-output")
+}
+
+// loadEncryptionKey returns the key encrypting the artifacts if encryptOutput is set: the key in keyFile,
+// or the key derived from $AKSE_ENCRYPTION_PASSPHRASE. keyFile is never read from $AKSE_ENCRYPTION_KEY_FILE,
+// which only decrypts the input API model.
+func loadEncryptionKey(encryptOutput bool, keyFile string) (*helpers.EncryptionKey, error) {
+	if !encryptOutput {
+		if keyFile != "" {
+			return nil, errors.New("--encryption-key-file requires --encrypt-output")
+		}
+		return nil, nil
+	}
+	if keyFile != "" {
+		return helpers.LoadEncryptionKeyFile(keyFile)
+	}
+	if passphrase := os.Getenv(helpers.EncryptionPassphraseEnvVar); passphrase != "" {
+		return helpers.NewPassphraseEncryptionKey(passphrase)
+	}
+	return nil, errors.Errorf("--encrypt-output requires --encryption-key-file or $%s", helpers.EncryptionPassphraseEnvVar)
+}
+
+// addAPIModelFormatFlag adds the flag selecting the format of the API model artifact
+func addAPIModelFormatFlag(f *flag.FlagSet, format *string) {
+	f.StringVar(format, "apimodel-format", "", "format of the generated API model, `json` or `yaml` (default: the format of --api-model)")
+}
+
+const (
+	validationOutputList = "list"
+	validationOutputJSON = "json"
+)
+
+// addValidationOutputFlag adds the flag selecting how the API model validation errors are rendered
+func addValidationOutputFlag(f *flag.FlagSet, output *string) {
+	f.StringVar(output, "validation-output", validationOutputList, "how API model validation errors are reported, `list` or `json` (printed to standard output)")
+}
+
+// mergeAPIModelInputs deep-merges the API model overlays into the first API model of apimodelPaths in order,
+// then applies the patch files, the --set-json values and the --set values. It returns the path of the
+// merged temporary API model file, which holds the decrypted API model and must be removed by the caller,
+// or an empty string if there is nothing to merge.
+func mergeAPIModelInputs(apimodelPaths, patchFiles, setJSON, set []string) (string, error) {
+	if len(apimodelPaths) < 2 && len(patchFiles) == 0 && len(setJSON) == 0 && len(set) == 0 {
+		return "", nil
+	}
+	apimodelPath := apimodelPaths[0]
+	if len(apimodelPaths) > 1 {
+		var err error
+		if apimodelPath, err = transform.MergeAPIModelLayers(apimodelPaths); err != nil {
+			return "", err
+		}
+		if len(patchFiles) == 0 && len(setJSON) == 0 && len(set) == 0 {
+			return apimodelPath, nil
+		}
+		defer os.Remove(apimodelPath)
+	}
+	m := make(map[string]transform.APIModelValue)
+	if err := transform.MapJSONValues(m, setJSON); err != nil {
+		return "", err
+	}
+	transform.MapValues(m, set)
+	return transform.MergeAPIModel(apimodelPath, patchFiles, m)
+}
+
+// validateAPIModelAsVLabs converts cs to a vlabs ContainerService and validates it.
+// Every validation error is reported, as a list in the returned error with the warnings listed to out,
+// or as JSON written to out.
+func validateAPIModelAsVLabs(cs *api.ContainerService, output string, out io.Writer) error {
+	errs := api.ConvertContainerServiceToVLabs(cs).ValidateAll(false)
+	switch strings.ToLower(output) {
+	case "", validationOutputList:
+		if warnings := errs.Warnings(); len(warnings) > 0 {
+			if _, err := fmt.Fprintln(out, warnings.List()); err != nil {
+				return err
+			}
+		}
+		return errs.Err()
+	case validationOutputJSON:
+		b, err := errs.JSON()
+		if err != nil {
+			return errors.Wrap(err, "serializing validation errors")
+		}
+		if _, err = fmt.Fprintln(out, string(b)); err != nil {
+			return err
+		}
+		if n := len(errs.Errors()); n > 0 {
+			return errors.Errorf("found %d validation errors", n)
+		}
+		return nil
+	default:
+		return errors.Errorf("--validation-output must be %q or %q", validationOutputList, validationOutputJSON)
+	}
+}
+
+// newArtifactWriter returns an ArtifactWriter writing the API model in format, or in the format
+// of the API model file at apiModelPath if format is empty. The comments of a YAML API model are preserved.
+func newArtifactWriter(translator *i18n.Translator, format, apiModelPath string) (*engine.ArtifactWriter, error) {
+	w := &engine.ArtifactWriter{Translator: translator, APIModelFormat: strings.ToLower(format)}
+	switch w.APIModelFormat {
+	case "":
+		w.APIModelFormat = engine.APIModelFormatJSON
+		if api.IsYAMLFile(apiModelPath) {
+			w.APIModelFormat = engine.APIModelFormatYAML
+		}
+	case engine.APIModelFormatJSON, engine.APIModelFormatYAML:
+	default:
+		return nil, errors.Errorf("--apimodel-format must be %q or %q", engine.APIModelFormatJSON, engine.APIModelFormatYAML)
+	}
+	if w.APIModelFormat == engine.APIModelFormatYAML && api.IsYAMLFile(apiModelPath) {
+		b, err := helpers.ReadDecryptedFile(apiModelPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", apiModelPath)
+		}
+		w.OriginalAPIModel = b
+	}
+	return w, nil
+}
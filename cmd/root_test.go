@@ -4,13 +4,24 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
 	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers/fakearm"
+	"github.com/Azure/aks-engine-azurestack/pkg/engine"
 	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
 	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
 	"github.com/google/uuid"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -43,7 +54,7 @@ func TestNewRootCmd(t *testing.T) {
 		t.Fatalf("root command should have use %s equal %s, short %s equal %s and long %s equal to %s", command.Use, rootName, command.Short, rootShortDescription, command.Long, rootLongDescription)
 	}
 	// The commands need to be listed in alphabetical order
-	expectedCommands := []*cobra.Command{newAddPoolCmd(), getCompletionCmd(command), newDeployCmd(), newGenerateCmd(), newGetLogsCmd(), newGetVersionsCmd(), newOrchestratorsCmd(), newRotateCertsCmd(), newScaleCmd(), newUpgradeCmd(), newVersionCmd()}
+	expectedCommands := []*cobra.Command{newAddPoolCmd(), newAPIModelCmd(), getCompletionCmd(command), newConfigCmd(), newCreateKubeConfigCmd(), newDecryptCmd(), newDeployCmd(), newGenerateCmd(), newGetCertsCmd(), newGetLogsCmd(), newGetVersionsCmd(), newMigrateAPIModelCmd(), newOrchestratorsCmd(), newRenderCmd(), newRotateCertsCmd(), newScaleCmd(), newSchemaCmd(), newUpgradeCmd(), newVersionCmd()}
 	rc := command.Commands()
 
 	for i, c := range expectedCommands {
@@ -315,6 +326,46 @@ func TestValidateAuthArgs(t *testing.T) {
 			},
 			expected: nil,
 		},
+		{
+			name: "ValidClientCertificatePFXAuth",
+			authArgs: authArgs{
+				rawSubscriptionID:   validID,
+				rawClientID:         validID,
+				CertificatePath:     "/a/path/cert.pfx",
+				CertificatePassword: "password",
+				AuthMethod:          "client_certificate",
+				RawAzureEnvironment: "AZUREPUBLICCLOUD",
+			},
+			expected: nil,
+		},
+		{
+			name: "DeviceCodeAuthExpectsValidClientID",
+			authArgs: authArgs{
+				rawSubscriptionID:   validID,
+				rawClientID:         invalidID,
+				AuthMethod:          "device_code",
+				RawAzureEnvironment: "AZUREPUBLICCLOUD",
+			},
+			expected: errors.New(`parsing --client-id: invalid UUID length: 9`),
+		},
+		{
+			name: "ValidDeviceCodeAuthWithoutClientID",
+			authArgs: authArgs{
+				rawSubscriptionID:   validID,
+				AuthMethod:          "device_code",
+				RawAzureEnvironment: "AZUREPUBLICCLOUD",
+			},
+			expected: nil,
+		},
+		{
+			name: "ValidAzureCLIAuth",
+			authArgs: authArgs{
+				rawSubscriptionID:   validID,
+				AuthMethod:          "azure_cli",
+				RawAzureEnvironment: "AZUREPUBLICCLOUD",
+			},
+			expected: nil,
+		},
 	} {
 		test := tc
 		t.Run(test.name, func(t *testing.T) {
@@ -328,6 +379,31 @@ func TestValidateAuthArgs(t *testing.T) {
 	}
 }
 
+func TestIsPFXCertificate(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	g.Expect((&authArgs{CertificatePath: "/certs/spn.pfx"}).isPFXCertificate()).To(BeTrue())
+	g.Expect((&authArgs{CertificatePath: "/certs/SPN.P12"}).isPFXCertificate()).To(BeTrue())
+	g.Expect((&authArgs{CertificatePath: "/certs/spn.crt"}).isPFXCertificate()).To(BeFalse())
+	g.Expect((&authArgs{CertificatePath: "/certs/spn.pfx", PrivateKeyPath: "/certs/spn.key"}).isPFXCertificate()).To(BeFalse())
+}
+
+func TestGetCredentialsUnsupportedIdentitySystem(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	for _, method := range []string{"client_secret", "client_certificate", "device_code", "azure_cli", "unknown"} {
+		a := &authArgs{
+			RawAzureEnvironment: api.AzureStackCloud,
+			AuthMethod:          method,
+			IdentitySystem:      "ldap",
+		}
+		_, err := a.getCredentials(cloud.Configuration{})
+		g.Expect(err).To(MatchError(fmt.Sprintf("--auth-method: ERROR: method unsupported. method=%q identitysystem=%q", method, "ldap")))
+	}
+}
+
 func prepareCustomCloudProfile() (*api.ContainerService, error) {
 	const (
 		name                         = "azurestackcloud"
@@ -429,8 +505,32 @@ func TestWriteArtifacts(t *testing.T) {
 	outdir, del := makeTmpDir(t)
 	defer del()
 
-	err = writeArtifacts(outdir, cs, "vlabs", &i18n.Translator{})
+	err = writeArtifacts(outdir, cs, "vlabs", "", &i18n.Translator{})
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "")
+
+	key, err := loadEncryptionKey(false, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key).To(BeNil())
+	_, err = loadEncryptionKey(false, "output.key")
+	g.Expect(err).To(MatchError("--encryption-key-file requires --encrypt-output"))
+	_, err = loadEncryptionKey(true, "")
+	g.Expect(err).To(MatchError("--encrypt-output requires --encryption-key-file or $AKSE_ENCRYPTION_PASSPHRASE"))
+
+	keyPath := filepath.Join(t.TempDir(), "output.key")
+	g.Expect(os.WriteFile(keyPath, bytes.Repeat([]byte{1}, 32), 0600)).To(Succeed())
+	key, err = loadEncryptionKey(true, keyPath)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key).NotTo(BeNil())
+
+	t.Setenv(helpers.EncryptionPassphraseEnvVar, "passphrase")
+	key, err = loadEncryptionKey(true, "")
 	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key).NotTo(BeNil())
 }
 
 func makeTmpDir(t *testing.T) (string, func()) {
@@ -440,3 +540,136 @@ func makeTmpDir(t *testing.T) (string, func()) {
 	}
 	return tmpDir, func() { defer os.RemoveAll(tmpDir) }
 }
+
+func TestGetARMTransport(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"--record-arm", t.TempDir(), "--replay-arm", t.TempDir(), "version"})
+	g.Expect(root.Execute()).To(MatchError("--record-arm and --replay-arm are mutually exclusive"))
+	recordARMDir, replayARMDir = "", ""
+
+	transport, err := getARMTransport("cc6b141e-6afc-4786-9bf6-e3b9a5601460")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(transport).To(BeNil())
+
+	recordARMDir = t.TempDir()
+	transport, err = getARMTransport("cc6b141e-6afc-4786-9bf6-e3b9a5601460")
+	recordARMDir = ""
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(transport).To(BeAssignableToTypeOf(&armhelpers.RecordingTransport{}))
+
+	fakeARMStateFile = filepath.Join(t.TempDir(), "state.json")
+	transport, err = getARMTransport("cc6b141e-6afc-4786-9bf6-e3b9a5601460")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(transport).To(BeAssignableToTypeOf(&fakearm.Transport{}))
+	a := &authArgs{AuthMethod: "client_certificate", RawAzureEnvironment: "AzurePublicCloud", rawSubscriptionID: "cc6b141e-6afc-4786-9bf6-e3b9a5601460"}
+	g.Expect(a.validateAuthArgs()).To(Succeed())
+	client, err := a.getAzureClient(&fake.TokenCredential{}, cloud.AzurePublic)
+	fakeARMStateFile = ""
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = client.EnsureResourceGroup(context.Background(), "rg", "local", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	replayARMDir = t.TempDir()
+	_, err = getARMTransport("cc6b141e-6afc-4786-9bf6-e3b9a5601460")
+	g.Expect(err).To(HaveOccurred())
+
+	a = &authArgs{AuthMethod: "client_secret", RawAzureEnvironment: "AzurePublicCloud", rawSubscriptionID: "cc6b141e-6afc-4786-9bf6-e3b9a5601460"}
+	g.Expect(a.validateAuthArgs()).To(Succeed())
+	credential, err := a.getCredentials(cloud.AzurePublic)
+	replayARMDir = ""
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(credential).To(BeAssignableToTypeOf(&fake.TokenCredential{}))
+}
+
+func TestMergeAPIModelInputsTempFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	overlay := filepath.Join(t.TempDir(), "overlay.yaml")
+	g.Expect(os.WriteFile(overlay, []byte("properties:\n  masterProfile:\n    count: 3\n"), 0600)).To(Succeed())
+
+	merged, err := mergeAPIModelInputs([]string{"../pkg/engine/testdata/simple/kubernetes.json", overlay}, nil, nil, []string{"linuxProfile.adminUsername=admin"})
+	g.Expect(err).NotTo(HaveOccurred())
+	// the merged overlays are removed once the --set values are merged
+	files, _ := filepath.Glob(filepath.Join(tmpDir, "mergedApiModel*"))
+	g.Expect(files).To(ConsistOf(merged))
+
+	gc := &generateCmd{apimodelSourcePath: "../pkg/engine/testdata/simple/kubernetes.json", apimodelPath: merged}
+	gc.removeMergedAPIModel()
+	g.Expect(merged).NotTo(BeAnExistingFile())
+
+	dc := &deployCmd{apimodelPath: "../pkg/engine/testdata/simple/kubernetes.json"}
+	dc.removeMergedAPIModel()
+	g.Expect(dc.apimodelPath).To(BeAnExistingFile())
+}
+
+func TestNewArtifactWriter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cluster.yaml")
+	g.Expect(os.WriteFile(yamlPath, []byte("# cluster\napiVersion: vlabs\n"), 0600)).To(Succeed())
+
+	w, err := newArtifactWriter(&i18n.Translator{}, "", "cluster.json")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.APIModelFormat).To(Equal(engine.APIModelFormatJSON))
+
+	w, err = newArtifactWriter(&i18n.Translator{}, "", yamlPath)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.APIModelFormat).To(Equal(engine.APIModelFormatYAML))
+	g.Expect(string(w.OriginalAPIModel)).To(HavePrefix("# cluster"))
+
+	w, err = newArtifactWriter(&i18n.Translator{}, "YAML", "cluster.json")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.APIModelFormat).To(Equal(engine.APIModelFormatYAML))
+	g.Expect(w.OriginalAPIModel).To(BeNil())
+
+	_, err = newArtifactWriter(&i18n.Translator{}, "toml", yamlPath)
+	g.Expect(err).To(MatchError(`--apimodel-format must be "json" or "yaml"`))
+}
+
+func TestAPIModelFileInDir(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(apiModelFileInDir(dir)).To(Equal(filepath.Join(dir, "apimodel.json")))
+	g.Expect(os.WriteFile(filepath.Join(dir, "apimodel.yaml"), []byte("apiVersion: vlabs\n"), 0600)).To(Succeed())
+	g.Expect(apiModelFileInDir(dir)).To(Equal(filepath.Join(dir, "apimodel.yaml")))
+}
+
+func TestValidateAPIModelAsVLabs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	apimodel := strings.Replace(getAPIModel(ExampleAPIModelWithDNSPrefix, false, "clientID", "clientSecret"), `"mytestcluster"`, `"-invalid"`, 1)
+	apimodel = strings.Replace(apimodel, `"linuxpool1"`, `"Invalid_Pool"`, 1)
+	apiloader := &api.Apiloader{Translator: &i18n.Translator{}}
+	cs, _, err := apiloader.DeserializeContainerService([]byte(apimodel), false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	cs.Properties.MasterProfile.Distro = api.Ubuntu
+
+	var out bytes.Buffer
+	err = validateAPIModelAsVLabs(cs, validationOutputList, &out)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("found 3 validation errors:\n- error properties.masterProfile.dnsPrefix [InvalidDNSPrefix]: "))
+	g.Expect(err.Error()).To(ContainSubstring("\n- error properties.agentPoolProfiles[0].name [InvalidName]: "))
+	g.Expect(err.Error()).To(ContainSubstring("\n- error properties.linuxProfile.ssh.publicKeys[0].keyData [MissingProperty]: "))
+	g.Expect(err.Error()).NotTo(ContainSubstring("warning"))
+	// the warnings are listed to the output
+	g.Expect(out.String()).To(HavePrefix("- warning properties.masterProfile.distro [EndOfLifeDistro]: "))
+
+	out.Reset()
+	err = validateAPIModelAsVLabs(cs, validationOutputJSON, &out)
+	g.Expect(err).To(MatchError("found 3 validation errors"))
+	var errs []vlabs.ValidationError
+	g.Expect(json.Unmarshal(out.Bytes(), &errs)).To(Succeed())
+	g.Expect(errs).To(HaveLen(4))
+	g.Expect(errs[3].Severity).To(Equal(vlabs.SeverityWarning))
+	g.Expect(errs[1].Path).To(Equal("properties.agentPoolProfiles[0].name"))
+	g.Expect(errs[1].Code).To(Equal(vlabs.CodeInvalidName))
+
+	err = validateAPIModelAsVLabs(cs, "yaml", &out)
+	g.Expect(err).To(MatchError(`--validation-output must be "list" or "json"`))
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/Azure/aks-engine-azurestack/pkg/engine"
 	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
 	"github.com/Azure/aks-engine-azurestack/pkg/helpers/ssh"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/to"
 	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
 	"github.com/Azure/aks-engine-azurestack/pkg/kubernetes"
 	"github.com/pkg/errors"
@@ -55,8 +56,23 @@ const (
 
 	vmasSSHPort = 22
 	vmssSSHPort = 50001
+
+	rotateCertsScopeAll      = "all"
+	rotateCertsScopeLeafOnly = "leaf-only"
+
+	rotateCertsJournalFile = "rotate-certs-journal.json"
+
+	rotateCertsPhaseTrust    = "trust"
+	rotateCertsPhasePods     = "pods"
+	rotateCertsPhaseLeaves   = "leaves"
+	rotateCertsPhaseFinalize = "finalize"
 )
 
+// rotateCertsPhases are the phases of a CA rotation keeping the API server available, in execution order.
+// The pods are rolled before the leaf certificates are reissued so that they trust the new CA
+// by the time the API server presents a certificate signed by it
+var rotateCertsPhases = []string{rotateCertsPhaseTrust, rotateCertsPhasePods, rotateCertsPhaseLeaves, rotateCertsPhaseFinalize}
+
 type nodeMap = map[string]*ssh.RemoteHost
 type fileMap = map[string]*ssh.RemoteFile
 
@@ -72,6 +88,10 @@ type rotateCertsCmd struct {
 	linuxSSHPrivateKeyPath string
 	outputDirectory        string
 	force                  bool
+	separateCAs            bool
+	scope                  string
+	resume                 bool
+	phase                  string
 
 	// computed
 	backupDirectory   string
@@ -79,6 +99,7 @@ type rotateCertsCmd struct {
 	cs                *api.ContainerService
 	loader            *api.Apiloader
 	newCertsProfile   *api.CertificateProfile
+	currentProfile    *api.CertificateProfile
 	kubeClient        *kubernetes.CompositeClientSet
 	armClient         *ops.ARMClientWrapper
 	nodes             nodeMap
@@ -87,6 +108,7 @@ type rotateCertsCmd struct {
 	windowsAuthConfig *ssh.AuthConfig
 	jumpbox           *ssh.JumpBox
 	sshPort           int
+	journal           *ops.Journal
 }
 
 func newRotateCertsCmd() *cobra.Command {
@@ -116,7 +138,7 @@ func newRotateCertsCmd() *cobra.Command {
 
 	f.StringVarP(&rcc.location, "location", "l", "", "Azure location where the cluster is deployed")
 	f.StringVarP(&rcc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed")
-	f.StringVarP(&rcc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file")
+	f.StringVarP(&rcc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file")
 	f.StringVar(&rcc.sshHostURI, "ssh-host", "", "FQDN, or IP address, of an SSH listener that can reach all nodes in the cluster")
 	f.StringVar(&rcc.linuxSSHPrivateKeyPath, "linux-ssh-private-key", "", "path to a valid private SSH key to access the cluster's Linux nodes")
 	_ = command.MarkFlagRequired("location")
@@ -127,6 +149,10 @@ func newRotateCertsCmd() *cobra.Command {
 
 	f.StringVarP(&rcc.newCertsPath, "certificate-profile", "", "", "path to a JSON file containing the new set of certificates")
 	f.BoolVarP(&rcc.force, "force", "", false, "force execution even if API Server is not responsive")
+	f.BoolVar(&rcc.separateCAs, "separate-cas", false, "sign the etcd and front-proxy certificates with their own CAs, migrating a cluster sharing the cluster CA")
+	f.StringVar(&rcc.scope, "scope", rotateCertsScopeAll, fmt.Sprintf("certificates to rotate. Allowed values: %s (new CAs and leaf certificates), %s (new leaf certificates signed by the current CAs, no reboot)", rotateCertsScopeAll, rotateCertsScopeLeafOnly))
+	f.BoolVar(&rcc.resume, "resume", false, "resume an interrupted certificate rotation from the last step completed on each node")
+	f.StringVar(&rcc.phase, "phase", "", fmt.Sprintf("run a single phase of a CA rotation keeping the API server available, no reboot. Allowed values, in order: %s", strings.Join(rotateCertsPhases, ", ")))
 
 	addAuthFlags(rcc.getAuthArgs(), f)
 
@@ -167,6 +193,23 @@ func (rcc *rotateCertsCmd) validateArgs() (err error) {
 			return errors.Errorf("specified --certificate-profile does not exist (%s)", rcc.newCertsPath)
 		}
 	}
+	if rcc.scope == "" {
+		rcc.scope = rotateCertsScopeAll
+	}
+	if rcc.scope != rotateCertsScopeAll && rcc.scope != rotateCertsScopeLeafOnly {
+		return errors.Errorf("--scope must be %s or %s", rotateCertsScopeAll, rotateCertsScopeLeafOnly)
+	}
+	if rcc.scope == rotateCertsScopeLeafOnly && rcc.separateCAs {
+		return errors.Errorf("--separate-cas cannot be set with --scope=%s, creating new CAs requires a full rotation", rotateCertsScopeLeafOnly)
+	}
+	if rcc.resume && (rcc.newCertsPath != "" || rcc.separateCAs) {
+		return errors.New("--certificate-profile and --separate-cas cannot be set with --resume, the certificates of the interrupted rotation are reused")
+	}
+	if rcc.phase != "" {
+		if err = rcc.validatePhaseArgs(); err != nil {
+			return err
+		}
+	}
 	if rcc.outputDirectory == "" {
 		rcc.outputDirectory = path.Join(filepath.Dir(rcc.apiModelPath), "_rotate_certs_output")
 		if err = os.MkdirAll(rcc.outputDirectory, 0755); err != nil {
@@ -176,6 +219,36 @@ func (rcc *rotateCertsCmd) validateArgs() (err error) {
 	if _, err := os.ReadDir(rcc.outputDirectory); err != nil {
 		return errors.Wrapf(err, "reading output directory %s", rcc.outputDirectory)
 	}
+	_, err = os.Stat(rcc.journalPath())
+	switch {
+	case rcc.phase != "":
+		if rcc.phase != rotateCertsPhaseTrust && os.IsNotExist(err) {
+			return errors.Errorf("no CA rotation in progress, run --phase=%s first", rotateCertsPhaseTrust)
+		}
+	case rcc.resume && os.IsNotExist(err):
+		return errors.Errorf("no certificate rotation to resume, %s does not exist", rcc.journalPath())
+	case !rcc.resume && err == nil:
+		return errors.Errorf("a previous certificate rotation did not complete, set --resume to continue it or delete %s to start over", rcc.outputDirectory)
+	}
+	return nil
+}
+
+func (rcc *rotateCertsCmd) validatePhaseArgs() error {
+	if previousPhase(rcc.phase) == "" && rcc.phase != rotateCertsPhaseTrust {
+		return errors.Errorf("--phase must be one of: %s", strings.Join(rotateCertsPhases, ", "))
+	}
+	if rcc.scope == rotateCertsScopeLeafOnly {
+		return errors.Errorf("--phase cannot be set with --scope=%s, the phases rotate the CAs", rotateCertsScopeLeafOnly)
+	}
+	if rcc.separateCAs {
+		return errors.New("--separate-cas cannot be set with --phase, migrating to separate CAs requires a full rotation")
+	}
+	if rcc.resume {
+		return errors.New("--resume cannot be set with --phase, running a phase again resumes it")
+	}
+	if rcc.newCertsPath != "" && rcc.phase != rotateCertsPhaseTrust {
+		return errors.Errorf("--certificate-profile can only be set with --phase=%s, which creates the new certificates", rotateCertsPhaseTrust)
+	}
 	return nil
 }
 
@@ -188,6 +261,19 @@ func (rcc *rotateCertsCmd) loadAPIModel() (err error) {
 		if rcc.newCertsProfile, err = rcc.loader.LoadCertificateProfileFromFile(rcc.newCertsPath); err != nil {
 			return errors.Wrap(err, "error parsing certificate-profile")
 		}
+		if rcc.newCertsProfile.CaCertificateChain != "" {
+			if err = helpers.VerifyCertificateChain(rcc.newCertsProfile.CaCertificate, rcc.newCertsProfile.CaCertificateChain); err != nil {
+				return errors.Wrap(err, "error validating the CA certificate chain of certificate-profile")
+			}
+		}
+		if rcc.scope == rotateCertsScopeLeafOnly && rcc.newCertsProfile.CaCertificate != "" &&
+			(rcc.cs.Properties.CertificateProfile == nil || strings.TrimSpace(rcc.newCertsProfile.CaCertificate) != strings.TrimSpace(rcc.cs.Properties.CertificateProfile.CaCertificate)) {
+			return errors.Errorf("the CA of certificate-profile must be the cluster CA with --scope=%s", rotateCertsScopeLeafOnly)
+		}
+		if rcc.phase != "" && rcc.newCertsProfile.SeparateCAs != nil &&
+			rcc.newCertsProfile.HasSeparateCAs() != rcc.cs.Properties.CertificateProfile.HasSeparateCAs() {
+			return errors.New("the separateCAs setting of certificate-profile cannot change with --phase, migrating to separate CAs requires a full rotation")
+		}
 	}
 	if rcc.cs.Properties.IsCustomCloudProfile() {
 		if err = writeCustomCloudProfile(rcc.cs); err != nil {
@@ -248,10 +334,19 @@ func (rcc *rotateCertsCmd) init() (err error) {
 }
 
 func (rcc *rotateCertsCmd) run() (err error) {
-	if err = rcc.backupCerts(); err != nil {
+	if err = rcc.initJournal(); err != nil {
+		return err
+	}
+	if err = rcc.clusterStep("backup-artifacts", rcc.backupCerts); err != nil {
 		return errors.Wrap(err, "backing up current state")
 	}
-	if err = rcc.updateCertificateProfile(); err != nil {
+	rcc.currentProfile = rcc.cs.Properties.CertificateProfile
+	if rcc.journal.Done("certificate-profile", ops.ClusterWide) {
+		err = rcc.loadRotatedCertificateProfile()
+	} else {
+		err = rcc.clusterStep("certificate-profile", rcc.updateCertificateProfile)
+	}
+	if err != nil {
 		return errors.Wrap(err, "updating certificate profile")
 	}
 	rcc.kubeClient, err = rcc.getKubeClient()
@@ -280,10 +375,13 @@ func (rcc *rotateCertsCmd) run() (err error) {
 		}
 	}
 
-	if err = rcc.rotateMasterCerts(); err != nil {
+	if rcc.phase != "" {
+		return rcc.runPhase()
+	}
+	if err = rcc.clusterStep("control-plane", rcc.rotateMasterCerts); err != nil {
 		return errors.Wrap(err, "rotating certificates")
 	}
-	if err = rcc.rotateAgentCerts(); err != nil {
+	if err = rcc.clusterStep("agents", rcc.rotateAgentCerts); err != nil {
 		return errors.Wrap(err, "rotating certificates")
 	}
 
@@ -295,9 +393,212 @@ func (rcc *rotateCertsCmd) run() (err error) {
 	return nil
 }
 
+func (rcc *rotateCertsCmd) journalPath() string {
+	return filepath.Join(rcc.outputDirectory, rotateCertsJournalFile)
+}
+
+// initJournal starts a new journal, or loads the journal of the interrupted rotation if resuming
+func (rcc *rotateCertsCmd) initJournal() (err error) {
+	if rcc.phase != "" {
+		return rcc.initPhaseJournal()
+	}
+	if !rcc.resume {
+		rcc.journal = ops.NewJournal(rcc.journalPath(), rcc.scope)
+		return nil
+	}
+	if rcc.journal, err = ops.LoadJournal(rcc.journalPath()); err != nil {
+		return errors.Wrap(err, "loading the journal of the interrupted certificate rotation")
+	}
+	rcc.scope = rcc.journal.Scope
+	log.Infof("Resuming the interrupted certificate rotation, scope: %s", rcc.scope)
+	return nil
+}
+
+// initPhaseJournal starts a new journal with the first phase of a CA rotation,
+// or loads the journal of the phases completed so far
+func (rcc *rotateCertsCmd) initPhaseJournal() (err error) {
+	if _, err = os.Stat(rcc.journalPath()); os.IsNotExist(err) {
+		rcc.journal = ops.NewJournal(rcc.journalPath(), rcc.scope)
+		rcc.journal.Phased = true
+		return nil
+	}
+	if rcc.journal, err = ops.LoadJournal(rcc.journalPath()); err != nil {
+		return errors.Wrap(err, "loading the journal of the CA rotation")
+	}
+	if !rcc.journal.Phased {
+		return errors.Errorf("a previous certificate rotation did not complete, set --resume to continue it or delete %s to start over", rcc.outputDirectory)
+	}
+	if previous := previousPhase(rcc.phase); previous != "" && !rcc.journal.Done(phaseStep(previous), ops.ClusterWide) {
+		return errors.Errorf("phase %s has to complete before phase %s", previous, rcc.phase)
+	}
+	return nil
+}
+
+// runPhase runs a single phase of the CA rotation, the apimodel is only updated by the last phase
+func (rcc *rotateCertsCmd) runPhase() (err error) {
+	f := rcc.rotatePhaseCerts
+	if rcc.phase == rotateCertsPhasePods {
+		f = rcc.rollPods
+	}
+	if err = rcc.clusterStep(phaseStep(rcc.phase), f); err != nil {
+		return errors.Wrapf(err, "running phase %s", rcc.phase)
+	}
+	if next := nextPhase(rcc.phase); next != "" {
+		log.Infof("Phase %s completed, run --phase=%s to continue the CA rotation", rcc.phase, next)
+		return nil
+	}
+	if err = rcc.updateAPIModel(); err != nil {
+		return errors.Wrap(err, "updating apimodel")
+	}
+	log.Infoln("Certificate rotation completed")
+	return nil
+}
+
+// rotatePhaseCerts distributes the certificates of the phase and restarts the components using them
+func (rcc *rotateCertsCmd) rotatePhaseCerts() error {
+	if err := rcc.rotateMasterCerts(); err != nil {
+		return errors.Wrap(err, "rotating control plane certificates")
+	}
+	if err := rcc.rotateAgentCerts(); err != nil {
+		return errors.Wrap(err, "rotating agent certificates")
+	}
+	return nil
+}
+
+// rollPods recreates the service account tokens and the pods using them, so that they trust the new CA
+func (rcc *rotateCertsCmd) rollPods() error {
+	agents, err := rcc.getAgentNodes()
+	if err != nil {
+		return errors.Wrap(err, "listing cluster nodes")
+	}
+	rcc.nodes = rcc.getControlPlaneNodes()
+	for k, v := range agents {
+		rcc.nodes[k] = v
+	}
+	log.Info("Recreating service account tokens")
+	if err = ops.RotateServiceAccountTokens(rcc.kubeClient); err != nil {
+		return err
+	}
+	if err = rcc.waitForKubeSystemReadiness(); err != nil {
+		log.Errorf("waitForKubeSystemReadiness returned an error: %s", err.Error())
+	}
+	return nil
+}
+
+func phaseStep(phase string) string {
+	return fmt.Sprintf("phase-%s", phase)
+}
+
+// previousPhase returns the phase that has to complete before phase, empty if phase is the first one or unknown
+func previousPhase(phase string) string {
+	for i := 1; i < len(rotateCertsPhases); i++ {
+		if rotateCertsPhases[i] == phase {
+			return rotateCertsPhases[i-1]
+		}
+	}
+	return ""
+}
+
+// nextPhase returns the phase following phase, empty if phase is the last one or unknown
+func nextPhase(phase string) string {
+	for i := 0; i < len(rotateCertsPhases)-1; i++ {
+		if rotateCertsPhases[i] == phase {
+			return rotateCertsPhases[i+1]
+		}
+	}
+	return ""
+}
+
+// phaseCertificateProfile returns the certificates distributed by a phase of the CA rotation.
+// Until the rotation is finalized the CA files trust both the current and the new CAs,
+// the leaf certificates and the CA key are the current ones until the leaves phase
+func phaseCertificateProfile(phase string, current, rotated *api.CertificateProfile) *api.CertificateProfile {
+	if phase == rotateCertsPhaseFinalize {
+		return rotated
+	}
+	p, trusted := *current, rotated
+	if phase == rotateCertsPhaseLeaves {
+		p, trusted = *rotated, current
+	}
+	// the controller manager signs with the CA key and the first certificate of ca.crt
+	p.CaCertificate = trustBundle(p.GetCACertificateBundle(), trusted.GetCACertificateBundle())
+	p.CaCertificateChain = ""
+	if current.HasSeparateCAs() && rotated.HasSeparateCAs() {
+		p.EtcdCaCertificate = trustBundle(p.EtcdCaCertificate, trusted.EtcdCaCertificate)
+		p.FrontProxyCaCertificate = trustBundle(p.FrontProxyCaCertificate, trusted.FrontProxyCaCertificate)
+	}
+	return &p
+}
+
+func trustBundle(certificates ...string) string {
+	var b strings.Builder
+	for _, c := range certificates {
+		b.WriteString(strings.TrimSpace(c))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// distributedContainerService returns the container service with the certificates distributed to the nodes
+func (rcc *rotateCertsCmd) distributedContainerService() *api.ContainerService {
+	if rcc.phase == "" {
+		return rcc.cs
+	}
+	cs, properties := *rcc.cs, *rcc.cs.Properties
+	properties.CertificateProfile = phaseCertificateProfile(rcc.phase, rcc.currentProfile, rcc.cs.Properties.CertificateProfile)
+	cs.Properties = &properties
+	return &cs
+}
+
+// clusterStep runs a step unless a previous run completed it, and records its completion
+func (rcc *rotateCertsCmd) clusterStep(step string, f func() error) error {
+	if rcc.journal.Done(step, ops.ClusterWide) {
+		log.Infof("Skipping step %s, completed by a previous run", step)
+		return nil
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	return rcc.journal.Complete(step, ops.ClusterWide)
+}
+
+// forEachNode runs a step on the nodes a previous run did not complete it on, and records its completion
+func (rcc *rotateCertsCmd) forEachNode(step string, f func(node *ssh.RemoteHost) error) error {
+	if rcc.phase != "" {
+		// each phase runs the node steps again
+		step = fmt.Sprintf("%s/%s", rcc.phase, step)
+	}
+	for _, node := range rcc.nodes {
+		if rcc.journal.Done(step, node.URI) {
+			log.Debugf("Node: %s. Skipping step %s, completed by a previous run", node.URI, step)
+			continue
+		}
+		log.Debugf("Node: %s. Step: %s", node.URI, step)
+		if err := f(node); err != nil {
+			return err
+		}
+		if err := rcc.journal.Complete(step, node.URI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRotatedCertificateProfile loads the certificates the interrupted rotation wrote to the output directory
+func (rcc *rotateCertsCmd) loadRotatedCertificateProfile() error {
+	p := apiModelFileInDir(rcc.outputDirectory)
+	log.Infof("Loading the certificates of the interrupted rotation from %s", p)
+	cs, _, err := rcc.loader.LoadContainerServiceFromFile(p, false, false, nil)
+	if err != nil {
+		return errors.Wrapf(err, "loading %s", p)
+	}
+	rcc.cs.Properties.CertificateProfile = cs.Properties.CertificateProfile
+	return nil
+}
+
 func (rcc *rotateCertsCmd) backupCerts() error {
 	log.Infof("Backing up artifacts to directory %s", rcc.backupDirectory)
-	if err := writeArtifacts(rcc.backupDirectory, rcc.cs, rcc.apiVersion, rcc.loader.Translator); err != nil {
+	if err := writeArtifacts(rcc.backupDirectory, rcc.cs, rcc.apiVersion, rcc.apiModelPath, rcc.loader.Translator); err != nil {
 		return errors.Wrap(err, "writing artifacts")
 	}
 	return nil
@@ -309,10 +610,21 @@ func (rcc *rotateCertsCmd) updateCertificateProfile() error {
 			return errors.Wrap(err, "generating artifacts")
 		}
 	} else {
-		rcc.cs.Properties.CertificateProfile = rcc.newCertsProfile
+		current := rcc.cs.Properties.CertificateProfile
+		rcc.cs.Properties.CertificateProfile = keepCertificateSettings(current, rcc.newCertsProfile)
+		if rcc.scope == rotateCertsScopeLeafOnly {
+			rcc.cs.Properties.CertificateProfile = keepCertificateAuthorities(current, rcc.cs.Properties.CertificateProfile)
+		}
+		if rcc.separateCAs {
+			rcc.cs.Properties.CertificateProfile.SeparateCAs = to.BoolPtr(true)
+		}
+		// generate the certificates missing from the new profile, signed by its CA
+		if _, _, err := rcc.cs.SetDefaultCerts(rcc.defaultCertParams(current)); err != nil {
+			return errors.Wrap(err, "generating missing certificates")
+		}
 	}
 	log.Infof("Writing artifacts to output directory %s", rcc.outputDirectory)
-	if err := writeArtifacts(rcc.outputDirectory, rcc.cs, rcc.apiVersion, rcc.loader.Translator); err != nil {
+	if err := writeArtifacts(rcc.outputDirectory, rcc.cs, rcc.apiVersion, rcc.apiModelPath, rcc.loader.Translator); err != nil {
 		return errors.Wrap(err, "writing artifacts")
 	}
 	return nil
@@ -320,13 +632,78 @@ func (rcc *rotateCertsCmd) updateCertificateProfile() error {
 
 func (rcc *rotateCertsCmd) generateTLSArtifacts() error {
 	log.Infoln("Generating new certificates")
-	rcc.cs.Properties.CertificateProfile = &api.CertificateProfile{}
-	if ok, _, err := rcc.cs.SetDefaultCerts(api.DefaultCertParams{PkiKeySize: helpers.DefaultPkiKeySize}); !ok || err != nil {
+	// the new certificates keep the key algorithm and the certificate settings of the cluster
+	current := rcc.cs.Properties.CertificateProfile
+	rcc.cs.Properties.CertificateProfile = keepCertificateSettings(current, &api.CertificateProfile{})
+	if rcc.scope == rotateCertsScopeLeafOnly {
+		rcc.cs.Properties.CertificateProfile = keepCertificateAuthorities(current, rcc.cs.Properties.CertificateProfile)
+	}
+	if rcc.separateCAs {
+		rcc.cs.Properties.CertificateProfile.SeparateCAs = to.BoolPtr(true)
+	}
+	if ok, _, err := rcc.cs.SetDefaultCerts(rcc.defaultCertParams(current)); !ok || err != nil {
 		return errors.Wrap(err, "generating new certificates")
 	}
 	return nil
 }
 
+// defaultCertParams returns the params of the generated certificates. The phases of a CA rotation keep the apiserver key,
+// which signs the service account tokens, so that the tokens stay valid while the pods are rolled
+func (rcc *rotateCertsCmd) defaultCertParams(current *api.CertificateProfile) api.DefaultCertParams {
+	params := api.DefaultCertParams{PkiKeySize: helpers.DefaultPkiKeySize}
+	if rcc.phase != "" && current != nil {
+		params.APIServerPrivateKey = current.APIServerPrivateKey
+	}
+	return params
+}
+
+// keepCertificateSettings returns the new certificate profile with the key algorithm and
+// the certificate settings of the current one, unless the new profile sets them
+func keepCertificateSettings(current, updated *api.CertificateProfile) *api.CertificateProfile {
+	if current == nil || updated == nil {
+		return updated
+	}
+	if updated.KeyAlgorithm == "" {
+		updated.KeyAlgorithm = current.KeyAlgorithm
+	}
+	if updated.SeparateCAs == nil {
+		updated.SeparateCAs = current.SeparateCAs
+	}
+	if updated.CaSettings == nil {
+		updated.CaSettings = current.CaSettings
+	}
+	if updated.APIServerSettings == nil {
+		updated.APIServerSettings = current.APIServerSettings
+	}
+	if updated.ClientSettings == nil {
+		updated.ClientSettings = current.ClientSettings
+	}
+	if updated.KubeConfigSettings == nil {
+		updated.KubeConfigSettings = current.KubeConfigSettings
+	}
+	if updated.EtcdSettings == nil {
+		updated.EtcdSettings = current.EtcdSettings
+	}
+	return updated
+}
+
+// keepCertificateAuthorities returns the new certificate profile with the CAs of the current one,
+// the leaf certificates missing from the new profile are then signed by the current CAs
+func keepCertificateAuthorities(current, updated *api.CertificateProfile) *api.CertificateProfile {
+	if current == nil || updated == nil {
+		return updated
+	}
+	updated.CaCertificate = current.CaCertificate
+	updated.CaPrivateKey = current.CaPrivateKey
+	updated.CaCertificateChain = current.CaCertificateChain
+	updated.SeparateCAs = current.SeparateCAs
+	updated.EtcdCaCertificate = current.EtcdCaCertificate
+	updated.EtcdCaPrivateKey = current.EtcdCaPrivateKey
+	updated.FrontProxyCaCertificate = current.FrontProxyCaCertificate
+	updated.FrontProxyCaPrivateKey = current.FrontProxyCaPrivateKey
+	return updated
+}
+
 // getControlPlaneNodes ...
 func (rcc *rotateCertsCmd) getControlPlaneNodes() nodeMap {
 	nodes := make(nodeMap)
@@ -388,24 +765,21 @@ func (rcc *rotateCertsCmd) distributeCerts() (err error) {
 		}
 		return nil
 	}
-	masterCerts, linuxCerts, windowsCerts, e := getFilesToDistribute(rcc.cs, "/etc/kubernetes/rotate-certs/certs")
+	masterCerts, linuxCerts, windowsCerts, e := getFilesToDistribute(rcc.distributedContainerService(), "/etc/kubernetes/rotate-certs/certs")
 	if e != nil {
 		return errors.Wrap(e, "collecting files to distribute")
 	}
-	for _, node := range rcc.nodes {
+	return rcc.forEachNode("distribute", func(node *ssh.RemoteHost) error {
 		log.Debugf("Uploading certificates to node %s", node.URI)
 		if isMaster(node) {
-			err = upload(masterCerts, node)
+			return upload(masterCerts, node)
 		} else if isLinuxAgent(node) {
-			err = upload(linuxCerts, node)
+			return upload(linuxCerts, node)
 		} else if isWindowsAgent(node) {
-			err = upload(windowsCerts, node)
-		}
-		if err != nil {
-			return err
+			return upload(windowsCerts, node)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (rcc *rotateCertsCmd) rotateMasterCerts() (err error) {
@@ -458,6 +832,10 @@ func (rcc *rotateCertsCmd) rotateAgentCerts() (err error) {
 	if err = rcc.waitForNodesReady(keys(rcc.nodes)); err != nil {
 		return err
 	}
+	if rcc.phase != "" {
+		// the pods phase recreates the service account tokens
+		return nil
+	}
 	log.Info("Recreating service account tokens")
 	if err = ops.RotateServiceAccountTokens(rcc.kubeClient); err != nil {
 		return err
@@ -471,25 +849,45 @@ func (rcc *rotateCertsCmd) rotateAgentCerts() (err error) {
 func (rcc *rotateCertsCmd) backupRemote() error {
 	log.Info("Backing up node certificates")
 	step := "backup"
-	for _, node := range rcc.nodes {
+	return rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
 		if err := execStepsSequence(isLinux, node, execRemoteFunc(remoteBashScript(step))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
 		if err := execStepsSequence(isWindowsAgent, node, execRemoteFunc(remotePowershellScript("Backup"))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (rcc *rotateCertsCmd) rotateMasters() error {
 	log.Info("Rotating control plane certificates")
 	step := "cp_certs"
-	for _, node := range rcc.nodes {
-		log.Debugf("Node: %s. Step: %s", node.URI, step)
+	err := rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
 		if err := execStepsSequence(isMaster, node, execRemoteFunc(remoteBashScript(step))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if rcc.scope == rotateCertsScopeLeafOnly || rcc.phase != "" {
+		// the CAs did not change, or the nodes trust both the current and the new CAs, restarting the control plane components
+		// is enough for them to load the new certificates, restart_control_plane execution has to remain serial, otherwise etcd would lose its quorum
+		log.Info("Restarting control plane components")
+		step = "restart_control_plane"
+		err = rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
+			if err := execStepsSequence(isMaster, node, execRemoteFunc(remoteBashScript(step))); err != nil {
+				return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
+			}
+			return nil
+		})
+		if err != nil || rcc.cs.Properties.CertificateProfile.HasSeparateCAs() {
+			// cp_certs copied the front-proxy certificates signed by the front-proxy CA
+			return err
+		}
+		return rcc.rotateFrontProxyCerts()
 	}
 	if err := rcc.rebootNodes(rcc.cs.Properties.GetMasterVMNameList()...); err != nil {
 		return err
@@ -500,50 +898,53 @@ func (rcc *rotateCertsCmd) rotateMasters() error {
 	if err := rcc.waitForNodesReady(keys(rcc.nodes)); err != nil {
 		return err
 	}
+	return rcc.rotateFrontProxyCerts()
+}
+
+// rotateFrontProxyCerts reissues the front-proxy certificates of the clusters sharing the cluster CA,
+// the first control plane node generates them and stores them in etcd, the other nodes fetch them from etcd
+func (rcc *rotateCertsCmd) rotateFrontProxyCerts() error {
 	log.Info("Rotating front-proxy certificates")
-	step = "cp_proxy"
+	step := "cp_proxy"
 	// cp_proxy execution has to remain serial, otherwise it will break the front-proxy PKI rotation
-	for _, node := range rcc.nodes {
-		log.Debugf("Node: %s. Step: %s", node.URI, step)
+	return rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
 		if err := execStepsSequence(isMaster, node, execRemoteFunc(remoteBashScript(step))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (rcc *rotateCertsCmd) rotateAgents() error {
 	log.Info("Rotating agents certificates")
 	step := "agent_certs"
-	for _, node := range rcc.nodes {
-		log.Debugf("Node: %s. Step: %s", node.URI, step)
+	return rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
 		if err := execStepsSequence(isLinuxAgent, node, execRemoteFunc(remoteBashScript(step)), deletePodFunc(rcc.kubeClient, kubeProxyLabels)); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
 		if err := execStepsSequence(isWindowsAgent, node, execRemoteFunc(remotePowershellScript("Start-CertRotation"))); err != nil {
 			return errors.Wrapf(err, "executing Start-CertRotation function on remote host %s", node.URI)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (rcc *rotateCertsCmd) cleanupRemote() error {
 	step := "cleanup"
-	for _, node := range rcc.nodes {
-		log.Debugf("Node: %s. Step: %s", node.URI, step)
+	return rcc.forEachNode(step, func(node *ssh.RemoteHost) error {
 		if err := execStepsSequence(isLinux, node, execRemoteFunc(remoteBashScript(step))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
 		if err := execStepsSequence(isWindowsAgent, node, execRemoteFunc(remotePowershellScript("Clean"))); err != nil {
 			return errors.Wrapf(err, "executing %s function on remote host %s", step, node.URI)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (rcc *rotateCertsCmd) updateAPIModel() error {
 	log.Infof("Generating new artifacts")
-	if err := writeArtifacts(filepath.Dir(rcc.apiModelPath), rcc.cs, rcc.apiVersion, rcc.loader.Translator); err != nil {
+	if err := writeArtifacts(filepath.Dir(rcc.apiModelPath), rcc.cs, rcc.apiVersion, rcc.apiModelPath, rcc.loader.Translator); err != nil {
 		return errors.Wrap(err, "writing artifacts")
 	}
 	if err := os.RemoveAll(rcc.outputDirectory); err != nil {
@@ -564,9 +965,12 @@ func execStepsSequence(cond nodeCondition, node *ssh.RemoteHost, steps ...func(n
 	return nil
 }
 
+// executeRemote runs a script on a node over SSH, tests replace it to record the remote steps
+var executeRemote = ssh.ExecuteRemote
+
 func execRemoteFunc(script string) func(node *ssh.RemoteHost) error {
 	return func(node *ssh.RemoteHost) error {
-		out, err := ssh.ExecuteRemote(context.Background(), node, script)
+		out, err := executeRemote(context.Background(), node, script)
 		if err != nil {
 			log.Debugf("Remote command output: %s", out)
 		}
@@ -635,11 +1039,15 @@ func (rcc *rotateCertsCmd) waitForKubeSystemReadiness() error {
 func (rcc *rotateCertsCmd) rebootNodes(nodes ...string) error {
 	log.Info("Rebooting control plane nodes")
 	if rcc.cs.Properties.MasterProfile.IsAvailabilitySet() {
-		for _, node := range nodes {
-			log.Debugf("Node: %s. Step: reboot", node)
+		step := "reboot"
+		for _, node := range rcc.journal.Pending(step, nodes) {
+			log.Debugf("Node: %s. Step: %s", node, step)
 			if err := rcc.armClient.RestartVirtualMachine(rcc.resourceGroupName, node); err != nil {
 				return errors.Wrapf(err, "rebooting host %s", node)
 			}
+			if err := rcc.journal.Complete(step, node); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -690,7 +1098,7 @@ func getFilesToDistribute(cs *api.ContainerService, dir string) (fileMap, fileMa
 	masterFiles := fileMap{
 		"apiserver.crt":     ssh.NewRemoteFile(path.Join(dir, "apiserver.crt"), crtPermissions, rootUserGroup, []byte(p.APIServerCertificate)),
 		"apiserver.key":     ssh.NewRemoteFile(path.Join(dir, "apiserver.key"), keyPermissions, rootUserGroup, []byte(p.APIServerPrivateKey)),
-		"ca.crt":            ssh.NewRemoteFile(path.Join(dir, "ca.crt"), crtPermissions, rootUserGroup, []byte(p.CaCertificate)),
+		"ca.crt":            ssh.NewRemoteFile(path.Join(dir, "ca.crt"), crtPermissions, rootUserGroup, []byte(p.GetCACertificateBundle())),
 		"ca.key":            ssh.NewRemoteFile(path.Join(dir, "ca.key"), keyPermissions, rootUserGroup, []byte(p.CaPrivateKey)),
 		"client.crt":        ssh.NewRemoteFile(path.Join(dir, "client.crt"), crtPermissions, rootUserGroup, []byte(p.ClientCertificate)),
 		"client.key":        ssh.NewRemoteFile(path.Join(dir, "client.key"), keyPermissions, rootUserGroup, []byte(p.ClientPrivateKey)),
@@ -709,6 +1117,12 @@ func getFilesToDistribute(cs *api.ContainerService, dir string) (fileMap, fileMa
 		key := fmt.Sprintf("etcdpeer%d.key", i)
 		masterFiles[key] = ssh.NewRemoteFile(path.Join(dir, key), keyPermissions, etcdUserGroup, []byte(p.EtcdPeerPrivateKeys[i]))
 	}
+	if p.HasSeparateCAs() {
+		masterFiles["etcd-ca.crt"] = ssh.NewRemoteFile(path.Join(dir, "etcd-ca.crt"), crtPermissions, rootUserGroup, []byte(p.EtcdCaCertificate))
+		masterFiles["proxy-ca.crt"] = ssh.NewRemoteFile(path.Join(dir, "proxy-ca.crt"), crtPermissions, rootUserGroup, []byte(p.FrontProxyCaCertificate))
+		masterFiles["proxy.crt"] = ssh.NewRemoteFile(path.Join(dir, "proxy.crt"), crtPermissions, rootUserGroup, []byte(p.FrontProxyClientCertificate))
+		masterFiles["proxy.key"] = ssh.NewRemoteFile(path.Join(dir, "proxy.key"), keyPermissions, rootUserGroup, []byte(p.FrontProxyClientPrivateKey))
+	}
 	linuxFiles := fileMap{
 		"ca.crt":     masterFiles["ca.crt"],
 		"client.crt": masterFiles["client.crt"],
@@ -716,7 +1130,7 @@ func getFilesToDistribute(cs *api.ContainerService, dir string) (fileMap, fileMa
 		"script":     linuxScript,
 	}
 	windowsFiles := fileMap{
-		"ca.crt":     ssh.NewRemoteFile(fmt.Sprintf("$env:temp\\%s", "ca.crt"), "", "", []byte(p.CaCertificate)),
+		"ca.crt":     ssh.NewRemoteFile(fmt.Sprintf("$env:temp\\%s", "ca.crt"), "", "", []byte(p.GetCACertificateBundle())),
 		"client.crt": ssh.NewRemoteFile(fmt.Sprintf("$env:temp\\%s", "client.crt"), "", "", []byte(p.ClientCertificate)),
 		"client.key": ssh.NewRemoteFile(fmt.Sprintf("$env:temp\\%s", "client.key"), "", "", []byte(p.ClientPrivateKey)),
 		"script":     windowsScript,
@@ -4,9 +4,15 @@
 package cmd
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	ops "github.com/Azure/aks-engine-azurestack/cmd/rotatecerts"
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
 	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/ssh"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/to"
 	"github.com/google/uuid"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -39,6 +45,11 @@ func TestRotateCertsCmdValidateArgs(t *testing.T) {
 
 	existingFile := "../examples/kubernetes.json"
 	missingFile := "./random/file"
+	emptyDir := t.TempDir()
+	interruptedDir := t.TempDir()
+	if err := ops.NewJournal(filepath.Join(interruptedDir, rotateCertsJournalFile), rotateCertsScopeAll).Complete("backup-artifacts", ops.ClusterWide); err != nil {
+		t.Fatalf("failed to write the journal: %s", err)
+	}
 
 	cases := []struct {
 		rcc         *rotateCertsCmd
@@ -140,6 +151,136 @@ func TestRotateCertsCmdValidateArgs(t *testing.T) {
 			},
 			name: "Unset generateCerts if newCertsPath is set",
 		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				scope:                  "ca-only",
+			},
+			expectedErr: errors.New("--scope must be all or leaf-only"),
+			name:        "Invalid scope",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				scope:                  rotateCertsScopeLeafOnly,
+				separateCAs:            true,
+			},
+			expectedErr: errors.New("--separate-cas cannot be set with --scope=leaf-only, creating new CAs requires a full rotation"),
+			name:        "Leaf-only scope with separate CAs",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				newCertsPath:           existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				resume:                 true,
+			},
+			expectedErr: errors.New("--certificate-profile and --separate-cas cannot be set with --resume, the certificates of the interrupted rotation are reused"),
+			name:        "Resume with new certs profile",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				outputDirectory:        emptyDir,
+				resume:                 true,
+			},
+			expectedErr: errors.Errorf("no certificate rotation to resume, %s does not exist", filepath.Join(emptyDir, rotateCertsJournalFile)),
+			name:        "Resume without journal",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				outputDirectory:        interruptedDir,
+			},
+			expectedErr: errors.Errorf("a previous certificate rotation did not complete, set --resume to continue it or delete %s to start over", interruptedDir),
+			name:        "Interrupted rotation without resume",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				outputDirectory:        interruptedDir,
+				resume:                 true,
+				scope:                  rotateCertsScopeLeafOnly,
+			},
+			expectedErr: nil,
+			name:        "Resume interrupted rotation",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				phase:                  "rollback",
+			},
+			expectedErr: errors.New("--phase must be one of: trust, pods, leaves, finalize"),
+			name:        "Invalid phase",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				phase:                  rotateCertsPhaseTrust,
+				resume:                 true,
+			},
+			expectedErr: errors.New("--resume cannot be set with --phase, running a phase again resumes it"),
+			name:        "Phase with resume",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				newCertsPath:           existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				phase:                  rotateCertsPhaseLeaves,
+			},
+			expectedErr: errors.New("--certificate-profile can only be set with --phase=trust, which creates the new certificates"),
+			name:        "Leaves phase with new certs profile",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				outputDirectory:        emptyDir,
+				phase:                  rotateCertsPhasePods,
+			},
+			expectedErr: errors.New("no CA rotation in progress, run --phase=trust first"),
+			name:        "Pods phase without journal",
+		},
+		{
+			rcc: &rotateCertsCmd{
+				apiModelPath:           existingFile,
+				linuxSSHPrivateKeyPath: existingFile,
+				sshHostURI:             "server.example.com",
+				location:               "southcentralus",
+				outputDirectory:        emptyDir,
+				phase:                  rotateCertsPhaseTrust,
+			},
+			expectedErr: nil,
+			name:        "Trust phase",
+		},
 	}
 	for _, tc := range cases {
 		c := tc
@@ -175,3 +316,198 @@ func TestRotateCertsCmdValidateArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestKeepCertificateSettings(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	current := &api.CertificateProfile{
+		CaCertificate:     "ca",
+		KeyAlgorithm:      "ECDSA-P256",
+		SeparateCAs:       to.BoolPtr(true),
+		EtcdCaCertificate: "etcd-ca",
+		CaSettings:        &api.CertificateSettings{ValidityDays: 3650},
+		APIServerSettings: &api.CertificateSettings{ExtraSANs: []string{"api.contoso.com"}},
+	}
+	generated := keepCertificateSettings(current, &api.CertificateProfile{})
+	g.Expect(generated.CaCertificate).To(BeEmpty())
+	g.Expect(generated.EtcdCaCertificate).To(BeEmpty())
+	g.Expect(generated.KeyAlgorithm).To(Equal("ECDSA-P256"))
+	g.Expect(generated.HasSeparateCAs()).To(BeTrue())
+	g.Expect(generated.CaSettings).To(Equal(current.CaSettings))
+	g.Expect(generated.APIServerSettings).To(Equal(current.APIServerSettings))
+
+	provided := keepCertificateSettings(current, &api.CertificateProfile{CaCertificate: "new-ca", CaSettings: &api.CertificateSettings{ValidityDays: 365}})
+	g.Expect(provided.CaCertificate).To(Equal("new-ca"))
+	g.Expect(provided.CaSettings.ValidityDays).To(Equal(365))
+	g.Expect(provided.APIServerSettings).To(Equal(current.APIServerSettings))
+
+	g.Expect(keepCertificateSettings(nil, provided)).To(Equal(provided))
+}
+
+func TestKeepCertificateAuthorities(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	current := &api.CertificateProfile{
+		CaCertificate:           "ca",
+		CaPrivateKey:            "ca-key",
+		CaCertificateChain:      "chain",
+		SeparateCAs:             to.BoolPtr(true),
+		EtcdCaCertificate:       "etcd-ca",
+		EtcdCaPrivateKey:        "etcd-ca-key",
+		FrontProxyCaCertificate: "front-proxy-ca",
+		FrontProxyCaPrivateKey:  "front-proxy-ca-key",
+		APIServerCertificate:    "apiserver",
+	}
+	updated := keepCertificateAuthorities(current, &api.CertificateProfile{ClientCertificate: "client"})
+	g.Expect(updated.CaCertificate).To(Equal("ca"))
+	g.Expect(updated.CaPrivateKey).To(Equal("ca-key"))
+	g.Expect(updated.CaCertificateChain).To(Equal("chain"))
+	g.Expect(updated.HasSeparateCAs()).To(BeTrue())
+	g.Expect(updated.EtcdCaPrivateKey).To(Equal("etcd-ca-key"))
+	g.Expect(updated.FrontProxyCaPrivateKey).To(Equal("front-proxy-ca-key"))
+	g.Expect(updated.ClientCertificate).To(Equal("client"))
+	g.Expect(updated.APIServerCertificate).To(BeEmpty())
+
+	g.Expect(keepCertificateAuthorities(nil, updated)).To(Equal(updated))
+}
+
+func TestGenerateTLSArtifactsLeafOnly(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cs := api.CreateMockContainerService("testcluster", "", 3, 2, false)
+	cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+	_, _, err := cs.SetDefaultCerts(api.DefaultCertParams{PkiKeySize: 2048})
+	g.Expect(err).ToNot(HaveOccurred())
+	current := *cs.Properties.CertificateProfile
+
+	rcc := &rotateCertsCmd{cs: cs, scope: rotateCertsScopeLeafOnly}
+	g.Expect(rcc.generateTLSArtifacts()).To(Succeed())
+	rotated := cs.Properties.CertificateProfile
+	g.Expect(rotated.CaCertificate).To(Equal(current.CaCertificate))
+	g.Expect(rotated.CaPrivateKey).To(Equal(current.CaPrivateKey))
+	g.Expect(rotated.APIServerCertificate).ToNot(Equal(current.APIServerCertificate))
+	g.Expect(rotated.ClientCertificate).ToNot(Equal(current.ClientCertificate))
+	g.Expect(rotated.EtcdPeerCertificates).To(HaveLen(3))
+	g.Expect(rotated.EtcdPeerCertificates[0]).ToNot(Equal(current.EtcdPeerCertificates[0]))
+
+	// the control plane components are restarted rather than rebooted, and the front-proxy certificates are reissued
+	var steps []string
+	executeRemote = func(ctx context.Context, node *ssh.RemoteHost, script string) (string, error) {
+		steps = append(steps, node.URI+" "+script)
+		return "", nil
+	}
+	defer func() { executeRemote = ssh.ExecuteRemote }()
+	rcc.nodes = nodeMap{
+		"k8s-master-12345678-0": {URI: "k8s-master-12345678-0", OperatingSystem: api.Linux},
+		"k8s-agent-12345678-0":  {URI: "k8s-agent-12345678-0", OperatingSystem: api.Linux},
+	}
+	rcc.journal = ops.NewJournal(filepath.Join(t.TempDir(), rotateCertsJournalFile), rotateCertsScopeLeafOnly)
+	g.Expect(rcc.rotateMasters()).To(Succeed())
+	g.Expect(steps).To(Equal([]string{
+		"k8s-master-12345678-0 " + remoteBashScript("cp_certs"),
+		"k8s-master-12345678-0 " + remoteBashScript("restart_control_plane"),
+		"k8s-master-12345678-0 " + remoteBashScript("cp_proxy"),
+	}))
+
+	// cp_certs copies the front-proxy certificates signed by the front-proxy CA
+	steps = nil
+	cs.Properties.CertificateProfile.SeparateCAs = to.BoolPtr(true)
+	rcc.phase = rotateCertsPhaseLeaves
+	g.Expect(rcc.rotateMasters()).To(Succeed())
+	g.Expect(steps).To(Equal([]string{
+		"k8s-master-12345678-0 " + remoteBashScript("cp_certs"),
+		"k8s-master-12345678-0 " + remoteBashScript("restart_control_plane"),
+	}))
+	cs.Properties.CertificateProfile.SeparateCAs = nil
+	rcc.phase = ""
+
+	rcc.scope = rotateCertsScopeAll
+	g.Expect(rcc.generateTLSArtifacts()).To(Succeed())
+	g.Expect(cs.Properties.CertificateProfile.CaCertificate).ToNot(Equal(current.CaCertificate))
+}
+
+func TestInitPhaseJournal(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	rcc := &rotateCertsCmd{outputDirectory: dir, scope: rotateCertsScopeAll, phase: rotateCertsPhaseTrust}
+	g.Expect(rcc.initJournal()).To(Succeed())
+	g.Expect(rcc.journal.Phased).To(BeTrue())
+	g.Expect(rcc.journal.Complete(phaseStep(rotateCertsPhaseTrust), ops.ClusterWide)).To(Succeed())
+
+	rcc = &rotateCertsCmd{outputDirectory: dir, scope: rotateCertsScopeAll, phase: rotateCertsPhasePods}
+	g.Expect(rcc.initJournal()).To(Succeed())
+
+	rcc.phase = rotateCertsPhaseLeaves
+	err := rcc.initJournal()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("phase pods has to complete before phase leaves"))
+
+	interruptedDir := t.TempDir()
+	g.Expect(ops.NewJournal(filepath.Join(interruptedDir, rotateCertsJournalFile), rotateCertsScopeAll).Complete("backup-artifacts", ops.ClusterWide)).To(Succeed())
+	rcc = &rotateCertsCmd{outputDirectory: interruptedDir, scope: rotateCertsScopeAll, phase: rotateCertsPhaseTrust}
+	g.Expect(rcc.initJournal()).ToNot(Succeed())
+
+	g.Expect(nextPhase(rotateCertsPhaseLeaves)).To(Equal(rotateCertsPhaseFinalize))
+	g.Expect(nextPhase(rotateCertsPhaseFinalize)).To(BeEmpty())
+	g.Expect(previousPhase(rotateCertsPhaseTrust)).To(BeEmpty())
+}
+
+func TestPhaseCertificateProfile(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	current := &api.CertificateProfile{
+		CaCertificate:        "old-ca",
+		CaPrivateKey:         "old-ca-key",
+		CaCertificateChain:   "old-root",
+		APIServerCertificate: "old-apiserver",
+		SeparateCAs:          to.BoolPtr(true),
+		EtcdCaCertificate:    "old-etcd-ca",
+	}
+	rotated := &api.CertificateProfile{
+		CaCertificate:        "new-ca",
+		CaPrivateKey:         "new-ca-key",
+		APIServerCertificate: "new-apiserver",
+		SeparateCAs:          to.BoolPtr(true),
+		EtcdCaCertificate:    "new-etcd-ca",
+	}
+
+	trust := phaseCertificateProfile(rotateCertsPhaseTrust, current, rotated)
+	g.Expect(trust.GetCACertificateBundle()).To(Equal("old-ca\nold-root\nnew-ca\n"))
+	g.Expect(trust.CaPrivateKey).To(Equal("old-ca-key"))
+	g.Expect(trust.APIServerCertificate).To(Equal("old-apiserver"))
+	g.Expect(trust.EtcdCaCertificate).To(Equal("old-etcd-ca\nnew-etcd-ca\n"))
+
+	leaves := phaseCertificateProfile(rotateCertsPhaseLeaves, current, rotated)
+	g.Expect(leaves.GetCACertificateBundle()).To(Equal("new-ca\nold-ca\nold-root\n"))
+	g.Expect(leaves.CaPrivateKey).To(Equal("new-ca-key"))
+	g.Expect(leaves.APIServerCertificate).To(Equal("new-apiserver"))
+	g.Expect(leaves.EtcdCaCertificate).To(Equal("new-etcd-ca\nold-etcd-ca\n"))
+
+	g.Expect(phaseCertificateProfile(rotateCertsPhaseFinalize, current, rotated)).To(Equal(rotated))
+	g.Expect(current.CaCertificate).To(Equal("old-ca"))
+	g.Expect(rotated.CaCertificate).To(Equal("new-ca"))
+}
+
+func TestGenerateTLSArtifactsPhased(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	cs := api.CreateMockContainerService("testcluster", "", 1, 2, false)
+	cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+	_, _, err := cs.SetDefaultCerts(api.DefaultCertParams{PkiKeySize: 2048})
+	g.Expect(err).ToNot(HaveOccurred())
+	current := *cs.Properties.CertificateProfile
+
+	rcc := &rotateCertsCmd{cs: cs, scope: rotateCertsScopeAll, phase: rotateCertsPhaseTrust}
+	g.Expect(rcc.generateTLSArtifacts()).To(Succeed())
+	rotated := cs.Properties.CertificateProfile
+	g.Expect(rotated.CaCertificate).ToNot(Equal(current.CaCertificate))
+	g.Expect(rotated.APIServerCertificate).ToNot(Equal(current.APIServerCertificate))
+	g.Expect(rotated.APIServerPrivateKey).To(Equal(current.APIServerPrivateKey))
+	g.Expect(rotated.ClientPrivateKey).ToNot(Equal(current.ClientPrivateKey))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package rotatecerts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ClusterWide is the node name of the steps that are not executed on a single node
+const ClusterWide = "cluster"
+
+// Journal records the completed steps of a certificate rotation
+// so that an interrupted rotation can be resumed.
+//
+// The journal is written to disk each time a step completes.
+type Journal struct {
+	// Scope is the scope of the certificate rotation
+	Scope string `json:"scope"`
+	// Phased is true if the rotation runs one phase at a time
+	Phased bool `json:"phased,omitempty"`
+	// Steps maps each step to the nodes it completed on
+	Steps map[string][]string `json:"steps"`
+
+	path string
+}
+
+// NewJournal returns an empty journal persisted to path
+func NewJournal(path, scope string) *Journal {
+	return &Journal{
+		Scope: scope,
+		Steps: map[string][]string{},
+		path:  path,
+	}
+}
+
+// LoadJournal reads the journal persisted to path
+func LoadJournal(path string) (*Journal, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading journal %s", path)
+	}
+	j := NewJournal(path, "")
+	if err = json.Unmarshal(b, j); err != nil {
+		return nil, errors.Wrapf(err, "parsing journal %s", path)
+	}
+	if j.Steps == nil {
+		j.Steps = map[string][]string{}
+	}
+	return j, nil
+}
+
+// Done returns true if the step completed on the node
+func (j *Journal) Done(step, node string) bool {
+	for _, n := range j.Steps[step] {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records that the step completed on the node and persists the journal
+func (j *Journal) Complete(step, node string) error {
+	if j.Done(step, node) {
+		return nil
+	}
+	j.Steps[step] = append(j.Steps[step], node)
+	return j.save()
+}
+
+// Pending returns the nodes the step did not complete on
+func (j *Journal) Pending(step string, nodes []string) []string {
+	pending := make([]string, 0)
+	for _, n := range nodes {
+		if !j.Done(step, n) {
+			pending = append(pending, n)
+		}
+	}
+	return pending
+}
+
+// save writes the journal to a temporary file first so that it is never left truncated
+func (j *Journal) save() error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "serializing journal")
+	}
+	tmp := filepath.Join(filepath.Dir(j.path), "."+filepath.Base(j.path)+".tmp")
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrapf(err, "writing journal %s", tmp)
+	}
+	if err = os.Rename(tmp, j.path); err != nil {
+		return errors.Wrapf(err, "writing journal %s", j.path)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package rotatecerts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "journal.json")
+	j := NewJournal(path, "leaf-only")
+	j.Phased = true
+	g.Expect(j.Done("cp_certs", "k8s-master-0")).To(BeFalse())
+
+	g.Expect(j.Complete("backup-artifacts", ClusterWide)).To(Succeed())
+	g.Expect(j.Complete("cp_certs", "k8s-master-0")).To(Succeed())
+	g.Expect(j.Complete("cp_certs", "k8s-master-0")).To(Succeed())
+	g.Expect(j.Steps["cp_certs"]).To(Equal([]string{"k8s-master-0"}))
+	g.Expect(j.Pending("cp_certs", []string{"k8s-master-0", "k8s-master-1"})).To(Equal([]string{"k8s-master-1"}))
+
+	t.Run("the journal is persisted on each completed step", func(t *testing.T) {
+		loaded, err := LoadJournal(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(loaded.Scope).To(Equal("leaf-only"))
+		g.Expect(loaded.Phased).To(BeTrue())
+		g.Expect(loaded.Done("backup-artifacts", ClusterWide)).To(BeTrue())
+		g.Expect(loaded.Done("cp_certs", "k8s-master-0")).To(BeTrue())
+		g.Expect(loaded.Done("cp_certs", "k8s-master-1")).To(BeFalse())
+
+		g.Expect(loaded.Complete("cp_certs", "k8s-master-1")).To(Succeed())
+		reloaded, err := LoadJournal(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(reloaded.Pending("cp_certs", []string{"k8s-master-0", "k8s-master-1"})).To(BeEmpty())
+	})
+
+	t.Run("loading a missing or invalid journal fails", func(t *testing.T) {
+		_, err := LoadJournal(filepath.Join(t.TempDir(), "missing.json"))
+		g.Expect(err).To(HaveOccurred())
+
+		invalid := filepath.Join(t.TempDir(), "invalid.json")
+		g.Expect(os.WriteFile(invalid, []byte("{"), 0600)).To(Succeed())
+		_, err = LoadJournal(invalid)
+		g.Expect(err).To(HaveOccurred())
+	})
+}
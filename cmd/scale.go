@@ -68,6 +68,7 @@ const (
 	scaleShortDescription = "Scale an existing AKS Engine-created Kubernetes cluster"
 	scaleLongDescription  = "Scale an existing AKS Engine-created Kubernetes cluster by specifying a new desired number of nodes in a node pool"
 	apiModelFilename      = "apimodel.json"
+	apiModelYAMLFilename  = "apimodel.yaml"
 )
 
 // NewScaleCmd run a command to upgrade a Kubernetes cluster
@@ -89,7 +90,7 @@ func newScaleCmd() *cobra.Command {
 	f := scaleCmd.Flags()
 	f.StringVarP(&sc.location, "location", "l", "", "location the cluster is deployed in")
 	f.StringVarP(&sc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed")
-	f.StringVarP(&sc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file")
+	f.StringVarP(&sc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file")
 	f.StringVar(&sc.deploymentDirectory, "deployment-dir", "", "the location of the output from `generate`")
 	f.IntVarP(&sc.newDesiredAgentCount, "new-node-count", "c", 0, "desired number of nodes")
 	f.StringVar(&sc.agentPoolToScale, "node-pool", "", "node pool to scale")
@@ -149,12 +150,12 @@ func (sc *scaleCmd) load() error {
 	sc.logger = log.NewEntry(log.New())
 	var err error
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), sc.authArgs.retryOptions.GetOperationTimeout())
 	defer cancel()
 	sc.updateVMSSModel = true
 
 	if sc.apiModelPath == "" {
-		sc.apiModelPath = filepath.Join(sc.deploymentDirectory, apiModelFilename)
+		sc.apiModelPath = apiModelFileInDir(sc.deploymentDirectory)
 	}
 
 	if _, err = os.Stat(sc.apiModelPath); os.IsNotExist(err) {
@@ -271,7 +272,7 @@ func (sc *scaleCmd) run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), sc.authArgs.retryOptions.GetOperationTimeout())
 	defer cancel()
 	orchestratorInfo := sc.containerService.Properties.OrchestratorProfile
 	var currentNodeCount, highestUsedIndex, index, winPoolIndex int
@@ -535,7 +536,7 @@ func (sc *scaleCmd) saveAPIModel() error {
 	}
 	sc.containerService.Properties.AgentPoolProfiles[sc.agentPoolIndex].Count = sc.newDesiredAgentCount
 
-	b, err := apiloader.SerializeContainerService(sc.containerService, apiVersion)
+	b, err := apiloader.SerializeContainerServiceForFile(sc.containerService, apiVersion, sc.apiModelPath)
 
 	if err != nil {
 		return err
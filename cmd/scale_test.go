@@ -4,9 +4,13 @@
 package cmd
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers/fakearm"
+	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -239,3 +243,26 @@ func TestVmInVMASAgentPool(t *testing.T) {
 		})
 	}
 }
+
+func TestScaleCmdFakeARM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stateFile, outputDirectory := deployToFakeARM(t)
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"scale", "--fake-arm-state", stateFile, "--api-model", filepath.Join(outputDirectory, "apimodel.json"),
+		"--location", "local", "--resource-group", "fakearm-rg", "--node-pool", "linuxpool", "--new-node-count", "4",
+		"--auth-method", "client_secret", "--subscription-id", fakearm.DefaultSubscriptionID, "--azure-env", "AzureStackCloud"})
+	g.Expect(root.Execute()).To(Succeed())
+
+	server, err := fakearm.NewServer(fakearm.WithStateFile(stateFile))
+	g.Expect(err).NotTo(HaveOccurred())
+	var agents []string
+	for _, vm := range server.Resources("fakearm-rg", "Microsoft.Compute/virtualMachines") {
+		if name := vm["name"].(string); strings.HasPrefix(name, "k8s-linuxpool-") {
+			agents = append(agents, name)
+		}
+	}
+	g.Expect(agents).To(HaveLen(4))
+	g.Expect(agents[3]).To(HaveSuffix("-3"))
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/api/schema"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	schemaName             = "schema"
+	schemaShortDescription = "Print the JSON Schema of the cluster definition"
+	schemaLongDescription  = "Print the JSON Schema of the vlabs cluster definition, or validate a cluster definition against it. " +
+		"Editors use the schema to validate and autocomplete cluster definitions."
+)
+
+type schemaCmd struct {
+	outputFile   string
+	apimodelPath string
+}
+
+func newSchemaCmd() *cobra.Command {
+	sc := schemaCmd{}
+
+	command := &cobra.Command{
+		Use:   schemaName,
+		Short: schemaShortDescription,
+		Long:  schemaLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.run(cmd.OutOrStdout())
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&sc.outputFile, "output", "o", "", "file the schema is written to (defaults to standard output)")
+	f.StringVarP(&sc.apimodelPath, "api-model", "m", "", "path to a JSON or YAML cluster definition to validate against the schema instead of printing it")
+
+	return command
+}
+
+func (sc *schemaCmd) run(out io.Writer) error {
+	s := schema.ForContainerService()
+	if sc.apimodelPath != "" {
+		return sc.validate(s, out)
+	}
+	b, err := helpers.JSONMarshalIndent(s, "", "  ", false)
+	if err != nil {
+		return errors.Wrap(err, "serializing the JSON Schema")
+	}
+	if sc.outputFile == "" {
+		_, err = out.Write(b)
+		return err
+	}
+	if err = os.WriteFile(sc.outputFile, b, 0644); err != nil {
+		return errors.Wrapf(err, "writing the JSON Schema to %s", sc.outputFile)
+	}
+	return nil
+}
+
+func (sc *schemaCmd) validate(s *schema.Schema, out io.Writer) error {
+	contents, err := helpers.ReadDecryptedFile(sc.apimodelPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading API model file %s", sc.apimodelPath)
+	}
+	if contents, err = api.ConvertYAMLToJSON(contents); err != nil {
+		return err
+	}
+	errs, err := s.Validate(contents)
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintln(out, e.Error())
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("%s does not match the schema, found %d errors", sc.apimodelPath, len(errs))
+	}
+	return nil
+}
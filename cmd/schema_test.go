@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/schema"
+	. "github.com/onsi/gomega"
+)
+
+func TestSchemaCmd_ShouldCreate(t *testing.T) {
+	command := newSchemaCmd()
+
+	g := NewGomegaWithT(t)
+	g.Expect(command.Use).Should(Equal(schemaName))
+	g.Expect(command.Short).Should(Equal(schemaShortDescription))
+	g.Expect(command.Long).Should(Equal(schemaLongDescription))
+	g.Expect(command.Flags().Lookup("output")).NotTo(BeNil())
+	g.Expect(command.Flags().Lookup("api-model")).NotTo(BeNil())
+}
+
+func TestSchemaCmd_Run(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	var out bytes.Buffer
+	sc := &schemaCmd{}
+	g.Expect(sc.run(&out)).To(Succeed())
+	s := &schema.Schema{}
+	g.Expect(json.Unmarshal(out.Bytes(), s)).To(Succeed())
+	g.Expect(s.Schema).To(Equal(schema.Draft))
+	g.Expect(s.Definitions).To(HaveKey("AgentPoolProfile"))
+
+	sc.outputFile = filepath.Join(t.TempDir(), "apimodel.schema.json")
+	g.Expect(sc.run(&out)).To(Succeed())
+	b, err := os.ReadFile(sc.outputFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b).To(Equal(out.Bytes()))
+}
+
+func TestSchemaCmd_Validate(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	g.Expect(os.WriteFile(valid, []byte("apiVersion: vlabs\nproperties:\n  masterProfile:\n    count: 1\n    dnsPrefix: test\n    vmSize: Standard_D2_v2\n  linuxProfile:\n    adminUsername: azureuser\n    ssh:\n      publicKeys:\n        - keyData: ssh-rsa AAAA\n"), 0600)).To(Succeed())
+	var out bytes.Buffer
+	sc := &schemaCmd{apimodelPath: valid}
+	g.Expect(sc.run(&out)).To(Succeed())
+	g.Expect(out.String()).To(BeEmpty())
+
+	invalid := filepath.Join(dir, "invalid.json")
+	g.Expect(os.WriteFile(invalid, []byte(`{"apiVersion": "vlabs", "properties": {"masterProfile": {"count": "1", "dnsPrefix": "test", "vmSize": "Standard_D2_v2"}}}`), 0600)).To(Succeed())
+	sc.apimodelPath = invalid
+	err := sc.run(&out)
+	g.Expect(err).To(MatchError(invalid + " does not match the schema, found 2 errors"))
+	g.Expect(out.String()).To(Equal("properties.masterProfile.count: expected an integer, got a string\nproperties.linuxProfile: required key is missing\n"))
+}
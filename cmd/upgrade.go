@@ -80,7 +80,7 @@ func newUpgradeCmd() *cobra.Command {
 	f := upgradeCmd.Flags()
 	f.StringVarP(&uc.location, "location", "l", "", "location the cluster is deployed in (required)")
 	f.StringVarP(&uc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed (required)")
-	f.StringVarP(&uc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file")
+	f.StringVarP(&uc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json or apimodel.yaml file")
 	f.StringVar(&uc.deploymentDirectory, "deployment-dir", "", "the location of the output from `generate`")
 	f.StringVarP(&uc.upgradeVersion, "upgrade-version", "k", "", "desired kubernetes version (required)")
 	f.StringVarP(&uc.kubeconfigPath, "kubeconfig", "b", "", "the path of the kubeconfig file")
@@ -146,12 +146,12 @@ func (uc *upgradeCmd) validate(cmd *cobra.Command) error {
 func (uc *upgradeCmd) loadCluster() error {
 	var err error
 
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), uc.getAuthArgs().retryOptions.GetOperationTimeout())
 	defer cancel()
 
 	// Load apimodel from the directory.
 	if uc.apiModelPath == "" {
-		uc.apiModelPath = filepath.Join(uc.deploymentDirectory, apiModelFilename)
+		uc.apiModelPath = apiModelFileInDir(uc.deploymentDirectory)
 	}
 
 	if _, err = os.Stat(uc.apiModelPath); os.IsNotExist(err) {
@@ -419,7 +419,7 @@ func (uc *upgradeCmd) run(cmd *cobra.Command, args []string) error {
 			Locale: uc.locale,
 		},
 	}
-	b, err := apiloader.SerializeContainerService(uc.containerService, uc.apiVersion)
+	b, err := apiloader.SerializeContainerServiceForFile(uc.containerService, uc.apiVersion, uc.apiModelPath)
 	if err != nil {
 		return err
 	}
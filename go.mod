@@ -33,9 +33,11 @@ require (
 	golang.org/x/text v0.21.0
 	gopkg.in/go-playground/validator.v9 v9.25.0
 	gopkg.in/ini.v1 v1.41.0
+	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/api v0.27.13
 	k8s.io/apimachinery v0.27.13
 	k8s.io/client-go v0.27.13
+	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
@@ -93,13 +95,11 @@ require (
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	k8s.io/klog/v2 v2.90.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20230501164219-8b0f38b5fd1f // indirect
 	k8s.io/utils v0.0.0-20230209194617-a36077c30491 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
 
 replace google.golang.org/grpc => google.golang.org/grpc v1.56.3
@@ -75,6 +75,13 @@ configureSecrets(){
   echo "${ETCD_SERVER_CERTIFICATE}" | base64 --decode >"${etcdserver_crt}"
   echo "${ETCD_CLIENT_CERTIFICATE}" | base64 --decode >"${etcdclient_crt}"
   echo "${ETCD_PEER_CERT}" | base64 --decode >"${etcdpeer_crt}"
+{{- if HasSeparateCAs}}
+  local etcdca_crt="/etc/kubernetes/certs/etcd-ca.crt"
+  touch "${etcdca_crt}"
+  chmod 0644 "${etcdca_crt}"
+  chown root:root "${etcdca_crt}"
+  echo "${ETCD_CA_CERTIFICATE}" | base64 --decode >"${etcdca_crt}"
+{{- end}}
 }
 configureEtcd() {
   set -x
@@ -146,9 +153,21 @@ ensureCron() {
   fi
 }
 generateAggregatedAPICerts() {
+{{- if HasSeparateCAs}}
+  {{- /* The front-proxy certificates are signed by the front-proxy CA of the apimodel */}}
+  local proxyca_crt="/etc/kubernetes/certs/proxy-ca.crt" proxy_crt="/etc/kubernetes/certs/proxy.crt" proxy_key="/etc/kubernetes/certs/proxy.key"
+  touch "${proxyca_crt}" "${proxy_crt}" "${proxy_key}"
+  chmod 0644 "${proxyca_crt}" "${proxy_crt}"
+  chmod 0600 "${proxy_key}"
+  chown root:root "${proxyca_crt}" "${proxy_crt}" "${proxy_key}"
+  echo "${FRONT_PROXY_CA_CERTIFICATE}" | base64 --decode >"${proxyca_crt}"
+  echo "${FRONT_PROXY_CLIENT_CERTIFICATE}" | base64 --decode >"${proxy_crt}"
+  echo "${FRONT_PROXY_CLIENT_PRIVATE_KEY}" | base64 --decode >"${proxy_key}"
+{{- else}}
   local f=/etc/kubernetes/generate-proxy-certs.sh
   wait_for_file 1200 1 $f || exit {{GetCSEErrorCode "ERR_FILE_WATCH_TIMEOUT"}}
   $f
+{{- end}}
 }
 configureKubeletServerCert() {
   local kubeletserver_key="/etc/kubernetes/certs/kubeletserver.key" kubeletserver_crt="/etc/kubernetes/certs/kubeletserver.crt"
@@ -555,7 +574,7 @@ ensureLabelExclusionForAzurePolicyAddon() {
 {{end}}
 ensureEtcd() {
   local etcd_client_url="https://${PRIVATE_IP}:2379"
-  retrycmd 120 5 25 curl --cacert /etc/kubernetes/certs/ca.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${etcd_client_url}/v2/machines || exit {{GetCSEErrorCode "ERR_ETCD_RUNNING_TIMEOUT"}}
+  retrycmd 120 5 25 curl --cacert /etc/kubernetes/certs/{{if HasSeparateCAs}}etcd-ca{{else}}ca{{end}}.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${etcd_client_url}/v2/machines || exit {{GetCSEErrorCode "ERR_ETCD_RUNNING_TIMEOUT"}}
   wait_for_file 1200 1 /etc/systemd/system/etcd-monitor.service || exit {{GetCSEErrorCode "ERR_FILE_WATCH_TIMEOUT"}}
   systemctlEnableAndStart etcd-monitor || exit {{GetCSEErrorCode "ERR_SYSTEMCTL_START_FAIL"}}
 }
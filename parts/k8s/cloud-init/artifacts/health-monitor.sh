@@ -86,7 +86,7 @@ etcd_monitoring() {
   sleep 300 {{/* Wait for 5 minutes for etcd to be functional/stable */}}
   local max_seconds=10 output=""
   local endpoint="https://${PRIVATE_IP}:2379"
-  local monitor_cmd="curl -s -S -m ${max_seconds} --cacert /etc/kubernetes/certs/ca.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${endpoint}/v2/machines"
+  local monitor_cmd="curl -s -S -m ${max_seconds} --cacert /etc/kubernetes/certs/{{if HasSeparateCAs}}etcd-ca{{else}}ca{{end}}.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${endpoint}/v2/machines"
   while true; do
     if ! output=$(${monitor_cmd}); then
       echo $output
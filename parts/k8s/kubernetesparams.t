@@ -86,6 +86,32 @@
         },
       {{end}}
     {{end}}
+    {{if HasSeparateCAs}}
+    "etcdCaCertificate": {
+      "metadata": {
+        "description": "The base 64 certificate authority certificate of etcd"
+      },
+      "type": "string"
+    },
+    "frontProxyCaCertificate": {
+      "metadata": {
+        "description": "The base 64 certificate authority certificate of the aggregation layer"
+      },
+      "type": "string"
+    },
+    "frontProxyClientCertificate": {
+      "metadata": {
+        "description": "The base 64 client certificate used by the apiserver to proxy requests to the aggregated apiservers"
+      },
+      "type": "string"
+    },
+    "frontProxyClientPrivateKey": {
+      "metadata": {
+        "description": "The base 64 client private key used by the apiserver to proxy requests to the aggregated apiservers"
+      },
+      "type": "securestring"
+    },
+    {{end}}
     "apiServerCertificate": {
       "metadata": {
         "description": "The base 64 server certificate used on the master"
@@ -31,11 +31,35 @@ cp_certs() {
   cp -p ${NEW_CERTS_DIR}/client.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/apiserver.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/kubeconfig /home/$(logname)/.kube/config
+  cp_etcd_ca
+  if [ -f ${NEW_CERTS_DIR}/proxy-ca.crt ]; then
+    cp -p ${NEW_CERTS_DIR}/proxy-ca.crt ${NEW_CERTS_DIR}/proxy.* /etc/kubernetes/certs/
+  fi
 
   rm -f /var/lib/kubelet/pki/kubelet-client-current.pem
 }
 
+# etcd-ca.crt is only distributed when etcd has its own CA,
+# etcd and its clients trust ca.crt otherwise
+cp_etcd_ca() {
+  local ca_file=/etc/kubernetes/certs/ca.crt
+  if [ -f ${NEW_CERTS_DIR}/etcd-ca.crt ]; then
+    cp -p ${NEW_CERTS_DIR}/etcd-ca.crt /etc/kubernetes/certs/
+    ca_file=/etc/kubernetes/certs/etcd-ca.crt
+  fi
+  if [ -f /etc/default/etcd ]; then
+    sed -i -E "s#(--peer-trusted-ca-file=|--trusted-ca-file=)/etc/kubernetes/certs/(etcd-)?ca.crt#\1${ca_file}#g" /etc/default/etcd
+    sed -i -E "s#^ETCDCTL_CA_FILE=.*#ETCDCTL_CA_FILE=${ca_file}#" /etc/environment
+    sed -i -E "s#--etcd-cafile=/etc/kubernetes/certs/(etcd-)?ca.crt#--etcd-cafile=${ca_file}#" /etc/kubernetes/manifests/kube-apiserver.yaml
+    sed -i -E "s#--cacert /etc/kubernetes/certs/(etcd-)?ca.crt --cert /etc/kubernetes/certs/etcdclient.crt#--cacert ${ca_file} --cert /etc/kubernetes/certs/etcdclient.crt#" /usr/local/bin/health-monitor.sh
+  fi
+}
+
 cp_proxy() {
+  # the front-proxy certificates signed by the front-proxy CA are copied by cp_certs
+  if [ -f ${NEW_CERTS_DIR}/proxy-ca.crt ]; then
+    return
+  fi
   source /etc/environment
   local NODE_INDEX
   NODE_INDEX=$(hostname | tail -c 2)
@@ -45,6 +69,22 @@ cp_proxy() {
   /etc/kubernetes/generate-proxy-certs.sh
 }
 
+# restarts etcd and the control plane static pods so they load the new certificates signed by the current CA,
+# the kubelet recreates the static pods once their manifests are moved back
+restart_control_plane() {
+  if [ -f /etc/default/etcd ]; then
+    systemctl_restart 10 5 60 etcd
+  fi
+  mkdir -p ${WD}/manifests
+  # the manifests are already moved out if a previous run stopped halfway
+  if compgen -G "/etc/kubernetes/manifests/*.yaml" >/dev/null; then
+    mv /etc/kubernetes/manifests/*.yaml ${WD}/manifests/
+    sleep 30
+  fi
+  mv ${WD}/manifests/*.yaml /etc/kubernetes/manifests/
+  systemctl_restart 10 5 10 kubelet
+}
+
 agent_certs() {
   cp -p ${NEW_CERTS_DIR}/ca.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/client.* /etc/kubernetes/certs/
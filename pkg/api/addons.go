@@ -388,6 +388,25 @@ func (cs *ContainerService) setAddonsConfig(isUpgrade bool) {
 		},
 	}
 
+	defaultKubeletCSRApproverAddonsConfig := KubernetesAddon{
+		Name:    common.KubeletCSRApproverAddonName,
+		Enabled: to.BoolPtr(o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled()),
+		Config: map[string]string{
+			"providerIPPrefixes":  getNodeIPPrefixes(cs.Properties),
+			"bypassDNSResolution": "true",
+		},
+		Containers: []KubernetesContainerSpec{
+			{
+				Name:           common.KubeletCSRApproverAddonName,
+				Image:          specConfig.MCRKubernetesImageBase + k8sComponents[common.KubeletCSRApproverAddonName],
+				CPURequests:    "10m",
+				MemoryRequests: "32Mi",
+				CPULimits:      "100m",
+				MemoryLimits:   "128Mi",
+			},
+		},
+	}
+
 	defaultAppGwAddonsConfig := KubernetesAddon{
 		Name:    common.AppGwIngressAddonName,
 		Enabled: to.BoolPtr(DefaultAppGwIngressAddonEnabled),
@@ -780,6 +799,7 @@ func (cs *ContainerService) setAddonsConfig(isUpgrade bool) {
 		defaultAzureFileCSIDriverAddonsConfig,
 		defaultsAzurePolicyAddonsConfig,
 		defaultNodeProblemDetectorConfig,
+		defaultKubeletCSRApproverAddonsConfig,
 		defaultKubeDNSAddonsConfig,
 		defaultCorednsAddonsConfig,
 		defaultKubeProxyAddonsConfig,
@@ -977,6 +997,37 @@ func appendAddonIfNotPresent(addons []KubernetesAddon, addon KubernetesAddon) []
 	return addons
 }
 
+// getNodeIPPrefixes returns the comma-separated address prefixes of the node IPs: the master and agent subnets,
+// or the vnetCidr of a custom VNET whose subnets are not in the API model. It returns an empty string if they are unknown.
+func getNodeIPPrefixes(p *Properties) string {
+	if p.MasterProfile == nil {
+		return ""
+	}
+	var subnets []string
+	if p.MasterProfile.IsCustomVNET() {
+		subnets = append(subnets, p.MasterProfile.VnetCidr)
+	} else {
+		subnets = append(subnets, p.MasterProfile.Subnet, p.MasterProfile.AgentSubnet)
+		for _, profile := range p.AgentPoolProfiles {
+			subnets = append(subnets, profile.Subnet)
+		}
+		if p.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") || p.FeatureFlags.IsFeatureEnabled("EnableIPv6Only") {
+			subnets = append(subnets, p.MasterProfile.SubnetIPv6)
+		}
+	}
+	var prefixes []string
+	seen := map[string]bool{}
+	for _, subnet := range subnets {
+		for _, prefix := range strings.Split(subnet, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" && !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return strings.Join(prefixes, ",")
+}
+
 func getAddonsIndexByName(addons []KubernetesAddon, name string) int {
 	for i := range addons {
 		if addons[i].Name == name {
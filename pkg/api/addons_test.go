@@ -3832,6 +3832,51 @@ func TestSetAddonsConfig(t *testing.T) {
 				},
 			}, "1.15.4"),
 		},
+		{
+			name: "kubelet-csr-approver addon enabled by kubelet server TLS bootstrap",
+			cs: &ContainerService{
+				Properties: &Properties{
+					OrchestratorProfile: &OrchestratorProfile{
+						OrchestratorVersion: "1.15.4",
+						KubernetesConfig: &KubernetesConfig{
+							KubernetesImageBaseType: common.KubernetesImageBaseTypeMCR,
+							DNSServiceIP:            DefaultKubernetesDNSServiceIP,
+							KubeletConfig: map[string]string{
+								"--cluster-domain": "cluster.local",
+							},
+							ClusterSubnet:                   DefaultKubernetesSubnet,
+							ProxyMode:                       KubeProxyModeIPTables,
+							NetworkPlugin:                   NetworkPluginAzure,
+							EnableKubeletServerTLSBootstrap: to.BoolPtr(true),
+						},
+					},
+					MasterProfile: &MasterProfile{
+						Subnet: DefaultKubernetesMasterSubnet,
+					},
+				},
+			},
+			isUpgrade: false,
+			expectedAddons: concatenateDefaultAddons([]KubernetesAddon{
+				{
+					Name:    common.KubeletCSRApproverAddonName,
+					Enabled: to.BoolPtr(true),
+					Config: map[string]string{
+						"providerIPPrefixes":  DefaultKubernetesMasterSubnet,
+						"bypassDNSResolution": "true",
+					},
+					Containers: []KubernetesContainerSpec{
+						{
+							Name:           common.KubeletCSRApproverAddonName,
+							Image:          specConfig.MCRKubernetesImageBase + k8sComponentsByVersionMap["1.15.4"][common.KubeletCSRApproverAddonName],
+							CPURequests:    "10m",
+							MemoryRequests: "32Mi",
+							CPULimits:      "100m",
+							MemoryLimits:   "128Mi",
+						},
+					},
+				},
+			}, "1.15.4"),
+		},
 		{
 			name: "pod-security-policy upgrade to 1.15",
 			cs: &ContainerService{
@@ -4530,6 +4575,7 @@ func TestSetAddonsConfig(t *testing.T) {
 				common.KubeDNSAddonName,
 				common.KubeProxyAddonName,
 				common.NodeProblemDetectorAddonName,
+				common.KubeletCSRApproverAddonName,
 				common.PodSecurityPolicyAddonName,
 				common.AADAdminGroupAddonName,
 				common.SecretsStoreCSIDriverAddonName,
@@ -5271,3 +5317,74 @@ func TestKubeProxyImageSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNodeIPPrefixes(t *testing.T) {
+	cases := []struct {
+		name     string
+		p        *Properties
+		expected string
+	}{
+		{
+			name:     "no master profile",
+			p:        &Properties{},
+			expected: "",
+		},
+		{
+			name: "master and agent subnets",
+			p: &Properties{
+				MasterProfile: &MasterProfile{Subnet: DefaultKubernetesMasterSubnet, SubnetIPv6: DefaultKubernetesMasterSubnetIPv6},
+				AgentPoolProfiles: []*AgentPoolProfile{
+					{Subnet: DefaultKubernetesMasterSubnet},
+					{Subnet: "10.241.0.0/16"},
+				},
+			},
+			expected: "10.240.0.0/16,10.241.0.0/16",
+		},
+		{
+			name: "VMSS masters",
+			p: &Properties{
+				MasterProfile: &MasterProfile{Subnet: DefaultKubernetesMasterSubnet, AgentSubnet: DefaultKubernetesAgentSubnetVMSS},
+				AgentPoolProfiles: []*AgentPoolProfile{
+					{},
+				},
+			},
+			expected: DefaultKubernetesMasterSubnet + "," + DefaultKubernetesAgentSubnetVMSS,
+		},
+		{
+			name: "IPv6 dual stack",
+			p: &Properties{
+				MasterProfile: &MasterProfile{Subnet: DefaultKubernetesMasterSubnet, SubnetIPv6: DefaultKubernetesMasterSubnetIPv6},
+				FeatureFlags:  &FeatureFlags{EnableIPv6DualStack: true},
+			},
+			expected: DefaultKubernetesMasterSubnet + "," + DefaultKubernetesMasterSubnetIPv6,
+		},
+		{
+			name: "custom VNET",
+			p: &Properties{
+				MasterProfile: &MasterProfile{
+					VnetSubnetID: "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME",
+					VnetCidr:     "10.100.0.0/16",
+				},
+			},
+			expected: "10.100.0.0/16",
+		},
+		{
+			name: "custom VNET w/o vnetCidr",
+			p: &Properties{
+				MasterProfile: &MasterProfile{
+					VnetSubnetID: "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME",
+				},
+			},
+			expected: "",
+		},
+	}
+	for _, tc := range cases {
+		c := tc
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := getNodeIPPrefixes(c.p); actual != c.expected {
+				t.Errorf("expected %s to be %s", actual, c.expected)
+			}
+		})
+	}
+}
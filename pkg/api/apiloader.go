@@ -6,8 +6,10 @@ package api
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"reflect"
 
+	"github.com/Azure/aks-engine-azurestack/pkg/api/schema"
 	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
 	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
 	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
@@ -27,14 +29,22 @@ const (
 // Apiloader represents the object that loads api model
 type Apiloader struct {
 	Translator *i18n.Translator
+	// APIModelDir is the directory the relative file: secret references are resolved from,
+	// LoadContainerServiceFromFile defaults it to the directory of the file
+	APIModelDir string
 }
 
-// LoadContainerServiceFromFile loads an AKS Cluster API Model from a JSON file
+// LoadContainerServiceFromFile loads an AKS Cluster API Model from a JSON or YAML file
 func (a *Apiloader) LoadContainerServiceFromFile(jsonFile string, validate, isUpdate bool, existingContainerService *ContainerService) (*ContainerService, string, error) {
-	contents, e := os.ReadFile(jsonFile)
+	contents, e := helpers.ReadDecryptedFile(jsonFile)
 	if e != nil {
 		return nil, "", a.Translator.Errorf("error reading file %s: %s", jsonFile, e.Error())
 	}
+	if a.APIModelDir == "" {
+		loader := *a
+		loader.APIModelDir = filepath.Dir(jsonFile)
+		a = &loader
+	}
 	return a.DeserializeContainerService(contents, validate, isUpdate, existingContainerService)
 }
 
@@ -63,8 +73,12 @@ func LoadDefaultContainerServiceProperties() (TypeMeta, *vlabs.Properties) {
 
 // DeserializeContainerService loads an AKS Engine Cluster API Model, validates it, and returns the unversioned representation
 func (a *Apiloader) DeserializeContainerService(contents []byte, validate, isUpdate bool, existingContainerService *ContainerService) (*ContainerService, string, error) {
+	contents, err := ConvertYAMLToJSON(contents)
+	if err != nil {
+		return nil, "", err
+	}
 	m := &TypeMeta{}
-	if err := json.Unmarshal(contents, &m); err != nil {
+	if err = json.Unmarshal(contents, &m); err != nil {
 		return nil, "", err
 	}
 
@@ -79,6 +93,10 @@ func (a *Apiloader) LoadContainerService(
 	version string,
 	validate, isUpdate bool,
 	existingContainerService *ContainerService) (*ContainerService, error) {
+	contents, err := ConvertYAMLToJSON(contents)
+	if err != nil {
+		return nil, err
+	}
 	var curOrchVersion string
 	hasExistingCS := existingContainerService != nil
 	if hasExistingCS {
@@ -88,7 +106,7 @@ func (a *Apiloader) LoadContainerService(
 	case vlabs.APIVersion:
 		containerService := &vlabs.ContainerService{}
 		if e := json.Unmarshal(contents, &containerService); e != nil {
-			return nil, e
+			return nil, schemaTypeErrors(contents, e)
 		}
 		if containerService.Properties.OrchestratorProfile == nil {
 			containerService.Properties.OrchestratorProfile = &vlabs.OrchestratorProfile{}
@@ -96,6 +114,10 @@ func (a *Apiloader) LoadContainerService(
 		if e := checkJSONKeys(contents, reflect.TypeOf(*containerService), reflect.TypeOf(TypeMeta{})); e != nil {
 			return nil, e
 		}
+		secretReferences, e := resolveSecretReferences(containerService, a.APIModelDir)
+		if e != nil {
+			return nil, e
+		}
 		if hasExistingCS {
 			vecs := ConvertContainerServiceToVLabs(existingContainerService)
 			if e := containerService.Merge(vecs); e != nil {
@@ -109,10 +131,10 @@ func (a *Apiloader) LoadContainerService(
 		}
 
 		var unversioned *ContainerService
-		var err error
 		if unversioned, err = ConvertVLabsContainerService(containerService, isUpdate); err != nil {
 			return nil, err
 		}
+		unversioned.SecretReferences = secretReferences
 		if curOrchVersion != "" &&
 			(containerService.Properties.OrchestratorProfile == nil ||
 				(containerService.Properties.OrchestratorProfile.OrchestratorVersion == "" &&
@@ -126,11 +148,25 @@ func (a *Apiloader) LoadContainerService(
 	}
 }
 
+// schemaTypeErrors replaces an error decoding a vlabs API model by the values of the wrong type
+// found by the API model schema, which are reported with their JSON path
+func schemaTypeErrors(contents []byte, err error) error {
+	errs, e := schema.ForContainerService().Validate(contents)
+	if e != nil {
+		return err
+	}
+	if typeErrs := errs.WithCode(schema.CodeInvalidType); len(typeErrs) > 0 {
+		return typeErrs
+	}
+	return err
+}
+
 // SerializeContainerService takes an unversioned container service and returns the bytes
 func (a *Apiloader) SerializeContainerService(containerService *ContainerService, version string) ([]byte, error) {
 	switch version {
 	case vlabs.APIVersion:
 		vlabsContainerService := ConvertContainerServiceToVLabs(containerService)
+		restoreSecretReferences(vlabsContainerService, containerService.SecretReferences)
 		armContainerService := &VlabsARMContainerService{}
 		armContainerService.ContainerService = vlabsContainerService
 		armContainerService.APIVersion = version
@@ -144,6 +180,30 @@ func (a *Apiloader) SerializeContainerService(containerService *ContainerService
 	}
 }
 
+// SerializeContainerServiceYAML takes an unversioned container service and returns the YAML bytes.
+// The comments of original, the YAML API model the container service was loaded from, are preserved where possible.
+func (a *Apiloader) SerializeContainerServiceYAML(containerService *ContainerService, version string, original []byte) ([]byte, error) {
+	b, err := a.SerializeContainerService(containerService, version)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertJSONToYAML(b, original)
+}
+
+// SerializeContainerServiceForFile takes an unversioned container service and returns the bytes to write to path,
+// YAML if path has a .yaml or .yml extension and JSON otherwise.
+// The comments of an existing YAML file at path are preserved where possible.
+func (a *Apiloader) SerializeContainerServiceForFile(containerService *ContainerService, version, path string) ([]byte, error) {
+	if !IsYAMLFile(path) {
+		return a.SerializeContainerService(containerService, version)
+	}
+	original, err := helpers.ReadDecryptedFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, a.Translator.Errorf("error reading file %s: %s", path, err.Error())
+	}
+	return a.SerializeContainerServiceYAML(containerService, version, original)
+}
+
 // LoadAgentpoolProfileFromFile loads an an AgentPoolProfile object from a JSON file
 func (a *Apiloader) LoadAgentpoolProfileFromFile(jsonFile string) (*AgentPoolProfile, error) {
 	contents, e := os.ReadFile(jsonFile)
@@ -155,6 +215,10 @@ func (a *Apiloader) LoadAgentpoolProfileFromFile(jsonFile string) (*AgentPoolPro
 
 // LoadAgentPoolProfile marshalls raw data into a strongly typed AgentPoolProfile return object
 func (a *Apiloader) LoadAgentPoolProfile(contents []byte) (*AgentPoolProfile, error) {
+	contents, err := ConvertYAMLToJSON(contents)
+	if err != nil {
+		return nil, err
+	}
 	agentPoolProfile := &AgentPoolProfile{}
 	if e := json.Unmarshal(contents, &agentPoolProfile); e != nil {
 		return nil, e
@@ -176,11 +240,15 @@ func (a *Apiloader) LoadCertificateProfileFromFile(jsonFile string) (*Certificat
 
 // LoadCertificateProfile marshalls raw data into a strongly typed CertificateProfile return object
 func (a *Apiloader) LoadCertificateProfile(content []byte) (*CertificateProfile, error) {
+	content, err := ConvertYAMLToJSON(content)
+	if err != nil {
+		return nil, err
+	}
 	certificateProfile := &CertificateProfile{}
-	if err := json.Unmarshal(content, &certificateProfile); err != nil {
+	if err = json.Unmarshal(content, &certificateProfile); err != nil {
 		return nil, err
 	}
-	if err := checkJSONKeys(content, reflect.TypeOf(*certificateProfile), reflect.TypeOf(TypeMeta{})); err != nil {
+	if err = checkJSONKeys(content, reflect.TypeOf(*certificateProfile), reflect.TypeOf(TypeMeta{})); err != nil {
 		return nil, err
 	}
 	return certificateProfile, nil
@@ -10,6 +10,7 @@ import (
 
 	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -213,6 +214,13 @@ func TestDeserializeContainerService(t *testing.T) {
 	if err == nil {
 		t.Errorf("expected error from malformed api model input")
 	}
+
+	// Test values of the wrong type are reported with their path
+	_, _, err = apiloader.DeserializeContainerService([]byte(strings.Replace(exampleAPIModel, `"count": 1`, `"count": "1"`, 1)), false, false, nil)
+	expected := "properties.masterProfile.count: expected an integer, got a string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, instead got: %v", expected, err)
+	}
 }
 
 func TestLoadDefaultContainerServiceProperties(t *testing.T) {
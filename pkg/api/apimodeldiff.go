@@ -0,0 +1,339 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// APIModelChangeImpact is how a change of the cluster definition is applied to a running cluster
+type APIModelChangeImpact string
+
+const (
+	// ImpactScale changes are applied by aks-engine-azurestack scale or addpool
+	ImpactScale APIModelChangeImpact = "scale"
+	// ImpactUpgrade changes are applied by aks-engine-azurestack upgrade
+	ImpactUpgrade APIModelChangeImpact = "upgrade"
+	// ImpactNodeReplacement changes only apply to new nodes, the existing nodes must be replaced
+	ImpactNodeReplacement APIModelChangeImpact = "node-replacement"
+	// ImpactNewDeployment changes cannot be applied to a running cluster, they require a fresh deployment
+	ImpactNewDeployment APIModelChangeImpact = "new-deployment"
+)
+
+// APIModelChangeImpacts are the impacts from the least to the most disruptive
+var APIModelChangeImpacts = []APIModelChangeImpact{ImpactScale, ImpactUpgrade, ImpactNodeReplacement, ImpactNewDeployment}
+
+// APIModelDifference is a difference between two cluster definitions
+type APIModelDifference struct {
+	// Path is the JSON path of the value, the agent pools and the other named array elements are matched
+	// by name, e.g. properties.agentPoolProfiles[name=linuxpool].vmSize
+	Path string `json:"path"`
+	// From is the value in the first cluster definition, nil if the value was added
+	From interface{} `json:"from,omitempty"`
+	// To is the value in the second cluster definition, nil if the value was removed
+	To interface{} `json:"to,omitempty"`
+	// Impact is how the change is applied to a running cluster
+	Impact APIModelChangeImpact `json:"impact"`
+}
+
+// apimodelChangeImpacts are the impacts of the changes by path, the array elements are [*].
+// A path matches the first rule equal to the path or to one of its parents, the rules of an array element only
+// match the element itself, i.e. the element added or removed. The other changes are applied by upgrade.
+var apimodelChangeImpacts = []struct {
+	path   string
+	impact APIModelChangeImpact
+}{
+	{"properties.agentPoolProfiles[*].count", ImpactScale},
+	{"properties.agentPoolProfiles[*].name", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].osType", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].availabilityProfile", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].availabilityZones", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].vnetSubnetID", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].dnsPrefix", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].singlePlacementGroup", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].platformFaultDomainCount", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].storageProfile", ImpactNewDeployment},
+	{"properties.agentPoolProfiles[*].vmSize", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].distro", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].imageReference", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].osDiskSizeGB", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].diskSizesGB", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].osDiskCachingType", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].dataDiskCachingType", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].auditDEnabled", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].ultraSSDEnabled", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].encryptionAtHost", ImpactNodeReplacement},
+	{"properties.agentPoolProfiles[*].enableVMSSNodePublicIP", ImpactNodeReplacement},
+	// an agent pool added or removed
+	{"properties.agentPoolProfiles[*]", ImpactScale},
+	{"properties.masterProfile.count", ImpactNewDeployment},
+	{"properties.masterProfile.dnsPrefix", ImpactNewDeployment},
+	{"properties.masterProfile.vnetSubnetID", ImpactNewDeployment},
+	{"properties.masterProfile.vnetCidr", ImpactNewDeployment},
+	{"properties.masterProfile.agentVnetSubnetID", ImpactNewDeployment},
+	{"properties.masterProfile.firstConsecutiveStaticIP", ImpactNewDeployment},
+	{"properties.masterProfile.availabilityProfile", ImpactNewDeployment},
+	{"properties.masterProfile.availabilityZones", ImpactNewDeployment},
+	{"properties.masterProfile.storageProfile", ImpactNewDeployment},
+	{"properties.masterProfile.vmSize", ImpactNodeReplacement},
+	{"properties.masterProfile.distro", ImpactNodeReplacement},
+	{"properties.masterProfile.imageReference", ImpactNodeReplacement},
+	{"properties.masterProfile.osDiskSizeGB", ImpactNodeReplacement},
+	{"properties.masterProfile.osDiskCachingType", ImpactNodeReplacement},
+	{"properties.masterProfile.auditDEnabled", ImpactNodeReplacement},
+	{"properties.masterProfile.ultraSSDEnabled", ImpactNodeReplacement},
+	{"properties.masterProfile.encryptionAtHost", ImpactNodeReplacement},
+	{"properties.orchestratorProfile.orchestratorType", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.networkPlugin", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.networkPolicy", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.networkMode", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.clusterSubnet", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.serviceCidr", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.dnsServiceIP", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.dockerBridgeSubnet", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.loadBalancerSku", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.privateCluster", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.useManagedIdentity", ImpactNewDeployment},
+	{"properties.orchestratorProfile.kubernetesConfig.userAssignedID", ImpactNewDeployment},
+	{"properties.linuxProfile.adminUsername", ImpactNewDeployment},
+	{"properties.linuxProfile.ssh", ImpactNodeReplacement},
+	{"properties.windowsProfile.adminUsername", ImpactNewDeployment},
+	{"properties.windowsProfile.imageReference", ImpactNodeReplacement},
+	{"properties.windowsProfile.imageVersion", ImpactNodeReplacement},
+	{"properties.windowsProfile.WindowsImageSourceUrl", ImpactNodeReplacement},
+	{"properties.windowsProfile.WindowsPublisher", ImpactNodeReplacement},
+	{"properties.windowsProfile.WindowsOffer", ImpactNodeReplacement},
+	{"properties.windowsProfile.WindowsSku", ImpactNodeReplacement},
+	{"properties.customCloudProfile", ImpactNewDeployment},
+	{"properties.aadProfile", ImpactNewDeployment},
+	{"properties.featureFlags", ImpactNewDeployment},
+	{"apiVersion", ImpactNewDeployment},
+	{"location", ImpactNewDeployment},
+}
+
+// arrayElementRegexp matches the array elements of a path, see apimodelChangeImpact
+var arrayElementRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+// apimodelChangeImpact returns the impact of a change of the value at path
+func apimodelChangeImpact(path string) APIModelChangeImpact {
+	path = arrayElementRegexp.ReplaceAllString(path, "[*]")
+	for _, r := range apimodelChangeImpacts {
+		if path == r.path {
+			return r.impact
+		}
+		if !strings.HasSuffix(r.path, "]") && (strings.HasPrefix(path, r.path+".") || strings.HasPrefix(path, r.path+"[")) {
+			return r.impact
+		}
+	}
+	return ImpactUpgrade
+}
+
+// DiffContainerServices returns the differences between the cluster definitions from and to, sorted by path.
+// The agent pools and the other named array elements are matched by name, the arrays of values are compared
+// regardless of their order, and the secrets, such as the certificate profile, are ignored.
+func DiffContainerServices(from, to *ContainerService) ([]APIModelDifference, error) {
+	f, secrets, err := apimodelDocument(from)
+	if err != nil {
+		return nil, err
+	}
+	t, toSecrets, err := apimodelDocument(to)
+	if err != nil {
+		return nil, err
+	}
+	for path := range toSecrets {
+		secrets[path] = true
+	}
+
+	var diffs []APIModelDifference
+	diffJSONValues("", f, t, func(path string, from, to interface{}) {
+		if secrets[arrayElementRegexp.ReplaceAllString(path, "")] {
+			return
+		}
+		diffs = append(diffs, APIModelDifference{Path: path, From: from, To: to, Impact: apimodelChangeImpact(path)})
+	})
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// apimodelDocument returns the vlabs JSON document of cs without its empty values,
+// and the paths of its secrets, see vlabsSecrets
+func apimodelDocument(cs *ContainerService) (interface{}, map[string]bool, error) {
+	v := ConvertContainerServiceToVLabs(cs)
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "serializing the API model")
+	}
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&doc); err != nil {
+		return nil, nil, errors.Wrap(err, "parsing the API model")
+	}
+	secrets := map[string]bool{}
+	for path := range vlabsSecrets(v) {
+		secrets[arrayElementRegexp.ReplaceAllString(path, "")] = true
+	}
+	return pruneJSON(doc), secrets, nil
+}
+
+// pruneJSON removes the null values, empty strings, empty objects and empty arrays of v,
+// which the cluster definition does not tell apart from missing values
+func pruneJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if e = pruneJSON(e); e == nil {
+				delete(t, k)
+			} else {
+				t[k] = e
+			}
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+	case string:
+		if t == "" {
+			return nil
+		}
+	}
+	return v
+}
+
+// diffJSONValues calls report with the path and the values of every difference between from and to
+func diffJSONValues(path string, from, to interface{}, report func(path string, from, to interface{})) {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range f {
+			keys[k] = true
+		}
+		for k := range t {
+			keys[k] = true
+		}
+		for k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffJSONValues(p, f[k], t[k], report)
+		}
+		return
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		if namedJSONArray(f) && namedJSONArray(t) {
+			diffNamedJSONArrays(path, f, t, report)
+			return
+		}
+		if scalarJSONArray(f) && scalarJSONArray(t) {
+			if !reflect.DeepEqual(sortedJSONArray(f), sortedJSONArray(t)) {
+				report(path, from, to)
+			}
+			return
+		}
+		if len(f) != len(t) {
+			break
+		}
+		for i := range f {
+			diffJSONValues(fmt.Sprintf("%s[%d]", path, i), f[i], t[i], report)
+		}
+		return
+	case nil:
+		if to == nil {
+			return
+		}
+		if t, ok := to.([]interface{}); ok && namedJSONArray(t) {
+			diffNamedJSONArrays(path, nil, t, report)
+			return
+		}
+	}
+	if from != nil && to == nil {
+		if f, ok := from.([]interface{}); ok && namedJSONArray(f) {
+			diffNamedJSONArrays(path, f, nil, report)
+			return
+		}
+	}
+	if !reflect.DeepEqual(from, to) {
+		report(path, from, to)
+	}
+}
+
+// diffNamedJSONArrays reports the differences between the elements of from and to with the same name,
+// and the elements added or removed
+func diffNamedJSONArrays(path string, from, to []interface{}, report func(path string, from, to interface{})) {
+	byName := func(a []interface{}) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, e := range a {
+			m[jsonElementName(e)] = e
+		}
+		return m
+	}
+	f, t := byName(from), byName(to)
+	for name, e := range f {
+		diffJSONValues(fmt.Sprintf("%s[name=%s]", path, name), e, t[name], report)
+	}
+	for name, e := range t {
+		if _, ok := f[name]; !ok {
+			diffJSONValues(fmt.Sprintf("%s[name=%s]", path, name), nil, e, report)
+		}
+	}
+}
+
+// namedJSONArray returns true if every element of a is an object with a name
+func namedJSONArray(a []interface{}) bool {
+	for _, e := range a {
+		if jsonElementName(e) == "" {
+			return false
+		}
+	}
+	return len(a) > 0
+}
+
+func jsonElementName(e interface{}) string {
+	o, ok := e.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := o["name"].(string)
+	return name
+}
+
+// scalarJSONArray returns true if no element of a is an object or an array
+func scalarJSONArray(a []interface{}) bool {
+	for _, e := range a {
+		switch e.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// sortedJSONArray returns the elements of the array of values a as sorted strings
+func sortedJSONArray(a []interface{}) []string {
+	s := make([]string, len(a))
+	for i, e := range a {
+		s[i] = fmt.Sprint(e)
+	}
+	sort.Strings(s)
+	return s
+}
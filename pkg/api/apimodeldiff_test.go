@@ -0,0 +1,140 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	. "github.com/onsi/gomega"
+)
+
+const diffAPIModel = `{
+  "apiVersion": "vlabs",
+  "location": "local",
+  "properties": {
+    "orchestratorProfile": {
+      "orchestratorRelease": "1.29",
+      "kubernetesConfig": {
+        "networkPlugin": "kubenet",
+        "apiServerConfig": {"--audit-log-maxage": "30"}
+      }
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "diff",
+      "vmSize": "Standard_D2_v2",
+      "availabilityZones": ["1", "2"]
+    },
+    "agentPoolProfiles": [
+      {"name": "linuxpool", "count": 3, "vmSize": "Standard_D2_v2"},
+      {"name": "otherpool", "count": 1, "vmSize": "Standard_D2_v2"}
+    ],
+    "linuxProfile": {
+      "adminUsername": "azureuser",
+      "ssh": {"publicKeys": [{"keyData": "ssh-rsa PUBLICKEY azureuser@linuxvm"}]}
+    },
+    "servicePrincipalProfile": {
+      "clientId": "ServicePrincipalClientID",
+      "secret": "ServicePrincipalSecret"
+    },
+    "certificateProfile": {
+      "caCertificate": "caCertificate",
+      "caPrivateKey": "caPrivateKey"
+    }
+  }
+}`
+
+func loadDiffAPIModel(t *testing.T, replacer *strings.Replacer) *ContainerService {
+	apiloader := &Apiloader{Translator: &i18n.Translator{}}
+	cs, _, err := apiloader.DeserializeContainerService([]byte(replacer.Replace(diffAPIModel)), false, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func TestDiffContainerServices(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	from := loadDiffAPIModel(t, strings.NewReplacer())
+
+	diffs, err := DiffContainerServices(from, from)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diffs).To(BeEmpty())
+
+	// the pools are reordered, the availability zones and the secrets changed: the API models are equivalent
+	to := loadDiffAPIModel(t, strings.NewReplacer(
+		`{"name": "linuxpool", "count": 3, "vmSize": "Standard_D2_v2"},
+      {"name": "otherpool", "count": 1, "vmSize": "Standard_D2_v2"}`,
+		`{"name": "otherpool", "count": 1, "vmSize": "Standard_D2_v2"},
+      {"name": "linuxpool", "count": 3, "vmSize": "Standard_D2_v2"}`,
+		`["1", "2"]`, `["2", "1"]`,
+		`"ServicePrincipalSecret"`, `"RotatedSecret"`,
+		`"caPrivateKey": "caPrivateKey"`, `"caPrivateKey": "RotatedPrivateKey"`))
+	diffs, err = DiffContainerServices(from, to)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diffs).To(BeEmpty())
+
+	to = loadDiffAPIModel(t, strings.NewReplacer(
+		`"name": "linuxpool", "count": 3, "vmSize": "Standard_D2_v2"`,
+		`"name": "linuxpool", "count": 5, "vmSize": "Standard_D4_v2"`,
+		`{"name": "otherpool", "count": 1, "vmSize": "Standard_D2_v2"}`,
+		`{"name": "newpool", "count": 1, "vmSize": "Standard_D2_v2"}`,
+		`"--audit-log-maxage": "30"`, `"--audit-log-maxage": "60"`,
+		`"networkPlugin": "kubenet"`, `"networkPlugin": "azure"`))
+	diffs, err = DiffContainerServices(from, to)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diffs).To(HaveLen(6))
+
+	expected := map[string]APIModelChangeImpact{
+		"properties.agentPoolProfiles[name=linuxpool].count":                                 ImpactScale,
+		"properties.agentPoolProfiles[name=linuxpool].vmSize":                                ImpactNodeReplacement,
+		"properties.agentPoolProfiles[name=newpool]":                                         ImpactScale,
+		"properties.agentPoolProfiles[name=otherpool]":                                       ImpactScale,
+		"properties.orchestratorProfile.kubernetesConfig.apiServerConfig.--audit-log-maxage": ImpactUpgrade,
+		"properties.orchestratorProfile.kubernetesConfig.networkPlugin":                      ImpactNewDeployment,
+	}
+	for i, d := range diffs {
+		if i > 0 {
+			g.Expect(d.Path >= diffs[i-1].Path).To(BeTrue(), "the differences are sorted by path")
+		}
+		g.Expect(expected).To(HaveKeyWithValue(d.Path, d.Impact))
+		switch d.Path {
+		case "properties.agentPoolProfiles[name=linuxpool].vmSize":
+			g.Expect(d.From).To(Equal("Standard_D2_v2"))
+			g.Expect(d.To).To(Equal("Standard_D4_v2"))
+		case "properties.agentPoolProfiles[name=newpool]":
+			g.Expect(d.From).To(BeNil())
+			g.Expect(d.To).NotTo(BeNil())
+		case "properties.agentPoolProfiles[name=otherpool]":
+			g.Expect(d.From).NotTo(BeNil())
+			g.Expect(d.To).To(BeNil())
+		}
+	}
+}
+
+func TestAPIModelChangeImpact(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cases := map[string]APIModelChangeImpact{
+		"properties.agentPoolProfiles[name=pool].count":                            ImpactScale,
+		"properties.agentPoolProfiles[name=pool].distro":                           ImpactNodeReplacement,
+		"properties.agentPoolProfiles[name=pool].imageReference.name":              ImpactNodeReplacement,
+		"properties.agentPoolProfiles[name=pool].osType":                           ImpactNewDeployment,
+		"properties.agentPoolProfiles[name=pool].kubernetesConfig.kubeletConfig.x": ImpactUpgrade,
+		"properties.masterProfile.count":                                           ImpactNewDeployment,
+		"properties.masterProfile.vmSize":                                          ImpactNodeReplacement,
+		"properties.orchestratorProfile.orchestratorRelease":                       ImpactUpgrade,
+		"properties.orchestratorProfile.kubernetesConfig.addons[name=coredns]":     ImpactUpgrade,
+		"properties.orchestratorProfile.kubernetesConfig.serviceCidr":              ImpactNewDeployment,
+		"properties.linuxProfile.ssh.publicKeys[0].keyData":                        ImpactNodeReplacement,
+		"properties.customCloudProfile.portalURL":                                  ImpactNewDeployment,
+		"location": ImpactNewDeployment,
+	}
+	for path, impact := range cases {
+		g.Expect(apimodelChangeImpact(path)).To(Equal(impact), path)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// IsYAMLFile returns true if the API model file name has a YAML extension
+func IsYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// isJSONDocument returns true if contents is a JSON object rather than a YAML document
+func isJSONDocument(contents []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(contents), []byte("{"))
+}
+
+// ConvertYAMLToJSON converts a YAML API model to JSON, resolving anchors and aliases.
+// JSON API models are returned unchanged.
+func ConvertYAMLToJSON(contents []byte) ([]byte, error) {
+	if isJSONDocument(contents) {
+		return contents, nil
+	}
+	b, err := k8syaml.YAMLToJSON(contents)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing YAML API model")
+	}
+	return b, nil
+}
+
+// ConvertJSONToYAML converts a serialized API model to YAML.
+// The comments of original, a previous YAML version of the same API model, are carried over
+// to the keys that still exist; list items are matched by their name, or else by their index.
+func ConvertJSONToYAML(contents, original []byte) ([]byte, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, doc); err != nil {
+		return nil, errors.Wrap(err, "converting API model to YAML")
+	}
+	resetYAMLStyle(doc)
+	if len(original) > 0 && !isJSONDocument(original) {
+		previous := &yaml.Node{}
+		if err := yaml.Unmarshal(original, previous); err == nil {
+			copyYAMLComments(previous, doc)
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, errors.Wrap(err, "converting API model to YAML")
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "converting API model to YAML")
+	}
+	return buf.Bytes(), nil
+}
+
+// resetYAMLStyle replaces the flow and quoted styles of a document decoded from JSON by the block style
+func resetYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	for _, c := range n.Content {
+		resetYAMLStyle(c)
+	}
+}
+
+func copyYAMLComments(from, to *yaml.Node) {
+	if from == nil || to == nil {
+		return
+	}
+	to.HeadComment, to.LineComment, to.FootComment = from.HeadComment, from.LineComment, from.FootComment
+	from = resolveYAMLAlias(from)
+	switch {
+	case from.Kind == yaml.DocumentNode && to.Kind == yaml.DocumentNode:
+		if len(from.Content) > 0 && len(to.Content) > 0 {
+			copyYAMLComments(from.Content[0], to.Content[0])
+		}
+	case from.Kind == yaml.MappingNode && to.Kind == yaml.MappingNode:
+		previous := yamlMappingEntries(from)
+		for i := 0; i+1 < len(to.Content); i += 2 {
+			if entry, ok := previous[strings.ToLower(to.Content[i].Value)]; ok {
+				copyYAMLComments(entry[0], to.Content[i])
+				copyYAMLComments(entry[1], to.Content[i+1])
+			}
+		}
+	case from.Kind == yaml.SequenceNode && to.Kind == yaml.SequenceNode:
+		byName := map[string]*yaml.Node{}
+		for _, item := range from.Content {
+			if name := yamlItemName(item); name != "" {
+				byName[name] = item
+			}
+		}
+		for i, item := range to.Content {
+			if name := yamlItemName(item); name != "" {
+				copyYAMLComments(byName[name], item)
+			} else if i < len(from.Content) {
+				copyYAMLComments(from.Content[i], item)
+			}
+		}
+	}
+}
+
+// yamlMappingEntries returns the key and value nodes of a mapping by lowercase key, including merged (<<) keys
+func yamlMappingEntries(n *yaml.Node) map[string][2]*yaml.Node {
+	entries := map[string][2]*yaml.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Value == "<<" {
+			merged := resolveYAMLAlias(value)
+			if merged.Kind == yaml.MappingNode {
+				for k, e := range yamlMappingEntries(merged) {
+					if _, ok := entries[k]; !ok {
+						entries[k] = e
+					}
+				}
+			}
+			continue
+		}
+		entries[strings.ToLower(key.Value)] = [2]*yaml.Node{key, value}
+	}
+	return entries
+}
+
+// yamlItemName returns the value of the name key of a list item, e.g. an agent pool profile
+func yamlItemName(n *yaml.Node) string {
+	n = resolveYAMLAlias(n)
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	if entry, ok := yamlMappingEntries(n)["name"]; ok {
+		return entry[1].Value
+	}
+	return ""
+}
+
+func resolveYAMLAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	. "github.com/onsi/gomega"
+)
+
+const yamlAPIModel = `# cluster definition of the test stamp
+apiVersion: vlabs
+location: local
+properties:
+  orchestratorProfile:
+    orchestratorRelease: "1.29"
+  masterProfile:
+    count: 1
+    dnsPrefix: yamlcluster # keep in sync with the stamp
+    vmSize: &size Standard_D2_v2
+  agentPoolProfiles:
+    # system pool
+    - name: linuxpool1
+      count: 2
+      vmSize: *size
+      availabilityProfile: AvailabilitySet
+    # user pool
+    - name: linuxpool2
+      count: 1
+      vmSize: *size
+      availabilityProfile: AvailabilitySet
+  linuxProfile:
+    adminUsername: azureuser
+    ssh:
+      publicKeys:
+        - keyData: ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC8
+`
+
+func newTestApiloader() *Apiloader {
+	return &Apiloader{Translator: &i18n.Translator{}}
+}
+
+func TestDeserializeContainerServiceYAML(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cs, version, err := newTestApiloader().DeserializeContainerService([]byte(yamlAPIModel), false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version).To(Equal("vlabs"))
+	g.Expect(cs.Location).To(Equal("local"))
+	g.Expect(cs.Properties.AgentPoolProfiles).To(HaveLen(2))
+	g.Expect(cs.Properties.AgentPoolProfiles[1].VMSize).To(Equal("Standard_D2_v2"))
+	g.Expect(cs.Properties.LinuxProfile.SSH.PublicKeys[0].KeyData).To(Equal("ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC8"))
+
+	// unknown keys are rejected as in JSON API models
+	_, _, err = newTestApiloader().DeserializeContainerService([]byte(strings.Replace(yamlAPIModel, "dnsPrefix", "dnsPrefx", 1)), false, false, nil)
+	g.Expect(err).To(MatchError(`Unknown JSON tag properties.masterProfile.dnsPrefx, did you mean "dnsPrefix"?`))
+
+	_, _, err = newTestApiloader().DeserializeContainerService([]byte("apiVersion: [vlabs"), false, false, nil)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(HavePrefix("parsing YAML API model"))
+}
+
+func TestSerializeContainerServiceYAML(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	a := newTestApiloader()
+	cs, version, err := a.DeserializeContainerService([]byte(yamlAPIModel), false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	// the first pool is removed, its comment must not move to the remaining pool
+	cs.Properties.AgentPoolProfiles = cs.Properties.AgentPoolProfiles[1:]
+
+	b, err := a.SerializeContainerServiceYAML(cs, version, []byte(yamlAPIModel))
+	g.Expect(err).NotTo(HaveOccurred())
+	out := string(b)
+	g.Expect(out).To(HavePrefix("# cluster definition of the test stamp\n"))
+	g.Expect(out).To(ContainSubstring("dnsPrefix: yamlcluster # keep in sync with the stamp"))
+	g.Expect(out).To(ContainSubstring("# user pool\n"))
+	g.Expect(out).NotTo(ContainSubstring("# system pool"))
+
+	roundTrip, _, err := a.DeserializeContainerService(b, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(roundTrip.Properties.AgentPoolProfiles).To(HaveLen(1))
+	g.Expect(roundTrip.Properties.AgentPoolProfiles[0].Name).To(Equal("linuxpool2"))
+	g.Expect(roundTrip.Properties.OrchestratorProfile.OrchestratorVersion).To(Equal(cs.Properties.OrchestratorProfile.OrchestratorVersion))
+}
+
+func TestSerializeContainerServiceForFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	a := newTestApiloader()
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "apimodel.yaml")
+	g.Expect(os.WriteFile(yamlPath, []byte(yamlAPIModel), 0600)).To(Succeed())
+	cs, version, err := a.LoadContainerServiceFromFile(yamlPath, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	b, err := a.SerializeContainerServiceForFile(cs, version, yamlPath)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("# system pool"))
+
+	b, err = a.SerializeContainerServiceForFile(cs, version, filepath.Join(dir, "apimodel.json"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(HavePrefix("{"))
+
+	b, err = a.SerializeContainerServiceForFile(cs, version, filepath.Join(dir, "new.yml"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(HavePrefix("apiVersion: vlabs\n"))
+}
+
+func TestIsYAMLFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(IsYAMLFile("cluster.yaml")).To(BeTrue())
+	g.Expect(IsYAMLFile("_output/cluster/apimodel.YML")).To(BeTrue())
+	g.Expect(IsYAMLFile("apimodel.json")).To(BeFalse())
+}
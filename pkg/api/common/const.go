@@ -221,6 +221,8 @@ const (
 	PodSecurityPolicyAddonName = "pod-security-policy"
 	// NodeProblemDetectorAddonName is the name of the node problem detector addon
 	NodeProblemDetectorAddonName = "node-problem-detector"
+	// KubeletCSRApproverAddonName is the name of the kubelet serving certificate signing request approver addon
+	KubeletCSRApproverAddonName = "kubelet-csr-approver"
 	// SecretsStoreCSIDriverAddonName is the name of the secrets-store-csi-driver addon
 	SecretsStoreCSIDriverAddonName = "csi-secrets-store"
 	// CSISecretsStoreDriverContainerName is the name of the secrets-store container in the csi-secrets-store addon
@@ -291,6 +291,7 @@ func convertKubernetesConfigToVLabs(apiCfg *KubernetesConfig, vlabsCfg *vlabs.Ku
 	vlabsCfg.LoadBalancerOutboundIPs = apiCfg.LoadBalancerOutboundIPs
 	vlabsCfg.EnableRbac = apiCfg.EnableRbac
 	vlabsCfg.EnableSecureKubelet = apiCfg.EnableSecureKubelet
+	vlabsCfg.EnableKubeletServerTLSBootstrap = apiCfg.EnableKubeletServerTLSBootstrap
 	vlabsCfg.EnableAggregatedAPIs = apiCfg.EnableAggregatedAPIs
 	vlabsCfg.EnableDataEncryptionAtRest = apiCfg.EnableDataEncryptionAtRest
 	vlabsCfg.EnableEncryptionWithExternalKms = apiCfg.EnableEncryptionWithExternalKms
@@ -636,6 +637,7 @@ func convertServicePrincipalProfileToVLabs(api *ServicePrincipalProfile, v *vlab
 func convertCertificateProfileToVLabs(api *CertificateProfile, vlabs *vlabs.CertificateProfile) {
 	vlabs.CaCertificate = api.CaCertificate
 	vlabs.CaPrivateKey = api.CaPrivateKey
+	vlabs.CaCertificateChain = api.CaCertificateChain
 	vlabs.APIServerCertificate = api.APIServerCertificate
 	vlabs.APIServerPrivateKey = api.APIServerPrivateKey
 	vlabs.ClientCertificate = api.ClientCertificate
@@ -648,6 +650,32 @@ func convertCertificateProfileToVLabs(api *CertificateProfile, vlabs *vlabs.Cert
 	vlabs.EtcdClientPrivateKey = api.EtcdClientPrivateKey
 	vlabs.EtcdPeerCertificates = api.EtcdPeerCertificates
 	vlabs.EtcdPeerPrivateKeys = api.EtcdPeerPrivateKeys
+	vlabs.SeparateCAs = api.SeparateCAs
+	vlabs.EtcdCaCertificate = api.EtcdCaCertificate
+	vlabs.EtcdCaPrivateKey = api.EtcdCaPrivateKey
+	vlabs.FrontProxyCaCertificate = api.FrontProxyCaCertificate
+	vlabs.FrontProxyCaPrivateKey = api.FrontProxyCaPrivateKey
+	vlabs.FrontProxyClientCertificate = api.FrontProxyClientCertificate
+	vlabs.FrontProxyClientPrivateKey = api.FrontProxyClientPrivateKey
+	vlabs.KeyAlgorithm = api.KeyAlgorithm
+	vlabs.CaSettings = convertCertificateSettingsToVLabs(api.CaSettings)
+	vlabs.APIServerSettings = convertCertificateSettingsToVLabs(api.APIServerSettings)
+	vlabs.ClientSettings = convertCertificateSettingsToVLabs(api.ClientSettings)
+	vlabs.KubeConfigSettings = convertCertificateSettingsToVLabs(api.KubeConfigSettings)
+	vlabs.EtcdSettings = convertCertificateSettingsToVLabs(api.EtcdSettings)
+}
+
+func convertCertificateSettingsToVLabs(api *CertificateSettings) *vlabs.CertificateSettings {
+	if api == nil {
+		return nil
+	}
+	return &vlabs.CertificateSettings{
+		ValidityDays:       api.ValidityDays,
+		Organization:       api.Organization,
+		OrganizationalUnit: api.OrganizationalUnit,
+		Country:            api.Country,
+		ExtraSANs:          api.ExtraSANs,
+	}
 }
 
 func convertAADProfileToVLabs(api *AADProfile, vlabs *vlabs.AADProfile) {
@@ -300,6 +300,7 @@ func convertVLabsKubernetesConfig(vlabs *vlabs.KubernetesConfig, api *Kubernetes
 	api.LoadBalancerOutboundIPs = vlabs.LoadBalancerOutboundIPs
 	api.EnableRbac = vlabs.EnableRbac
 	api.EnableSecureKubelet = vlabs.EnableSecureKubelet
+	api.EnableKubeletServerTLSBootstrap = vlabs.EnableKubeletServerTLSBootstrap
 	api.EnableAggregatedAPIs = vlabs.EnableAggregatedAPIs
 	api.EnableDataEncryptionAtRest = vlabs.EnableDataEncryptionAtRest
 	api.EnableEncryptionWithExternalKms = vlabs.EnableEncryptionWithExternalKms
@@ -683,6 +684,7 @@ func convertVLabsServicePrincipalProfile(vlabs *vlabs.ServicePrincipalProfile, a
 func convertVLabsCertificateProfile(vlabs *vlabs.CertificateProfile, api *CertificateProfile) {
 	api.CaCertificate = vlabs.CaCertificate
 	api.CaPrivateKey = vlabs.CaPrivateKey
+	api.CaCertificateChain = vlabs.CaCertificateChain
 	api.APIServerCertificate = vlabs.APIServerCertificate
 	api.APIServerPrivateKey = vlabs.APIServerPrivateKey
 	api.ClientCertificate = vlabs.ClientCertificate
@@ -695,6 +697,32 @@ func convertVLabsCertificateProfile(vlabs *vlabs.CertificateProfile, api *Certif
 	api.EtcdClientPrivateKey = vlabs.EtcdClientPrivateKey
 	api.EtcdPeerCertificates = vlabs.EtcdPeerCertificates
 	api.EtcdPeerPrivateKeys = vlabs.EtcdPeerPrivateKeys
+	api.SeparateCAs = vlabs.SeparateCAs
+	api.EtcdCaCertificate = vlabs.EtcdCaCertificate
+	api.EtcdCaPrivateKey = vlabs.EtcdCaPrivateKey
+	api.FrontProxyCaCertificate = vlabs.FrontProxyCaCertificate
+	api.FrontProxyCaPrivateKey = vlabs.FrontProxyCaPrivateKey
+	api.FrontProxyClientCertificate = vlabs.FrontProxyClientCertificate
+	api.FrontProxyClientPrivateKey = vlabs.FrontProxyClientPrivateKey
+	api.KeyAlgorithm = vlabs.KeyAlgorithm
+	api.CaSettings = convertVLabsCertificateSettings(vlabs.CaSettings)
+	api.APIServerSettings = convertVLabsCertificateSettings(vlabs.APIServerSettings)
+	api.ClientSettings = convertVLabsCertificateSettings(vlabs.ClientSettings)
+	api.KubeConfigSettings = convertVLabsCertificateSettings(vlabs.KubeConfigSettings)
+	api.EtcdSettings = convertVLabsCertificateSettings(vlabs.EtcdSettings)
+}
+
+func convertVLabsCertificateSettings(vlabs *vlabs.CertificateSettings) *CertificateSettings {
+	if vlabs == nil {
+		return nil
+	}
+	return &CertificateSettings{
+		ValidityDays:       vlabs.ValidityDays,
+		Organization:       vlabs.Organization,
+		OrganizationalUnit: vlabs.OrganizationalUnit,
+		Country:            vlabs.Country,
+		ExtraSANs:          vlabs.ExtraSANs,
+	}
 }
 
 func convertVLabsAADProfile(vlabs *vlabs.AADProfile, api *AADProfile) {
@@ -41,6 +41,9 @@ func (cs *ContainerService) setAPIServerConfig() {
 		} else {
 			// Configuration for local etcd
 			staticAPIServerConfig["--etcd-cafile"] = "/etc/kubernetes/certs/ca.crt"
+			if cs.Properties.CertificateProfile.HasSeparateCAs() {
+				staticAPIServerConfig["--etcd-cafile"] = "/etc/kubernetes/certs/etcd-ca.crt"
+			}
 			staticAPIServerConfig["--etcd-servers"] = fmt.Sprintf("https://127.0.0.1:%s", strconv.Itoa(DefaultMasterEtcdClientPort))
 		}
 	}
@@ -101,6 +104,11 @@ func (cs *ContainerService) setAPIServerConfig() {
 		defaultAPIServerConfig["--authorization-mode"] = "Node,RBAC"
 	}
 
+	// Verify the kubelet serving certificates, signed by the cluster CA
+	if o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled() {
+		defaultAPIServerConfig["--kubelet-certificate-authority"] = "/etc/kubernetes/certs/ca.crt"
+	}
+
 	if common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.20.0-alpha.1") {
 		defaultAPIServerConfig["--service-account-issuer"] = "https://kubernetes.default.svc.cluster.local"
 		defaultAPIServerConfig["--service-account-signing-key-file"] = "/etc/kubernetes/certs/apiserver.key"
@@ -150,6 +158,11 @@ func (cs *ContainerService) setAPIServerConfig() {
 		}
 	}
 
+	// Remove the kubelet serving certificate authority, kubelets serve self-signed certificates
+	if !o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled() {
+		delete(o.KubernetesConfig.APIServerConfig, "--kubelet-certificate-authority")
+	}
+
 	// Enforce flags removal that don't work with specific versions, to accommodate upgrade
 	// Remove flags that are not compatible with any supported versions
 	for _, key := range []string{"--admission-control", "--repair-malformed-updates"} {
@@ -353,6 +353,27 @@ func TestAPIServerConfigEnableSecureKubelet(t *testing.T) {
 	}
 }
 
+func TestAPIServerConfigEnableKubeletServerTLSBootstrap(t *testing.T) {
+	// Test EnableKubeletServerTLSBootstrap = true
+	cs := CreateMockContainerService("testcluster", defaultTestClusterVer, 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EnableKubeletServerTLSBootstrap = to.BoolPtr(true)
+	cs.setAPIServerConfig()
+	a := cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	if a["--kubelet-certificate-authority"] != "/etc/kubernetes/certs/ca.crt" {
+		t.Fatalf("got unexpected '--kubelet-certificate-authority' API server config value for EnableKubeletServerTLSBootstrap=true: %s",
+			a["--kubelet-certificate-authority"])
+	}
+
+	// Test EnableKubeletServerTLSBootstrap = false, after it was enabled
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EnableKubeletServerTLSBootstrap = to.BoolPtr(false)
+	cs.setAPIServerConfig()
+	a = cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	if _, ok := a["--kubelet-certificate-authority"]; ok {
+		t.Fatalf("got unexpected '--kubelet-certificate-authority' API server config value for EnableKubeletServerTLSBootstrap=false: %s",
+			a["--kubelet-certificate-authority"])
+	}
+}
+
 func TestAPIServerConfigDefaultAdmissionControls(t *testing.T) {
 	cases := []struct {
 		name                 string
@@ -576,6 +597,21 @@ func TestAPIServerCosmosEtcd(t *testing.T) {
 	}
 }
 
+func TestAPIServerSeparateCAs(t *testing.T) {
+	cs := CreateMockContainerService("testcluster", "", 3, 2, false)
+	cs.Properties.CertificateProfile = &CertificateProfile{SeparateCAs: to.BoolPtr(true)}
+	cs.setAPIServerConfig()
+	a := cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	if a["--etcd-cafile"] != "/etc/kubernetes/certs/etcd-ca.crt" {
+		t.Fatalf("got unexpected '--etcd-cafile' API server config with separate CAs: %s",
+			a["--etcd-cafile"])
+	}
+	if a["--client-ca-file"] != "/etc/kubernetes/certs/ca.crt" {
+		t.Fatalf("got unexpected '--client-ca-file' API server config with separate CAs: %s",
+			a["--client-ca-file"])
+	}
+}
+
 func TestAPIServerFeatureGates(t *testing.T) {
 	// test defaultTestClusterVer
 	cs := CreateMockContainerService("testcluster", defaultTestClusterVer, 3, 2, false)
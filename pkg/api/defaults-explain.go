@@ -0,0 +1,338 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+// ValueSource tells whether a value of the cluster definition was set by the user or by the defaults
+type ValueSource string
+
+const (
+	// ValueSourceUser is a value set in the cluster definition and kept by the defaults
+	ValueSourceUser ValueSource = "user"
+	// ValueSourceDefault is a value filled in by the defaults
+	ValueSourceDefault ValueSource = "default"
+	// ValueSourceOverridden is a value set in the cluster definition and replaced by the defaults
+	ValueSourceOverridden ValueSource = "overridden"
+)
+
+// The kinds of rules a default depends on
+const (
+	DefaultRuleStatic             = "static"
+	DefaultRuleVersionDependent   = "version-dependent"
+	DefaultRuleCloudDependent     = "cloud-dependent"
+	DefaultRuleAzureStackSpecific = "Azure Stack-specific"
+	DefaultRuleGenerated          = "generated"
+)
+
+// redactedValue replaces the secrets of the cluster definition in a DefaultsReport
+const redactedValue = "<redacted>"
+
+// unsetValue is the value of a property set by the user and removed by the defaults
+const unsetValue = "<unset>"
+
+// DefaultedValue explains where a value of the cluster definition comes from
+type DefaultedValue struct {
+	// Path is the JSON path of the value in the vlabs cluster definition
+	Path string `json:"path"`
+	// Value is the final value, once the defaults are set
+	Value string `json:"value"`
+	// Source tells whether the user set the value
+	Source ValueSource `json:"source"`
+	// Rule is the defaults that chose the value and what they depend on, for the values not set by the user
+	Rule string `json:"rule,omitempty"`
+}
+
+// DefaultsReport explains every value of a cluster definition once the defaults are set, sorted by path
+type DefaultsReport []DefaultedValue
+
+// defaultsAreas maps the properties of the cluster definition to the defaults that set them,
+// the first area whose key is part of a path applies
+var defaultsAreas = []struct {
+	key, area string
+}{
+	{".kubeletConfig", "kubelet defaults"},
+	{".apiServerConfig", "kube-apiserver defaults"},
+	{".controllerManagerConfig", "kube-controller-manager defaults"},
+	{".cloudControllerManagerConfig", "cloud-controller-manager defaults"},
+	{".schedulerConfig", "kube-scheduler defaults"},
+	{".sysctldConfig", "sysctl defaults"},
+	{".addons", "addon defaults"},
+	{".components", "component defaults"},
+	{".containerdConfig", "containerd defaults"},
+	{"properties.customCloudProfile", "custom cloud defaults"},
+	{"properties.orchestratorProfile", "orchestrator defaults"},
+	{"properties.masterProfile", "master profile defaults"},
+	{"properties.agentPoolProfiles", "agent pool defaults"},
+	{"properties.linuxProfile", "Linux profile defaults"},
+	{"properties.windowsProfile", "Windows profile defaults"},
+	{"properties.extensionProfiles", "extension defaults"},
+	{"properties.telemetryProfile", "telemetry defaults"},
+}
+
+// ExplainDefaults explains the values of after, the cluster definition apimodel once its defaults are set with params.
+// The rule of a default value is found by setting the defaults again with another Kubernetes version and in another
+// cloud: the values that change depend on the version or on the cloud.
+func ExplainDefaults(apimodel []byte, after *ContainerService, params PropertiesDefaultsParams) (DefaultsReport, error) {
+	user, err := userValues(apimodel)
+	if err != nil {
+		return nil, err
+	}
+	final, err := flattenContainerService(after)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := []struct {
+		rule   string
+		mutate func(cs *ContainerService) bool
+	}{
+		{DefaultRuleGenerated, func(cs *ContainerService) bool { return true }},
+		{DefaultRuleVersionDependent, func(cs *ContainerService) bool { return useAnotherVersion(cs, after) }},
+		{cloudRule(after), useAnotherCloud},
+	}
+	rules := map[string][]string{}
+	for _, variant := range variants {
+		values, err := defaultedValuesOf(apimodel, after, params, variant.mutate)
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting the defaults to find the %s values", variant.rule)
+		}
+		if values == nil {
+			continue
+		}
+		for path, value := range final {
+			if v, ok := values[path]; !ok || v != value {
+				rules[path] = append(rules[path], variant.rule)
+			}
+		}
+	}
+
+	var report DefaultsReport
+	for path, value := range final {
+		dv := DefaultedValue{Path: path, Value: value, Source: ValueSourceDefault}
+		u, ok := user[strings.ToLower(path)]
+		switch {
+		case ok && (u.value == value || u.value == after.SecretReferences[path].Reference):
+			// secret references are resolved when the cluster definition is loaded
+			dv.Source = ValueSourceUser
+		case ok:
+			dv.Source = ValueSourceOverridden
+		case value == "":
+			// empty strings are unset values
+			continue
+		}
+		if dv.Source != ValueSourceUser {
+			dv.Rule = explainRule(path, rules[path])
+		}
+		if isSecretPath(path) {
+			dv.Value = redactedValue
+		}
+		report = append(report, dv)
+	}
+	finalPaths := map[string]bool{}
+	for path := range final {
+		finalPaths[strings.ToLower(path)] = true
+	}
+	for lowerPath, u := range user {
+		if !finalPaths[lowerPath] {
+			report = append(report, DefaultedValue{Path: u.path, Value: unsetValue, Source: ValueSourceOverridden, Rule: explainRule(u.path, nil)})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Path < report[j].Path })
+	return report, nil
+}
+
+// Defaults returns the values of the report filled in or replaced by the defaults
+func (r DefaultsReport) Defaults() DefaultsReport {
+	var defaults DefaultsReport
+	for _, v := range r {
+		if v.Source != ValueSourceUser {
+			defaults = append(defaults, v)
+		}
+	}
+	return defaults
+}
+
+// cloudRule returns the rule of the values that change when after is deployed to another cloud
+func cloudRule(after *ContainerService) string {
+	if after.Properties.IsAzureStackCloud() {
+		return DefaultRuleAzureStackSpecific
+	}
+	return DefaultRuleCloudDependent
+}
+
+// useAnotherVersion sets the version of cs to the newest supported Kubernetes version of another minor release
+// than the version of after
+func useAnotherVersion(cs, after *ContainerService) bool {
+	current := minorRelease(after.Properties.OrchestratorProfile.OrchestratorVersion)
+	var other semver.Version
+	for _, v := range common.GetAllSupportedKubernetesVersions(false, after.Properties.HasWindows(), after.Properties.IsAzureStackCloud()) {
+		sv, err := semver.Make(v)
+		if err != nil || minorRelease(v) == current {
+			continue
+		}
+		if sv.GT(other) {
+			other = sv
+		}
+	}
+	if other.Major == 0 {
+		return false
+	}
+	cs.Properties.OrchestratorProfile.OrchestratorVersion = other.String()
+	return true
+}
+
+func minorRelease(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// useAnotherCloud moves an Azure Stack cluster to Azure, and an Azure cluster to Azure China
+func useAnotherCloud(cs *ContainerService) bool {
+	if cs.Properties.IsCustomCloudProfile() {
+		cs.Properties.CustomCloudProfile = nil
+		cs.Location = "westus2"
+		return true
+	}
+	cs.Location = "chinaeast2"
+	return true
+}
+
+// defaultedValuesOf loads apimodel, changes it with mutate and sets its defaults. What was retrieved from the cloud
+// or generated by the defaults of after is reused. It returns nil if mutate did not change the cluster definition.
+func defaultedValuesOf(apimodel []byte, after *ContainerService, params PropertiesDefaultsParams, mutate func(cs *ContainerService) bool) (map[string]string, error) {
+	cs, _, err := (&Apiloader{}).DeserializeContainerService(apimodel, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Properties.OrchestratorProfile == nil {
+		cs.Properties.OrchestratorProfile = &OrchestratorProfile{}
+	}
+	if !mutate(cs) {
+		return nil, nil
+	}
+	if cs.Properties.IsCustomCloudProfile() && after.Properties.IsCustomCloudProfile() && after.Properties.CustomCloudProfile.Environment != nil {
+		env := *after.Properties.CustomCloudProfile.Environment
+		cs.Properties.CustomCloudProfile.Environment = &env
+	}
+	if after.Properties.CertificateProfile != nil {
+		certs := *after.Properties.CertificateProfile
+		cs.Properties.CertificateProfile = &certs
+	}
+	if _, err = cs.SetPropertiesDefaults(params); err != nil {
+		return nil, err
+	}
+	return flattenContainerService(cs)
+}
+
+// explainRule describes the defaults that set the value at path, and the kinds of rules they depend on
+func explainRule(path string, kinds []string) string {
+	area := "defaults"
+	for _, a := range defaultsAreas {
+		if strings.Contains(strings.ToLower(path), strings.ToLower(a.key)) {
+			area = a.area
+			break
+		}
+	}
+	if len(kinds) == 0 {
+		kinds = []string{DefaultRuleStatic}
+	}
+	return fmt.Sprintf("%s (%s)", area, strings.Join(kinds, ", "))
+}
+
+// isSecretPath returns true if the value at path must not be printed
+func isSecretPath(path string) bool {
+	key := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
+	for _, secret := range []string{"secret", "password", "privatekey", "encryptionkey", "token"} {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenContainerService returns the values of the vlabs cluster definition of cs by JSON path.
+// The certificates are left out.
+func flattenContainerService(cs *ContainerService) (map[string]string, error) {
+	b, err := json.Marshal(ConvertContainerServiceToVLabs(cs))
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing the API model")
+	}
+	var doc interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing the API model")
+	}
+	values := map[string]string{}
+	flattenJSON(doc, "", values)
+	for path := range values {
+		if strings.HasPrefix(path, "properties.certificateProfile.") {
+			delete(values, path)
+		}
+	}
+	return values, nil
+}
+
+// userValue is a value set in the cluster definition, at path
+type userValue struct {
+	path, value string
+}
+
+// userValues returns the values set in the cluster definition apimodel by lower case JSON path,
+// as its keys are not case sensitive. The API version and the certificates are left out.
+func userValues(apimodel []byte) (map[string]userValue, error) {
+	contents, err := ConvertYAMLToJSON(apimodel)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(contents, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing the API model")
+	}
+	values := map[string]string{}
+	flattenJSON(doc, "", values)
+	user := map[string]userValue{}
+	for path, value := range values {
+		lowerPath := strings.ToLower(path)
+		if lowerPath != "apiversion" && !strings.HasPrefix(lowerPath, "properties.certificateprofile.") {
+			user[lowerPath] = userValue{path: path, value: value}
+		}
+	}
+	return user, nil
+}
+
+// flattenJSON adds the leaf values of the JSON document doc at path to values, by JSON path
+func flattenJSON(doc interface{}, path string, values map[string]string) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			flattenJSON(child, childPath, values)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenJSON(child, fmt.Sprintf("%s[%d]", path, i), values)
+		}
+	case nil:
+	case string:
+		values[path] = v
+	default:
+		b, _ := json.Marshal(v)
+		values[path] = string(b)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const explainDefaultsAPIModel = `{
+	"apiVersion": "vlabs",
+	"location": "local",
+	"properties": {
+		"orchestratorProfile": {
+			"kubernetesConfig": {
+				"networkPlugin": "kubenet",
+				"kubeletConfig": {"--max-pods": "50", "--allow-privileged": "true"}
+			}
+		},
+		"customCloudProfile": {
+			"portalURL": "https://portal.local.azurestack.external/",
+			"environment": {
+				"name": "AzureStackCloud",
+				"serviceManagementEndpoint": "https://management.azurestack.onmicrosoft.com/36f71706-54df-4305-9847-5b038a4cf189",
+				"resourceManagerEndpoint": "https://management.local.azurestack.external/",
+				"activeDirectoryEndpoint": "https://login.windows.net/",
+				"graphEndpoint": "https://graph.windows.net/",
+				"storageEndpointSuffix": "local.azurestack.external",
+				"keyVaultDNSSuffix": "vault.local.azurestack.external",
+				"resourceManagerVMDNSSuffix": "cloudapp.azurestack.external"
+			}
+		},
+		"masterProfile": {"count": 1, "dnsPrefix": "explain", "vmSize": "Standard_D2_v2"},
+		"agentPoolProfiles": [{"name": "pool", "count": 1, "vmSize": "Standard_D2_v2"}],
+		"linuxProfile": {"adminUsername": "azureuser", "ssh": {"publicKeys": [{"keyData": "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC8"}]}},
+		"servicePrincipalProfile": {"clientId": "ServicePrincipalClientID", "secret": "myServicePrincipalClientSecret"}
+	}
+}`
+
+func TestExplainDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	apimodel := []byte(explainDefaultsAPIModel)
+	cs, _, err := (&Apiloader{}).DeserializeContainerService(apimodel, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	params := PropertiesDefaultsParams{PkiKeySize: 2048}
+	_, err = cs.SetPropertiesDefaults(params)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	report, err := ExplainDefaults(apimodel, cs, params)
+	g.Expect(err).NotTo(HaveOccurred())
+	values := map[string]DefaultedValue{}
+	for i, v := range report {
+		values[v.Path] = v
+		if i > 0 {
+			g.Expect(report[i-1].Path < v.Path).To(BeTrue(), "the report is sorted by path")
+		}
+		g.Expect(v.Path).NotTo(HavePrefix("properties.certificateProfile"))
+		g.Expect(v.Path).NotTo(Equal("apiVersion"))
+	}
+
+	const kubernetesConfig = "properties.orchestratorProfile.kubernetesConfig"
+	g.Expect(values).To(HaveKeyWithValue("location", DefaultedValue{Path: "location", Value: "local", Source: ValueSourceUser}))
+	g.Expect(values).To(HaveKeyWithValue(kubernetesConfig+".kubeletConfig.--max-pods", DefaultedValue{
+		Path: kubernetesConfig + ".kubeletConfig.--max-pods", Value: "50", Source: ValueSourceUser,
+	}))
+	g.Expect(values).To(HaveKeyWithValue(kubernetesConfig+".kubeletConfig.--allow-privileged", DefaultedValue{
+		Path: kubernetesConfig + ".kubeletConfig.--allow-privileged", Value: unsetValue, Source: ValueSourceOverridden, Rule: "kubelet defaults (static)",
+	}))
+	g.Expect(values).To(HaveKeyWithValue(kubernetesConfig+".kubeletConfig.--node-status-update-frequency", DefaultedValue{
+		Path: kubernetesConfig + ".kubeletConfig.--node-status-update-frequency", Value: DefaultAzureStackKubernetesNodeStatusUpdateFrequency, Source: ValueSourceDefault, Rule: "kubelet defaults (Azure Stack-specific)",
+	}))
+	g.Expect(values).To(HaveKeyWithValue(kubernetesConfig+".dnsServiceIP", DefaultedValue{
+		Path: kubernetesConfig + ".dnsServiceIP", Value: DefaultKubernetesDNSServiceIP, Source: ValueSourceDefault, Rule: "orchestrator defaults (static)",
+	}))
+	g.Expect(values["properties.orchestratorProfile.orchestratorVersion"].Rule).To(Equal("orchestrator defaults (version-dependent)"))
+	g.Expect(values["properties.servicePrincipalProfile.secret"].Value).To(Equal(redactedValue))
+	g.Expect(values).NotTo(HaveKey("properties.customCloudProfile.environment.galleryEndpoint"), "empty values are left out")
+
+	g.Expect(report.Defaults()).NotTo(ContainElement(values["location"]))
+	g.Expect(report.Defaults()).To(ContainElement(values[kubernetesConfig+".dnsServiceIP"]))
+}
+
+func TestExplainRule(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(explainRule("properties.agentPoolProfiles[0].kubernetesConfig.kubeletConfig.--max-pods", nil)).To(Equal("kubelet defaults (static)"))
+	g.Expect(explainRule("properties.agentPoolProfiles[0].vmSize", []string{DefaultRuleVersionDependent, DefaultRuleCloudDependent})).To(Equal("agent pool defaults (version-dependent, cloud-dependent)"))
+	g.Expect(explainRule("properties.featureFlags.enableIPv6Only", nil)).To(Equal("defaults (static)"))
+}
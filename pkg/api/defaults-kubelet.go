@@ -278,6 +278,7 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 		invalidFeatureGates = append(invalidFeatureGates, "RetroactiveDefaultStorageClass")
 	}
 	removeInvalidFeatureGates(o.KubernetesConfig.KubeletConfig, invalidFeatureGates)
+	setKubeletServerTLSBootstrapFlags(o.KubernetesConfig.KubeletConfig, o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled())
 
 	// Master-specific kubelet config changes go here
 	if cs.Properties.MasterProfile != nil {
@@ -324,6 +325,7 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 
 		removeKubeletFlags(cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig, o.OrchestratorVersion)
 		removeInvalidFeatureGates(cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig, invalidFeatureGates)
+		setKubeletServerTLSBootstrapFlags(cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig, o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled())
 
 		if cs.Properties.AnyAgentIsLinux() {
 			if val, ok := cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig["--register-with-taints"]; !ok {
@@ -389,6 +391,7 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 
 		removeKubeletFlags(profile.KubernetesConfig.KubeletConfig, o.OrchestratorVersion)
 		removeInvalidFeatureGates(profile.KubernetesConfig.KubeletConfig, invalidFeatureGates)
+		setKubeletServerTLSBootstrapFlags(profile.KubernetesConfig.KubeletConfig, o.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled())
 		if cs.Properties.OrchestratorProfile.KubernetesConfig.IsAddonEnabled(common.AADPodIdentityAddonName) && !profile.IsWindows() {
 			if val, ok := profile.KubernetesConfig.KubeletConfig["--register-with-taints"]; !ok {
 				profile.KubernetesConfig.KubeletConfig["--register-with-taints"] = fmt.Sprintf("%s=true:NoSchedule", common.AADPodIdentityTaintKey)
@@ -401,6 +404,19 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 	}
 }
 
+// setKubeletServerTLSBootstrapFlags replaces the self-signed kubelet serving certificate
+// by a certificate requested from the cluster CA, if server TLS bootstrap is enabled
+func setKubeletServerTLSBootstrapFlags(k map[string]string, enabled bool) {
+	if enabled {
+		for _, key := range []string{"--tls-cert-file", "--tls-private-key-file"} {
+			delete(k, key)
+		}
+		k["--rotate-server-certificates"] = "true"
+	} else {
+		delete(k, "--rotate-server-certificates")
+	}
+}
+
 func removeKubeletFlags(k map[string]string, v string) {
 	// Get rid of values not supported until v1.10
 	if !common.IsKubernetesVersionGe(v, "1.10.0") {
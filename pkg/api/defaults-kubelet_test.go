@@ -943,6 +943,45 @@ func TestKubeletRotateCertificates(t *testing.T) {
 	}
 }
 
+func TestKubeletServerTLSBootstrap(t *testing.T) {
+	cs := CreateMockContainerService("testcluster", defaultTestClusterVer, 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EnableKubeletServerTLSBootstrap = to.BoolPtr(true)
+	cs.setKubeletConfig(false)
+	for _, k := range []map[string]string{
+		cs.Properties.OrchestratorProfile.KubernetesConfig.KubeletConfig,
+		cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig,
+		cs.Properties.AgentPoolProfiles[0].KubernetesConfig.KubeletConfig,
+	} {
+		if k["--rotate-server-certificates"] != "true" {
+			t.Fatalf("got unexpected '--rotate-server-certificates' kubelet config value for EnableKubeletServerTLSBootstrap=true: %s",
+				k["--rotate-server-certificates"])
+		}
+		for _, key := range []string{"--tls-cert-file", "--tls-private-key-file"} {
+			if _, ok := k[key]; ok {
+				t.Fatalf("got unexpected '%s' kubelet config value for EnableKubeletServerTLSBootstrap=true: %s", key, k[key])
+			}
+		}
+	}
+
+	// Test EnableKubeletServerTLSBootstrap = false, after it was enabled
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EnableKubeletServerTLSBootstrap = to.BoolPtr(false)
+	cs.setKubeletConfig(true)
+	for _, k := range []map[string]string{
+		cs.Properties.OrchestratorProfile.KubernetesConfig.KubeletConfig,
+		cs.Properties.MasterProfile.KubernetesConfig.KubeletConfig,
+		cs.Properties.AgentPoolProfiles[0].KubernetesConfig.KubeletConfig,
+	} {
+		if _, ok := k["--rotate-server-certificates"]; ok {
+			t.Fatalf("got unexpected '--rotate-server-certificates' kubelet config value for EnableKubeletServerTLSBootstrap=false: %s",
+				k["--rotate-server-certificates"])
+		}
+		if k["--tls-cert-file"] != "/etc/kubernetes/certs/kubeletserver.crt" {
+			t.Fatalf("got unexpected '--tls-cert-file' kubelet config value for EnableKubeletServerTLSBootstrap=false: %s",
+				k["--tls-cert-file"])
+		}
+	}
+}
+
 func TestKubeletConfigFeatureGates(t *testing.T) {
 	// test user-overrides
 	cs := CreateMockContainerService("testcluster", "", 3, 2, false)
@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	compute "github.com/Azure/azure-sdk-for-go/profile/p20200901/resourcemanager/compute/armcompute"
 
@@ -952,6 +953,8 @@ func (p *Properties) setTelemetryProfileDefaults() {
 // DefaultCertParams is the params when we set the default certs.
 type DefaultCertParams struct {
 	PkiKeySize int
+	// APIServerPrivateKey is the PEM private key of a generated apiserver certificate, a new key if empty
+	APIServerPrivateKey string
 }
 
 // SetDefaultCerts generates and sets defaults for the container certificateProfile, returns true if certs are generated
@@ -1015,8 +1018,10 @@ func (cs *ContainerService) SetDefaultCerts(params DefaultCertParams) (bool, []n
 	} else {
 		var err error
 		pkiKeyCertPairParams := helpers.PkiKeyCertPairParams{
-			CommonName: "ca",
-			PkiKeySize: params.PkiKeySize,
+			CommonName:      "ca",
+			PkiKeySize:      params.PkiKeySize,
+			PkiKeyAlgorithm: p.CertificateProfile.KeyAlgorithm,
+			Settings:        p.CertificateProfile.CaSettings.pkiCertificateSettings(),
 		}
 
 		caPair, err = helpers.CreatePkiKeyCertPair(pkiKeyCertPairParams)
@@ -1026,6 +1031,20 @@ func (cs *ContainerService) SetDefaultCerts(params DefaultCertParams) (bool, []n
 
 		p.CertificateProfile.CaCertificate = caPair.CertificatePem
 		p.CertificateProfile.CaPrivateKey = caPair.PrivateKeyPem
+		// the generated CA is a root CA
+		p.CertificateProfile.CaCertificateChain = ""
+	}
+
+	// etcd and the front-proxy get their own CAs, the specified pairs or new pairs
+	var etcdCaPair, frontProxyCaPair *helpers.PkiKeyCertPair
+	if p.CertificateProfile.HasSeparateCAs() {
+		var err error
+		if etcdCaPair, err = p.CertificateProfile.separateCaPair(provided["etcdca"], "etcd-ca", &p.CertificateProfile.EtcdCaCertificate, &p.CertificateProfile.EtcdCaPrivateKey, params.PkiKeySize); err != nil {
+			return false, ips, err
+		}
+		if frontProxyCaPair, err = p.CertificateProfile.separateCaPair(provided["frontproxyca"], "front-proxy-ca", &p.CertificateProfile.FrontProxyCaCertificate, &p.CertificateProfile.FrontProxyCaPrivateKey, params.PkiKeySize); err != nil {
+			return false, ips, err
+		}
 	}
 
 	serviceCIDR := p.OrchestratorProfile.KubernetesConfig.ServiceCIDR
@@ -1051,7 +1070,16 @@ func (cs *ContainerService) SetDefaultCerts(params DefaultCertParams) (bool, []n
 	pkiParams.ExtraIPs = ips
 	pkiParams.MasterCount = p.MasterProfile.Count
 	pkiParams.PkiKeySize = params.PkiKeySize
-	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, etcdClientPair, etcdPeerPairs, err :=
+	pkiParams.PkiKeyAlgorithm = p.CertificateProfile.KeyAlgorithm
+	pkiParams.CaCertificateChain = p.CertificateProfile.CaCertificateChain
+	pkiParams.APIServerSettings = p.CertificateProfile.APIServerSettings.pkiCertificateSettings()
+	pkiParams.ClientSettings = p.CertificateProfile.ClientSettings.pkiCertificateSettings()
+	pkiParams.KubeConfigSettings = p.CertificateProfile.KubeConfigSettings.pkiCertificateSettings()
+	pkiParams.EtcdSettings = p.CertificateProfile.EtcdSettings.pkiCertificateSettings()
+	pkiParams.EtcdCaPair = etcdCaPair
+	pkiParams.FrontProxyCaPair = frontProxyCaPair
+	pkiParams.APIServerPrivateKeyPem = params.APIServerPrivateKey
+	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, etcdClientPair, etcdPeerPairs, frontProxyClientPair, err :=
 		helpers.CreatePki(pkiParams)
 	if err != nil {
 		return false, ips, err
@@ -1070,7 +1098,11 @@ func (cs *ContainerService) SetDefaultCerts(params DefaultCertParams) (bool, []n
 		p.CertificateProfile.KubeConfigCertificate = kubeConfigPair.CertificatePem
 		p.CertificateProfile.KubeConfigPrivateKey = kubeConfigPair.PrivateKeyPem
 	}
-	if !provided["etcd"] || !provided["ca"] {
+	etcdCaKey := "ca"
+	if p.CertificateProfile.HasSeparateCAs() {
+		etcdCaKey = "etcdca"
+	}
+	if !provided["etcd"] || !provided[etcdCaKey] {
 		p.CertificateProfile.EtcdServerCertificate = etcdServerPair.CertificatePem
 		p.CertificateProfile.EtcdServerPrivateKey = etcdServerPair.PrivateKeyPem
 		p.CertificateProfile.EtcdClientCertificate = etcdClientPair.CertificatePem
@@ -1082,10 +1114,53 @@ func (cs *ContainerService) SetDefaultCerts(params DefaultCertParams) (bool, []n
 			p.CertificateProfile.EtcdPeerPrivateKeys[i] = v.PrivateKeyPem
 		}
 	}
+	if frontProxyClientPair != nil && (!provided["frontproxy"] || !provided["frontproxyca"]) {
+		p.CertificateProfile.FrontProxyClientCertificate = frontProxyClientPair.CertificatePem
+		p.CertificateProfile.FrontProxyClientPrivateKey = frontProxyClientPair.PrivateKeyPem
+	}
 
 	return true, ips, nil
 }
 
+// separateCaPair returns the specified CA pair of etcd or the front-proxy, or generates and sets a new pair
+func (c *CertificateProfile) separateCaPair(provided bool, commonName string, certificate, privateKey *string, pkiKeySize int) (*helpers.PkiKeyCertPair, error) {
+	if provided {
+		return &helpers.PkiKeyCertPair{CertificatePem: *certificate, PrivateKeyPem: *privateKey}, nil
+	}
+	pair, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{
+		CommonName:      commonName,
+		PkiKeySize:      pkiKeySize,
+		PkiKeyAlgorithm: c.KeyAlgorithm,
+		Settings:        c.CaSettings.pkiCertificateSettings(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	*certificate = pair.CertificatePem
+	*privateKey = pair.PrivateKeyPem
+	return pair, nil
+}
+
+// pkiCertificateSettings returns the settings of the generated certificate, the extra SANs are split into DNS names and IP addresses
+func (s *CertificateSettings) pkiCertificateSettings() helpers.PkiCertificateSettings {
+	var settings helpers.PkiCertificateSettings
+	if s == nil {
+		return settings
+	}
+	settings.ValidityDuration = time.Duration(s.ValidityDays) * 24 * time.Hour
+	settings.Organization = s.Organization
+	settings.OrganizationalUnit = s.OrganizationalUnit
+	settings.Country = s.Country
+	for _, san := range s.ExtraSANs {
+		if ip := net.ParseIP(san); ip != nil {
+			settings.ExtraIPs = append(settings.ExtraIPs, ip)
+		} else {
+			settings.ExtraFQDNs = append(settings.ExtraFQDNs, san)
+		}
+	}
+	return settings
+}
+
 func areAllTrue(m map[string]bool) bool {
 	for _, v := range m {
 		if !v {
@@ -1127,6 +1202,11 @@ func certsAlreadyPresent(c *CertificateProfile, m int) map[string]bool {
 		g["kubeconfig"] = len(c.KubeConfigCertificate) > 0 && len(c.KubeConfigPrivateKey) > 0
 		g["client"] = len(c.ClientCertificate) > 0 && len(c.ClientPrivateKey) > 0
 		g["etcd"] = etcdPeer && len(c.EtcdClientCertificate) > 0 && len(c.EtcdClientPrivateKey) > 0 && len(c.EtcdServerCertificate) > 0 && len(c.EtcdServerPrivateKey) > 0
+		if c.HasSeparateCAs() {
+			g["etcdca"] = len(c.EtcdCaCertificate) > 0 && len(c.EtcdCaPrivateKey) > 0
+			g["frontproxyca"] = len(c.FrontProxyCaCertificate) > 0 && len(c.FrontProxyCaPrivateKey) > 0
+			g["frontproxy"] = len(c.FrontProxyClientCertificate) > 0 && len(c.FrontProxyClientPrivateKey) > 0
+		}
 	}
 	return g
 }
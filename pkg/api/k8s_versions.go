@@ -32,6 +32,7 @@ const (
 	azurePolicyImageReference                         string = "mcr.microsoft.com/azure-policy/policy-kubernetes-addon-prod:prod_20201023.1"
 	gatekeeperImageReference                          string = "mcr.microsoft.com/oss/open-policy-agent/gatekeeper:v3.2.3"
 	nodeProblemDetectorImageReference                 string = "registry.k8s.io/node-problem-detector/node-problem-detector:v0.8.4"
+	kubeletCSRApproverImageReference                  string = "oss/kubernetes/kubelet-csr-approver:v1.2.2"
 	csiAzureFileImageReference                        string = "oss/kubernetes-csi/azurefile-csi:v1.9.0"
 	azureCloudControllerManagerImageReference         string = "oss/kubernetes/azure-cloud-controller-manager:v1.1.1"
 	azureCloudNodeManagerImageReference               string = "oss/kubernetes/azure-cloud-node-manager:v1.1.1"
@@ -896,6 +897,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -979,6 +981,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1062,6 +1065,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1145,6 +1149,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1228,6 +1233,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1311,6 +1317,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1394,6 +1401,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1477,6 +1485,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1560,6 +1569,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1643,6 +1653,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1726,6 +1737,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1809,6 +1821,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1892,6 +1905,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -1971,6 +1985,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -2047,6 +2062,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -2120,6 +2136,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -2191,6 +2208,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.CSIProvisionerContainerName:                k8sComponent[common.CSIProvisionerContainerName],
 			common.CSIAttacherContainerName:                   k8sComponent[common.CSIAttacherContainerName],
 			common.CSILivenessProbeContainerName:              k8sComponent[common.CSILivenessProbeContainerName],
@@ -2261,6 +2279,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 			"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 			common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -2322,6 +2341,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 			"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 			common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -2383,6 +2403,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 			"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 			common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -2444,6 +2465,7 @@ func getK8sVersionComponents(version, kubernetesImageBaseType string, overrides
 			common.AzurePolicyAddonName:                       azurePolicyImageReference,
 			common.GatekeeperContainerName:                    gatekeeperImageReference,
 			common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+			common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 			common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 			"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 			common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -67,6 +67,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.CSIProvisionerContainerName:                "oss/kubernetes-csi/csi-provisioner:v3.0.0",
 		common.CSIAttacherContainerName:                   "oss/kubernetes-csi/csi-attacher:v3.3.0",
 		common.CSILivenessProbeContainerName:              "oss/kubernetes-csi/livenessprobe:v2.5.0",
@@ -155,6 +156,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.CSIProvisionerContainerName:                "oss/kubernetes-csi/csi-provisioner:v3.0.0",
 		common.CSIAttacherContainerName:                   "oss/kubernetes-csi/csi-attacher:v3.3.0",
 		common.CSILivenessProbeContainerName:              "oss/kubernetes-csi/livenessprobe:v2.5.0",
@@ -246,6 +248,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -327,6 +330,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -404,6 +408,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -480,6 +485,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -549,6 +555,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -615,6 +622,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -681,6 +689,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -746,6 +755,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -909,6 +919,7 @@ func TestGetK8sVersionComponents(t *testing.T) {
 		common.AzurePolicyAddonName:                       azurePolicyImageReference,
 		common.GatekeeperContainerName:                    gatekeeperImageReference,
 		common.NodeProblemDetectorAddonName:               nodeProblemDetectorImageReference,
+		common.KubeletCSRApproverAddonName:                kubeletCSRApproverImageReference,
 		common.KubeFlannelContainerName:                   kubeFlannelImageReference,
 		"flannel" + common.FlannelInstallCNIContainerName: flannelInstallCNIImageReference,
 		common.KubeRBACProxyContainerName:                 KubeRBACProxyImageReference,
@@ -0,0 +1,122 @@
+// Code generated for package schema by descgen DO NOT EDIT. (@generated)
+// sources:
+// ../vlabs/azenvtypes.go
+// ../vlabs/const.go
+// ../vlabs/doc.go
+// ../vlabs/merge.go
+// ../vlabs/orchestratorversiontypes.go
+// ../vlabs/types.go
+// ../vlabs/validate.go
+// ../vlabs/validationerrors.go
+
+package schema
+
+// descriptions holds the doc comments of the API model types by type name and by type and field name
+var descriptions = map[string]string{
+	"AADProfile":                                     "AADProfile specifies attributes for AAD integration",
+	"AADProfile.AdminGroupID":                        "The Azure Active Directory Group Object ID that will be assigned the cluster-admin RBAC role. Optional",
+	"AADProfile.ClientAppID":                         "The client AAD application ID.",
+	"AADProfile.ServerAppID":                         "The server AAD application ID.",
+	"AADProfile.TenantID":                            "The AAD tenant ID to use for authentication. If not specified, will use the tenant of the deployment subscription. Optional",
+	"AddonNodePoolsConfig":                           "AddonNodePoolsConfig defines configuration for pool-specific cluster-autoscaler configuration",
+	"AgentPoolProfile":                               "AgentPoolProfile represents an agent pool definition",
+	"AgentPoolProfile.VMSSName":                      "VMSSName is a read-only field; its value will be computed during template generation",
+	"AgentPoolProfile.subnet":                        "subnet is internal",
+	"AgentPoolProfileRole":                           "AgentPoolProfileRole represents an agent role",
+	"AzureEndpointConfig":                            "AzureEndpointConfig describes an Azure endpoint",
+	"AzureEnvironmentSpecConfig":                     "AzureEnvironmentSpecConfig is the overall configuration differences in different cloud environments.",
+	"AzureOSImageConfig":                             "AzureOSImageConfig describes an Azure OS image",
+	"CertificateProfile":                             "CertificateProfile represents the definition of the master cluster The JSON parameters could be either a plain text, or referenced to a secret in a keyvault. In the latter case, the format of the parameter's value should be \"/subscriptions/<SUB_ID>/resourceGroups/<RG_NAME>/providers/Microsoft.KeyVault/vaults/<KV_NAME>/secrets/<NAME>[/<VERSION>]\" where: SUB_ID is the subscription ID of the keyvault RG_NAME is the resource group of the keyvault KV_NAME is the name of the keyvault NAME is the name of the secret VERSION (optional) is the version of the secret (default: the latest version)",
+	"CertificateProfile.APIServerCertificate":        "ApiServerCertificate is the rest api server certificate, and signed by the CA",
+	"CertificateProfile.APIServerPrivateKey":         "ApiServerPrivateKey is the rest api server private key, and signed by the CA",
+	"CertificateProfile.APIServerSettings":           "APIServerSettings are the validity, subject and SAN settings of the generated apiserver certificate",
+	"CertificateProfile.CaCertificate":               "CaCertificate is the certificate authority certificate.",
+	"CertificateProfile.CaCertificateChain":          "CaCertificateChain is the chain of the issuers of the CA certificate up to the root CA, when the CA is an intermediate CA",
+	"CertificateProfile.CaPrivateKey":                "CaPrivateKey is the certificate authority key.",
+	"CertificateProfile.CaSettings":                  "CaSettings are the validity and subject settings of the generated CA certificate",
+	"CertificateProfile.ClientCertificate":           "ClientCertificate is the certificate used by the client kubelet services and signed by the CA",
+	"CertificateProfile.ClientPrivateKey":            "ClientPrivateKey is the private key used by the client kubelet services and signed by the CA",
+	"CertificateProfile.ClientSettings":              "ClientSettings are the validity, subject and SAN settings of the generated kubelet client certificate",
+	"CertificateProfile.EtcdCaCertificate":           "EtcdCaCertificate is the certificate authority certificate of etcd, when SeparateCAs is enabled",
+	"CertificateProfile.EtcdCaPrivateKey":            "EtcdCaPrivateKey is the certificate authority key of etcd, when SeparateCAs is enabled",
+	"CertificateProfile.EtcdClientCertificate":       "EtcdClientCertificate is etcd client certificate, and signed by the CA",
+	"CertificateProfile.EtcdClientPrivateKey":        "EtcdClientPrivateKey is the etcd client private key, and signed by the CA",
+	"CertificateProfile.EtcdPeerCertificates":        "EtcdPeerCertificates is list of etcd peer certificates, and signed by the CA",
+	"CertificateProfile.EtcdPeerPrivateKeys":         "EtcdPeerPrivateKeys is list of etcd peer private keys, and signed by the CA",
+	"CertificateProfile.EtcdServerCertificate":       "EtcdServerCertificate is the server certificate for etcd, and signed by the CA",
+	"CertificateProfile.EtcdServerPrivateKey":        "EtcdServerPrivateKey is the server private key for etcd, and signed by the CA",
+	"CertificateProfile.EtcdSettings":                "EtcdSettings are the validity, subject and SAN settings of the generated etcd server, client and peer certificates",
+	"CertificateProfile.FrontProxyCaCertificate":     "FrontProxyCaCertificate is the certificate authority certificate of the aggregation layer, when SeparateCAs is enabled",
+	"CertificateProfile.FrontProxyCaPrivateKey":      "FrontProxyCaPrivateKey is the certificate authority key of the aggregation layer, when SeparateCAs is enabled",
+	"CertificateProfile.FrontProxyClientCertificate": "FrontProxyClientCertificate is the client certificate used by the apiserver to proxy requests to the aggregated apiservers, and signed by the front-proxy CA",
+	"CertificateProfile.FrontProxyClientPrivateKey":  "FrontProxyClientPrivateKey is the client private key used by the apiserver to proxy requests to the aggregated apiservers",
+	"CertificateProfile.KeyAlgorithm":                "KeyAlgorithm is the algorithm of the generated private keys: RSA (default), ECDSA-P256, ECDSA-P384 or Ed25519",
+	"CertificateProfile.KubeConfigCertificate":       "KubeConfigCertificate is the client certificate used for kubectl cli and signed by the CA",
+	"CertificateProfile.KubeConfigPrivateKey":        "KubeConfigPrivateKey is the client private key used for kubectl cli and signed by the CA",
+	"CertificateProfile.KubeConfigSettings":          "KubeConfigSettings are the validity, subject and SAN settings of the generated kubectl client certificate",
+	"CertificateProfile.SeparateCAs":                 "SeparateCAs signs the etcd certificates and the front-proxy client certificate with their own CAs instead of the CA",
+	"CertificateSettings":                            "CertificateSettings are the settings of a generated certificate",
+	"CertificateSettings.Country":                    "Country is the list of two-letter country codes of the certificate subject",
+	"CertificateSettings.ExtraSANs":                  "ExtraSANs are the extra DNS names and IP addresses of the certificate",
+	"CertificateSettings.Organization":               "Organization is the list of organizations of the certificate subject",
+	"CertificateSettings.OrganizationalUnit":         "OrganizationalUnit is the list of organizational units of the certificate subject",
+	"CertificateSettings.ValidityDays":               "ValidityDays is the number of days the certificate is valid, 30 years if zero",
+	"ContainerService":                               "ContainerService complies with the ARM model of resource definition in a JSON template.",
+	"CustomCloudProfile":                             "CustomCloudProfile represents the custom cloud profile",
+	"CustomFile":                                     "CustomFile has source as the full absolute source path to a file and dest is the full absolute desired destination path to put the file on a master node",
+	"CustomNodesDNS":                                 "CustomNodesDNS represents the Search Domain",
+	"CustomSearchDomain":                             "CustomSearchDomain represents the Search Domain when the custom vnet has a windows server DNS as a nameserver.",
+	"DependenciesLocation":                           "DependenciesLocation represents location to retrieve the dependencies.",
+	"Distro":                                         "Distro represents Linux distro to use for Linux VMs",
+	"Environment":                                    "Environment represents a set of endpoints for each of Azure's Clouds.",
+	"Extension":                                      "Extension represents an extension definition in the master or agentPoolProfile",
+	"ExtensionProfile":                               "ExtensionProfile represents an extension definition",
+	"ExtensionProfile.Script":                        "This is only needed for preprovision extensions and it needs to be a bash script",
+	"FeatureFlags":                                   "FeatureFlags defines feature-flag restricted functionality",
+	"ImageReference":                                 "ImageReference represents a reference to an Image resource in Azure.",
+	"KeyVaultCertificate":                            "KeyVaultCertificate specifies a certificate to install On Linux, the certificate file is placed under the /var/lib/waagent directory with the file name <UppercaseThumbprint>.crt for the X509 certificate file and <UppercaseThumbprint>.prv for the private key. Both of these files are .pem formatted. On windows the certificate will be saved in the specified store.",
+	"KeyVaultID":                                     "KeyVaultID specifies a key vault",
+	"KeyVaultSecrets":                                "KeyVaultSecrets specifies certificates to install on the pool of machines from a given key vault the key vault specified must have been granted read permissions to CRP",
+	"KeyvaultSecretRef":                              "KeyvaultSecretRef is a reference to a secret in a keyvault. The format of 'VaultID' value should be \"/subscriptions/<SUB_ID>/resourceGroups/<RG_NAME>/providers/Microsoft.KeyVault/vaults/<KV_NAME>\" where: SUB_ID is the subscription ID of the keyvault RG_NAME is the resource group of the keyvault KV_NAME is the name of the keyvault The 'SecretName' is the name of the secret in the keyvault The 'SecretVersion' (optional) is the version of the secret (default: the latest version)",
+	"KubeProxyMode":                                  "KubeProxyMode is for iptables and ipvs (and future others)",
+	"KubernetesAddon":                                "KubernetesAddon defines a list of addons w/ configuration to include with the cluster deployment",
+	"KubernetesComponent":                            "KubernetesComponent defines a component w/ configuration to include with the cluster deployment",
+	"KubernetesConfig":                               "KubernetesConfig contains the Kubernetes config structure, containing Kubernetes specific configuration",
+	"KubernetesConfig.DockerEngineVersion":           "Deprecated",
+	"KubernetesConfig.PodSecurityPolicyConfig":       "Deprecated",
+	"KubernetesConfig.UserAssignedClientID":          "Note: cannot be provided in config. Used *only* for transferring this to azure.json.",
+	"KubernetesContainerSpec":                        "KubernetesContainerSpec defines configuration for a container spec",
+	"KubernetesSpecConfig":                           "KubernetesSpecConfig is the kubernetes container images used.",
+	"KubernetesSpecConfig.ACIConnectorImageBase":     "Deprecated",
+	"LinuxProfile":                                   "LinuxProfile represents the linux parameters passed to the cluster",
+	"MasterProfile":                                  "MasterProfile represents the definition of the master cluster",
+	"MasterProfile.CosmosEtcd":                       "True: uses cosmos etcd endpoint instead of installing etcd on masters",
+	"MasterProfile.FQDN":                             "Master LB public endpoint/FQDN with port The format will be FQDN:2376 Not used during PUT, returned as part of GET",
+	"MasterProfile.subnet":                           "subnet is internal",
+	"MasterProfile.subnetIPv6":                       "subnetIPv6 is internal",
+	"OSType":                                         "OSType represents OS types of agents",
+	"OrchestratorProfile":                            "OrchestratorProfile contains Orchestrator properties",
+	"OrchestratorProfile.OrchestratorType":           "OrchestratorType is a legacy property, this should always be set to \"Kubernetes\"",
+	"OrchestratorVersionProfile":                     "OrchestratorVersionProfile contains information of a supported orchestrator version: - orchestrator type and version - whether this orchestrator version is deployed by default if orchestrator release is not specified - list of available upgrades for this orchestrator version",
+	"OrchestratorVersionProfileList":                 "OrchestratorVersionProfileList contains list of version profiles for supported orchestrators",
+	"PoolUpgradeProfile":                             "PoolUpgradeProfile contains pool properties: - orchestrator type and version - pool name (for agent pool) - OS type of the VMs in the pool - list of applicable upgrades",
+	"PrivateCluster":                                 "PrivateCluster defines the configuration for a private cluster",
+	"PrivateJumpboxProfile":                          "PrivateJumpboxProfile represents a jumpbox definition",
+	"Properties":                                     "Properties represents the AKS cluster definition",
+	"ProvisioningState":                              "ProvisioningState represents the current state of container service resource.",
+	"PublicKey":                                      "PublicKey represents an SSH key for LinuxProfile",
+	"ResourceIdentifier":                             "ResourceIdentifier contains a set of Azure resource IDs.",
+	"ResourcePurchasePlan":                           "ResourcePurchasePlan defines resource plan as required by ARM for billing purposes.",
+	"RuntimeHandlers":                                "RuntimeHandlers configures the runtime settings in containerd",
+	"ServicePrincipalProfile":                        "ServicePrincipalProfile contains the client and secret used by the cluster for Azure Resource CRUD The 'Secret' and 'KeyvaultSecretRef' parameters are mutually exclusive The 'Secret' parameter should be a secret in plain text. The 'KeyvaultSecretRef' parameter is a reference to a secret in a keyvault.",
+	"Severity":                                       "Severity is the severity of a validation error",
+	"TelemetryProfile":                               "TelemetryProfile contains settings for collecting telemtry. Note telemtry is currently enabled/disabled with the 'EnableTelemetry' feature flag.",
+	"UpgradeProfile":                                 "UpgradeProfile contains cluster properties: - orchestrator type and version for the cluster - list of pool profiles, constituting the cluster",
+	"ValidationError":                                "ValidationError is a problem found in a cluster definition, at the JSON path of the property at fault",
+	"ValidationErrors":                               "ValidationErrors are all the problems found in a cluster definition",
+	"WindowsLicenseType":                             "WindowsLicenseType represents Windows license type",
+	"WindowsProfile":                                 "WindowsProfile represents the windows parameters passed to the cluster",
+	"WindowsRuntimes":                                "WindowsRuntimes configures containerd runtimes that are available on the windows nodes",
+	"fieldError":                                     "fieldError attributes a validation error to a property of the validated object",
+	"validationCollector":                            "validationCollector collects the errors of the validations of a cluster definition",
+}
@@ -0,0 +1,7 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package schema generates the JSON Schema of the vlabs API model and validates cluster definitions against it.
+package schema
+
+//go:generate go run ./internal/descgen -src ../vlabs -pkg $GOPACKAGE -o descriptions_generated.go
@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// descgen extracts the doc comments of the types of an API model package
+// so that they can be used as the descriptions of the generated JSON Schema.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	var src, pkg, out string
+	flag.StringVar(&src, "src", "", "directory of the API model package")
+	flag.StringVar(&pkg, "pkg", "", "package of the generated file")
+	flag.StringVar(&out, "o", "", "generated file")
+	flag.Parse()
+	if src == "" || pkg == "" || out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, src, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	descriptions := map[string]string{}
+	var sources []string
+	for _, p := range pkgs {
+		for name, f := range p.Files {
+			sources = append(sources, filepath.ToSlash(name))
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					doc := ts.Doc
+					if doc == nil && len(gen.Specs) == 1 {
+						doc = gen.Doc
+					}
+					add(descriptions, ts.Name.Name, doc)
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						addFields(descriptions, ts.Name.Name, st)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(sources)
+
+	keys := make([]string, 0, len(descriptions))
+	for k := range descriptions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated for package %s by descgen DO NOT EDIT. (@generated)\n// sources:\n", pkg)
+	for _, s := range sources {
+		fmt.Fprintf(&buf, "// %s\n", s)
+	}
+	fmt.Fprintf(&buf, "\npackage %s\n\n", pkg)
+	buf.WriteString("// descriptions holds the doc comments of the API model types by type name and by type and field name\n")
+	buf.WriteString("var descriptions = map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%q: %q,\n", k, descriptions[k])
+	}
+	buf.WriteString("}\n")
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// addFields adds the doc comments of the fields of a struct, including the fields of its anonymous struct fields
+func addFields(descriptions map[string]string, prefix string, st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		doc := field.Doc
+		if doc == nil {
+			doc = field.Comment
+		}
+		for _, name := range field.Names {
+			key := prefix + "." + name.Name
+			add(descriptions, key, doc)
+			if inner, ok := field.Type.(*ast.StructType); ok {
+				addFields(descriptions, key, inner)
+			}
+		}
+	}
+}
+
+func add(descriptions map[string]string, key string, doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	if text := strings.Join(strings.Fields(doc.Text()), " "); text != "" {
+		descriptions[key] = text
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
+)
+
+const (
+	// Draft is the JSON Schema version of the generated schema
+	Draft = "http://json-schema.org/draft-07/schema#"
+	// ID identifies the schema of the vlabs API model
+	ID = "https://github.com/Azure/aks-engine-azurestack/schemas/vlabs/apimodel.json"
+
+	definitionsRef = "#/definitions/"
+)
+
+// Schema is a JSON Schema document or subschema
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	// Properties are the known keys of an object
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is either false, for objects that only accept their known keys,
+	// or the schema of the values of a map
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// addonNames are the names of the addons that can be configured in kubernetesConfig.addons
+var addonNames = []string{
+	common.AADAdminGroupAddonName,
+	common.AADPodIdentityAddonName,
+	common.ACIConnectorAddonName,
+	common.AntreaAddonName,
+	common.AppGwIngressAddonName,
+	common.AuditPolicyAddonName,
+	common.AzureArcOnboardingAddonName,
+	common.AzureCloudProviderAddonName,
+	common.AzureCNINetworkMonitorAddonName,
+	common.AzureCSIStorageClassesAddonName,
+	common.AzureDiskCSIDriverAddonName,
+	common.AzureFileCSIDriverAddonName,
+	common.AzureNetworkPolicyAddonName,
+	common.AzurePolicyAddonName,
+	common.AzureStorageClassesAddonName,
+	common.CalicoAddonName,
+	common.CiliumAddonName,
+	common.CloudNodeManagerAddonName,
+	common.ClusterAutoscalerAddonName,
+	common.ContainerMonitoringAddonName,
+	common.CoreDNSAddonName,
+	common.DashboardAddonName,
+	common.FlannelAddonName,
+	common.IPMASQAgentAddonName,
+	common.KubeDNSAddonName,
+	common.KubeletCSRApproverAddonName,
+	common.KubeProxyAddonName,
+	common.MetricsServerAddonName,
+	common.NodeProblemDetectorAddonName,
+	common.NVIDIADevicePluginAddonName,
+	common.PodSecurityPolicyAddonName,
+	common.ReschedulerAddonName,
+	common.ScheduledMaintenanceAddonName,
+	common.SecretsStoreCSIDriverAddonName,
+	common.SMBFlexVolumeAddonName,
+	common.TillerAddonName,
+}
+
+// typeEnums holds the valid values of the named types of the API model
+var typeEnums = map[reflect.Type][]interface{}{
+	reflect.TypeOf(vlabs.Distro("")):               stringValues(vlabs.DistroValues),
+	reflect.TypeOf(vlabs.OSType("")):               {string(vlabs.Linux), string(vlabs.Windows)},
+	reflect.TypeOf(vlabs.DependenciesLocation("")): stringValues(vlabs.DependenciesLocationValues),
+	reflect.TypeOf(vlabs.KubeProxyMode("")):        {"", string(vlabs.KubeProxyModeIPTables), string(vlabs.KubeProxyModeIPVS)},
+	reflect.TypeOf(vlabs.AgentPoolProfileRole("")): {string(vlabs.AgentPoolProfileRoleEmpty), string(vlabs.AgentPoolProfileRoleInfra)},
+}
+
+// fieldEnums holds the valid values of the string fields of the API model, by type and field name
+var fieldEnums = map[string][]interface{}{
+	"KubernetesConfig.NetworkPlugin":          stringValues(vlabs.NetworkPluginValues[:]),
+	"KubernetesConfig.NetworkPolicy":          stringValues(vlabs.NetworkPolicyValues[:]),
+	"KubernetesConfig.NetworkMode":            stringValues(vlabs.NetworkModeValues[:]),
+	"KubernetesConfig.ContainerRuntime":       stringValues(vlabs.ContainerRuntimeValues[:]),
+	"KubernetesConfig.LoadBalancerSku":        {"", vlabs.BasicLoadBalancerSku, vlabs.StandardLoadBalancerSku},
+	"MasterProfile.AvailabilityProfile":       {"", vlabs.AvailabilitySet, vlabs.VirtualMachineScaleSets},
+	"AgentPoolProfile.AvailabilityProfile":    {"", vlabs.AvailabilitySet, vlabs.VirtualMachineScaleSets},
+	"PrivateJumpboxProfile.StorageProfile":    {"", vlabs.ManagedDisks, vlabs.StorageAccount},
+	"KubernetesAddon.Name":                    stringValues(addonNames),
+	"KubernetesAddon.Mode":                    {"", vlabs.AddonModeEnsureExists, vlabs.AddonModeReconcile},
+	"CustomCloudProfile.IdentitySystem":       {"", vlabs.AzureADIdentitySystem, vlabs.ADFSIdentitySystem},
+	"CustomCloudProfile.AuthenticationMethod": {"", vlabs.ClientSecretAuthMethod, vlabs.ClientCertificateAuthMethod},
+}
+
+// stringValues converts a list of string values to enum values
+func stringValues[T ~string](values []T) []interface{} {
+	enum := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		enum = append(enum, string(v))
+	}
+	return enum
+}
+
+// ForContainerService returns the JSON Schema of the vlabs API model
+func ForContainerService() *Schema {
+	g := &generator{definitions: map[string]*Schema{}}
+	root := g.structSchema(reflect.TypeOf(vlabs.ContainerService{}), "ContainerService")
+	root.Schema = Draft
+	root.ID = ID
+	root.Title = "AKS Engine cluster definition"
+	root.Properties["apiVersion"] = &Schema{
+		Type:        "string",
+		Description: "The version of the API model",
+		Enum:        []interface{}{vlabs.APIVersion},
+	}
+	root.Required = append([]string{"apiVersion"}, root.Required...)
+	root.Definitions = g.definitions
+	return root
+}
+
+type generator struct {
+	definitions map[string]*Schema
+}
+
+// schemaFor returns the schema of a Go type; key identifies the type, or the anonymous struct, in descriptions
+func (g *generator) schemaFor(t reflect.Type, key string) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if enum, ok := typeEnums[t]; ok {
+		return &Schema{Type: "string", Enum: enum}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, key)
+		}
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// reserve the definition before walking the fields of recursive types
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.structSchema(t, t.Name())
+		}
+		return &Schema{Ref: definitionsRef + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem(), key)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem(), key)}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema returns the schema of the JSON object of a struct
+func (g *generator) structSchema(t reflect.Type, key string) *Schema {
+	s := &Schema{
+		Type:                 "object",
+		Description:          descriptions[key],
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: false,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fieldKey := key + "." + f.Name
+		p := g.schemaFor(f.Type, fieldKey)
+		if enum, ok := fieldEnums[fieldKey]; ok {
+			p.Enum = enum
+		}
+		if applyValidateTag(p, f.Type, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		if d := descriptions[fieldKey]; d != "" {
+			if p.Ref != "" {
+				// siblings of $ref are ignored, wrap the reference to keep the description
+				p = &Schema{Description: d, Ref: p.Ref}
+			} else {
+				p.Description = d
+			}
+		}
+		s.Properties[name] = p
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// applyValidateTag translates the rules of a validate struct tag to the schema of a field,
+// and returns true if the field is required
+func applyValidateTag(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		switch {
+		case rule == "dive":
+			// the following rules apply to the items of the list
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+			t = t.Elem()
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+		case rule == "required":
+			required = required || target == s
+		case strings.HasPrefix(rule, "min="), strings.HasPrefix(rule, "max="):
+			n, err := strconv.Atoi(rule[4:])
+			if err != nil {
+				continue
+			}
+			applyBound(target, t, strings.HasPrefix(rule, "min="), n)
+		case strings.Contains(rule, "eq="), strings.Contains(rule, "len=0"):
+			var enum []interface{}
+			for _, alt := range strings.Split(rule, "|") {
+				switch {
+				case alt == "len=0":
+					enum = append(enum, "")
+				case strings.HasPrefix(alt, "eq="):
+					if t.Kind() == reflect.String {
+						enum = append(enum, alt[3:])
+					} else if n, err := strconv.Atoi(alt[3:]); err == nil {
+						enum = append(enum, n)
+					}
+				}
+			}
+			target.Enum = enum
+		}
+	}
+	return required
+}
+
+func applyBound(s *Schema, t reflect.Type, isMin bool, n int) {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if isMin {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	default:
+		f := float64(n)
+		if isMin {
+			s.Minimum = &f
+		} else {
+			s.Maximum = &f
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
+	. "github.com/onsi/gomega"
+)
+
+func TestForContainerService(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := ForContainerService()
+	g.Expect(s.Schema).To(Equal(Draft))
+	g.Expect(s.Required).To(Equal([]string{"apiVersion", "properties"}))
+	g.Expect(s.Properties["apiVersion"].Enum).To(Equal([]interface{}{vlabs.APIVersion}))
+	g.Expect(s.Properties["properties"].Ref).To(Equal("#/definitions/Properties"))
+
+	master := s.Definitions["MasterProfile"]
+	g.Expect(master.Description).To(Equal("MasterProfile represents the definition of the master cluster"))
+	g.Expect(master.AdditionalProperties).To(Equal(false))
+	g.Expect(master.Required).To(ConsistOf("count", "dnsPrefix", "vmSize"))
+	g.Expect(master.Properties["count"].Enum).To(Equal([]interface{}{1, 3, 5}))
+	g.Expect(master.Properties["distro"].Enum).To(ContainElement(string(vlabs.Ubuntu2204)))
+	g.Expect(*master.Properties["osDiskSizeGB"].Maximum).To(Equal(float64(2048)))
+
+	pool := s.Definitions["AgentPoolProfile"]
+	g.Expect(pool.Properties["availabilityProfile"].Enum).To(ContainElement(vlabs.VirtualMachineScaleSets))
+	g.Expect(pool.Properties["storageProfile"].Enum).To(Equal([]interface{}{vlabs.StorageAccount, vlabs.ManagedDisks, vlabs.Ephemeral, ""}))
+	g.Expect(*pool.Properties["diskSizesGB"].MaxItems).To(Equal(4))
+	g.Expect(*pool.Properties["diskSizesGB"].Items.Maximum).To(Equal(float64(32767)))
+
+	k := s.Definitions["KubernetesConfig"]
+	g.Expect(k.Properties["networkPlugin"].Enum).To(ContainElement(vlabs.NetworkPluginKubenet))
+	g.Expect(k.Properties["addons"].Items.Ref).To(Equal("#/definitions/KubernetesAddon"))
+	g.Expect(s.Definitions["KubernetesAddon"].Properties["name"].Enum).To(ContainElement(common.CoreDNSAddonName))
+	g.Expect(k.Properties["kubeletConfig"].AdditionalProperties).To(Equal(&Schema{Type: "string"}))
+
+	ssh := s.Definitions["LinuxProfile"].Properties["ssh"]
+	g.Expect(ssh.Required).To(Equal([]string{"publicKeys"}))
+	g.Expect(*ssh.Properties["publicKeys"].MinItems).To(Equal(1))
+
+	// every reference resolves to a definition
+	b, err := json.Marshal(s)
+	g.Expect(err).NotTo(HaveOccurred())
+	var refs func(interface{})
+	refs = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				g.Expect(s.Definitions).To(HaveKey(ref[len(definitionsRef):]))
+			}
+			for _, c := range v {
+				refs(c)
+			}
+		case []interface{}:
+			for _, c := range v {
+				refs(c)
+			}
+		}
+	}
+	var doc interface{}
+	g.Expect(json.Unmarshal(b, &doc)).To(Succeed())
+	refs(doc)
+}
+
+func TestValidateExamples(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := ForContainerService()
+	files, err := filepath.Glob("../../engine/testdata/*/*.json")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(files).NotTo(BeEmpty())
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		g.Expect(err).NotTo(HaveOccurred())
+		errs, err := s.Validate(b)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(errs).To(BeEmpty(), f)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	errs, err := ForContainerService().Validate([]byte(`{
+		"apiVersion": "vlabs",
+		"properties": {
+			"orchestratorProfile": {"kubernetesConfig": {"networkPlugin": "weave", "addons": [{"name": "coredns", "enabled": "yes"}]}},
+			"masterProfile": {"count": 2, "DNSPrefix": "cluster", "vmSize": "Standard_D2_v2", "osDiskSizeGB": 4096},
+			"agentPoolProfiles": [{"name": "pool", "count": "3", "vmSize": "Standard_D2_v2", "diskSizesGB": [1, 2, 3, 4, 5], "zone": "1"}],
+			"linuxProfile": {"adminUsername": "azureuser", "ssh": {"publicKeys": []}}
+		}
+	}`))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	type result struct{ Path, Code string }
+	var results []result
+	for _, e := range errs {
+		results = append(results, result{e.Path, e.Code})
+	}
+	g.Expect(results).To(ConsistOf(
+		result{"properties.agentPoolProfiles[0].count", CodeInvalidType},
+		result{"properties.agentPoolProfiles[0].diskSizesGB", CodeOutOfRange},
+		result{"properties.agentPoolProfiles[0].zone", CodeUnknownKey},
+		result{"properties.linuxProfile.ssh.publicKeys", CodeOutOfRange},
+		result{"properties.masterProfile.count", CodeInvalidValue},
+		result{"properties.masterProfile.osDiskSizeGB", CodeOutOfRange},
+		result{"properties.orchestratorProfile.kubernetesConfig.addons[0].enabled", CodeInvalidType},
+		result{"properties.orchestratorProfile.kubernetesConfig.networkPlugin", CodeInvalidValue},
+	))
+	g.Expect(errs.WithCode(CodeInvalidType).Error()).To(Equal(
+		"properties.agentPoolProfiles[0].count: expected an integer, got a string; " +
+			"properties.orchestratorProfile.kubernetesConfig.addons[0].enabled: expected a boolean, got a string"))
+	g.Expect(errs.WithCode(CodeInvalidValue)[0].Message).To(Equal("2 is not one of 1, 3, 5"))
+
+	errs, err = ForContainerService().Validate([]byte(`{"properties": null}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Error()).To(Equal("apiVersion: required key is missing"))
+
+	_, err = ForContainerService().Validate([]byte(`{`))
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,253 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Validation error codes
+const (
+	// CodeInvalidType is the code of values of the wrong JSON type
+	CodeInvalidType = "InvalidType"
+	// CodeInvalidValue is the code of values that are not one of the values allowed by the schema
+	CodeInvalidValue = "InvalidValue"
+	// CodeOutOfRange is the code of numbers and lists outside of the bounds of the schema
+	CodeOutOfRange = "OutOfRange"
+	// CodeRequired is the code of missing required keys
+	CodeRequired = "Required"
+	// CodeUnknownKey is the code of keys not defined by the schema
+	CodeUnknownKey = "UnknownKey"
+)
+
+// ValidationError is a violation of the schema by the value at a JSON path of a cluster definition
+type ValidationError struct {
+	Path    string
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors are all the violations of the schema by a cluster definition
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// WithCode returns the validation errors with the given code
+func (e ValidationErrors) WithCode(code string) ValidationErrors {
+	var filtered ValidationErrors
+	for _, err := range e {
+		if err.Code == code {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+// Validate validates a JSON cluster definition against the schema.
+// The returned error is only set if contents is not a JSON document.
+func (s *Schema) Validate(contents []byte) (ValidationErrors, error) {
+	var doc interface{}
+	if err := json.Unmarshal(contents, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing API model")
+	}
+	return s.ValidateDocument(doc), nil
+}
+
+// ValidateDocument validates a decoded JSON cluster definition against the schema
+func (s *Schema) ValidateDocument(doc interface{}) ValidationErrors {
+	v := &validator{root: s}
+	v.validate(s, doc, "")
+	return v.errs
+}
+
+type validator struct {
+	root *Schema
+	errs ValidationErrors
+}
+
+func (v *validator) addf(path, code, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = v.root.Definitions[strings.TrimPrefix(s.Ref, definitionsRef)]
+	}
+	return s
+}
+
+func (v *validator) validate(s *Schema, value interface{}, path string) {
+	s = v.resolve(s)
+	// like encoding/json, null is accepted for every type and leaves the zero value
+	if s == nil || value == nil {
+		return
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		v.addf(path, CodeInvalidType, "expected %s, got %s", article(s.Type), article(typeOf(value)))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		v.addf(path, CodeInvalidValue, "%s is not one of %s", formatValue(value), formatEnum(s.Enum))
+	}
+	switch value := value.(type) {
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			v.addf(path, CodeOutOfRange, "%v is less than the minimum %v", value, *s.Minimum)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			v.addf(path, CodeOutOfRange, "%v is greater than the maximum %v", value, *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			v.addf(path, CodeOutOfRange, "expected at least %d items, got %d", *s.MinItems, len(value))
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			v.addf(path, CodeOutOfRange, "expected at most %d items, got %d", *s.MaxItems, len(value))
+		}
+		for i, item := range value {
+			v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case map[string]interface{}:
+		v.validateObject(s, value, path)
+	}
+}
+
+func (v *validator) validateObject(s *Schema, value map[string]interface{}, path string) {
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	present := map[string]bool{}
+	for _, k := range keys {
+		childPath := joinPath(path, k)
+		name, p := lookupProperty(s.Properties, k)
+		if p != nil {
+			present[name] = true
+			v.validate(p, value[k], childPath)
+			continue
+		}
+		switch additional := s.AdditionalProperties.(type) {
+		case *Schema:
+			v.validate(additional, value[k], childPath)
+		case bool:
+			if !additional {
+				v.addf(childPath, CodeUnknownKey, "unknown key %q", k)
+			}
+		}
+	}
+	for _, name := range s.Required {
+		if !present[name] {
+			v.addf(joinPath(path, name), CodeRequired, "required key is missing")
+		}
+	}
+}
+
+// lookupProperty finds the property of a key, ignoring case like encoding/json
+func lookupProperty(properties map[string]*Schema, key string) (string, *Schema) {
+	if p, ok := properties[key]; ok {
+		return key, p
+	}
+	for name, p := range properties {
+		if strings.EqualFold(name, key) {
+			return name, p
+		}
+	}
+	return "", nil
+}
+
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == t
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+func article(t string) string {
+	switch t {
+	case "array", "integer", "object":
+		return "an " + t
+	default:
+		return "a " + t
+	}
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		switch e := e.(type) {
+		case int:
+			if n, ok := value.(float64); ok && n == float64(e) {
+				return true
+			}
+		case string:
+			if value == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func formatEnum(enum []interface{}) string {
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		values = append(values, formatValue(e))
+	}
+	return strings.Join(values, ", ")
+}
+
+// joinPath returns the path of the key of the object at path, the path of the document is empty
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,236 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The schemes of the secret references resolved by the built-in secret providers
+const (
+	// SecretReferenceSchemeEnv refers to an environment variable, e.g. env:AZURE_CLIENT_SECRET
+	SecretReferenceSchemeEnv = "env"
+	// SecretReferenceSchemeFile refers to the contents of a file, e.g. file:/etc/aks-engine/etcd-encryption-key,
+	// a relative path is relative to the directory of the cluster definition
+	SecretReferenceSchemeFile = "file"
+	// SecretReferenceSchemePlugin refers to a secret resolved by a secret provider plugin, e.g. plugin:vault:kv/cluster/sp
+	SecretReferenceSchemePlugin = "plugin"
+)
+
+// SecretProviderPluginPrefix is the prefix of the secret provider plugin executables: the reference plugin:vault:kv/cluster/sp
+// is resolved by running aks-engine-azurestack-secret-vault, found in the PATH, with kv/cluster/sp as argument,
+// the secret is its standard output.
+const SecretProviderPluginPrefix = "aks-engine-azurestack-secret-"
+
+// SecretProvider resolves the secret references of a scheme
+type SecretProvider interface {
+	// Resolve returns the secret the reference, without its scheme, refers to
+	Resolve(reference string) (string, error)
+}
+
+// SecretProviderFunc is a function implementing SecretProvider
+type SecretProviderFunc func(reference string) (string, error)
+
+// Resolve returns f(reference)
+func (f SecretProviderFunc) Resolve(reference string) (string, error) {
+	return f(reference)
+}
+
+// SecretReference is a reference a secret of the cluster definition was resolved from when it was loaded
+type SecretReference struct {
+	// Reference is the value of the secret in the cluster definition, e.g. env:AZURE_CLIENT_SECRET
+	Reference string
+	// Value is the secret the reference was resolved to
+	Value string
+}
+
+var (
+	secretProvidersLock sync.RWMutex
+	secretProviders     = map[string]SecretProvider{
+		SecretReferenceSchemeEnv:  SecretProviderFunc(resolveEnvSecret),
+		SecretReferenceSchemeFile: SecretProviderFunc(resolveFileSecret),
+	}
+	// secretReferenceRegexp matches the references, the scheme is lower case so that a literal secret
+	// is not mistaken for a reference
+	secretReferenceRegexp = regexp.MustCompile(`^([a-z][a-z0-9-]*):(.+)$`)
+	// pluginReferenceRegexp matches the plugin name and its reference in the reference of the plugin scheme
+	pluginReferenceRegexp = regexp.MustCompile(`^([a-z][a-z0-9-]*):(.+)$`)
+	// lookPath finds the secret provider plugins, it is replaced by the tests
+	lookPath = exec.LookPath
+)
+
+// RegisterSecretProvider registers the provider resolving the secret references of scheme,
+// replacing the current provider of scheme if any. The plugin scheme cannot be replaced.
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersLock.Lock()
+	defer secretProvidersLock.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// parseSecretReference returns the scheme, the provider and the reference of value,
+// or a nil provider if value is a literal secret
+func parseSecretReference(value string) (string, SecretProvider, string, error) {
+	m := secretReferenceRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return "", nil, "", nil
+	}
+	scheme, reference := m[1], m[2]
+	if scheme == SecretReferenceSchemePlugin {
+		p := pluginReferenceRegexp.FindStringSubmatch(reference)
+		if p == nil {
+			return "", nil, "", errors.Errorf("invalid secret provider plugin reference %s, expected plugin:<name>:<reference>", value)
+		}
+		return scheme, pluginSecretProvider(p[1]), p[2], nil
+	}
+	secretProvidersLock.RLock()
+	provider := secretProviders[scheme]
+	secretProvidersLock.RUnlock()
+	return scheme, provider, reference, nil
+}
+
+func resolveEnvSecret(reference string) (string, error) {
+	v, ok := os.LookupEnv(reference)
+	if !ok {
+		return "", errors.Errorf("environment variable %s is not set", reference)
+	}
+	return v, nil
+}
+
+func resolveFileSecret(reference string) (string, error) {
+	b, err := os.ReadFile(reference)
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(b)), nil
+}
+
+// pluginSecretProvider runs the secret provider plugin of the given name
+type pluginSecretProvider string
+
+func (p pluginSecretProvider) Resolve(reference string) (string, error) {
+	path, err := lookPath(SecretProviderPluginPrefix + string(p))
+	if err != nil {
+		return "", errors.Wrapf(err, "finding the secret provider plugin %s", p)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, reference)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "running %s: %s", path, strings.TrimSpace(stderr.String()))
+	}
+	return trimNewline(stdout.String()), nil
+}
+
+// trimNewline removes the newline ending s, if any
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+// vlabsSecrets returns the secrets of cs that can be secret references, by JSON path:
+// the service principal secret, the Windows admin password, the etcd encryption key and the certificate profile
+func vlabsSecrets(cs *vlabs.ContainerService) map[string]*string {
+	secrets := map[string]*string{}
+	p := cs.Properties
+	if p == nil {
+		return secrets
+	}
+	if p.ServicePrincipalProfile != nil {
+		secrets["properties.servicePrincipalProfile.secret"] = &p.ServicePrincipalProfile.Secret
+	}
+	if p.WindowsProfile != nil {
+		secrets["properties.windowsProfile.adminPassword"] = &p.WindowsProfile.AdminPassword
+	}
+	if p.OrchestratorProfile != nil && p.OrchestratorProfile.KubernetesConfig != nil {
+		secrets["properties.orchestratorProfile.kubernetesConfig.etcdEncryptionKey"] = &p.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey
+	}
+	if p.CertificateProfile != nil {
+		v := reflect.ValueOf(p.CertificateProfile).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			path := "properties.certificateProfile." + strings.SplitN(v.Type().Field(i).Tag.Get("json"), ",", 2)[0]
+			// only the certificates, their chain and the private keys, not the settings of the generated ones
+			if !strings.HasSuffix(path, "Certificate") && !strings.HasSuffix(path, "Certificates") && !strings.HasSuffix(path, "Chain") &&
+				!strings.HasSuffix(path, "PrivateKey") && !strings.HasSuffix(path, "PrivateKeys") {
+				continue
+			}
+			switch f := v.Field(i).Addr().Interface().(type) {
+			case *string:
+				secrets[path] = f
+			case *[]string:
+				for j := range *f {
+					secrets[fmt.Sprintf("%s[%d]", path, j)] = &(*f)[j]
+				}
+			}
+		}
+	}
+	return secrets
+}
+
+// resolveSecretReferences replaces the secret references of cs by the secrets they refer to,
+// and returns the references by JSON path. The relative file: references are resolved from dir, if not empty,
+// and saved with their absolute path so that the API models written to other directories refer to the same files.
+func resolveSecretReferences(cs *vlabs.ContainerService, dir string) (map[string]SecretReference, error) {
+	var references map[string]SecretReference
+	for path, secret := range vlabsSecrets(cs) {
+		scheme, provider, reference, err := parseSecretReference(*secret)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving the secret reference of %s", path)
+		}
+		if provider == nil {
+			continue
+		}
+		if scheme == SecretReferenceSchemeFile && dir != "" && !filepath.IsAbs(reference) {
+			if reference, err = filepath.Abs(filepath.Join(dir, reference)); err != nil {
+				return nil, errors.Wrapf(err, "resolving the secret reference %s of %s", *secret, path)
+			}
+			*secret = SecretReferenceSchemeFile + ":" + reference
+		}
+		value, err := provider.Resolve(reference)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving the secret reference %s of %s", *secret, path)
+		}
+		if references == nil {
+			references = map[string]SecretReference{}
+		}
+		references[path] = SecretReference{Reference: *secret, Value: value}
+		*secret = value
+	}
+	return references, nil
+}
+
+// restoreSecretReferences replaces the secrets of cs, once converted to vlabs, by the references they were resolved from.
+// A secret that changed since it was resolved, e.g. a rotated certificate, is kept.
+func restoreSecretReferences(cs *vlabs.ContainerService, references map[string]SecretReference) {
+	if len(references) == 0 {
+		return
+	}
+	if p := cs.Properties; p != nil && p.CertificateProfile != nil {
+		// the certificate arrays are shared with the unversioned cluster definition
+		p.CertificateProfile.EtcdPeerCertificates = append([]string(nil), p.CertificateProfile.EtcdPeerCertificates...)
+		p.CertificateProfile.EtcdPeerPrivateKeys = append([]string(nil), p.CertificateProfile.EtcdPeerPrivateKeys...)
+	}
+	for path, secret := range vlabsSecrets(cs) {
+		r, ok := references[path]
+		if !ok {
+			continue
+		}
+		if *secret != r.Value {
+			log.Warnf("%s changed since it was resolved from %s, the new value is saved in place of the reference", path, r.Reference)
+			continue
+		}
+		*secret = r.Reference
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/vlabs"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+const secretReferencesAPIModel = `{
+  "apiVersion": "vlabs",
+  "location": "local",
+  "properties": {
+    "orchestratorProfile": {
+      "kubernetesConfig": {
+        "etcdEncryptionKey": "file:%s"
+      }
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "secretrefs",
+      "vmSize": "Standard_D2_v2"
+    },
+    "linuxProfile": {
+      "adminUsername": "azureuser",
+      "ssh": {
+        "publicKeys": [
+          {
+            "keyData": "ssh-rsa PUBLICKEY azureuser@linuxvm"
+          }
+        ]
+      }
+    },
+    "windowsProfile": {
+      "adminUsername": "azureuser",
+      "adminPassword": "test:literal"
+    },
+    "servicePrincipalProfile": {
+      "clientId": "ServicePrincipalClientID",
+      "secret": "env:TEST_SECRET_REFERENCES_SP_SECRET"
+    },
+    "certificateProfile": {
+      "caCertificate": "caCertificate",
+      "caPrivateKey": "test:ca-key",
+      "etcdPeerCertificates": [
+        "etcdPeerCertificate0"
+      ],
+      "etcdPeerPrivateKeys": [
+        "test:etcd-peer-key-0"
+      ]
+    }
+  }
+}`
+
+func TestSecretReferences(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "etcd-encryption-key")
+	g.Expect(os.WriteFile(keyFile, []byte("ZXRjZC1lbmNyeXB0aW9uLWtleQ==\n"), 0600)).To(Succeed())
+	t.Setenv("TEST_SECRET_REFERENCES_SP_SECRET", "sp-secret")
+	RegisterSecretProvider("test", SecretProviderFunc(func(reference string) (string, error) {
+		if reference == "literal" {
+			return "", errors.New("not a reference")
+		}
+		return "resolved-" + reference, nil
+	}))
+	defer func() {
+		secretProvidersLock.Lock()
+		delete(secretProviders, "test")
+		secretProvidersLock.Unlock()
+	}()
+	apimodel := strings.Replace(secretReferencesAPIModel, "%s", keyFile, 1)
+	apiloader := &Apiloader{Translator: &i18n.Translator{}}
+
+	// the literal password fails the test provider
+	_, _, err := apiloader.DeserializeContainerService([]byte(apimodel), false, false, nil)
+	g.Expect(err).To(MatchError("resolving the secret reference test:literal of properties.windowsProfile.adminPassword: not a reference"))
+
+	apimodel = strings.Replace(apimodel, "test:literal", "Pass:word1", 1)
+	cs, _, err := apiloader.DeserializeContainerService([]byte(apimodel), false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cs.Properties.ServicePrincipalProfile.Secret).To(Equal("sp-secret"))
+	g.Expect(cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey).To(Equal("ZXRjZC1lbmNyeXB0aW9uLWtleQ=="))
+	g.Expect(cs.Properties.WindowsProfile.AdminPassword).To(Equal("Pass:word1"))
+	g.Expect(cs.Properties.CertificateProfile.CaPrivateKey).To(Equal("resolved-ca-key"))
+	g.Expect(cs.Properties.CertificateProfile.EtcdPeerPrivateKeys).To(Equal([]string{"resolved-etcd-peer-key-0"}))
+	g.Expect(cs.SecretReferences).To(HaveLen(4))
+	g.Expect(vlabsSecrets(&vlabs.ContainerService{Properties: &vlabs.Properties{CertificateProfile: &vlabs.CertificateProfile{KeyAlgorithm: "ECDSA-P256"}}})).NotTo(HaveKey("properties.certificateProfile.keyAlgorithm"))
+	g.Expect(cs.SecretReferences).To(HaveKeyWithValue("properties.certificateProfile.etcdPeerPrivateKeys[0]", SecretReference{Reference: "test:etcd-peer-key-0", Value: "resolved-etcd-peer-key-0"}))
+
+	b, err := apiloader.SerializeContainerService(cs, vlabs.APIVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring(`"secret": "env:TEST_SECRET_REFERENCES_SP_SECRET"`))
+	g.Expect(string(b)).To(ContainSubstring(`"etcdEncryptionKey": "file:` + keyFile + `"`))
+	g.Expect(string(b)).To(ContainSubstring(`"caPrivateKey": "test:ca-key"`))
+	g.Expect(string(b)).To(ContainSubstring(`"test:etcd-peer-key-0"`))
+	g.Expect(string(b)).To(ContainSubstring(`"adminPassword": "Pass:word1"`))
+	g.Expect(string(b)).NotTo(ContainSubstring("sp-secret"))
+	// the unversioned cluster definition keeps the secrets
+	g.Expect(cs.Properties.CertificateProfile.EtcdPeerPrivateKeys).To(Equal([]string{"resolved-etcd-peer-key-0"}))
+
+	// a rotated secret is saved in place of its reference
+	cs.Properties.CertificateProfile.CaPrivateKey = "rotated-ca-key"
+	b, err = apiloader.SerializeContainerService(cs, vlabs.APIVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring(`"caPrivateKey": "rotated-ca-key"`))
+	g.Expect(string(b)).To(ContainSubstring(`"secret": "env:TEST_SECRET_REFERENCES_SP_SECRET"`))
+
+	os.Unsetenv("TEST_SECRET_REFERENCES_SP_SECRET")
+	_, _, err = apiloader.DeserializeContainerService([]byte(apimodel), false, false, nil)
+	g.Expect(err).To(MatchError("resolving the secret reference env:TEST_SECRET_REFERENCES_SP_SECRET of properties.servicePrincipalProfile.secret: environment variable TEST_SECRET_REFERENCES_SP_SECRET is not set"))
+}
+
+func TestSecretProviderPlugin(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, SecretProviderPluginPrefix+"vault")
+	g.Expect(os.WriteFile(plugin, []byte("#!/bin/sh\n[ \"$1\" = missing ] && echo \"no secret $1\" >&2 && exit 1\necho \"secret of $1\"\n"), 0700)).To(Succeed())
+	defer func(f func(string) (string, error)) { lookPath = f }(lookPath)
+	lookPath = func(file string) (string, error) {
+		if file == SecretProviderPluginPrefix+"vault" {
+			return plugin, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	_, provider, reference, err := parseSecretReference("plugin:vault:kv/cluster/sp")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).NotTo(BeNil())
+	g.Expect(provider.Resolve(reference)).To(Equal("secret of kv/cluster/sp"))
+	_, provider, reference, err = parseSecretReference("plugin:vault:missing")
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = provider.Resolve(reference)
+	g.Expect(err).To(MatchError(ContainSubstring("no secret missing")))
+	_, provider, reference, err = parseSecretReference("plugin:other:kv/cluster/sp")
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = provider.Resolve(reference)
+	g.Expect(err).To(MatchError("finding the secret provider plugin other: not found"))
+
+	// the plugins are only run for the plugin: references
+	for _, literal := range []string{"vault:kv/cluster/sp", "other:kv/cluster/sp", "Vault:kv", "-----BEGIN CERTIFICATE-----", "vault:", "Plugin:vault:kv"} {
+		_, provider, _, err = parseSecretReference(literal)
+		g.Expect(err).NotTo(HaveOccurred(), literal)
+		g.Expect(provider).To(BeNil(), literal)
+	}
+	for _, invalid := range []string{"plugin:vault", "plugin:vault:", "plugin:Vault:kv"} {
+		_, _, _, err = parseSecretReference(invalid)
+		g.Expect(err).To(MatchError(ContainSubstring("expected plugin:<name>:<reference>")), invalid)
+	}
+}
+
+func TestSecretReferencesRelativeFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, "secrets"), 0700)).To(Succeed())
+	keyFile := filepath.Join(dir, "secrets", "etcd-encryption-key")
+	g.Expect(os.WriteFile(keyFile, []byte("ZXRjZC1lbmNyeXB0aW9uLWtleQ==\n"), 0600)).To(Succeed())
+	t.Setenv("TEST_SECRET_REFERENCES_SP_SECRET", "sp-secret")
+	apimodel := strings.Replace(secretReferencesAPIModel, "%s", "secrets/etcd-encryption-key", 1)
+	for _, r := range []string{"test:literal", "test:ca-key", "test:etcd-peer-key-0"} {
+		apimodel = strings.Replace(apimodel, r, "literal", 1)
+	}
+	apimodelFile := filepath.Join(dir, "kubernetes.json")
+	g.Expect(os.WriteFile(apimodelFile, []byte(apimodel), 0600)).To(Succeed())
+	apiloader := &Apiloader{Translator: &i18n.Translator{}}
+
+	// the path is relative to the cluster definition, not to the current directory
+	cs, _, err := apiloader.LoadContainerServiceFromFile(apimodelFile, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey).To(Equal("ZXRjZC1lbmNyeXB0aW9uLWtleQ=="))
+	// and saved as an absolute path
+	b, err := apiloader.SerializeContainerService(cs, vlabs.APIVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring(`"etcdEncryptionKey": "file:` + keyFile + `"`))
+
+	// the cluster definition merged in a temporary file is loaded from the directory of its source
+	merged := filepath.Join(t.TempDir(), "mergedApiModel")
+	g.Expect(os.WriteFile(merged, []byte(apimodel), 0600)).To(Succeed())
+	_, _, err = apiloader.LoadContainerServiceFromFile(merged, false, false, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("resolving the secret reference file:")))
+	apiloader.APIModelDir = dir
+	cs, _, err = apiloader.LoadContainerServiceFromFile(merged, false, false, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey).To(Equal("ZXRjZC1lbmNyeXB0aW9uLWtleQ=="))
+}
@@ -5,12 +5,41 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
+// unknownJSONKey is a key of a JSON document that does not match any field of the type it is decoded to
+type unknownJSONKey struct {
+	// path is the path of the key in the document, e.g. properties.agentPoolProfiles[0].vmsize
+	path string
+	// suggestion is the closest valid key, if any
+	suggestion string
+}
+
+func (k unknownJSONKey) String() string {
+	if k.suggestion == "" {
+		return k.path
+	}
+	return fmt.Sprintf("%s, did you mean %q?", k.path, k.suggestion)
+}
+
+// unknownJSONKeysError lists every unknown key of a JSON document
+type unknownJSONKeysError []unknownJSONKey
+
+func (e unknownJSONKeysError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("Unknown JSON tag %s", e[0])
+	}
+	lines := make([]string, 0, len(e))
+	for _, k := range e {
+		lines = append(lines, fmt.Sprintf("- %s", k))
+	}
+	return fmt.Sprintf("Unknown JSON tags:\n%s", strings.Join(lines, "\n"))
+}
+
 func checkJSONKeys(data []byte, types ...reflect.Type) error {
 	var raw interface{}
 	if e := json.Unmarshal(data, &raw); e != nil {
@@ -21,17 +50,37 @@ func checkJSONKeys(data []byte, types ...reflect.Type) error {
 }
 
 func checkMapKeys(o map[string]interface{}, types ...reflect.Type) error {
+	var unknown unknownJSONKeysError
+	collectUnknownKeys(o, "", &unknown, types...)
+	if len(unknown) > 0 {
+		return unknown
+	}
+	return nil
+}
+
+// collectUnknownKeys appends to unknown the keys of the object o at path, and of its children,
+// that do not match a JSON field of types
+func collectUnknownKeys(o map[string]interface{}, path string, unknown *unknownJSONKeysError, types ...reflect.Type) {
 	fieldMap := createJSONFieldMap(types)
-	for k, v := range o {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := o[k]
+		childPath := k
+		if path != "" {
+			childPath = path + "." + k
+		}
 		f, present := fieldMap[strings.ToLower(k)]
 		if !present {
-			return errors.Errorf("Unknown JSON tag %s", k)
+			*unknown = append(*unknown, unknownJSONKey{path: childPath, suggestion: suggestJSONKey(k, types)})
+			continue
 		}
 		if f.Type.Kind() == reflect.Struct && v != nil {
 			if childMap, exists := v.(map[string]interface{}); exists {
-				if e := checkMapKeys(childMap, f.Type); e != nil {
-					return e
-				}
+				collectUnknownKeys(childMap, childPath, unknown, f.Type)
 			}
 		}
 		if f.Type.Kind() == reflect.Slice && v != nil {
@@ -39,26 +88,21 @@ func checkMapKeys(o map[string]interface{}, types ...reflect.Type) error {
 			if elementType.Kind() == reflect.Ptr {
 				elementType = elementType.Elem()
 			}
-			if childSlice, exists := v.([]interface{}); exists {
-				for _, child := range childSlice {
+			if childSlice, exists := v.([]interface{}); exists && elementType.Kind() == reflect.Struct {
+				for i, child := range childSlice {
 					if childMap, exists := child.(map[string]interface{}); exists {
-						if e := checkMapKeys(childMap, elementType); e != nil {
-							return e
-						}
+						collectUnknownKeys(childMap, fmt.Sprintf("%s[%d]", childPath, i), unknown, elementType)
 					}
 				}
 			}
 		}
 		if f.Type.Kind() == reflect.Ptr && v != nil {
 			elementType := f.Type.Elem()
-			if childMap, exists := v.(map[string]interface{}); exists {
-				if e := checkMapKeys(childMap, elementType); e != nil {
-					return e
-				}
+			if childMap, exists := v.(map[string]interface{}); exists && elementType.Kind() == reflect.Struct {
+				collectUnknownKeys(childMap, childPath, unknown, elementType)
 			}
 		}
 	}
-	return nil
 }
 
 func createJSONFieldMap(types []reflect.Type) map[string]reflect.StructField {
@@ -78,3 +122,46 @@ func createJSONFieldMap(types []reflect.Type) map[string]reflect.StructField {
 	}
 	return fieldMap
 }
+
+// suggestJSONKey returns the JSON field of types closest to key, or an empty string if none is close enough.
+// Keys are compared ignoring case, and a field is close enough when at most a third of key has to be edited.
+func suggestJSONKey(key string, types []reflect.Type) string {
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	var suggestion string
+	for _, t := range types {
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.SplitN(t.Field(i).Tag.Get("json"), ",", 2)[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d <= maxDistance {
+				suggestion, maxDistance = name, d-1
+			}
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
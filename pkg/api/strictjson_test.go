@@ -89,8 +89,8 @@ func TestCheckFailsOnUnexpectedJSONKeyAtSubLevel(t *testing.T) {
 	if e == nil {
 		t.Fatal("Unexpected JSON key was not detected")
 	}
-	if !strings.Contains(e.Error(), "spx") {
-		t.Errorf("Error message did not name unexpected JSON key 'spx': was %v", e) // TODO: f2.spx would be better
+	if !strings.Contains(e.Error(), "f2.spx") {
+		t.Errorf("Error message did not name unexpected JSON key 'f2.spx': was %v", e)
 	}
 }
 
@@ -117,8 +117,8 @@ func TestCheckFailsOnUnexpectedJSONKeyInArray(t *testing.T) {
 	if e == nil {
 		t.Fatal("Unexpected JSON key was not detected")
 	}
-	if !strings.Contains(e.Error(), "spz") {
-		t.Errorf("Error message did not name unexpected JSON key 'spz': was %v", e) // TODO: f2[1].spz might be better
+	if !strings.Contains(e.Error(), "f2.sp3[1].spz") {
+		t.Errorf("Error message did not name unexpected JSON key 'f2.sp3[1].spz': was %v", e)
 	}
 }
 
@@ -144,8 +144,8 @@ func TestCheckFailsOnUnexpectedJSONKeyInArrayAtSubLevel(t *testing.T) {
 	if e == nil {
 		t.Fatal("Unexpected JSON key was not detected")
 	}
-	if !strings.Contains(e.Error(), "spy") {
-		t.Errorf("Error message did not name unexpected JSON key 'spy': was %v", e) // TODO: f3[0].spy might be better
+	if !strings.Contains(e.Error(), "f3[0].spy") {
+		t.Errorf("Error message did not name unexpected JSON key 'f3[0].spy': was %v", e)
 	}
 }
 
@@ -162,8 +162,8 @@ func TestCheckFailsOnUnexpectedJSONKeyAtSubLevelViaPointer(t *testing.T) {
 	if e == nil {
 		t.Fatal("Unexpected JSON key was not detected")
 	}
-	if !strings.Contains(e.Error(), "spx") {
-		t.Errorf("Error message did not name unexpected JSON key 'spx': was %v", e) // TODO: f4.spx would be better
+	if !strings.Contains(e.Error(), "f4.spx") {
+		t.Errorf("Error message did not name unexpected JSON key 'f4.spx': was %v", e)
 	}
 }
 
@@ -189,8 +189,8 @@ func TestCheckFailsOnUnexpectedJSONKeyInArrayAtSubLevelViaPointer(t *testing.T)
 	if e == nil {
 		t.Fatal("Unexpected JSON key was not detected")
 	}
-	if !strings.Contains(e.Error(), "spy") {
-		t.Errorf("Error message did not name unexpected JSON key 'spy': was %v", e) // TODO: f3[0].spy might be better
+	if !strings.Contains(e.Error(), "f5[0].spy") || !strings.Contains(e.Error(), "f5[1].spz") {
+		t.Errorf("Error message did not name unexpected JSON keys 'f5[0].spy' and 'f5[1].spz': was %v", e)
 	}
 }
 
@@ -225,6 +225,47 @@ const jsonWithTypo = `
 	}
 }`
 
+func TestCheckReportsEveryUnexpectedJSONKeyWithSuggestions(t *testing.T) {
+	json := `
+	{
+		"f1": 1,
+		"f22": {},
+		"f4": {
+			"sp1": true,
+			"sp22": false,
+			"unrelated": true
+		}
+	}
+	`
+	e := checkJSONKeys([]byte(json), reflect.TypeOf(TestProfile{}))
+	expected := "Unknown JSON tags:\n" +
+		"- f22, did you mean \"f2\"?\n" +
+		"- f4.sp22, did you mean \"sp2\"?\n" +
+		"- f4.unrelated"
+	if e == nil || e.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, e)
+	}
+}
+
+func TestSuggestJSONKey(t *testing.T) {
+	cases := []struct {
+		key, expected string
+	}{
+		{"ventSubnetID", "vnetSubnetID"},
+		{"VNETSUBNETIDS", "vnetSubnetID"},
+		{"enforceUbuntuDisaStig", "enforceUbuntu2004DisaStig"},
+		{"EnforceKubernetesDisaSTIG", "enforceKubernetesDisaStig"},
+		{"osDiskSizeGBs", "osDiskSizeGB"},
+		{"count", "count"},
+		{"cloudInit", ""},
+	}
+	for _, c := range cases {
+		if s := suggestJSONKey(c.key, []reflect.Type{reflect.TypeOf(vlabs.MasterProfile{}), reflect.TypeOf(vlabs.FeatureFlags{})}); s != c.expected {
+			t.Errorf("expected suggestion %q for %q, got %q", c.expected, c.key, s)
+		}
+	}
+}
+
 func TestStrictJSONValidationIsAppliedToVersionsAbove20170701(t *testing.T) {
 	strictVersions := []string{vlabs.APIVersion}
 	a := &Apiloader{
@@ -234,7 +275,7 @@ func TestStrictJSONValidationIsAppliedToVersionsAbove20170701(t *testing.T) {
 		_, e := a.LoadContainerService([]byte(jsonWithTypo), version, true, false, nil)
 		if e == nil {
 			t.Error("Expected mistyped 'ventSubnetID' key to be detected but it wasn't")
-		} else if !strings.Contains(e.Error(), "ventSubnetID") {
+		} else if !strings.Contains(e.Error(), `properties.masterProfile.ventSubnetID, did you mean "vnetSubnetID"?`) {
 			t.Errorf("Expected error on 'ventSubnetID' but error was %v", e)
 		}
 	}
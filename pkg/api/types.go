@@ -10,6 +10,7 @@ import (
 	"hash/fnv"
 	"math/rand"
 	"net"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -47,6 +48,10 @@ type ContainerService struct {
 	Type     string                `json:"type"`
 
 	Properties *Properties `json:"properties,omitempty"`
+
+	// SecretReferences are the secret references the secrets were resolved from when the cluster definition was loaded,
+	// by JSON path. They are saved in place of the secrets.
+	SecretReferences map[string]SecretReference `json:"-"`
 }
 
 // Properties represents the AKS cluster definition
@@ -102,6 +107,8 @@ type CertificateProfile struct {
 	CaCertificate string `json:"caCertificate,omitempty" conform:"redact"`
 	// CaPrivateKey is the certificate authority key.
 	CaPrivateKey string `json:"caPrivateKey,omitempty" conform:"redact"`
+	// CaCertificateChain is the chain of the issuers of the CA certificate up to the root CA, when the CA is an intermediate CA
+	CaCertificateChain string `json:"caCertificateChain,omitempty"`
 	// ApiServerCertificate is the rest api server certificate, and signed by the CA
 	APIServerCertificate string `json:"apiServerCertificate,omitempty" conform:"redact"`
 	// ApiServerPrivateKey is the rest api server private key, and signed by the CA
@@ -126,6 +133,46 @@ type CertificateProfile struct {
 	EtcdPeerCertificates []string `json:"etcdPeerCertificates,omitempty" conform:"redact"`
 	// EtcdPeerPrivateKeys is list of etcd peer private keys, and signed by the CA
 	EtcdPeerPrivateKeys []string `json:"etcdPeerPrivateKeys,omitempty" conform:"redact"`
+	// SeparateCAs signs the etcd certificates and the front-proxy client certificate with their own CAs instead of the CA
+	SeparateCAs *bool `json:"separateCAs,omitempty"`
+	// EtcdCaCertificate is the certificate authority certificate of etcd, when SeparateCAs is enabled
+	EtcdCaCertificate string `json:"etcdCaCertificate,omitempty" conform:"redact"`
+	// EtcdCaPrivateKey is the certificate authority key of etcd, when SeparateCAs is enabled
+	EtcdCaPrivateKey string `json:"etcdCaPrivateKey,omitempty" conform:"redact"`
+	// FrontProxyCaCertificate is the certificate authority certificate of the aggregation layer, when SeparateCAs is enabled
+	FrontProxyCaCertificate string `json:"frontProxyCaCertificate,omitempty" conform:"redact"`
+	// FrontProxyCaPrivateKey is the certificate authority key of the aggregation layer, when SeparateCAs is enabled
+	FrontProxyCaPrivateKey string `json:"frontProxyCaPrivateKey,omitempty" conform:"redact"`
+	// FrontProxyClientCertificate is the client certificate used by the apiserver to proxy requests to the aggregated apiservers, and signed by the front-proxy CA
+	FrontProxyClientCertificate string `json:"frontProxyClientCertificate,omitempty" conform:"redact"`
+	// FrontProxyClientPrivateKey is the client private key used by the apiserver to proxy requests to the aggregated apiservers
+	FrontProxyClientPrivateKey string `json:"frontProxyClientPrivateKey,omitempty" conform:"redact"`
+	// KeyAlgorithm is the algorithm of the generated private keys: RSA (default), ECDSA-P256, ECDSA-P384 or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// CaSettings are the validity and subject settings of the generated CA certificate
+	CaSettings *CertificateSettings `json:"caSettings,omitempty"`
+	// APIServerSettings are the validity, subject and SAN settings of the generated apiserver certificate
+	APIServerSettings *CertificateSettings `json:"apiServerSettings,omitempty"`
+	// ClientSettings are the validity, subject and SAN settings of the generated kubelet client certificate
+	ClientSettings *CertificateSettings `json:"clientSettings,omitempty"`
+	// KubeConfigSettings are the validity, subject and SAN settings of the generated kubectl client certificate
+	KubeConfigSettings *CertificateSettings `json:"kubeConfigSettings,omitempty"`
+	// EtcdSettings are the validity, subject and SAN settings of the generated etcd server, client and peer certificates
+	EtcdSettings *CertificateSettings `json:"etcdSettings,omitempty"`
+}
+
+// CertificateSettings are the settings of a generated certificate
+type CertificateSettings struct {
+	// ValidityDays is the number of days the certificate is valid, 30 years if zero
+	ValidityDays int `json:"validityDays,omitempty"`
+	// Organization is the list of organizations of the certificate subject
+	Organization []string `json:"organization,omitempty"`
+	// OrganizationalUnit is the list of organizational units of the certificate subject
+	OrganizationalUnit []string `json:"organizationalUnit,omitempty"`
+	// Country is the list of two-letter country codes of the certificate subject
+	Country []string `json:"country,omitempty"`
+	// ExtraSANs are the extra DNS names and IP addresses of the certificate
+	ExtraSANs []string `json:"extraSANs,omitempty"`
 }
 
 // LinuxProfile represents the linux parameters passed to the cluster
@@ -427,6 +474,7 @@ type KubernetesConfig struct {
 	UseInstanceMetadata                 *bool                 `json:"useInstanceMetadata,omitempty"`
 	EnableRbac                          *bool                 `json:"enableRbac,omitempty"`
 	EnableSecureKubelet                 *bool                 `json:"enableSecureKubelet,omitempty"`
+	EnableKubeletServerTLSBootstrap     *bool                 `json:"enableKubeletServerTLSBootstrap,omitempty"`
 	EnableAggregatedAPIs                bool                  `json:"enableAggregatedAPIs,omitempty"`
 	PrivateCluster                      *PrivateCluster       `json:"privateCluster,omitempty"`
 	GCHighThreshold                     int                   `json:"gchighthreshold,omitempty"`
@@ -909,6 +957,19 @@ func (p *Properties) IsAgentPoolMember(vmName string, a *AgentPoolProfile, index
 	return strings.HasPrefix(vmName, p.GetAgentVMPrefix(a, index))
 }
 
+// GetNodeNameRegexp returns a regular expression matching the names of the cluster nodes,
+// the host names of the master and agent pool VMs
+func (p *Properties) GetNodeNameRegexp() string {
+	prefixes := []string{}
+	if p.MasterProfile != nil {
+		prefixes = append(prefixes, regexp.QuoteMeta(p.GetMasterVMPrefix()))
+	}
+	for i, profile := range p.AgentPoolProfiles {
+		prefixes = append(prefixes, regexp.QuoteMeta(strings.ToLower(p.GetAgentVMPrefix(profile, i))))
+	}
+	return fmt.Sprintf("^(%s)[0-9a-z]+$", strings.Join(prefixes, "|"))
+}
+
 // GetVMType returns the type of VM "vmss" or "standard" to be passed to the cloud provider
 func (p *Properties) GetVMType() string {
 	if p.HasVMSSAgentPool() {
@@ -1911,6 +1972,11 @@ func (k *KubernetesConfig) IsRBACEnabled() bool {
 	return false
 }
 
+// IsKubeletServerTLSBootstrapEnabled checks if kubelets request serving certificates signed by the cluster CA
+func (k *KubernetesConfig) IsKubeletServerTLSBootstrapEnabled() bool {
+	return to.Bool(k.EnableKubeletServerTLSBootstrap)
+}
+
 // UserAssignedIDEnabled checks if the user assigned ID is enabled or not.
 func (k *KubernetesConfig) UserAssignedIDEnabled() bool {
 	return to.Bool(k.UseManagedIdentity) && k.UserAssignedID != ""
@@ -2378,6 +2444,19 @@ func (cs *ContainerService) GetProvisionScriptParametersCommon(input ProvisionSc
 	return provisionScriptParametersCommon.String()
 }
 
+// GetCACertificateBundle returns the CA certificate followed by its chain, the trust bundle of the cluster
+func (c *CertificateProfile) GetCACertificateBundle() string {
+	if c.CaCertificateChain == "" {
+		return c.CaCertificate
+	}
+	return strings.TrimRight(c.CaCertificate, "\n") + "\n" + c.CaCertificateChain
+}
+
+// HasSeparateCAs returns true if etcd and the front-proxy have their own certificate authorities
+func (c *CertificateProfile) HasSeparateCAs() bool {
+	return c != nil && to.Bool(c.SeparateCAs)
+}
+
 // FormatAzureProdFQDNByLocation constructs an Azure prod fqdn
 func FormatAzureProdFQDNByLocation(fqdnPrefix string, location string) string {
 	targetEnv := helpers.GetCloudTargetEnv(location)
@@ -6,6 +6,7 @@ package api
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -4969,6 +4970,57 @@ func TestIsAgentPoolMember(t *testing.T) {
 	}
 }
 
+func TestGetNodeNameRegexp(t *testing.T) {
+	p := &Properties{
+		ClusterID: "99117399",
+		OrchestratorProfile: &OrchestratorProfile{
+			OrchestratorType: Kubernetes,
+		},
+		MasterProfile: &MasterProfile{
+			Count:     3,
+			DNSPrefix: "myprefix",
+		},
+		AgentPoolProfiles: []*AgentPoolProfile{
+			{
+				Name:   "agentpool",
+				Count:  1,
+				OSType: Linux,
+			},
+			{
+				Name:                "vmsspool",
+				Count:               1,
+				AvailabilityProfile: VirtualMachineScaleSets,
+				OSType:              Linux,
+			},
+			{
+				Name:   "winpool",
+				Count:  1,
+				OSType: Windows,
+			},
+		},
+	}
+	expected := `^(k8s-master-99117399-|k8s-agentpool-99117399-|k8s-vmsspool-99117399-vmss|9911k8s02)[0-9a-z]+$`
+	if got := p.GetNodeNameRegexp(); got != expected {
+		t.Fatalf("expected GetNodeNameRegexp() to return %s, instead got %s", expected, got)
+	}
+
+	r := regexp.MustCompile(p.GetNodeNameRegexp())
+	for name, expected := range map[string]bool{
+		"k8s-master-99117399-0":            true,
+		"k8s-agentpool-99117399-1":         true,
+		"k8s-vmsspool-99117399-vmss00000a": true,
+		"9911k8s02000":                     true,
+		"k8s-master-12345678-0":            false,
+		"k8s-otherpool-99117399-0":         false,
+		"k8s-agentpool-99117399-":          false,
+		"evil-k8s-master-99117399-0":       false,
+	} {
+		if got := r.MatchString(name); got != expected {
+			t.Errorf("expected node name %s to match %t, instead got %t", name, expected, got)
+		}
+	}
+}
+
 func TestFormatAzureProdFQDN(t *testing.T) {
 	dnsPrefix := "santest"
 	var actual []string
@@ -7016,3 +7068,37 @@ func TestShouldEnableAzureCloudAddon(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCACertificateBundle(t *testing.T) {
+	cases := []struct {
+		name     string
+		profile  CertificateProfile
+		expected string
+	}{
+		{
+			name:     "self-signed CA",
+			profile:  CertificateProfile{CaCertificate: "ca\n"},
+			expected: "ca\n",
+		},
+		{
+			name:     "intermediate CA",
+			profile:  CertificateProfile{CaCertificate: "intermediate\n", CaCertificateChain: "root\n"},
+			expected: "intermediate\nroot\n",
+		},
+		{
+			name:     "intermediate CA without trailing newline",
+			profile:  CertificateProfile{CaCertificate: "intermediate", CaCertificateChain: "root\n"},
+			expected: "intermediate\nroot\n",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := c.profile.GetCACertificateBundle(); actual != c.expected {
+				t.Fatalf("expected GetCACertificateBundle() to return %q but instead returned %q", c.expected, actual)
+			}
+		})
+	}
+}
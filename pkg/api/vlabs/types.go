@@ -105,6 +105,8 @@ type CertificateProfile struct {
 	CaCertificate string `json:"caCertificate,omitempty"`
 	// CaPrivateKey is the certificate authority key.
 	CaPrivateKey string `json:"caPrivateKey,omitempty"`
+	// CaCertificateChain is the chain of the issuers of the CA certificate up to the root CA, when the CA is an intermediate CA
+	CaCertificateChain string `json:"caCertificateChain,omitempty"`
 	// ApiServerCertificate is the rest api server certificate, and signed by the CA
 	APIServerCertificate string `json:"apiServerCertificate,omitempty"`
 	// ApiServerPrivateKey is the rest api server private key, and signed by the CA
@@ -129,6 +131,46 @@ type CertificateProfile struct {
 	EtcdPeerCertificates []string `json:"etcdPeerCertificates,omitempty"`
 	// EtcdPeerPrivateKeys is list of etcd peer private keys, and signed by the CA
 	EtcdPeerPrivateKeys []string `json:"etcdPeerPrivateKeys,omitempty"`
+	// SeparateCAs signs the etcd certificates and the front-proxy client certificate with their own CAs instead of the CA
+	SeparateCAs *bool `json:"separateCAs,omitempty"`
+	// EtcdCaCertificate is the certificate authority certificate of etcd, when SeparateCAs is enabled
+	EtcdCaCertificate string `json:"etcdCaCertificate,omitempty"`
+	// EtcdCaPrivateKey is the certificate authority key of etcd, when SeparateCAs is enabled
+	EtcdCaPrivateKey string `json:"etcdCaPrivateKey,omitempty"`
+	// FrontProxyCaCertificate is the certificate authority certificate of the aggregation layer, when SeparateCAs is enabled
+	FrontProxyCaCertificate string `json:"frontProxyCaCertificate,omitempty"`
+	// FrontProxyCaPrivateKey is the certificate authority key of the aggregation layer, when SeparateCAs is enabled
+	FrontProxyCaPrivateKey string `json:"frontProxyCaPrivateKey,omitempty"`
+	// FrontProxyClientCertificate is the client certificate used by the apiserver to proxy requests to the aggregated apiservers, and signed by the front-proxy CA
+	FrontProxyClientCertificate string `json:"frontProxyClientCertificate,omitempty"`
+	// FrontProxyClientPrivateKey is the client private key used by the apiserver to proxy requests to the aggregated apiservers
+	FrontProxyClientPrivateKey string `json:"frontProxyClientPrivateKey,omitempty"`
+	// KeyAlgorithm is the algorithm of the generated private keys: RSA (default), ECDSA-P256, ECDSA-P384 or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// CaSettings are the validity and subject settings of the generated CA certificate
+	CaSettings *CertificateSettings `json:"caSettings,omitempty"`
+	// APIServerSettings are the validity, subject and SAN settings of the generated apiserver certificate
+	APIServerSettings *CertificateSettings `json:"apiServerSettings,omitempty"`
+	// ClientSettings are the validity, subject and SAN settings of the generated kubelet client certificate
+	ClientSettings *CertificateSettings `json:"clientSettings,omitempty"`
+	// KubeConfigSettings are the validity, subject and SAN settings of the generated kubectl client certificate
+	KubeConfigSettings *CertificateSettings `json:"kubeConfigSettings,omitempty"`
+	// EtcdSettings are the validity, subject and SAN settings of the generated etcd server, client and peer certificates
+	EtcdSettings *CertificateSettings `json:"etcdSettings,omitempty"`
+}
+
+// CertificateSettings are the settings of a generated certificate
+type CertificateSettings struct {
+	// ValidityDays is the number of days the certificate is valid, 30 years if zero
+	ValidityDays int `json:"validityDays,omitempty"`
+	// Organization is the list of organizations of the certificate subject
+	Organization []string `json:"organization,omitempty"`
+	// OrganizationalUnit is the list of organizational units of the certificate subject
+	OrganizationalUnit []string `json:"organizationalUnit,omitempty"`
+	// Country is the list of two-letter country codes of the certificate subject
+	Country []string `json:"country,omitempty"`
+	// ExtraSANs are the extra DNS names and IP addresses of the certificate
+	ExtraSANs []string `json:"extraSANs,omitempty"`
 }
 
 // LinuxProfile represents the linux parameters passed to the cluster
@@ -365,6 +407,7 @@ type KubernetesConfig struct {
 	UseInstanceMetadata                 *bool                 `json:"useInstanceMetadata,omitempty"`
 	EnableRbac                          *bool                 `json:"enableRbac,omitempty"`
 	EnableSecureKubelet                 *bool                 `json:"enableSecureKubelet,omitempty"`
+	EnableKubeletServerTLSBootstrap     *bool                 `json:"enableKubeletServerTLSBootstrap,omitempty"`
 	EnableAggregatedAPIs                bool                  `json:"enableAggregatedAPIs,omitempty"`
 	PrivateCluster                      *PrivateCluster       `json:"privateCluster,omitempty"`
 	GCHighThreshold                     int                   `json:"gchighthreshold,omitempty"`
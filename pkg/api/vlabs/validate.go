@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	validator "gopkg.in/go-playground/validator.v9"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
 var (
@@ -32,6 +33,7 @@ var (
 	labelKeyRegex                  *regexp.Regexp
 	diskEncryptionSetIDRegex       *regexp.Regexp
 	proximityPlacementGroupIDRegex *regexp.Regexp
+	countryCodeRegex               = regexp.MustCompile(`^[A-Z]{2}$`)
 	// Any version has to be available in a container image from mcr.microsoft.com/oss/etcd-io/etcd:v[Version]
 	etcdValidVersions = [...]string{"2.2.5", "2.3.0", "2.3.1", "2.3.2", "2.3.3", "2.3.4", "2.3.5", "2.3.6", "2.3.7", "2.3.8",
 		"3.0.0", "3.0.1", "3.0.2", "3.0.3", "3.0.4", "3.0.5", "3.0.6", "3.0.7", "3.0.8", "3.0.9", "3.0.10", "3.0.11", "3.0.12", "3.0.13", "3.0.14", "3.0.15", "3.0.16", "3.0.17",
@@ -128,53 +130,32 @@ func init() {
 
 // Validate implements APIObject
 func (a *Properties) validate(isUpdate bool) error {
-	if e := validate.Struct(a); e != nil {
-		return handleValidationErrors(e.(validator.ValidationErrors))
-	}
-	if e := a.ValidateOrchestratorProfile(isUpdate); e != nil {
-		return e
-	}
-	if e := a.validateMasterProfile(isUpdate); e != nil {
-		return e
-	}
-	if e := a.validateAgentPoolProfiles(isUpdate); e != nil {
-		return e
-	}
-	if e := a.validateZones(); e != nil {
-		return e
-	}
-	if e := a.validateLinuxProfile(); e != nil {
-		return e
-	}
-	if e := a.validateAddons(isUpdate); e != nil {
-		return e
-	}
-	if e := a.validateExtensions(); e != nil {
-		return e
-	}
-	if e := a.validateVNET(); e != nil {
-		return e
-	}
-	if e := a.validateServicePrincipalProfile(); e != nil {
-		return e
-	}
-
-	if e := a.validateAADProfile(); e != nil {
-		return e
-	}
-
-	if e := a.validateCustomKubeComponent(); e != nil {
-		return e
-	}
-
-	if e := a.validateAzureStackSupport(); e != nil {
-		return e
-	}
+	return a.validateAll(isUpdate).Err()
+}
 
-	if e := a.validateWindowsProfile(isUpdate); e != nil {
-		return e
-	}
-	return nil
+// validateAll runs every validation of the properties and collects their errors
+func (a *Properties) validateAll(isUpdate bool) ValidationErrors {
+	c := &validationCollector{}
+	if e := validate.Struct(a); e != nil {
+		c.addStructErrors(e.(validator.ValidationErrors))
+		// the other validations expect the required properties to be set
+		return c.errs
+	}
+	c.add("properties.orchestratorProfile", CodeInvalidOrchestratorProfile, a.ValidateOrchestratorProfile(isUpdate))
+	c.add("properties.masterProfile", CodeInvalidMasterProfile, a.validateMasterProfile(isUpdate))
+	c.add("properties.agentPoolProfiles", CodeInvalidAgentPoolProfile, a.validateAgentPoolProfiles(isUpdate))
+	c.add("properties", CodeInvalidAvailabilityZones, a.validateZones())
+	c.add("properties.linuxProfile", CodeInvalidLinuxProfile, a.validateLinuxProfile())
+	c.add("properties.orchestratorProfile.kubernetesConfig.addons", CodeInvalidAddon, a.validateAddons(isUpdate))
+	c.add("properties", CodeInvalidExtension, a.validateExtensions())
+	c.add("properties", CodeInvalidVNET, a.validateVNET())
+	c.add("properties.servicePrincipalProfile", CodeInvalidServicePrincipalProfile, a.validateServicePrincipalProfile())
+	c.add("properties.aadProfile", CodeInvalidAADProfile, a.validateAADProfile())
+	c.add("properties.certificateProfile", CodeInvalidCertificateProfile, a.validateCertificateProfile())
+	c.add("properties.orchestratorProfile.kubernetesConfig", CodeInvalidCustomKubeComponent, a.validateCustomKubeComponent())
+	c.add("properties", CodeUnsupportedOnAzureStack, a.validateAzureStackSupport())
+	c.add("properties.windowsProfile", CodeInvalidWindowsProfile, a.validateWindowsProfile(isUpdate))
+	return c.errs
 }
 
 func handleValidationErrors(e validator.ValidationErrors) error {
@@ -186,6 +167,7 @@ func handleValidationErrors(e validator.ValidationErrors) error {
 // ValidateOrchestratorProfile validates the orchestrator profile and the addons dependent on the version of the orchestrator
 func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 	o := a.OrchestratorProfile
+	var errs fieldErrors
 	// On updates we only need to make sure there is a supported patch version for the minor version
 	if !isUpdate {
 		version := common.RationalizeReleaseAndVersion(
@@ -197,15 +179,15 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			a.IsAzureStackCloud())
 		if a.IsAzureStackCloud() {
 			if version == "" && a.HasWindows() {
-				return errors.Errorf("the following OrchestratorProfile configuration is not supported on Azure Stack with OsType \"Windows\": OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, true, true))
+				return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported on Azure Stack with OsType \"Windows\": OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, true, true)))
 			} else if version == "" {
-				return errors.Errorf("the following OrchestratorProfile configuration is not supported on Azure Stack: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, false, true))
+				return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported on Azure Stack: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, false, true)))
 			}
 		} else {
 			if version == "" && a.HasWindows() {
-				return errors.Errorf("the following OrchestratorProfile configuration is not supported with OsType \"Windows\": OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, true, false))
+				return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported with OsType \"Windows\": OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, true, false)))
 			} else if version == "" {
-				return errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, false, false))
+				return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please use one of the following versions: %v", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, common.GetAllSupportedKubernetesVersions(false, false, false)))
 			}
 		}
 
@@ -221,19 +203,16 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			}
 
 			if sv.LT(minVersion) {
-				return errors.New("availabilityZone is only available in Kubernetes version 1.12 or greater")
+				errs.add("orchestratorVersion", "", errors.New("availabilityZone is only available in Kubernetes version 1.12 or greater"))
 			}
 		}
 
 		if o.KubernetesConfig != nil {
-			err := o.KubernetesConfig.Validate(version, a.HasWindows(), a.FeatureFlags.IsIPv6DualStackEnabled(), a.FeatureFlags.IsIPv6OnlyEnabled(), isUpdate)
-			if err != nil {
-				return err
-			}
+			errs.add("kubernetesConfig", "", o.KubernetesConfig.Validate(version, a.HasWindows(), a.FeatureFlags.IsIPv6DualStackEnabled(), a.FeatureFlags.IsIPv6OnlyEnabled(), isUpdate))
 
 			if o.KubernetesConfig.EnableAggregatedAPIs {
 				if !o.KubernetesConfig.IsRBACEnabled() {
-					return errors.New("enableAggregatedAPIs requires the enableRbac feature as a prerequisite")
+					errs.add("kubernetesConfig.enableAggregatedAPIs", "", errors.New("enableAggregatedAPIs requires the enableRbac feature as a prerequisite"))
 				}
 			}
 
@@ -241,7 +220,7 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 				if o.KubernetesConfig.EtcdEncryptionKey != "" {
 					_, err = base64.StdEncoding.DecodeString(o.KubernetesConfig.EtcdEncryptionKey)
 					if err != nil {
-						return errors.New("etcdEncryptionKey must be base64 encoded. Please provide a valid base64 encoded value or leave the etcdEncryptionKey empty to auto-generate the value")
+						errs.add("kubernetesConfig.etcdEncryptionKey", "", errors.New("etcdEncryptionKey must be base64 encoded. Please provide a valid base64 encoded value or leave the etcdEncryptionKey empty to auto-generate the value"))
 					}
 				}
 			}
@@ -255,7 +234,7 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			if to.Bool(o.KubernetesConfig.EnablePodSecurityPolicy) {
 				log.Warnf("EnablePodSecurityPolicy is deprecated in favor of the addon pod-security-policy.")
 				if !o.KubernetesConfig.IsRBACEnabled() {
-					return errors.Errorf("enablePodSecurityPolicy requires the enableRbac feature as a prerequisite")
+					errs.add("kubernetesConfig.enablePodSecurityPolicy", "", errors.Errorf("enablePodSecurityPolicy requires the enableRbac feature as a prerequisite"))
 				}
 				if len(o.KubernetesConfig.PodSecurityPolicyConfig) > 0 {
 					log.Warnf("Raw manifest for PodSecurityPolicy using PodSecurityPolicyConfig is deprecated in favor of the addon pod-security-policy. This will be ignored.")
@@ -264,19 +243,19 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 
 			if o.KubernetesConfig.LoadBalancerSku != "" {
 				if !strings.EqualFold(o.KubernetesConfig.LoadBalancerSku, StandardLoadBalancerSku) && !strings.EqualFold(o.KubernetesConfig.LoadBalancerSku, BasicLoadBalancerSku) {
-					return errors.Errorf("Invalid value for loadBalancerSku, only %s and %s are supported", StandardLoadBalancerSku, BasicLoadBalancerSku)
+					errs.add("kubernetesConfig.loadBalancerSku", "", errors.Errorf("Invalid value for loadBalancerSku, only %s and %s are supported", StandardLoadBalancerSku, BasicLoadBalancerSku))
 				}
 			}
 
 			if o.KubernetesConfig.LoadBalancerSku == StandardLoadBalancerSku {
 				if !to.Bool(a.OrchestratorProfile.KubernetesConfig.ExcludeMasterFromStandardLB) {
-					return errors.Errorf("standard loadBalancerSku should exclude master nodes. Please set KubernetesConfig \"ExcludeMasterFromStandardLB\" to \"true\"")
+					errs.add("kubernetesConfig.excludeMasterFromStandardLB", "", errors.Errorf("standard loadBalancerSku should exclude master nodes. Please set KubernetesConfig \"ExcludeMasterFromStandardLB\" to \"true\""))
 				}
 			}
 
 			if o.KubernetesConfig.LoadBalancerSku == BasicLoadBalancerSku {
 				if o.KubernetesConfig.LoadBalancerOutboundIPs != nil {
-					return errors.Errorf("kubernetesConfig.loadBalancerOutboundIPs configuration only supported for Standard loadBalancerSku=Standard")
+					errs.add("kubernetesConfig.loadBalancerOutboundIPs", "", errors.Errorf("kubernetesConfig.loadBalancerOutboundIPs configuration only supported for Standard loadBalancerSku=Standard"))
 				}
 			}
 
@@ -285,40 +264,39 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			}
 
 			if o.KubernetesConfig.MaximumLoadBalancerRuleCount < 0 {
-				return errors.New("maximumLoadBalancerRuleCount shouldn't be less than 0")
+				errs.add("kubernetesConfig.maximumLoadBalancerRuleCount", "", errors.New("maximumLoadBalancerRuleCount shouldn't be less than 0"))
 			}
 
 			if o.KubernetesConfig.LoadBalancerOutboundIPs != nil {
 				if to.Int(o.KubernetesConfig.LoadBalancerOutboundIPs) > common.MaxLoadBalancerOutboundIPs {
-					return errors.Errorf("kubernetesConfig.loadBalancerOutboundIPs was set to %d, the maximum allowed is %d", to.Int(o.KubernetesConfig.LoadBalancerOutboundIPs), common.MaxLoadBalancerOutboundIPs)
+					errs.add("kubernetesConfig.loadBalancerOutboundIPs", "", errors.Errorf("kubernetesConfig.loadBalancerOutboundIPs was set to %d, the maximum allowed is %d", to.Int(o.KubernetesConfig.LoadBalancerOutboundIPs), common.MaxLoadBalancerOutboundIPs))
 				}
 			}
 
 			// https://docs.microsoft.com/en-us/azure/load-balancer/load-balancer-outbound-rules-overview
 			if o.KubernetesConfig.LoadBalancerSku == StandardLoadBalancerSku && o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes != 0 && (o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes < 4 || o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes > 120) {
-				return errors.New("outboundRuleIdleTimeoutInMinutes shouldn't be less than 4 or greater than 120")
+				errs.add("kubernetesConfig.outboundRuleIdleTimeoutInMinutes", "", errors.New("outboundRuleIdleTimeoutInMinutes shouldn't be less than 4 or greater than 120"))
 			}
 
 			if a.IsAzureStackCloud() {
 				if common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.21.0") && !to.Bool(o.KubernetesConfig.UseCloudControllerManager) {
-					return errors.New("useCloudControllerManager should be set to true for Kubernetes v1.21+ clusters on Azure Stack Hub")
+					errs.add("kubernetesConfig.useCloudControllerManager", "", errors.New("useCloudControllerManager should be set to true for Kubernetes v1.21+ clusters on Azure Stack Hub"))
 				}
 
 				if common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.24.0") && o.KubernetesConfig.ContainerRuntime == Docker {
-					return errors.Errorf("Docker runtime is no longer supported for v1.24+ clusters, use %s containerRuntime value instead", Containerd)
+					errs.add("kubernetesConfig.containerRuntime", "", errors.Errorf("Docker runtime is no longer supported for v1.24+ clusters, use %s containerRuntime value instead", Containerd))
 				}
 
 				if to.Bool(o.KubernetesConfig.UseInstanceMetadata) {
-					return errors.New("useInstanceMetadata shouldn't be set to true as feature not yet supported on Azure Stack")
+					errs.add("kubernetesConfig.useInstanceMetadata", "", errors.New("useInstanceMetadata shouldn't be set to true as feature not yet supported on Azure Stack"))
 				}
 
 				if o.KubernetesConfig.EtcdDiskSizeGB != "" {
 					etcdDiskSizeGB, err := strconv.Atoi(o.KubernetesConfig.EtcdDiskSizeGB)
 					if err != nil {
-						return errors.Errorf("could not convert EtcdDiskSizeGB to int")
-					}
-					if etcdDiskSizeGB > MaxAzureStackManagedDiskSize {
-						return errors.Errorf("EtcdDiskSizeGB max size supported on Azure Stack is %d", MaxAzureStackManagedDiskSize)
+						errs.add("kubernetesConfig.etcdDiskSizeGB", "", errors.Errorf("could not convert EtcdDiskSizeGB to int"))
+					} else if etcdDiskSizeGB > MaxAzureStackManagedDiskSize {
+						errs.add("kubernetesConfig.etcdDiskSizeGB", "", errors.Errorf("EtcdDiskSizeGB max size supported on Azure Stack is %d", MaxAzureStackManagedDiskSize))
 					}
 				}
 			}
@@ -328,7 +306,7 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 					log.Warnf("EtcdStorageLimitGB of %d is larger than the recommended maximum of 8", o.KubernetesConfig.EtcdStorageLimitGB)
 				}
 				if o.KubernetesConfig.EtcdStorageLimitGB < 2 {
-					return errors.Errorf("EtcdStorageLimitGB value of %d is too small, the minimum allowed is 2", o.KubernetesConfig.EtcdStorageLimitGB)
+					errs.add("kubernetesConfig.etcdStorageLimitGB", "", errors.Errorf("EtcdStorageLimitGB value of %d is too small, the minimum allowed is 2", o.KubernetesConfig.EtcdStorageLimitGB))
 				}
 			}
 		}
@@ -345,59 +323,54 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			// if there isn't a supported patch version for this version fail
 			if patchVersion == "" {
 				if a.HasWindows() {
-					return errors.Errorf("the following OrchestratorProfile configuration is not supported with Windows agentpools: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion)
+					return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported with Windows agentpools: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion))
 				}
-				return errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion)
+				return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: \"%s\", OrchestratorRelease: \"%s\", OrchestratorVersion: \"%s\". Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion))
 			}
 		}
 	}
 
 	if a.HasFlatcar() && o.KubernetesConfig.NetworkPlugin == "azure" && o.KubernetesConfig.NetworkMode == NetworkModeBridge {
-		return errors.Errorf("Flatcar node pools require 'transparent' networkMode with Azure CNI")
+		errs.add("kubernetesConfig.networkMode", "", errors.Errorf("Flatcar node pools require 'transparent' networkMode with Azure CNI"))
 	}
 
-	return a.validateContainerRuntime(isUpdate)
+	errs.add("kubernetesConfig.containerRuntime", "", a.validateContainerRuntime(isUpdate))
+	return errs.err()
 }
 
 func (a *Properties) validateMasterProfile(isUpdate bool) error {
 	m := a.MasterProfile
+	var errs fieldErrors
 
 	if m.Count == 1 && !isUpdate {
 		log.Warnf("Running only 1 control plane VM not recommended for production clusters, use 3 or 5 for control plane redundancy")
 	}
 	if m.IsVirtualMachineScaleSets() && m.VnetSubnetID != "" && m.FirstConsecutiveStaticIP != "" {
-		return errors.New("when masterProfile's availabilityProfile is VirtualMachineScaleSets and a vnetSubnetID is specified, the firstConsecutiveStaticIP should be empty and will be determined by an offset from the first IP in the vnetCidr")
+		errs.add("firstConsecutiveStaticIP", CodeInvalidValue, errors.New("when masterProfile's availabilityProfile is VirtualMachineScaleSets and a vnetSubnetID is specified, the firstConsecutiveStaticIP should be empty and will be determined by an offset from the first IP in the vnetCidr"))
 	}
 
 	if m.ImageRef != nil {
-		if err := m.ImageRef.validateImageNameAndGroup(); err != nil {
-			return err
-		}
+		errs.add("imageReference", CodeInvalidImageReference, m.ImageRef.validateImageNameAndGroup())
 	}
 
 	if m.IsVirtualMachineScaleSets() {
 		if !isUpdate {
 			log.Warnf("Clusters with a VMSS control plane are not upgradable! You will not be able to upgrade your cluster using `aks-engine-azurestack upgrade`")
 		}
-		e := validateVMSS(a.OrchestratorProfile, false, m.StorageProfile, a.HasWindows(), a.IsAzureStackCloud())
-		if e != nil {
-			return e
-		}
+		errs.add("availabilityProfile", "", validateVMSS(a.OrchestratorProfile, false, m.StorageProfile, a.HasWindows(), a.IsAzureStackCloud()))
 		if !a.IsClusterAllVirtualMachineScaleSets() {
-			return errors.New("VirtualMachineScaleSets for master profile must be used together with virtualMachineScaleSets for agent profiles. Set \"availabilityProfile\" to \"VirtualMachineScaleSets\" for agent profiles")
+			errs.add("availabilityProfile", CodeMixedAvailabilityProfiles, errors.New("VirtualMachineScaleSets for master profile must be used together with virtualMachineScaleSets for agent profiles. Set \"availabilityProfile\" to \"VirtualMachineScaleSets\" for agent profiles"))
 		}
 
 		if a.OrchestratorProfile.KubernetesConfig != nil && to.Bool(a.OrchestratorProfile.KubernetesConfig.UseManagedIdentity) && a.OrchestratorProfile.KubernetesConfig.UserAssignedID == "" {
-			return errors.New("virtualMachineScaleSets for master profile can be used only with user assigned MSI ! Please specify \"userAssignedID\" in \"kubernetesConfig\"")
+			errs.add("availabilityProfile", "", errors.New("virtualMachineScaleSets for master profile can be used only with user assigned MSI ! Please specify \"userAssignedID\" in \"kubernetesConfig\""))
 		}
 	}
 	if m.SinglePlacementGroup != nil && m.AvailabilityProfile == AvailabilitySet {
-		return errors.New("singlePlacementGroup is only supported with VirtualMachineScaleSets")
+		errs.add("singlePlacementGroup", CodeInvalidValue, errors.New("singlePlacementGroup is only supported with VirtualMachineScaleSets"))
 	}
 
-	if e := validateProximityPlacementGroupID(m.ProximityPlacementGroupID); e != nil {
-		return e
-	}
+	errs.add("proximityPlacementGroupID", CodeInvalidResourceID, validateProximityPlacementGroupID(m.ProximityPlacementGroupID))
 
 	distroValues := DistroValues
 	if isUpdate {
@@ -406,21 +379,21 @@ func (a *Properties) validateMasterProfile(isUpdate bool) error {
 	if !validateDistro(m.Distro, distroValues) {
 		switch m.Distro {
 		case AKSDockerEngine, AKS1604Deprecated:
-			return errors.Errorf("The %s distro is deprecated, please use %s instead", m.Distro, AKSUbuntu1604)
+			errs.add("distro", CodeDeprecatedDistro, errors.Errorf("The %s distro is deprecated, please use %s instead", m.Distro, AKSUbuntu1604))
 		case AKS1804Deprecated:
-			return errors.Errorf("The %s distro is deprecated, please use %s instead", m.Distro, AKSUbuntu1804)
+			errs.add("distro", CodeDeprecatedDistro, errors.Errorf("The %s distro is deprecated, please use %s instead", m.Distro, AKSUbuntu1804))
 		default:
-			return errors.Errorf("The %s distro is not supported", m.Distro)
+			errs.add("distro", CodeUnsupportedDistro, errors.Errorf("The %s distro is not supported", m.Distro))
 		}
 	}
 
 	if to.Bool(m.AuditDEnabled) {
 		if m.Distro != "" && !m.IsUbuntu() {
-			return errors.Errorf("auditd was enabled for master vms, but an Ubuntu-based distro was not selected")
+			errs.add("auditDEnabled", CodeInvalidValue, errors.Errorf("auditd was enabled for master vms, but an Ubuntu-based distro was not selected"))
 		}
 	} else {
 		if a.FeatureFlags.IsEnforceUbuntuDisaStigEnabled() && m.Distro != "" && m.IsUbuntu() {
-			return errors.New("AuditD should be enabled in all Ubuntu-based pools if feature flag 'EnforceUbuntu2004DisaStig' or 'EnforceUbuntu2204DisaStig' is set")
+			errs.add("auditDEnabled", CodeInvalidValue, errors.New("AuditD should be enabled in all Ubuntu-based pools if feature flag 'EnforceUbuntu2004DisaStig' or 'EnforceUbuntu2204DisaStig' is set"))
 		}
 	}
 
@@ -431,167 +404,153 @@ func (a *Properties) validateMasterProfile(isUpdate bool) error {
 		}
 	}
 	if !validOSDiskCachingType {
-		return errors.Errorf("Invalid masterProfile osDiskCachingType value \"%s\", please use one of the following versions: %s", m.OSDiskCachingType, cachingTypesValidValues)
+		errs.add("osDiskCachingType", CodeInvalidValue, errors.Errorf("Invalid masterProfile osDiskCachingType value \"%s\", please use one of the following versions: %s", m.OSDiskCachingType, cachingTypesValidValues))
 	}
 
-	return common.ValidateDNSPrefix(m.DNSPrefix)
+	errs.add("dnsPrefix", CodeInvalidDNSPrefix, common.ValidateDNSPrefix(m.DNSPrefix))
+	return errs.err()
 }
 
 func (a *Properties) validateAgentPoolProfiles(isUpdate bool) error {
-
+	var errs fieldErrors
 	profileNames := make(map[string]bool)
-	for i, agentPoolProfile := range a.AgentPoolProfiles {
-		if e := validatePoolName(agentPoolProfile.Name); e != nil {
-			return e
-		}
+	for i := range a.AgentPoolProfiles {
+		errs.add(fmt.Sprintf("[%d]", i), "", a.validateAgentPoolProfile(i, isUpdate, profileNames))
+	}
+	return errs.err()
+}
 
-		// validate os type is linux if dual stack feature is enabled
-		if a.FeatureFlags.IsIPv6DualStackEnabled() || a.FeatureFlags.IsIPv6OnlyEnabled() {
-			if agentPoolProfile.OSType == Windows {
-				if a.FeatureFlags.IsIPv6DualStackEnabled() && !common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.19.0") {
-					return errors.Errorf("Dual stack IPv6 feature is supported on Windows only from Kubernetes version 1.19, but OrchestratorProfile.OrchestratorVersion is '%s'", a.OrchestratorProfile.OrchestratorVersion)
-				}
-				if a.FeatureFlags.IsIPv6OnlyEnabled() {
-					return errors.Errorf("Single stack IPv6 feature is supported only with Linux, but agent pool '%s' is of os type %s", agentPoolProfile.Name, agentPoolProfile.OSType)
-				}
+// validateAgentPoolProfile validates the agent pool profile at index i, profileNames holds the names of the pools validated before it
+func (a *Properties) validateAgentPoolProfile(i int, isUpdate bool, profileNames map[string]bool) error {
+	agentPoolProfile := a.AgentPoolProfiles[i]
+	var errs fieldErrors
+	errs.add("name", CodeInvalidName, validatePoolName(agentPoolProfile.Name))
+
+	// validate os type is linux if dual stack feature is enabled
+	if a.FeatureFlags.IsIPv6DualStackEnabled() || a.FeatureFlags.IsIPv6OnlyEnabled() {
+		if agentPoolProfile.OSType == Windows {
+			if a.FeatureFlags.IsIPv6DualStackEnabled() && !common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.19.0") {
+				errs.add("osType", CodeInvalidValue, errors.Errorf("Dual stack IPv6 feature is supported on Windows only from Kubernetes version 1.19, but OrchestratorProfile.OrchestratorVersion is '%s'", a.OrchestratorProfile.OrchestratorVersion))
 			}
-			if agentPoolProfile.Distro == Flatcar {
-				return errors.Errorf("Dual stack and single stack IPv6 feature is currently supported only with Ubuntu, but agent pool '%s' is of distro type %s", agentPoolProfile.Name, agentPoolProfile.Distro)
+			if a.FeatureFlags.IsIPv6OnlyEnabled() {
+				errs.add("osType", CodeInvalidValue, errors.Errorf("Single stack IPv6 feature is supported only with Linux, but agent pool '%s' is of os type %s", agentPoolProfile.Name, agentPoolProfile.OSType))
 			}
 		}
-
-		// validate that each AgentPoolProfile Name is unique
-		if _, ok := profileNames[agentPoolProfile.Name]; ok {
-			return errors.Errorf("profile name '%s' already exists, profile names must be unique across pools", agentPoolProfile.Name)
-		}
-		profileNames[agentPoolProfile.Name] = true
-
-		if e := validatePoolOSType(agentPoolProfile.OSType); e != nil {
-			return e
+		if agentPoolProfile.Distro == Flatcar {
+			errs.add("distro", CodeUnsupportedDistro, errors.Errorf("Dual stack and single stack IPv6 feature is currently supported only with Ubuntu, but agent pool '%s' is of distro type %s", agentPoolProfile.Name, agentPoolProfile.Distro))
 		}
+	}
 
-		if to.Bool(agentPoolProfile.AcceleratedNetworkingEnabled) || to.Bool(agentPoolProfile.AcceleratedNetworkingEnabledWindows) {
-			if a.IsAzureStackCloud() {
-				return errors.Errorf("AcceleratedNetworkingEnabled or AcceleratedNetworkingEnabledWindows shouldn't be set to true as feature is not yet supported on Azure Stack")
-			} else if e := validatePoolAcceleratedNetworking(agentPoolProfile.VMSize); e != nil {
-				return e
-			}
-		}
+	// validate that each AgentPoolProfile Name is unique
+	if _, ok := profileNames[agentPoolProfile.Name]; ok {
+		errs.add("name", CodeDuplicateName, errors.Errorf("profile name '%s' already exists, profile names must be unique across pools", agentPoolProfile.Name))
+	}
+	profileNames[agentPoolProfile.Name] = true
 
-		if to.Bool(agentPoolProfile.VMSSOverProvisioningEnabled) {
-			if agentPoolProfile.AvailabilityProfile == AvailabilitySet {
-				return errors.Errorf("You have specified VMSS Overprovisioning in agent pool %s, but you did not specify VMSS", agentPoolProfile.Name)
-			}
-		}
+	errs.add("osType", CodeInvalidValue, validatePoolOSType(agentPoolProfile.OSType))
 
-		if to.Bool(agentPoolProfile.AuditDEnabled) {
-			if agentPoolProfile.Distro != "" && !agentPoolProfile.IsUbuntu() {
-				return errors.Errorf("You have enabled auditd in agent pool %s, but you did not specify an Ubuntu-based distro", agentPoolProfile.Name)
-			}
+	if to.Bool(agentPoolProfile.AcceleratedNetworkingEnabled) || to.Bool(agentPoolProfile.AcceleratedNetworkingEnabledWindows) {
+		if a.IsAzureStackCloud() {
+			errs.add("acceleratedNetworkingEnabled", CodeUnsupportedOnAzureStack, errors.Errorf("AcceleratedNetworkingEnabled or AcceleratedNetworkingEnabledWindows shouldn't be set to true as feature is not yet supported on Azure Stack"))
 		} else {
-			if a.FeatureFlags.IsEnforceUbuntuDisaStigEnabled() && agentPoolProfile.IsUbuntu() {
-				return errors.New("AuditD should be enabled in all Ubuntu-based pools if feature flag 'EnforceUbuntu2004DisaStig' or 'EnforceUbuntu2204DisaStig' is set")
-			}
+			errs.add("acceleratedNetworkingEnabled", CodeInvalidValue, validatePoolAcceleratedNetworking(agentPoolProfile.VMSize))
 		}
+	}
 
-		if to.Bool(agentPoolProfile.EnableVMSSNodePublicIP) {
-			if agentPoolProfile.AvailabilityProfile == AvailabilitySet {
-				return errors.Errorf("You have enabled VMSS node public IP in agent pool %s, but you did not specify VMSS", agentPoolProfile.Name)
-			}
-			if !strings.EqualFold(a.OrchestratorProfile.KubernetesConfig.LoadBalancerSku, BasicLoadBalancerSku) {
-				return errors.Errorf("You have enabled VMSS node public IP in agent pool %s, but you did not specify Basic Load Balancer SKU", agentPoolProfile.Name)
-			}
+	if to.Bool(agentPoolProfile.VMSSOverProvisioningEnabled) {
+		if agentPoolProfile.AvailabilityProfile == AvailabilitySet {
+			errs.add("vmssOverProvisioningEnabled", CodeInvalidValue, errors.Errorf("You have specified VMSS Overprovisioning in agent pool %s, but you did not specify VMSS", agentPoolProfile.Name))
 		}
+	}
 
-		if e := agentPoolProfile.validateOrchestratorSpecificProperties(); e != nil {
-			return e
+	if to.Bool(agentPoolProfile.AuditDEnabled) {
+		if agentPoolProfile.Distro != "" && !agentPoolProfile.IsUbuntu() {
+			errs.add("auditDEnabled", CodeInvalidValue, errors.Errorf("You have enabled auditd in agent pool %s, but you did not specify an Ubuntu-based distro", agentPoolProfile.Name))
 		}
-
-		if agentPoolProfile.ImageRef != nil {
-			if e := agentPoolProfile.ImageRef.validateImageNameAndGroup(); e != nil {
-				return e
-			}
+	} else {
+		if a.FeatureFlags.IsEnforceUbuntuDisaStigEnabled() && agentPoolProfile.IsUbuntu() {
+			errs.add("auditDEnabled", CodeInvalidValue, errors.New("AuditD should be enabled in all Ubuntu-based pools if feature flag 'EnforceUbuntu2004DisaStig' or 'EnforceUbuntu2204DisaStig' is set"))
 		}
+	}
 
-		if e := agentPoolProfile.validateAvailabilityProfile(); e != nil {
-			return e
+	if to.Bool(agentPoolProfile.EnableVMSSNodePublicIP) {
+		if agentPoolProfile.AvailabilityProfile == AvailabilitySet {
+			errs.add("enableVMSSNodePublicIP", CodeInvalidValue, errors.Errorf("You have enabled VMSS node public IP in agent pool %s, but you did not specify VMSS", agentPoolProfile.Name))
+		} else if !strings.EqualFold(a.OrchestratorProfile.KubernetesConfig.LoadBalancerSku, BasicLoadBalancerSku) {
+			errs.add("enableVMSSNodePublicIP", CodeInvalidValue, errors.Errorf("You have enabled VMSS node public IP in agent pool %s, but you did not specify Basic Load Balancer SKU", agentPoolProfile.Name))
 		}
+	}
 
-		if e := agentPoolProfile.validateRoles(); e != nil {
-			return e
-		}
+	errs.add("", "", agentPoolProfile.validateOrchestratorSpecificProperties())
 
-		if e := agentPoolProfile.validateCustomNodeLabels(); e != nil {
-			return e
-		}
+	if agentPoolProfile.ImageRef != nil {
+		errs.add("imageReference", CodeInvalidImageReference, agentPoolProfile.ImageRef.validateImageNameAndGroup())
+	}
 
-		if agentPoolProfile.AvailabilityProfile != AvailabilitySet {
-			e := validateVMSS(a.OrchestratorProfile, isUpdate, agentPoolProfile.StorageProfile, a.HasWindows(), a.IsAzureStackCloud())
-			if e != nil {
-				return e
-			}
-		}
+	errs.add("availabilityProfile", CodeInvalidValue, agentPoolProfile.validateAvailabilityProfile())
+	errs.add("role", CodeInvalidValue, agentPoolProfile.validateRoles())
+	errs.add("customNodeLabels", CodeInvalidNodeLabel, agentPoolProfile.validateCustomNodeLabels())
 
-		if a.AgentPoolProfiles[i].AvailabilityProfile != a.AgentPoolProfiles[0].AvailabilityProfile {
-			return errors.New("mixed mode availability profiles are not allowed. Please set either VirtualMachineScaleSets or AvailabilitySet in availabilityProfile for all agent pools")
-		}
+	if agentPoolProfile.AvailabilityProfile != AvailabilitySet {
+		errs.add("availabilityProfile", "", validateVMSS(a.OrchestratorProfile, isUpdate, agentPoolProfile.StorageProfile, a.HasWindows(), a.IsAzureStackCloud()))
+	}
 
-		if a.AgentPoolProfiles[i].SinglePlacementGroup != nil && a.AgentPoolProfiles[i].AvailabilityProfile == AvailabilitySet {
-			return errors.New("singlePlacementGroup is only supported with VirtualMachineScaleSets")
-		}
+	if a.AgentPoolProfiles[i].AvailabilityProfile != a.AgentPoolProfiles[0].AvailabilityProfile {
+		errs.add("availabilityProfile", CodeMixedAvailabilityProfiles, errors.New("mixed mode availability profiles are not allowed. Please set either VirtualMachineScaleSets or AvailabilitySet in availabilityProfile for all agent pools"))
+	}
 
-		distroValues := DistroValues
-		if isUpdate {
-			distroValues = append(distroValues, AKSDockerEngine, AKS1604Deprecated, AKS1804Deprecated)
-		}
-		if !validateDistro(agentPoolProfile.Distro, distroValues) {
-			switch agentPoolProfile.Distro {
-			case AKSDockerEngine, AKS1604Deprecated:
-				return errors.Errorf("The %s distro is deprecated, please use %s instead", agentPoolProfile.Distro, AKSUbuntu1604)
-			case AKS1804Deprecated:
-				return errors.Errorf("The %s distro is deprecated, please use %s instead", agentPoolProfile.Distro, AKSUbuntu1804)
-			default:
-				return errors.Errorf("The %s distro is not supported", agentPoolProfile.Distro)
-			}
-		}
+	if a.AgentPoolProfiles[i].SinglePlacementGroup != nil && a.AgentPoolProfiles[i].AvailabilityProfile == AvailabilitySet {
+		errs.add("singlePlacementGroup", CodeInvalidValue, errors.New("singlePlacementGroup is only supported with VirtualMachineScaleSets"))
+	}
 
-		if e := agentPoolProfile.validateLoadBalancerBackendAddressPoolIDs(); e != nil {
-			return e
+	distroValues := DistroValues
+	if isUpdate {
+		distroValues = append(distroValues, AKSDockerEngine, AKS1604Deprecated, AKS1804Deprecated)
+	}
+	if !validateDistro(agentPoolProfile.Distro, distroValues) {
+		switch agentPoolProfile.Distro {
+		case AKSDockerEngine, AKS1604Deprecated:
+			errs.add("distro", CodeDeprecatedDistro, errors.Errorf("The %s distro is deprecated, please use %s instead", agentPoolProfile.Distro, AKSUbuntu1604))
+		case AKS1804Deprecated:
+			errs.add("distro", CodeDeprecatedDistro, errors.Errorf("The %s distro is deprecated, please use %s instead", agentPoolProfile.Distro, AKSUbuntu1804))
+		default:
+			errs.add("distro", CodeUnsupportedDistro, errors.Errorf("The %s distro is not supported", agentPoolProfile.Distro))
 		}
+	}
 
-		if agentPoolProfile.IsEphemeral() {
-			log.Warnf("Ephemeral disks are enabled for Agent Pool %s. This feature in AKS-Engine is experimental, and data could be lost in some cases.", agentPoolProfile.Name)
-		}
+	errs.add("loadBalancerBackendAddressPoolIDs", CodeInvalidValue, agentPoolProfile.validateLoadBalancerBackendAddressPoolIDs())
 
-		if e := validateProximityPlacementGroupID(agentPoolProfile.ProximityPlacementGroupID); e != nil {
-			return e
-		}
-		var validOSDiskCachingType, validDataDiskCachingType bool
-		for _, valid := range cachingTypesValidValues {
-			if valid == agentPoolProfile.OSDiskCachingType {
-				validOSDiskCachingType = true
-			}
-			if valid == agentPoolProfile.DataDiskCachingType {
-				validDataDiskCachingType = true
-			}
-		}
-		if !validOSDiskCachingType {
-			return errors.Errorf("Invalid osDiskCachingType value \"%s\" for agentPoolProfile \"%s\", please use one of the following versions: %s", agentPoolProfile.OSDiskCachingType, agentPoolProfile.Name, cachingTypesValidValues)
+	if agentPoolProfile.IsEphemeral() {
+		log.Warnf("Ephemeral disks are enabled for Agent Pool %s. This feature in AKS-Engine is experimental, and data could be lost in some cases.", agentPoolProfile.Name)
+	}
+
+	errs.add("proximityPlacementGroupID", CodeInvalidResourceID, validateProximityPlacementGroupID(agentPoolProfile.ProximityPlacementGroupID))
+	var validOSDiskCachingType, validDataDiskCachingType bool
+	for _, valid := range cachingTypesValidValues {
+		if valid == agentPoolProfile.OSDiskCachingType {
+			validOSDiskCachingType = true
 		}
-		if !validDataDiskCachingType {
-			return errors.Errorf("Invalid dataDiskCachingType value \"%s\" for agentPoolProfile \"%s\", please use one of the following versions: %s", agentPoolProfile.DataDiskCachingType, agentPoolProfile.Name, cachingTypesValidValues)
+		if valid == agentPoolProfile.DataDiskCachingType {
+			validDataDiskCachingType = true
 		}
-		if agentPoolProfile.IsEphemeral() {
-			if agentPoolProfile.OSDiskCachingType != "" && agentPoolProfile.OSDiskCachingType != string(compute.CachingTypesReadOnly) {
-				return errors.Errorf("Invalid osDiskCachingType value \"%s\" for agentPoolProfile \"%s\" using Ephemeral Disk, you must use: %s", agentPoolProfile.OSDiskCachingType, agentPoolProfile.Name, string(compute.CachingTypesReadOnly))
-			}
+	}
+	if !validOSDiskCachingType {
+		errs.add("osDiskCachingType", CodeInvalidValue, errors.Errorf("Invalid osDiskCachingType value \"%s\" for agentPoolProfile \"%s\", please use one of the following versions: %s", agentPoolProfile.OSDiskCachingType, agentPoolProfile.Name, cachingTypesValidValues))
+	}
+	if !validDataDiskCachingType {
+		errs.add("dataDiskCachingType", CodeInvalidValue, errors.Errorf("Invalid dataDiskCachingType value \"%s\" for agentPoolProfile \"%s\", please use one of the following versions: %s", agentPoolProfile.DataDiskCachingType, agentPoolProfile.Name, cachingTypesValidValues))
+	}
+	if agentPoolProfile.IsEphemeral() {
+		if agentPoolProfile.OSDiskCachingType != "" && agentPoolProfile.OSDiskCachingType != string(compute.CachingTypesReadOnly) {
+			errs.add("osDiskCachingType", CodeInvalidValue, errors.Errorf("Invalid osDiskCachingType value \"%s\" for agentPoolProfile \"%s\" using Ephemeral Disk, you must use: %s", agentPoolProfile.OSDiskCachingType, agentPoolProfile.Name, string(compute.CachingTypesReadOnly)))
 		}
 	}
 
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateZones() error {
+	var errs fieldErrors
 	if a.HasAvailabilityZones() {
 		var poolsWithZones, poolsWithoutZones []string
 		for _, pool := range a.AgentPoolProfiles {
@@ -625,26 +584,27 @@ func (a *Properties) validateZones() error {
 			}
 		} else {
 			// agent pool profiles
-			for _, agentPoolProfile := range a.AgentPoolProfiles {
+			for i, agentPoolProfile := range a.AgentPoolProfiles {
 				if agentPoolProfile.AvailabilityProfile == AvailabilitySet {
-					return errors.New("Availability Zones are not supported with an AvailabilitySet. Please either remove availabilityProfile or set availabilityProfile to VirtualMachineScaleSets")
+					errs.add(fmt.Sprintf("agentPoolProfiles[%d].availabilityProfile", i), "", errors.New("Availability Zones are not supported with an AvailabilitySet. Please either remove availabilityProfile or set availabilityProfile to VirtualMachineScaleSets"))
 				}
 			}
 			if a.OrchestratorProfile.KubernetesConfig != nil && a.OrchestratorProfile.KubernetesConfig.LoadBalancerSku != "" && !strings.EqualFold(a.OrchestratorProfile.KubernetesConfig.LoadBalancerSku, StandardLoadBalancerSku) {
-				return errors.New("Availability Zones requires Standard LoadBalancer. Please set KubernetesConfig \"LoadBalancerSku\" to \"Standard\"")
+				errs.add("orchestratorProfile.kubernetesConfig.loadBalancerSku", "", errors.New("Availability Zones requires Standard LoadBalancer. Please set KubernetesConfig \"LoadBalancerSku\" to \"Standard\""))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateLinuxProfile() error {
+	var errs fieldErrors
 	var validEth0MTU bool
 	if a.LinuxProfile.Eth0MTU != 0 {
 		if a.OrchestratorProfile != nil &&
 			a.OrchestratorProfile.KubernetesConfig != nil &&
 			a.OrchestratorProfile.KubernetesConfig.NetworkPlugin == NetworkPluginKubenet {
-			return errors.Errorf("Custom linuxProfile eth0MTU value not allowed when using Kubenet")
+			errs.add("eth0MTU", CodeInvalidValue, errors.Errorf("Custom linuxProfile eth0MTU value not allowed when using Kubenet"))
 		}
 		for _, valid := range linuxEth0MTUAllowedValues {
 			if valid == a.LinuxProfile.Eth0MTU {
@@ -658,21 +618,23 @@ func (a *Properties) validateLinuxProfile() error {
 				allowedMTUs += strconv.Itoa(mtu) + ", "
 			}
 			allowedMTUs = strings.TrimRight(allowedMTUs, ", ")
-			return errors.Errorf("Invalid linuxProfile eth0MTU value \"%d\", please use one of the following values: %s", a.LinuxProfile.Eth0MTU, allowedMTUs)
+			errs.add("eth0MTU", CodeInvalidValue, errors.Errorf("Invalid linuxProfile eth0MTU value \"%d\", please use one of the following values: %s", a.LinuxProfile.Eth0MTU, allowedMTUs))
 		}
 	}
-	for _, publicKey := range a.LinuxProfile.SSH.PublicKeys {
+	for i, publicKey := range a.LinuxProfile.SSH.PublicKeys {
 		if e := validate.Var(publicKey.KeyData, "required"); e != nil {
-			return errors.New("KeyData in LinuxProfile.SSH.PublicKeys cannot be empty string")
+			errs.add(fmt.Sprintf("ssh.publicKeys[%d].keyData", i), CodeMissingProperty, errors.New("KeyData in LinuxProfile.SSH.PublicKeys cannot be empty string"))
 		}
 	}
 	if a.LinuxProfile.EnableUnattendedUpgrades == nil {
 		log.Warnf("linuxProfile.enableUnattendedUpgrades configuration was not declared, your cluster nodes will be configured to run unattended-upgrade by default")
 	}
-	return validateKeyVaultSecrets(a.LinuxProfile.Secrets, false)
+	errs.add("secrets", "", validateKeyVaultSecrets(a.LinuxProfile.Secrets, false))
+	return errs.err()
 }
 
 func (a *Properties) validateAddons(isUpdate bool) error {
+	var errs fieldErrors
 	if a.OrchestratorProfile.KubernetesConfig != nil && a.OrchestratorProfile.KubernetesConfig.Addons != nil {
 		var isAvailabilitySets bool
 		var kubeDNSEnabled bool
@@ -683,19 +645,20 @@ func (a *Properties) validateAddons(isUpdate bool) error {
 				isAvailabilitySets = true
 			}
 		}
-		for _, addon := range a.OrchestratorProfile.KubernetesConfig.Addons {
+		for i, addon := range a.OrchestratorProfile.KubernetesConfig.Addons {
+			field := fmt.Sprintf("[%d]", i)
 			if addon.Data != "" {
 				if len(addon.Config) > 0 || len(addon.Containers) > 0 {
-					return errors.New("Config and containers should be empty when addon.Data is specified")
+					errs.add(field+".data", "", errors.New("Config and containers should be empty when addon.Data is specified"))
 				}
 				if _, err := base64.StdEncoding.DecodeString(addon.Data); err != nil {
-					return errors.Errorf("Addon %s's data should be base64 encoded", addon.Name)
+					errs.add(field+".data", "", errors.Errorf("Addon %s's data should be base64 encoded", addon.Name))
 				}
 			}
 
 			if addon.Mode != "" {
 				if addon.Mode != AddonModeEnsureExists && addon.Mode != AddonModeReconcile {
-					return errors.Errorf("addon %s has a mode configuration '%s', must be either %s or %s", addon.Name, addon.Mode, AddonModeEnsureExists, AddonModeReconcile)
+					errs.add(field+".mode", "", errors.Errorf("addon %s has a mode configuration '%s', must be either %s or %s", addon.Name, addon.Mode, AddonModeEnsureExists, AddonModeReconcile))
 				}
 			}
 
@@ -704,91 +667,93 @@ func (a *Properties) validateAddons(isUpdate bool) error {
 				switch addon.Name {
 				case "cluster-autoscaler":
 					if isAvailabilitySets {
-						return errors.Errorf("cluster-autoscaler addon can only be used with VirtualMachineScaleSets. Please specify \"availabilityProfile\": \"%s\"", VirtualMachineScaleSets)
+						errs.add(field, "", errors.Errorf("cluster-autoscaler addon can only be used with VirtualMachineScaleSets. Please specify \"availabilityProfile\": \"%s\"", VirtualMachineScaleSets))
 					}
-					for _, pool := range addon.Pools {
+					for j, pool := range addon.Pools {
+						poolField := fmt.Sprintf("%s.pools[%d]", field, j)
 						if pool.Name == "" {
-							return errors.Errorf("cluster-autoscaler addon pools configuration must have a 'name' property that correlates with a pool name in the agentPoolProfiles array")
+							errs.add(poolField+".name", CodeMissingProperty, errors.Errorf("cluster-autoscaler addon pools configuration must have a 'name' property that correlates with a pool name in the agentPoolProfiles array"))
+							continue
 						}
 						if a.GetAgentPoolByName(pool.Name) == nil {
-							return errors.Errorf("cluster-autoscaler addon pool 'name' %s does not match any agentPoolProfiles nodepool name", pool.Name)
+							errs.add(poolField+".name", "", errors.Errorf("cluster-autoscaler addon pool 'name' %s does not match any agentPoolProfiles nodepool name", pool.Name))
 						}
 						if pool.Config != nil {
 							var min, max int
-							var err error
+							var minErr, maxErr error
 							if pool.Config["min-nodes"] != "" {
-								min, err = strconv.Atoi(pool.Config["min-nodes"])
-								if err != nil {
-									return errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid 'min-nodes' config, must be a string int, got %s", pool.Name, pool.Config["min-nodes"])
+								min, minErr = strconv.Atoi(pool.Config["min-nodes"])
+								if minErr != nil {
+									errs.add(poolField+".config.min-nodes", "", errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid 'min-nodes' config, must be a string int, got %s", pool.Name, pool.Config["min-nodes"]))
 								}
 							}
 							if pool.Config["max-nodes"] != "" {
-								max, err = strconv.Atoi(pool.Config["max-nodes"])
-								if err != nil {
-									return errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid 'max-nodes' config, must be a string int, got %s", pool.Name, pool.Config["max-nodes"])
+								max, maxErr = strconv.Atoi(pool.Config["max-nodes"])
+								if maxErr != nil {
+									errs.add(poolField+".config.max-nodes", "", errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid 'max-nodes' config, must be a string int, got %s", pool.Name, pool.Config["max-nodes"]))
 								}
 							}
-							if min > max {
-								return errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid config, 'max-nodes' %d must be greater than or equal to 'min-nodes' %d", pool.Name, max, min)
+							if minErr == nil && maxErr == nil && min > max {
+								errs.add(poolField+".config", "", errors.Errorf("cluster-autoscaler addon pool 'name' %s has invalid config, 'max-nodes' %d must be greater than or equal to 'min-nodes' %d", pool.Name, max, min))
 							}
 						}
 					}
 				case "aad":
 					if !a.HasAADAdminGroupID() {
-						return errors.New("aad addon can't be enabled without a valid aadProfile w/ adminGroupID")
+						errs.add(field, "", errors.New("aad addon can't be enabled without a valid aadProfile w/ adminGroupID"))
 					}
 				case "appgw-ingress":
 					if (a.ServicePrincipalProfile == nil || len(a.ServicePrincipalProfile.ObjectID) == 0) &&
 						!to.Bool(a.OrchestratorProfile.KubernetesConfig.UseManagedIdentity) {
-						return errors.New("appgw-ingress add-ons requires 'objectID' to be specified or UseManagedIdentity to be true")
+						errs.add(field, "", errors.New("appgw-ingress add-ons requires 'objectID' to be specified or UseManagedIdentity to be true"))
 					}
 
 					if a.OrchestratorProfile.KubernetesConfig.NetworkPlugin != "azure" {
-						return errors.New("appgw-ingress add-ons can only be used with Network Plugin as 'azure'")
+						errs.add(field, "", errors.New("appgw-ingress add-ons can only be used with Network Plugin as 'azure'"))
 					}
 
 					if len(addon.Config["appgw-subnet"]) == 0 {
-						return errors.New("appgw-ingress add-ons requires 'appgw-subnet' in the Config. It is used to provision the subnet for Application Gateway in the vnet")
+						errs.add(field+".config.appgw-subnet", CodeMissingProperty, errors.New("appgw-ingress add-ons requires 'appgw-subnet' in the Config. It is used to provision the subnet for Application Gateway in the vnet"))
 					}
 				case "cloud-node-manager":
 					if !to.Bool(a.OrchestratorProfile.KubernetesConfig.UseCloudControllerManager) {
-						return errors.Errorf("%s add-on requires useCloudControllerManager to be true", addon.Name)
+						errs.add(field, "", errors.Errorf("%s add-on requires useCloudControllerManager to be true", addon.Name))
 					}
 					if !a.ShouldEnableAzureCloudAddon(addon.Name) {
 						minVersion := "1.16.0"
 						if a.HasWindows() {
 							minVersion = "1.18.0"
 						}
-						return errors.Errorf("%s add-on can only be used Kubernetes %s or above", addon.Name, minVersion)
+						errs.add(field, "", errors.Errorf("%s add-on can only be used Kubernetes %s or above", addon.Name, minVersion))
 					}
 				case common.CiliumAddonName:
 					if !common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.16.0") {
 						if a.OrchestratorProfile.KubernetesConfig.NetworkPolicy != NetworkPolicyCilium {
-							return errors.Errorf("%s addon may only be enabled if the networkPolicy=%s", common.CiliumAddonName, NetworkPolicyCilium)
+							errs.add(field, "", errors.Errorf("%s addon may only be enabled if the networkPolicy=%s", common.CiliumAddonName, NetworkPolicyCilium))
 						}
 					} else {
-						return errors.Errorf("%s addon is not supported on Kubernetes v1.16.0 or greater", common.CiliumAddonName)
+						errs.add(field, "", errors.Errorf("%s addon is not supported on Kubernetes v1.16.0 or greater", common.CiliumAddonName))
 					}
 				case common.AntreaAddonName:
 					if a.OrchestratorProfile.KubernetesConfig.NetworkPolicy != NetworkPolicyAntrea {
-						return errors.Errorf("%s addon may only be enabled if the networkPolicy=%s", common.AntreaAddonName, NetworkPolicyAntrea)
+						errs.add(field, "", errors.Errorf("%s addon may only be enabled if the networkPolicy=%s", common.AntreaAddonName, NetworkPolicyAntrea))
 					}
 				case common.FlannelAddonName:
 					if isUpdate {
 						if a.OrchestratorProfile.KubernetesConfig.NetworkPolicy != "" {
-							return errors.Errorf("%s addon does not support NetworkPolicy, replace %s with \"\"", common.FlannelAddonName, a.OrchestratorProfile.KubernetesConfig.NetworkPolicy)
+							errs.add(field, "", errors.Errorf("%s addon does not support NetworkPolicy, replace %s with \"\"", common.FlannelAddonName, a.OrchestratorProfile.KubernetesConfig.NetworkPolicy))
 						}
 						networkPlugin := a.OrchestratorProfile.KubernetesConfig.NetworkPlugin
 						if networkPlugin != "" {
 							if networkPlugin != NetworkPluginFlannel {
-								return errors.Errorf("%s addon is not supported with networkPlugin=%s, please use networkPlugin=%s", common.FlannelAddonName, networkPlugin, NetworkPluginFlannel)
+								errs.add(field, "", errors.Errorf("%s addon is not supported with networkPlugin=%s, please use networkPlugin=%s", common.FlannelAddonName, networkPlugin, NetworkPluginFlannel))
 							}
 						}
 						if a.OrchestratorProfile.KubernetesConfig.ContainerRuntime != Containerd {
-							return errors.Errorf("%s addon is only supported with containerRuntime=%s", common.FlannelAddonName, Containerd)
+							errs.add(field, "", errors.Errorf("%s addon is only supported with containerRuntime=%s", common.FlannelAddonName, Containerd))
 						}
 					} else {
-						return errors.Errorf("%s addon is deprecated for new clusters", common.FlannelAddonName)
+						errs.add(field, "", errors.Errorf("%s addon is deprecated for new clusters", common.FlannelAddonName))
 					}
 				case common.KubeDNSAddonName:
 					kubeDNSEnabled = true
@@ -796,7 +761,7 @@ func (a *Properties) validateAddons(isUpdate bool) error {
 					corednsEnabled = true
 				case common.SecretsStoreCSIDriverAddonName:
 					if !common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.16.0") {
-						return errors.Errorf("%s add-on can only be used in 1.16+", addon.Name)
+						errs.add(field, "", errors.Errorf("%s add-on can only be used in 1.16+", addon.Name))
 					}
 				case common.PodSecurityPolicyAddonName:
 					if common.ShouldDisablePodSecurityPolicyAddon(a.OrchestratorProfile.OrchestratorVersion) {
@@ -805,25 +770,37 @@ func (a *Properties) validateAddons(isUpdate bool) error {
 							"See https://github.com/Azure/aks-engine-azurestack/blob/master/docs/topics/pod-security.md")
 					}
 				case common.AzureArcOnboardingAddonName:
-					if err := addon.validateArcAddonConfig(); err != nil {
-						return err
-					}
+					errs.add(field+".config", "", addon.validateArcAddonConfig())
 				case common.ReschedulerAddonName:
 					if isUpdate {
 						log.Warnf("The rescheduler addon has been deprecated and disabled, it will be removed during this update")
 					}
-					return errors.Errorf("The rescheduler addon has been deprecated and disabled, please remove it from your cluster configuration before creating a new cluster")
+					errs.add(field, "", errors.Errorf("The rescheduler addon has been deprecated and disabled, please remove it from your cluster configuration before creating a new cluster"))
 				case common.ContainerMonitoringAddonName:
 					if isUpdate {
 						log.Warnf("The container monitoring addon has been deprecated and disabled, it will be removed during this update")
 					}
-					return errors.Errorf("The container monitoring addon has been deprecated and disabled, please remove it from your cluster configuration before creating a new cluster")
+					errs.add(field, "", errors.Errorf("The container monitoring addon has been deprecated and disabled, please remove it from your cluster configuration before creating a new cluster"))
 				case common.DashboardAddonName:
 					log.Warnf("The kube-dashboard addon is deprecated, we recommend you install the dashboard yourself, see https://github.com/kubernetes/dashboard")
 				case common.AzureCNINetworkMonitorAddonName:
 					if isUpdate {
 						log.Warnf("The Azure CNI networkmonitor addon has been deprecated, it will be marked as disabled")
 					}
+				case common.KubeletCSRApproverAddonName:
+					// providerIPPrefixes defaults to the node subnets, and is empty when they cannot be determined
+					if prefixes, ok := addon.Config["providerIPPrefixes"]; ok {
+						if prefixes == "" {
+							errs.add(field+".config.providerIPPrefixes", "", errors.Errorf("%s addon cannot determine the node subnets, set 'providerIPPrefixes' in its config or masterProfile.vnetCidr", addon.Name))
+							break
+						}
+						for _, prefix := range strings.Split(prefixes, ",") {
+							if _, _, err := net.ParseCIDR(strings.TrimSpace(prefix)); err != nil {
+								errs.add(field+".config.providerIPPrefixes", "", errors.Errorf("%s addon has an invalid 'providerIPPrefixes' config %q, must be a comma-separated list of CIDRs", addon.Name, prefixes))
+								break
+							}
+						}
+					}
 				}
 			} else {
 				// Validation for addons if they are disabled
@@ -834,150 +811,276 @@ func (a *Properties) validateAddons(isUpdate bool) error {
 						if a.HasWindows() {
 							minVersion = "1.18.0"
 						}
-						return errors.Errorf("%s add-on is required when useCloudControllerManager is true in Kubernetes %s or above", addon.Name, minVersion)
+						errs.add(field, "", errors.Errorf("%s add-on is required when useCloudControllerManager is true in Kubernetes %s or above", addon.Name, minVersion))
 					}
 				case common.AzureCloudProviderAddonName:
-					return errors.Errorf("%s add-on is required, it cannot be disabled", addon.Name)
+					errs.add(field, "", errors.Errorf("%s add-on is required, it cannot be disabled", addon.Name))
 				}
 			}
 		}
 		if kubeDNSEnabled && corednsEnabled {
-			return errors.New("Both kube-dns and coredns addons are enabled, only one of these may be enabled on a cluster")
+			errs.add("", "", errors.New("Both kube-dns and coredns addons are enabled, only one of these may be enabled on a cluster"))
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateExtensions() error {
-	for _, agentPool := range a.AgentPoolProfiles {
+	var errs fieldErrors
+	for i, agentPool := range a.AgentPoolProfiles {
+		field := fmt.Sprintf("agentPoolProfiles[%d].extensions", i)
 		if len(agentPool.Extensions) != 0 && (len(agentPool.AvailabilityProfile) == 0 || agentPool.IsVirtualMachineScaleSets()) {
-			return errors.Errorf("Extensions are currently not supported with VirtualMachineScaleSets. Please specify \"availabilityProfile\": \"%s\"", AvailabilitySet)
+			errs.add(field, "", errors.Errorf("Extensions are currently not supported with VirtualMachineScaleSets. Please specify \"availabilityProfile\": \"%s\"", AvailabilitySet))
 		}
 
 		if agentPool.OSType == Windows && len(agentPool.Extensions) != 0 {
-			for _, e := range agentPool.Extensions {
+			for j, e := range agentPool.Extensions {
 				if e.Name == "prometheus-grafana-k8s" {
-					return errors.Errorf("prometheus-grafana-k8s extension is currently not supported for Windows agents")
+					errs.add(fmt.Sprintf("%s[%d].name", field, j), "", errors.Errorf("prometheus-grafana-k8s extension is currently not supported for Windows agents"))
 				}
 			}
 		}
 	}
 
-	for _, extension := range a.ExtensionProfiles {
+	for i, extension := range a.ExtensionProfiles {
 		if extension.ExtensionParametersKeyVaultRef != nil {
+			field := fmt.Sprintf("extensionProfiles[%d].parametersKeyvaultSecretRef", i)
 			if e := validate.Var(extension.ExtensionParametersKeyVaultRef.VaultID, "required"); e != nil {
-				return errors.Errorf("the Keyvault ID must be specified for Extension %s", extension.Name)
+				errs.add(field+".vaultID", CodeMissingProperty, errors.Errorf("the Keyvault ID must be specified for Extension %s", extension.Name))
 			}
 			if e := validate.Var(extension.ExtensionParametersKeyVaultRef.SecretName, "required"); e != nil {
-				return errors.Errorf("the Keyvault Secret must be specified for Extension %s", extension.Name)
+				errs.add(field+".secretName", CodeMissingProperty, errors.Errorf("the Keyvault Secret must be specified for Extension %s", extension.Name))
 			}
-			if !keyvaultIDRegex.MatchString(extension.ExtensionParametersKeyVaultRef.VaultID) {
-				return errors.Errorf("Extension %s's keyvault secret reference is of incorrect format", extension.Name)
+			if extension.ExtensionParametersKeyVaultRef.VaultID != "" && !keyvaultIDRegex.MatchString(extension.ExtensionParametersKeyVaultRef.VaultID) {
+				errs.add(field+".vaultID", CodeInvalidResourceID, errors.Errorf("Extension %s's keyvault secret reference is of incorrect format", extension.Name))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateVNET() error {
+	var errs fieldErrors
 	isCustomVNET := a.MasterProfile.IsCustomVNET()
-	for _, agentPool := range a.AgentPoolProfiles {
+	for i, agentPool := range a.AgentPoolProfiles {
 		if agentPool.IsCustomVNET() != isCustomVNET {
-			return errors.New("Multiple VNET Subnet configurations specified.  The master profile and each agent pool profile must all specify a custom VNET Subnet, or none at all")
+			errs.add(fmt.Sprintf("agentPoolProfiles[%d].vnetSubnetID", i), "", errors.New("Multiple VNET Subnet configurations specified.  The master profile and each agent pool profile must all specify a custom VNET Subnet, or none at all"))
+			// the other checks compare the subnets of the master and agent pool profiles
+			return errs.err()
 		}
 	}
 	if isCustomVNET {
 		if a.MasterProfile.IsVirtualMachineScaleSets() && a.MasterProfile.AgentVnetSubnetID == "" {
-			return errors.New("when master profile is using VirtualMachineScaleSets and is custom vnet, set \"vnetsubnetid\" and \"agentVnetSubnetID\" for master profile")
+			errs.add("masterProfile.agentVnetSubnetID", CodeMissingProperty, errors.New("when master profile is using VirtualMachineScaleSets and is custom vnet, set \"vnetsubnetid\" and \"agentVnetSubnetID\" for master profile"))
 		}
 
 		subscription, resourcegroup, vnetname, _, e := common.GetVNETSubnetIDComponents(a.MasterProfile.VnetSubnetID)
-		if e != nil {
-			return e
-		}
+		errs.add("masterProfile.vnetSubnetID", CodeInvalidResourceID, e)
 
-		for _, agentPool := range a.AgentPoolProfiles {
+		for i, agentPool := range a.AgentPoolProfiles {
+			field := fmt.Sprintf("agentPoolProfiles[%d].vnetSubnetID", i)
 			agentSubID, agentRG, agentVNET, _, err := common.GetVNETSubnetIDComponents(agentPool.VnetSubnetID)
 			if err != nil {
-				return err
+				errs.add(field, CodeInvalidResourceID, err)
+				continue
 			}
-			if agentSubID != subscription ||
+			if e == nil && (agentSubID != subscription ||
 				agentRG != resourcegroup ||
-				agentVNET != vnetname {
-				return errors.New("Multiple VNETS specified.  The master profile and each agent pool must reference the same VNET (but it is ok to reference different subnets on that VNET)")
+				agentVNET != vnetname) {
+				errs.add(field, "", errors.New("Multiple VNETS specified.  The master profile and each agent pool must reference the same VNET (but it is ok to reference different subnets on that VNET)"))
 			}
 		}
 
 		masterFirstIP := net.ParseIP(a.MasterProfile.FirstConsecutiveStaticIP)
 		if masterFirstIP == nil && !a.MasterProfile.IsVirtualMachineScaleSets() {
-			return errors.Errorf("MasterProfile.FirstConsecutiveStaticIP (with VNET Subnet specification) '%s' is an invalid IP address", a.MasterProfile.FirstConsecutiveStaticIP)
+			errs.add("masterProfile.firstConsecutiveStaticIP", CodeInvalidValue, errors.Errorf("MasterProfile.FirstConsecutiveStaticIP (with VNET Subnet specification) '%s' is an invalid IP address", a.MasterProfile.FirstConsecutiveStaticIP))
 		}
 
 		if a.MasterProfile.VnetCidr != "" {
 			_, _, err := net.ParseCIDR(a.MasterProfile.VnetCidr)
 			if err != nil {
-				return errors.Errorf("MasterProfile.VnetCidr '%s' contains invalid cidr notation", a.MasterProfile.VnetCidr)
+				errs.add("masterProfile.vnetCidr", CodeInvalidValue, errors.Errorf("MasterProfile.VnetCidr '%s' contains invalid cidr notation", a.MasterProfile.VnetCidr))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateServicePrincipalProfile() error {
 	useManagedIdentityDisabled := a.OrchestratorProfile.KubernetesConfig != nil &&
 		a.OrchestratorProfile.KubernetesConfig.UseManagedIdentity != nil && !to.Bool(a.OrchestratorProfile.KubernetesConfig.UseManagedIdentity)
 
+	var errs fieldErrors
 	if useManagedIdentityDisabled {
 		if a.ServicePrincipalProfile == nil {
-			return errors.Errorf("ServicePrincipalProfile must be specified")
+			return onField("", CodeMissingProperty, errors.Errorf("ServicePrincipalProfile must be specified"))
 		}
 		if e := validate.Var(a.ServicePrincipalProfile.ClientID, "required"); e != nil {
-			return errors.Errorf("the service principal client ID must be specified")
+			errs.add("clientId", CodeMissingProperty, errors.Errorf("the service principal client ID must be specified"))
 		}
 		if (len(a.ServicePrincipalProfile.Secret) == 0 && a.ServicePrincipalProfile.KeyvaultSecretRef == nil) ||
 			(len(a.ServicePrincipalProfile.Secret) != 0 && a.ServicePrincipalProfile.KeyvaultSecretRef != nil) {
-			return errors.Errorf("either the service principal client secret or keyvault secret reference must be specified")
+			errs.add("secret", "", errors.Errorf("either the service principal client secret or keyvault secret reference must be specified"))
 		}
 
 		if a.OrchestratorProfile.KubernetesConfig != nil && to.Bool(a.OrchestratorProfile.KubernetesConfig.EnableEncryptionWithExternalKms) && len(a.ServicePrincipalProfile.ObjectID) == 0 {
-			return errors.Errorf("the service principal object ID must be specified when enableEncryptionWithExternalKms is true")
+			errs.add("objectId", CodeMissingProperty, errors.Errorf("the service principal object ID must be specified when enableEncryptionWithExternalKms is true"))
 		}
 
 		if a.ServicePrincipalProfile.KeyvaultSecretRef != nil {
 			if e := validate.Var(a.ServicePrincipalProfile.KeyvaultSecretRef.VaultID, "required"); e != nil {
-				return errors.Errorf("the Keyvault ID must be specified for the Service Principle")
+				errs.add("keyvaultSecretRef.vaultID", CodeMissingProperty, errors.Errorf("the Keyvault ID must be specified for the Service Principle"))
 			}
 			if e := validate.Var(a.ServicePrincipalProfile.KeyvaultSecretRef.SecretName, "required"); e != nil {
-				return errors.Errorf("the Keyvault Secret must be specified for the Service Principle")
+				errs.add("keyvaultSecretRef.secretName", CodeMissingProperty, errors.Errorf("the Keyvault Secret must be specified for the Service Principle"))
 			}
-			if !keyvaultIDRegex.MatchString(a.ServicePrincipalProfile.KeyvaultSecretRef.VaultID) {
-				return errors.Errorf("service principal client keyvault secret reference is of incorrect format")
+			if a.ServicePrincipalProfile.KeyvaultSecretRef.VaultID != "" && !keyvaultIDRegex.MatchString(a.ServicePrincipalProfile.KeyvaultSecretRef.VaultID) {
+				errs.add("keyvaultSecretRef.vaultID", CodeInvalidResourceID, errors.Errorf("service principal client keyvault secret reference is of incorrect format"))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *Properties) validateAADProfile() error {
+	var errs fieldErrors
 	if profile := a.AADProfile; profile != nil {
 		if _, err := uuid.Parse(profile.ClientAppID); err != nil {
-			return errors.Errorf("clientAppID '%v' is invalid", profile.ClientAppID)
+			errs.add("clientAppID", CodeInvalidValue, errors.Errorf("clientAppID '%v' is invalid", profile.ClientAppID))
 		}
 		if _, err := uuid.Parse(profile.ServerAppID); err != nil {
-			return errors.Errorf("serverAppID '%v' is invalid", profile.ServerAppID)
+			errs.add("serverAppID", CodeInvalidValue, errors.Errorf("serverAppID '%v' is invalid", profile.ServerAppID))
 		}
 		if len(profile.TenantID) > 0 {
 			if _, err := uuid.Parse(profile.TenantID); err != nil {
-				return errors.Errorf("tenantID '%v' is invalid", profile.TenantID)
+				errs.add("tenantID", CodeInvalidValue, errors.Errorf("tenantID '%v' is invalid", profile.TenantID))
 			}
 		}
 		if len(profile.AdminGroupID) > 0 {
 			if _, err := uuid.Parse(profile.AdminGroupID); err != nil {
-				return errors.Errorf("adminGroupID '%v' is invalid", profile.AdminGroupID)
+				errs.add("adminGroupID", CodeInvalidValue, errors.Errorf("adminGroupID '%v' is invalid", profile.AdminGroupID))
 			}
 		}
 	}
-	return nil
+	return errs.err()
+}
+
+func (a *Properties) validateCertificateProfile() error {
+	profile := a.CertificateProfile
+	if profile == nil {
+		return nil
+	}
+	var errs fieldErrors
+	if profile.KeyAlgorithm != "" {
+		supported := false
+		for _, algorithm := range helpers.PkiKeyAlgorithms {
+			if profile.KeyAlgorithm == algorithm {
+				supported = true
+			}
+		}
+		if !supported {
+			errs.add("keyAlgorithm", CodeInvalidValue, errors.Errorf("keyAlgorithm '%s' is not supported, the supported key algorithms are %s", profile.KeyAlgorithm, strings.Join(helpers.PkiKeyAlgorithms, ", ")))
+		}
+	}
+
+	if profile.CaCertificateChain != "" {
+		if profile.CaCertificate == "" {
+			errs.add("caCertificateChain", CodeMissingProperty, errors.New("caCertificateChain requires caCertificate, the intermediate CA issued by the chain"))
+		} else if err := helpers.VerifyCertificateChain(profile.CaCertificate, profile.CaCertificateChain); err != nil {
+			errs.add("caCertificateChain", CodeInvalidValue, errors.Wrap(err, "caCertificateChain does not issue caCertificate"))
+		}
+	}
+
+	errs.add("", "", profile.validateSeparateCAs())
+
+	// the certificates must not outlive the CA that signs them
+	caValidityDays := int(helpers.ValidityDuration.Hours() / 24)
+	if profile.CaSettings != nil {
+		if len(profile.CaSettings.ExtraSANs) > 0 {
+			errs.add("caSettings.extraSANs", CodeInvalidValue, errors.New("caSettings.extraSANs is not supported, the CA certificate has no SANs"))
+		}
+		if field, err := validateCertificateSettings("caSettings", profile.CaSettings, 0); err != nil {
+			errs.add(field, CodeInvalidValue, err)
+		}
+		if profile.CaSettings.ValidityDays > 0 {
+			caValidityDays = profile.CaSettings.ValidityDays
+		}
+	}
+	for _, s := range []struct {
+		field    string
+		settings *CertificateSettings
+	}{
+		{"apiServerSettings", profile.APIServerSettings},
+		{"clientSettings", profile.ClientSettings},
+		{"kubeConfigSettings", profile.KubeConfigSettings},
+		{"etcdSettings", profile.EtcdSettings},
+	} {
+		if field, err := validateCertificateSettings(s.field, s.settings, caValidityDays); err != nil {
+			errs.add(field, CodeInvalidValue, err)
+		}
+	}
+	return errs.err()
+}
+
+// validateSeparateCAs validates the etcd and front-proxy CAs, which are only used with separateCAs
+func (profile *CertificateProfile) validateSeparateCAs() error {
+	var errs fieldErrors
+	for _, pair := range []struct {
+		certificateField, privateKeyField string
+		certificate, privateKey           string
+	}{
+		{"etcdCaCertificate", "etcdCaPrivateKey", profile.EtcdCaCertificate, profile.EtcdCaPrivateKey},
+		{"frontProxyCaCertificate", "frontProxyCaPrivateKey", profile.FrontProxyCaCertificate, profile.FrontProxyCaPrivateKey},
+		{"frontProxyClientCertificate", "frontProxyClientPrivateKey", profile.FrontProxyClientCertificate, profile.FrontProxyClientPrivateKey},
+	} {
+		if pair.certificate == "" && pair.privateKey == "" {
+			continue
+		}
+		if !to.Bool(profile.SeparateCAs) {
+			errs.add(pair.certificateField, CodeInvalidValue, errors.Errorf("%s is only used when separateCAs is enabled", pair.certificateField))
+		} else if pair.certificate == "" {
+			errs.add(pair.certificateField, CodeMissingProperty, errors.Errorf("%s requires %s", pair.privateKeyField, pair.certificateField))
+		} else if pair.privateKey == "" {
+			errs.add(pair.privateKeyField, CodeMissingProperty, errors.Errorf("%s requires %s", pair.certificateField, pair.privateKeyField))
+		}
+	}
+	if to.Bool(profile.SeparateCAs) && profile.CaCertificate != "" {
+		if strings.TrimSpace(profile.EtcdCaCertificate) == strings.TrimSpace(profile.CaCertificate) {
+			errs.add("etcdCaCertificate", CodeInvalidValue, errors.New("etcdCaCertificate must not be the caCertificate, etcd would trust every client certificate of the cluster"))
+		}
+		if strings.TrimSpace(profile.FrontProxyCaCertificate) == strings.TrimSpace(profile.CaCertificate) {
+			errs.add("frontProxyCaCertificate", CodeInvalidValue, errors.New("frontProxyCaCertificate must not be the caCertificate"))
+		}
+	}
+	return errs.err()
+}
+
+// validateCertificateSettings validates the settings of a generated certificate, the validity of which must not
+// exceed maxValidityDays unless zero. It returns the invalid field and the error.
+func validateCertificateSettings(name string, settings *CertificateSettings, maxValidityDays int) (string, error) {
+	if settings == nil {
+		return "", nil
+	}
+	if settings.ValidityDays < 0 {
+		return name + ".validityDays", errors.Errorf("%s.validityDays %d must not be negative", name, settings.ValidityDays)
+	}
+	if maxValidityDays > 0 && settings.ValidityDays > maxValidityDays {
+		return name + ".validityDays", errors.Errorf("%s.validityDays %d exceeds the validity of the CA certificate, %d days", name, settings.ValidityDays, maxValidityDays)
+	}
+	for _, country := range settings.Country {
+		if !countryCodeRegex.MatchString(country) {
+			return name + ".country", errors.Errorf("%s.country '%s' is not a two-letter country code", name, country)
+		}
+	}
+	for _, san := range settings.ExtraSANs {
+		if net.ParseIP(san) != nil {
+			continue
+		}
+		if len(k8svalidation.IsDNS1123Subdomain(san)) > 0 && len(k8svalidation.IsWildcardDNS1123Subdomain(san)) > 0 {
+			return name + ".extraSANs", errors.Errorf("%s.extraSANs '%s' is neither an IP address nor a DNS name", name, san)
+		}
+	}
+	return "", nil
 }
 
 func (a *AgentPoolProfile) validateAvailabilityProfile() error {
@@ -1032,7 +1135,7 @@ func validateVMSS(o *OrchestratorProfile, isUpdate bool, storageProfile string,
 		hasWindows,
 		isAzureStackCloud)
 	if version == "" {
-		return errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: %s, OrchestratorRelease: %s, OrchestratorVersion: %s. Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion)
+		return onField("orchestratorVersion", "", errors.Errorf("the following OrchestratorProfile configuration is not supported: OrchestratorType: %s, OrchestratorRelease: %s, OrchestratorVersion: %s. Please check supported Release or Version for this build of aks-engine", o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion))
 	}
 
 	sv, err := semver.Make(version)
@@ -1090,28 +1193,21 @@ func (a *Properties) validateWindowsProfile(isUpdate bool) error {
 
 	w := a.WindowsProfile
 	if w == nil {
-		return errors.New("WindowsProfile is required when the cluster definition contains Windows agent pools")
+		return onField("", CodeMissingProperty, errors.New("WindowsProfile is required when the cluster definition contains Windows agent pools"))
 	}
+	var errs fieldErrors
 	if e := validate.Var(w.AdminUsername, "required"); e != nil {
-		return errors.New("WindowsProfile.AdminUsername is required, when agent pool specifies Windows")
+		errs.add("adminUsername", CodeMissingProperty, errors.New("WindowsProfile.AdminUsername is required, when agent pool specifies Windows"))
 	}
 	if e := validate.Var(w.AdminPassword, "required"); e != nil {
-		return errors.New("WindowsProfile.AdminPassword is required, when agent pool specifies Windows")
-	}
-	if !validatePasswordComplexity(w.AdminUsername, w.AdminPassword) {
-		return errors.New("WindowsProfile.AdminPassword complexity not met. Windows password should contain 3 of the following categories - uppercase letters(A-Z), lowercase(a-z) letters, digits(0-9), special characters (~!@#$%^&*_-+=`|\\(){}[]:;<>,.?/')")
-	}
-	if e := validateKeyVaultSecrets(w.Secrets, true); e != nil {
-		return e
-	}
-	if e := validateCsiProxyWindowsProperties(w, version); e != nil {
-		return e
-	}
-	if e := validateWindowsRuntimes(w.WindowsRuntimes); e != nil {
-		return e
-	}
-
-	return nil
+		errs.add("adminPassword", CodeMissingProperty, errors.New("WindowsProfile.AdminPassword is required, when agent pool specifies Windows"))
+	} else if !validatePasswordComplexity(w.AdminUsername, w.AdminPassword) {
+		errs.add("adminPassword", CodeInvalidValue, errors.New("WindowsProfile.AdminPassword complexity not met. Windows password should contain 3 of the following categories - uppercase letters(A-Z), lowercase(a-z) letters, digits(0-9), special characters (~!@#$%^&*_-+=`|\\(){}[]:;<>,.?/')"))
+	}
+	errs.add("secrets", "", validateKeyVaultSecrets(w.Secrets, true))
+	errs.add("enableCSIProxy", "", validateCsiProxyWindowsProperties(w, version))
+	errs.add("windowsRuntimes", "", validateWindowsRuntimes(w.WindowsRuntimes))
+	return errs.err()
 }
 
 func validateCsiProxyWindowsProperties(w *WindowsProfile, k8sVersion string) error {
@@ -1717,22 +1813,22 @@ func (a *Properties) validateCustomKubeComponent() error {
 		return nil
 	}
 
+	var errs fieldErrors
 	if common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.17.0") {
 		if k.CustomHyperkubeImage != "" {
-			return errors.New("customHyperkubeImage has no effect in Kubernetes version 1.17.0 or above")
+			errs.add("customHyperkubeImage", "", errors.New("customHyperkubeImage has no effect in Kubernetes version 1.17.0 or above"))
 		}
 	} else {
 		if k.isUsingCustomKubeComponent() {
-			return errors.New("customKubeAPIServerImage, customKubeControllerManagerImage, customKubeSchedulerImage or customKubeBinaryURL have no effect in Kubernetes version 1.16 or earlier")
+			errs.add("", "", errors.New("customKubeAPIServerImage, customKubeControllerManagerImage, customKubeSchedulerImage or customKubeBinaryURL have no effect in Kubernetes version 1.16 or earlier"))
 		}
 	}
 	if !common.IsKubernetesVersionGe(a.OrchestratorProfile.OrchestratorVersion, "1.16.0") {
 		if k.CustomKubeProxyImage != "" {
-			return errors.New("customKubeProxyImage has no effect in Kubernetes version 1.15 or earlier")
+			errs.add("customKubeProxyImage", "", errors.New("customKubeProxyImage has no effect in Kubernetes version 1.15 or earlier"))
 		}
 	}
-
-	return nil
+	return errs.err()
 }
 
 func validateName(name string, label string) error {
@@ -1880,21 +1976,32 @@ func (cs *ContainerService) validateCustomCloudProfile() error {
 	return nil
 }
 
-// Validate implements validation for ContainerService
+// Validate implements validation for ContainerService.
+// The returned error is a ValidationErrors that holds every error found.
 func (cs *ContainerService) Validate(isUpdate bool) error {
+	return cs.ValidateAll(isUpdate).Err()
+}
+
+// ValidateAll validates the cluster definition, and returns every error found with
+// the warnings about the deprecated properties it uses
+func (cs *ContainerService) ValidateAll(isUpdate bool) ValidationErrors {
+	c := &validationCollector{}
 	if e := cs.validateProperties(); e != nil {
-		return e
+		c.add("properties", CodeMissingProperty, e)
+		return c.errs
 	}
+	// the validation of the properties depends on the cloud the cluster is deployed to
 	if e := cs.validateLocation(); e != nil {
-		return e
+		c.add("location", CodeInvalidLocation, e)
+		return c.errs
 	}
 	if e := cs.validateCustomCloudProfile(); e != nil {
-		return e
+		c.add("properties.customCloudProfile", CodeInvalidCustomCloudProfile, e)
+		return c.errs
 	}
-	if e := cs.Properties.validate(isUpdate); e != nil {
-		return e
-	}
-	return nil
+	c.errs = append(c.errs, cs.Properties.validateAll(isUpdate)...)
+	c.errs = append(c.errs, cs.Properties.deprecationWarnings(isUpdate)...)
+	return c.errs
 }
 
 func (cs *ContainerService) validateLocation() error {
@@ -1926,22 +2033,25 @@ func validateDependenciesLocation(dependenciesLocation DependenciesLocation, dep
 
 // validateAzureStackSupport logs a warning if apimodel contains preview features and returns an error if a property is not supported on Azure Stack clouds
 func (a *Properties) validateAzureStackSupport() error {
+	var errs fieldErrors
 	if a.IsAzureStackCloud() {
-		networkPlugin := a.OrchestratorProfile.KubernetesConfig.NetworkPlugin
+		var networkPlugin string
+		if a.OrchestratorProfile.KubernetesConfig != nil {
+			networkPlugin = a.OrchestratorProfile.KubernetesConfig.NetworkPlugin
+		}
 		if networkPlugin != "azure" && networkPlugin != "kubenet" && networkPlugin != "" {
-			return errors.Errorf("kubernetesConfig.networkPlugin '%s' is not supported on Azure Stack clouds", networkPlugin)
+			errs.add("orchestratorProfile.kubernetesConfig.networkPlugin", "", errors.Errorf("kubernetesConfig.networkPlugin '%s' is not supported on Azure Stack clouds", networkPlugin))
 		}
 		if a.MasterProfile.AvailabilityProfile == VirtualMachineScaleSets {
-			return errors.Errorf("masterProfile.availabilityProfile should be set to '%s' on Azure Stack clouds", AvailabilitySet)
+			errs.add("masterProfile.availabilityProfile", "", errors.Errorf("masterProfile.availabilityProfile should be set to '%s' on Azure Stack clouds", AvailabilitySet))
 		}
-		for _, agentPool := range a.AgentPoolProfiles {
-			pool := agentPool
+		for i, pool := range a.AgentPoolProfiles {
 			if pool.AvailabilityProfile != AvailabilitySet {
-				return errors.Errorf("agentPoolProfiles[%s].availabilityProfile should be set to '%s' on Azure Stack clouds", pool.Name, AvailabilitySet)
+				errs.add(fmt.Sprintf("agentPoolProfiles[%d].availabilityProfile", i), "", errors.Errorf("agentPoolProfiles[%s].availabilityProfile should be set to '%s' on Azure Stack clouds", pool.Name, AvailabilitySet))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (a *KubernetesAddon) validateArcAddonConfig() error {
@@ -1566,6 +1566,9 @@ func TestProperties_ValidateInvalidExtensionProfiles(t *testing.T) {
 			cs := getK8sDefaultContainerService(true)
 			cs.Properties.ExtensionProfiles = test.extensionProfiles
 			err := cs.Validate(true)
+			if err != nil {
+				err = errors.New(firstValidationError(err))
+			}
 			if !helpers.EqualError(err, test.expectedErr) {
 				t.Errorf("expected error with message : %s, but got %s", test.expectedErr.Error(), err.Error())
 			}
@@ -1926,6 +1929,57 @@ func TestValidateAddons(t *testing.T) {
 		isUpdate    bool
 		expectedErr error
 	}{
+		{
+			name: "kubelet-csr-approver addon w/ unknown node subnets",
+			p: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					KubernetesConfig: &KubernetesConfig{
+						Addons: []KubernetesAddon{
+							{
+								Name:    common.KubeletCSRApproverAddonName,
+								Enabled: to.BoolPtr(true),
+								Config:  map[string]string{"providerIPPrefixes": ""},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: errors.New("kubelet-csr-approver addon cannot determine the node subnets, set 'providerIPPrefixes' in its config or masterProfile.vnetCidr"),
+		},
+		{
+			name: "kubelet-csr-approver addon w/ invalid providerIPPrefixes",
+			p: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					KubernetesConfig: &KubernetesConfig{
+						Addons: []KubernetesAddon{
+							{
+								Name:    common.KubeletCSRApproverAddonName,
+								Enabled: to.BoolPtr(true),
+								Config:  map[string]string{"providerIPPrefixes": "10.240.0.0/16,10.241.0.0"},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: errors.New(`kubelet-csr-approver addon has an invalid 'providerIPPrefixes' config "10.240.0.0/16,10.241.0.0", must be a comma-separated list of CIDRs`),
+		},
+		{
+			name: "kubelet-csr-approver addon w/ providerIPPrefixes",
+			p: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					KubernetesConfig: &KubernetesConfig{
+						Addons: []KubernetesAddon{
+							{
+								Name:    common.KubeletCSRApproverAddonName,
+								Enabled: to.BoolPtr(true),
+								Config:  map[string]string{"providerIPPrefixes": "10.240.0.0/16, fc00::/48"},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: nil,
+		},
 		{
 			name: "aad addon enabled w/ no AADProfile",
 			p: &Properties{
@@ -3482,7 +3536,7 @@ func TestProperties_ValidateZones(t *testing.T) {
 				if err == nil {
 					t.Errorf("error should have occurred")
 				} else {
-					if err.Error() != test.expectedErrStr {
+					if firstValidationError(err) != test.expectedErrStr {
 						t.Errorf("expected error with message : %s, but got : %s", test.expectedErrStr, err.Error())
 					}
 				}
@@ -3841,7 +3895,7 @@ func TestProperties_ValidateSinglePlacementGroup(t *testing.T) {
 			cs.Properties.MasterProfile = test.masterProfile
 			cs.Properties.AgentPoolProfiles = test.agentPoolProfiles
 			err := cs.Validate(true)
-			if err.Error() != test.expectedMsg {
+			if firstValidationError(err) != test.expectedMsg {
 				t.Errorf("expected error message : %s, but got %s", test.expectedMsg, err.Error())
 			}
 		})
@@ -4130,7 +4184,7 @@ func TestProperties_ValidateVNET(t *testing.T) {
 			cs.Properties.MasterProfile = test.masterProfile
 			cs.Properties.AgentPoolProfiles = test.agentPoolProfiles
 			err := cs.Validate(true)
-			if err.Error() != test.expectedMsg {
+			if firstValidationError(err) != test.expectedMsg {
 				t.Errorf("expected error message : %s, but got %s", test.expectedMsg, err.Error())
 			}
 		})
@@ -4410,7 +4464,7 @@ func TestAgentPoolProfile_ValidateVirtualMachineScaleSet(t *testing.T) {
 		cs.Properties.MasterProfile.VnetSubnetID = "vnet"
 		cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.10.10.240"
 		expectedMsg := "when masterProfile's availabilityProfile is VirtualMachineScaleSets and a vnetSubnetID is specified, the firstConsecutiveStaticIP should be empty and will be determined by an offset from the first IP in the vnetCidr"
-		if err := cs.Properties.validateMasterProfile(false); err.Error() != expectedMsg {
+		if err := cs.Properties.validateMasterProfile(false); firstValidationError(err) != expectedMsg {
 			t.Errorf("expected error with message : %s, but got %s", expectedMsg, err.Error())
 		}
 	})
@@ -4966,6 +5020,9 @@ func TestValidateLocation(t *testing.T) {
 				cs.Properties = nil
 			}
 			gotErr := cs.Validate(false)
+			if gotErr != nil {
+				gotErr = errors.New(firstValidationError(gotErr))
+			}
 			if !helpers.EqualError(gotErr, test.expectedErr) {
 				t.Logf("scenario %q", test.name)
 				t.Errorf("expected error: %v, got: %v", test.expectedErr, gotErr)
@@ -5722,3 +5779,191 @@ func TestValidateConnectedClusterProfile(t *testing.T) {
 		}
 	})
 }
+
+func TestProperties_ValidateCertificateProfile(t *testing.T) {
+	cs := getK8sDefaultContainerService(true)
+	for _, algorithm := range append([]string{""}, helpers.PkiKeyAlgorithms...) {
+		cs.Properties.CertificateProfile = &CertificateProfile{KeyAlgorithm: algorithm}
+		if err := cs.Properties.validateCertificateProfile(); err != nil {
+			t.Errorf("unexpected error for key algorithm %q: %s", algorithm, err)
+		}
+	}
+
+	cs.Properties.CertificateProfile = &CertificateProfile{KeyAlgorithm: "DSA"}
+	expectedMsg := "keyAlgorithm 'DSA' is not supported, the supported key algorithms are RSA, ECDSA-P256, ECDSA-P384, Ed25519"
+	err := cs.Validate(false)
+	if err == nil || err.Error() != expectedMsg {
+		t.Errorf("expected error message : %s to be thrown, but got : %v", expectedMsg, err)
+	}
+	errs := cs.Properties.validateAll(false)
+	if len(errs) != 1 || errs[0].Path != "properties.certificateProfile.keyAlgorithm" || errs[0].Code != CodeInvalidValue {
+		t.Errorf("expected a single %s error of properties.certificateProfile.keyAlgorithm, but got : %v", CodeInvalidValue, errs)
+	}
+}
+
+func TestProperties_ValidateCertificateSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       *CertificateProfile
+		expectedPath  string
+		expectedError string
+	}{
+		{
+			name: "valid settings",
+			profile: &CertificateProfile{
+				CaSettings:        &CertificateSettings{ValidityDays: 3650, Organization: []string{"Contoso"}, Country: []string{"US"}},
+				APIServerSettings: &CertificateSettings{ValidityDays: 365, ExtraSANs: []string{"api.contoso.com", "*.contoso.com", "10.0.0.4", "fd00::4"}},
+				ClientSettings:    &CertificateSettings{OrganizationalUnit: []string{"Platform"}},
+			},
+		},
+		{
+			name:          "negative validity",
+			profile:       &CertificateProfile{KubeConfigSettings: &CertificateSettings{ValidityDays: -1}},
+			expectedPath:  "properties.certificateProfile.kubeConfigSettings.validityDays",
+			expectedError: "kubeConfigSettings.validityDays -1 must not be negative",
+		},
+		{
+			name: "certificate outliving the CA",
+			profile: &CertificateProfile{
+				CaSettings:   &CertificateSettings{ValidityDays: 365},
+				EtcdSettings: &CertificateSettings{ValidityDays: 730},
+			},
+			expectedPath:  "properties.certificateProfile.etcdSettings.validityDays",
+			expectedError: "etcdSettings.validityDays 730 exceeds the validity of the CA certificate, 365 days",
+		},
+		{
+			name:          "certificate outliving the default CA validity",
+			profile:       &CertificateProfile{ClientSettings: &CertificateSettings{ValidityDays: 20000}},
+			expectedPath:  "properties.certificateProfile.clientSettings.validityDays",
+			expectedError: "clientSettings.validityDays 20000 exceeds the validity of the CA certificate, 10950 days",
+		},
+		{
+			name:          "invalid country",
+			profile:       &CertificateProfile{CaSettings: &CertificateSettings{Country: []string{"USA"}}},
+			expectedPath:  "properties.certificateProfile.caSettings.country",
+			expectedError: "caSettings.country 'USA' is not a two-letter country code",
+		},
+		{
+			name:          "CA extra SANs",
+			profile:       &CertificateProfile{CaSettings: &CertificateSettings{ExtraSANs: []string{"ca.contoso.com"}}},
+			expectedPath:  "properties.certificateProfile.caSettings.extraSANs",
+			expectedError: "caSettings.extraSANs is not supported, the CA certificate has no SANs",
+		},
+		{
+			name:          "invalid extra SAN",
+			profile:       &CertificateProfile{APIServerSettings: &CertificateSettings{ExtraSANs: []string{"api_contoso"}}},
+			expectedPath:  "properties.certificateProfile.apiServerSettings.extraSANs",
+			expectedError: "apiServerSettings.extraSANs 'api_contoso' is neither an IP address nor a DNS name",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			cs := getK8sDefaultContainerService(true)
+			cs.Properties.CertificateProfile = test.profile
+			err := cs.Properties.validateCertificateProfile()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.expectedError {
+				t.Errorf("expected error message : %s to be thrown, but got : %v", test.expectedError, err)
+			}
+			errs := cs.Properties.validateAll(false)
+			if len(errs) != 1 || errs[0].Path != test.expectedPath {
+				t.Errorf("expected a single error of %s, but got : %v", test.expectedPath, errs)
+			}
+		})
+	}
+}
+
+func TestProperties_ValidateCertificateChain(t *testing.T) {
+	caPair, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: helpers.PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	otherPair, err := helpers.CreatePkiKeyCertPair(helpers.PkiKeyCertPairParams{CommonName: "other", PkiKeyAlgorithm: helpers.PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the other CA: %s", err)
+	}
+
+	cs := getK8sDefaultContainerService(true)
+	cs.Properties.CertificateProfile = &CertificateProfile{CaCertificateChain: otherPair.CertificatePem}
+	expectedMsg := "caCertificateChain requires caCertificate, the intermediate CA issued by the chain"
+	if err = cs.Properties.validateCertificateProfile(); err == nil || err.Error() != expectedMsg {
+		t.Errorf("expected error message : %s to be thrown, but got : %v", expectedMsg, err)
+	}
+
+	cs.Properties.CertificateProfile = &CertificateProfile{CaCertificate: caPair.CertificatePem, CaCertificateChain: otherPair.CertificatePem}
+	err = cs.Properties.validateCertificateProfile()
+	if err == nil || !strings.HasPrefix(err.Error(), "caCertificateChain does not issue caCertificate") {
+		t.Errorf("expected the chain not issuing the CA to be rejected, but got : %v", err)
+	}
+	errs := cs.Properties.validateAll(false)
+	if len(errs) != 1 || errs[0].Path != "properties.certificateProfile.caCertificateChain" {
+		t.Errorf("expected a single error of properties.certificateProfile.caCertificateChain, but got : %v", errs)
+	}
+}
+
+func TestProperties_ValidateSeparateCAs(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       *CertificateProfile
+		expectedPath  string
+		expectedError string
+	}{
+		{
+			name:    "separate CAs generated",
+			profile: &CertificateProfile{SeparateCAs: to.BoolPtr(true)},
+		},
+		{
+			name:    "separate CAs provided",
+			profile: &CertificateProfile{SeparateCAs: to.BoolPtr(true), CaCertificate: "ca", EtcdCaCertificate: "etcd-ca", EtcdCaPrivateKey: "etcd-ca-key"},
+		},
+		{
+			name:          "etcd CA without separate CAs",
+			profile:       &CertificateProfile{EtcdCaCertificate: "etcd-ca", EtcdCaPrivateKey: "etcd-ca-key"},
+			expectedPath:  "properties.certificateProfile.etcdCaCertificate",
+			expectedError: "etcdCaCertificate is only used when separateCAs is enabled",
+		},
+		{
+			name:          "front-proxy CA without private key",
+			profile:       &CertificateProfile{SeparateCAs: to.BoolPtr(true), FrontProxyCaCertificate: "front-proxy-ca"},
+			expectedPath:  "properties.certificateProfile.frontProxyCaPrivateKey",
+			expectedError: "frontProxyCaCertificate requires frontProxyCaPrivateKey",
+		},
+		{
+			name:          "etcd CA reusing the CA",
+			profile:       &CertificateProfile{SeparateCAs: to.BoolPtr(true), CaCertificate: "ca", EtcdCaCertificate: "ca\n", EtcdCaPrivateKey: "ca-key"},
+			expectedPath:  "properties.certificateProfile.etcdCaCertificate",
+			expectedError: "etcdCaCertificate must not be the caCertificate, etcd would trust every client certificate of the cluster",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			cs := getK8sDefaultContainerService(true)
+			cs.Properties.CertificateProfile = test.profile
+			err := cs.Properties.validateCertificateProfile()
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.expectedError {
+				t.Errorf("expected error message : %s to be thrown, but got : %v", test.expectedError, err)
+			}
+			errs := cs.Properties.validateAll(false)
+			if len(errs) != 1 || errs[0].Path != test.expectedPath {
+				t.Errorf("expected a single error of %s, but got : %v", test.expectedPath, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vlabs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/to"
+	"github.com/pkg/errors"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// Severity is the severity of a validation error
+type Severity string
+
+const (
+	// SeverityError fails the validation
+	SeverityError Severity = "error"
+	// SeverityWarning reports the use of deprecated properties without failing the validation
+	SeverityWarning Severity = "warning"
+)
+
+// Validation error codes, these are stable and can be matched by tools
+const (
+	CodeMissingProperty                = "MissingProperty"
+	CodeInvalidValue                   = "InvalidValue"
+	CodeOutOfRange                     = "OutOfRange"
+	CodeInvalidName                    = "InvalidName"
+	CodeDuplicateName                  = "DuplicateName"
+	CodeInvalidDNSPrefix               = "InvalidDNSPrefix"
+	CodeInvalidImageReference          = "InvalidImageReference"
+	CodeInvalidNodeLabel               = "InvalidNodeLabel"
+	CodeInvalidResourceID              = "InvalidResourceID"
+	CodeMixedAvailabilityProfiles      = "MixedAvailabilityProfiles"
+	CodeUnsupportedDistro              = "UnsupportedDistro"
+	CodeDeprecatedDistro               = "DeprecatedDistro"
+	CodeInvalidLocation                = "InvalidLocation"
+	CodeInvalidCustomCloudProfile      = "InvalidCustomCloudProfile"
+	CodeInvalidOrchestratorProfile     = "InvalidOrchestratorProfile"
+	CodeInvalidMasterProfile           = "InvalidMasterProfile"
+	CodeInvalidAgentPoolProfile        = "InvalidAgentPoolProfile"
+	CodeInvalidAvailabilityZones       = "InvalidAvailabilityZones"
+	CodeInvalidLinuxProfile            = "InvalidLinuxProfile"
+	CodeInvalidAddon                   = "InvalidAddon"
+	CodeInvalidExtension               = "InvalidExtension"
+	CodeInvalidVNET                    = "InvalidVNET"
+	CodeInvalidServicePrincipalProfile = "InvalidServicePrincipalProfile"
+	CodeInvalidAADProfile              = "InvalidAADProfile"
+	CodeInvalidCertificateProfile      = "InvalidCertificateProfile"
+	CodeInvalidCustomKubeComponent     = "InvalidCustomKubeComponent"
+	CodeUnsupportedOnAzureStack        = "UnsupportedOnAzureStack"
+	CodeInvalidWindowsProfile          = "InvalidWindowsProfile"
+	CodeDeprecatedProperty             = "DeprecatedProperty"
+	CodeDeprecatedAddon                = "DeprecatedAddon"
+	CodeEndOfLifeDistro                = "EndOfLifeDistro"
+)
+
+// ValidationError is a problem found in a cluster definition, at the JSON path of the property at fault
+type ValidationError struct {
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors are all the problems found in a cluster definition
+type ValidationErrors []*ValidationError
+
+// Errors returns the validation errors of severity error
+func (e ValidationErrors) Errors() ValidationErrors {
+	return e.withSeverity(SeverityError)
+}
+
+// Warnings returns the validation errors of severity warning
+func (e ValidationErrors) Warnings() ValidationErrors {
+	return e.withSeverity(SeverityWarning)
+}
+
+func (e ValidationErrors) withSeverity(severity Severity) ValidationErrors {
+	var filtered ValidationErrors
+	for _, err := range e {
+		if err.Severity == severity {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+// Err returns the validation errors as an error if at least one has severity error, nil otherwise
+func (e ValidationErrors) Err() error {
+	if len(e.Errors()) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error returns the message of a single error, or the list of all the errors
+func (e ValidationErrors) Error() string {
+	errs := e.Errors()
+	if len(errs) == 1 {
+		return errs[0].Message
+	}
+	return fmt.Sprintf("found %d validation errors:\n%s", len(errs), errs.List())
+}
+
+// List renders the validation errors as a list, one error per line
+func (e ValidationErrors) List() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, fmt.Sprintf("- %s %s [%s]: %s", err.Severity, err.Path, err.Code, err.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JSON renders the validation errors as a JSON array
+func (e ValidationErrors) JSON() ([]byte, error) {
+	if e == nil {
+		e = ValidationErrors{}
+	}
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// AsValidationErrors returns the validation errors wrapped by err, if any
+func AsValidationErrors(err error) (ValidationErrors, bool) {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
+
+// fieldError attributes a validation error to a property of the validated object
+type fieldError struct {
+	field string
+	code  string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// onField attributes err, if not nil, to the property field of the validated object with a specific code
+func onField(field, code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fieldError{field: field, code: code, err: err}
+}
+
+// fieldErrors are the errors of the properties of a validated object, a validation collects them
+// to report every invalid property instead of the first one
+type fieldErrors []*fieldError
+
+// add collects err, if not nil, as an error of the property field, e.g. dnsPrefix or [0].name, or of the
+// validated object itself if field is empty. An empty code is replaced by the code of the validation.
+// The field of the errors attributed by onField, or collected by a nested validation, is relative to field.
+func (e *fieldErrors) add(field, code string, err error) {
+	if err == nil {
+		return
+	}
+	if nested, ok := err.(fieldErrors); ok {
+		for _, f := range nested {
+			*e = append(*e, &fieldError{field: joinField(field, f.field), code: f.code, err: f.err})
+		}
+		return
+	}
+	var f *fieldError
+	if errors.As(err, &f) {
+		field, code = joinField(field, f.field), f.code
+	}
+	*e = append(*e, &fieldError{field: field, code: code, err: err})
+}
+
+// err returns nil if no error was collected, the error if there is only one, and all of them otherwise
+func (e fieldErrors) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// Error returns the messages of the errors, one per line
+func (e fieldErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, f := range e {
+		messages = append(messages, f.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// joinField returns the path of field relative to path
+func joinField(path, field string) string {
+	switch {
+	case field == "":
+		return path
+	case path == "" || strings.HasPrefix(field, "["):
+		return path + field
+	default:
+		return path + "." + field
+	}
+}
+
+// validationCollector collects the errors of the validations of a cluster definition
+type validationCollector struct {
+	errs ValidationErrors
+}
+
+// add collects err, if not nil, as an error of the object at path, or as the errors of its properties
+// if err holds the fieldErrors of a validation
+func (c *validationCollector) add(path, code string, err error) {
+	if err == nil {
+		return
+	}
+	var errs fieldErrors
+	errs.add("", "", err)
+	for _, f := range errs {
+		fieldCode := f.code
+		if fieldCode == "" {
+			fieldCode = code
+		}
+		c.errs = append(c.errs, &ValidationError{Path: joinField(path, f.field), Severity: SeverityError, Code: fieldCode, Message: f.Error()})
+	}
+}
+
+// warn collects a warning about the property at path
+func (c *validationCollector) warn(path, code, format string, args ...interface{}) {
+	c.errs = append(c.errs, &ValidationError{Path: path, Severity: SeverityWarning, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// addStructErrors collects the errors of the validate struct tags of Properties
+func (c *validationCollector) addStructErrors(errs validator.ValidationErrors) {
+	for _, fe := range errs {
+		code := CodeInvalidValue
+		switch fe.Tag() {
+		case "required":
+			code = CodeMissingProperty
+		case "min", "max":
+			code = CodeOutOfRange
+		}
+		c.errs = append(c.errs, &ValidationError{
+			Path:     structNamespaceToJSONPath(reflect.TypeOf(Properties{}), fe.Namespace()),
+			Severity: SeverityError,
+			Code:     code,
+			Message:  handleValidationErrors(validator.ValidationErrors{fe}).Error(),
+		})
+	}
+}
+
+// structNamespaceToJSONPath converts the namespace of a struct field, e.g. Properties.AgentPoolProfiles[0].VMSize,
+// to its JSON path, e.g. properties.agentPoolProfiles[0].vmSize
+func structNamespaceToJSONPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	path := []string{"properties"}
+	for _, part := range parts[1:] {
+		name, index := part, ""
+		if i := strings.Index(part, "["); i >= 0 {
+			name, index = part[:i], part[i:]
+		}
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				if tag := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]; tag != "" && tag != "-" {
+					name = tag
+				}
+				t = f.Type
+			}
+		}
+		path = append(path, name+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// deprecationWarnings returns a warning for every deprecated property set in the cluster definition
+func (a *Properties) deprecationWarnings(isUpdate bool) ValidationErrors {
+	c := &validationCollector{}
+	eolDistro := func(path string, distro Distro) {
+		if distro == Ubuntu || distro == AKSUbuntu1604 {
+			c.warn(path, CodeEndOfLifeDistro, "The '%s' distro uses Ubuntu 16.04-LTS, which is End of Life (EOL) and will no longer receive security updates", distro)
+		}
+	}
+	if a.MasterProfile != nil {
+		eolDistro("properties.masterProfile.distro", a.MasterProfile.Distro)
+	}
+	for i, pool := range a.AgentPoolProfiles {
+		eolDistro(fmt.Sprintf("properties.agentPoolProfiles[%d].distro", i), pool.Distro)
+	}
+
+	if a.OrchestratorProfile == nil || a.OrchestratorProfile.KubernetesConfig == nil {
+		return c.errs
+	}
+	const kubernetesConfigPath = "properties.orchestratorProfile.kubernetesConfig"
+	k := a.OrchestratorProfile.KubernetesConfig
+	if to.Bool(k.EnablePodSecurityPolicy) {
+		c.warn(kubernetesConfigPath+".enablePodSecurityPolicy", CodeDeprecatedProperty, "EnablePodSecurityPolicy is deprecated in favor of the addon %s.", common.PodSecurityPolicyAddonName)
+	}
+	if len(k.PodSecurityPolicyConfig) > 0 {
+		c.warn(kubernetesConfigPath+".podSecurityPolicyConfig", CodeDeprecatedProperty, "Raw manifest for PodSecurityPolicy using PodSecurityPolicyConfig is deprecated in favor of the addon %s. This will be ignored.", common.PodSecurityPolicyAddonName)
+	}
+	if k.DockerEngineVersion != "" {
+		c.warn(kubernetesConfigPath+".dockerEngineVersion", CodeDeprecatedProperty, "docker-engine is deprecated in favor of moby, but you passed in a dockerEngineVersion configuration. This will be ignored.")
+	}
+	for i, addon := range k.Addons {
+		if !addon.IsEnabled() {
+			continue
+		}
+		path := fmt.Sprintf("%s.addons[%d]", kubernetesConfigPath, i)
+		switch addon.Name {
+		case common.DashboardAddonName:
+			c.warn(path, CodeDeprecatedAddon, "The kube-dashboard addon is deprecated, we recommend you install the dashboard yourself, see https://github.com/kubernetes/dashboard")
+		case common.AzureCNINetworkMonitorAddonName:
+			c.warn(path, CodeDeprecatedAddon, "The Azure CNI networkmonitor addon has been deprecated, it will be marked as disabled")
+		case common.ReschedulerAddonName, common.ContainerMonitoringAddonName:
+			// these are errors when creating a cluster
+			if isUpdate {
+				c.warn(path, CodeDeprecatedAddon, "The %s addon has been deprecated and disabled, it will be removed during this update", addon.Name)
+			}
+		}
+	}
+	return c.errs
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vlabs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers/to"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// firstValidationError returns the message of the first error collected by the validation
+func firstValidationError(err error) string {
+	if errs, ok := AsValidationErrors(err); ok {
+		return errs.Errors()[0].Message
+	}
+	if errs, ok := err.(fieldErrors); ok {
+		return errs[0].Error()
+	}
+	return err.Error()
+}
+
+func TestValidateAll(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cs := getK8sDefaultContainerService(false)
+	cs.Properties.OrchestratorProfile.OrchestratorVersion = common.RationalizeReleaseAndVersion(Kubernetes, "", "", false, false, false)
+	cs.Properties.MasterProfile.DNSPrefix = "-invalid"
+	cs.Properties.AgentPoolProfiles[0].Name = "Invalid_Name"
+	cs.Properties.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		Addons: []KubernetesAddon{
+			{Name: common.DashboardAddonName, Enabled: to.BoolPtr(true)},
+		},
+	}
+
+	errs := cs.ValidateAll(false)
+	type result struct {
+		Path     string
+		Severity Severity
+		Code     string
+	}
+	var results []result
+	for _, e := range errs {
+		results = append(results, result{e.Path, e.Severity, e.Code})
+	}
+	g.Expect(results).To(Equal([]result{
+		{"properties.masterProfile.dnsPrefix", SeverityError, CodeInvalidDNSPrefix},
+		{"properties.agentPoolProfiles[0].name", SeverityError, CodeInvalidName},
+		{"properties.orchestratorProfile.kubernetesConfig.addons[0]", SeverityWarning, CodeDeprecatedAddon},
+	}))
+
+	err := cs.Validate(false)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(HavePrefix("found 2 validation errors:\n- error properties.masterProfile.dnsPrefix [InvalidDNSPrefix]: "))
+
+	// warnings alone do not fail the validation
+	cs.Properties.MasterProfile.DNSPrefix = "valid"
+	cs.Properties.AgentPoolProfiles[0].Name = "agentpool"
+	g.Expect(cs.ValidateAll(false).Warnings()).To(HaveLen(1))
+	g.Expect(cs.Validate(false)).To(Succeed())
+}
+
+func TestValidateAllSections(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cs := getK8sDefaultContainerService(false)
+	p := cs.Properties
+	p.OrchestratorProfile.OrchestratorVersion = common.RationalizeReleaseAndVersion(Kubernetes, "", "", false, false, true)
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{NetworkPlugin: NetworkPluginCilium, NetworkPolicy: NetworkPolicyCilium}
+	p.CustomCloudProfile = &CustomCloudProfile{}
+	p.MasterProfile.DNSPrefix = "-invalid"
+	p.MasterProfile.OSDiskCachingType = "invalid"
+	p.AgentPoolProfiles = append(p.AgentPoolProfiles, &AgentPoolProfile{Name: "vmsspool", VMSize: "Standard_D2_v2", Count: 1, AvailabilityProfile: VirtualMachineScaleSets})
+	p.ExtensionProfiles = []*ExtensionProfile{
+		{Name: "first", ExtensionParametersKeyVaultRef: &KeyvaultSecretRef{}},
+		{Name: "second", ExtensionParametersKeyVaultRef: &KeyvaultSecretRef{VaultID: "invalid", SecretName: "secret"}},
+	}
+
+	type result struct {
+		Path string
+		Code string
+	}
+	var results []result
+	for _, e := range p.validateAll(false).Errors() {
+		results = append(results, result{e.Path, e.Code})
+	}
+	// the validation of a section goes on after its first error
+	g.Expect(results).To(ContainElements(
+		result{"properties.masterProfile.osDiskCachingType", CodeInvalidValue},
+		result{"properties.masterProfile.dnsPrefix", CodeInvalidDNSPrefix},
+		result{"properties.agentPoolProfiles[1].availabilityProfile", CodeMixedAvailabilityProfiles},
+		result{"properties.extensionProfiles[0].parametersKeyvaultSecretRef.vaultID", CodeMissingProperty},
+		result{"properties.extensionProfiles[0].parametersKeyvaultSecretRef.secretName", CodeMissingProperty},
+		result{"properties.extensionProfiles[1].parametersKeyvaultSecretRef.vaultID", CodeInvalidResourceID},
+		result{"properties.orchestratorProfile.kubernetesConfig.networkPlugin", CodeUnsupportedOnAzureStack},
+		result{"properties.agentPoolProfiles[1].availabilityProfile", CodeUnsupportedOnAzureStack},
+	))
+}
+
+func TestFieldErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var nested fieldErrors
+	nested.add("name", CodeInvalidName, errors.New("invalid name"))
+	nested.add("", "", onField("distro", CodeUnsupportedDistro, errors.New("unsupported distro")))
+	var errs fieldErrors
+	errs.add("", "", nil)
+	g.Expect(errs.err()).To(BeNil())
+	errs.add("[0]", "", nested.err())
+	errs.add("[1]", "", errors.New("invalid pool"))
+	g.Expect(errs.Error()).To(Equal("invalid name\nunsupported distro\ninvalid pool"))
+
+	c := &validationCollector{}
+	c.add("properties.agentPoolProfiles", CodeInvalidAgentPoolProfile, errs.err())
+	g.Expect(c.errs.List()).To(Equal("- error properties.agentPoolProfiles[0].name [InvalidName]: invalid name\n" +
+		"- error properties.agentPoolProfiles[0].distro [UnsupportedDistro]: unsupported distro\n" +
+		"- error properties.agentPoolProfiles[1] [InvalidAgentPoolProfile]: invalid pool"))
+}
+
+func TestValidateAllStructErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cs := getK8sDefaultContainerService(false)
+	cs.Properties.MasterProfile.Count = 2
+	cs.Properties.AgentPoolProfiles[0].VMSize = ""
+	cs.Properties.LinuxProfile.SSH.PublicKeys = nil
+
+	errs := cs.ValidateAll(false)
+	g.Expect(errs).To(HaveLen(3))
+	paths := map[string]string{}
+	for _, e := range errs {
+		paths[e.Path] = e.Code
+	}
+	g.Expect(paths).To(Equal(map[string]string{
+		"properties.masterProfile.count":         CodeInvalidValue,
+		"properties.agentPoolProfiles[0].vmSize": CodeMissingProperty,
+		"properties.linuxProfile.ssh.publicKeys": CodeMissingProperty,
+	}))
+}
+
+func TestStructNamespaceToJSONPath(t *testing.T) {
+	cases := map[string]string{
+		"Properties.MasterProfile.DNSPrefix":                              "properties.masterProfile.dnsPrefix",
+		"Properties.AgentPoolProfiles[2].VMSize":                          "properties.agentPoolProfiles[2].vmSize",
+		"Properties.LinuxProfile.SSH.PublicKeys[0].KeyData":               "properties.linuxProfile.ssh.publicKeys[0].keyData",
+		"Properties.OrchestratorProfile.KubernetesConfig.Addons[1].Name":  "properties.orchestratorProfile.kubernetesConfig.addons[1].name",
+		"Properties.OrchestratorProfile.KubernetesConfig.UnknownProperty": "properties.orchestratorProfile.kubernetesConfig.UnknownProperty",
+	}
+	for namespace, expected := range cases {
+		if path := structNamespaceToJSONPath(reflect.TypeOf(Properties{}), namespace); path != expected {
+			t.Errorf("expected %s to convert to %s, got %s", namespace, expected, path)
+		}
+	}
+}
+
+func TestValidationErrorsRendering(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	errs := ValidationErrors{
+		{Path: "properties.masterProfile.count", Severity: SeverityError, Code: CodeInvalidValue, Message: "count must be 1, 3 or 5"},
+		{Path: "properties.masterProfile.distro", Severity: SeverityWarning, Code: CodeEndOfLifeDistro, Message: "ubuntu is EOL"},
+	}
+	g.Expect(errs.Err()).To(HaveOccurred())
+	g.Expect(errs.Error()).To(Equal("count must be 1, 3 or 5"))
+	g.Expect(errs.Warnings().Err()).NotTo(HaveOccurred())
+	g.Expect(errs.List()).To(Equal("- error properties.masterProfile.count [InvalidValue]: count must be 1, 3 or 5\n" +
+		"- warning properties.masterProfile.distro [EndOfLifeDistro]: ubuntu is EOL"))
+
+	b, err := errs.JSON()
+	g.Expect(err).NotTo(HaveOccurred())
+	var decoded []map[string]string
+	g.Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+	g.Expect(decoded[1]).To(Equal(map[string]string{
+		"path":     "properties.masterProfile.distro",
+		"severity": "warning",
+		"code":     "EndOfLifeDistro",
+		"message":  "ubuntu is EOL",
+	}))
+
+	b, err = ValidationErrors(nil).JSON()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(Equal("[]"))
+
+	wrapped, ok := AsValidationErrors(errors.Wrap(errs, "validating"))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(wrapped).To(HaveLen(2))
+	_, ok = AsValidationErrors(errors.New("other"))
+	g.Expect(ok).To(BeFalse())
+}
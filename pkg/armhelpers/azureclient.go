@@ -46,6 +46,20 @@ type AzureClient struct {
 	disksClient                *compute.DisksClient
 	availabilitySetsClient     *compute.AvailabilitySetsClient
 	virtualMachineImagesClient *compute.VirtualMachineImagesClient
+	operationTimeout           time.Duration
+}
+
+// OperationTimeout returns the timeout of an ARM operation, DefaultARMOperationTimeout unless set by SetOperationTimeout
+func (az *AzureClient) OperationTimeout() time.Duration {
+	return az.operationTimeout
+}
+
+// SetOperationTimeout sets the timeout of ARM operations. A non-positive timeout restores DefaultARMOperationTimeout.
+func (az *AzureClient) SetOperationTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultARMOperationTimeout
+	}
+	az.operationTimeout = timeout
 }
 
 // GetKubernetesClient returns a KubernetesClient hooked up to the api server at the apiserverURL.
@@ -56,7 +70,7 @@ func (az *AzureClient) GetKubernetesClient(apiserverURL, kubeConfig string, inte
 // NewAzureClientWithClientSecret returns an AzureClient via client_id and client_secret
 func NewAzureClient(subscriptionID string, credential azcore.TokenCredential, options *arm.ClientOptions) (*AzureClient, error) {
 	var err error
-	c := &AzureClient{}
+	c := &AzureClient{operationTimeout: DefaultARMOperationTimeout}
 	c.authorizationClient, err = authorization.NewRoleAssignmentsClient(subscriptionID, credential, options)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create role assignments client")
@@ -107,7 +121,7 @@ func NewAzureClient(subscriptionID string, credential azcore.TokenCredential, op
 
 // EnsureProvidersRegistered checks if the AzureClient is registered to required resource providers and, if not, register subscription to providers
 func (az *AzureClient) EnsureProvidersRegistered(subscriptionID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), az.OperationTimeout())
 	ctx = policy.WithHTTPHeader(ctx, az.acceptLanguageHeader)
 	defer cancel()
 	pager := az.providersClient.NewListPager(nil)
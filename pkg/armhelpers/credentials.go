@@ -4,6 +4,8 @@
 package armhelpers
 
 import (
+	"context"
+	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -13,6 +15,15 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// adfsTenantID is the tenant used to authenticate against an ADFS identity system
+	adfsTenantID = "adfs"
+	// azureCLIClientID is the public client application ID of the Azure CLI,
+	// used for interactive logins when no client ID is provided
+	azureCLIClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
 )
 
 // NewDefaultCredential returns an AzureClient
@@ -123,6 +134,99 @@ func NewClientCertificateCredentialExternalTenant(cloud cloud.Configuration, sub
 	return azidentity.NewClientCertificateCredential("adfs", clientID, []*x509.Certificate{certificate}, privateKey, options)
 }
 
+// NewClientCertificateCredentialFromPFX returns a credential authenticating via client_id and a PKCS#12 (pfx) certificate
+func NewClientCertificateCredentialFromPFX(cloud cloud.Configuration, subscriptionID, clientID, pfxPath, password string) (*azidentity.ClientCertificateCredential, error) {
+	certificates, privateKey, err := parsePFXCertificate(pfxPath, password)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, err := getOAuthConfig(subscriptionID, cloud)
+	if err != nil {
+		return nil, err
+	}
+	options := &azidentity.ClientCertificateCredentialOptions{
+		SendCertificateChain:     true,
+		DisableInstanceDiscovery: true,
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud,
+		},
+	}
+	return azidentity.NewClientCertificateCredential(tenantID, clientID, certificates, privateKey, options)
+}
+
+// NewClientCertificateCredentialFromPFXExternalTenant returns a credential authenticating via client_id and a PKCS#12 (pfx) certificate
+// from a 3rd party tenant
+func NewClientCertificateCredentialFromPFXExternalTenant(cloud cloud.Configuration, subscriptionID, clientID, pfxPath, password string) (*azidentity.ClientCertificateCredential, error) {
+	certificates, privateKey, err := parsePFXCertificate(pfxPath, password)
+	if err != nil {
+		return nil, err
+	}
+	options := &azidentity.ClientCertificateCredentialOptions{
+		SendCertificateChain:     true,
+		DisableInstanceDiscovery: true,
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud,
+		},
+	}
+	return azidentity.NewClientCertificateCredential(adfsTenantID, clientID, certificates, privateKey, options)
+}
+
+// NewDeviceCodeCredential returns a credential authenticating via an interactive device code login.
+// The Azure CLI public client is used if clientID is empty.
+func NewDeviceCodeCredential(cloud cloud.Configuration, subscriptionID, clientID string) (*azidentity.DeviceCodeCredential, error) {
+	tenantID, err := getOAuthConfig(subscriptionID, cloud)
+	if err != nil {
+		return nil, err
+	}
+	return azidentity.NewDeviceCodeCredential(deviceCodeCredentialOptions(cloud, tenantID, clientID))
+}
+
+// NewDeviceCodeCredentialExternalTenant returns a credential authenticating via an interactive device code login
+// from a 3rd party tenant. The Azure CLI public client is used if clientID is empty.
+func NewDeviceCodeCredentialExternalTenant(cloud cloud.Configuration, clientID string) (*azidentity.DeviceCodeCredential, error) {
+	return azidentity.NewDeviceCodeCredential(deviceCodeCredentialOptions(cloud, adfsTenantID, clientID))
+}
+
+// NewAzureCLICredential returns a credential reusing the access tokens of the Azure CLI.
+// The Azure CLI must be logged in to a cloud registered with the same endpoints as the target cloud.
+func NewAzureCLICredential(cloud cloud.Configuration, subscriptionID string) (*azidentity.AzureCLICredential, error) {
+	tenantID, err := getOAuthConfig(subscriptionID, cloud)
+	if err != nil {
+		return nil, err
+	}
+	return azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{
+		Subscription: subscriptionID,
+		TenantID:     tenantID,
+	})
+}
+
+// NewAzureCLICredentialExternalTenant returns a credential reusing the access tokens of an Azure CLI logged in to a 3rd party tenant.
+// The Azure CLI must be logged in to a cloud registered with the same endpoints as the target cloud.
+func NewAzureCLICredentialExternalTenant(subscriptionID string) (*azidentity.AzureCLICredential, error) {
+	// the Azure CLI does not accept "adfs" as a tenant, the tenant of the logged in account is used instead
+	return azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{
+		Subscription: subscriptionID,
+	})
+}
+
+func deviceCodeCredentialOptions(cloud cloud.Configuration, tenantID, clientID string) *azidentity.DeviceCodeCredentialOptions {
+	if clientID == "" {
+		clientID = azureCLIClientID
+	}
+	return &azidentity.DeviceCodeCredentialOptions{
+		TenantID:                 tenantID,
+		ClientID:                 clientID,
+		DisableInstanceDiscovery: true,
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud,
+		},
+		UserPrompt: func(ctx context.Context, msg azidentity.DeviceCodeMessage) error {
+			log.Infoln(msg.Message)
+			return nil
+		},
+	}
+}
+
 func getOAuthConfig(subscriptionID string, cloud cloud.Configuration) (string, error) {
 	tenantID, err := GetTenantID(subscriptionID, cloud)
 	if err != nil {
@@ -158,3 +262,15 @@ func parseRsaPrivateKey(path string) (*rsa.PrivateKey, error) {
 
 	return nil, errors.Errorf("failed to parse private key as Pkcs#1 or Pkcs#8. (%s). (%s)", errPkcs1, errPkcs8)
 }
+
+func parsePFXCertificate(path, password string) ([]*x509.Certificate, crypto.PrivateKey, error) {
+	pfxData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to read pfx certificate")
+	}
+	certificates, privateKey, err := azidentity.ParseCertificates(pfxData, []byte(password))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse pfx certificate")
+	}
+	return certificates, privateKey, nil
+}
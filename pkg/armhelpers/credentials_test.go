@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armhelpers
+
+import (
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	. "github.com/onsi/gomega"
+)
+
+// testdata/client.pfx holds a self-signed certificate and its RSA key, encrypted with the password "password"
+const testPFXPath = "testdata/client.pfx"
+
+func TestParsePFXCertificate(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	certificates, privateKey, err := parsePFXCertificate(testPFXPath, "password")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(certificates).To(HaveLen(1))
+	g.Expect(certificates[0].Subject.CommonName).To(Equal("aks-engine-azurestack-test"))
+	key, ok := privateKey.(*rsa.PrivateKey)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(key.PublicKey.Equal(certificates[0].PublicKey)).To(BeTrue())
+
+	_, _, err = parsePFXCertificate(testPFXPath, "wrong")
+	g.Expect(err).To(MatchError(ContainSubstring("Failed to parse pfx certificate")))
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pfx")
+	g.Expect(os.WriteFile(invalid, []byte("not a pfx certificate"), 0600)).To(Succeed())
+	_, _, err = parsePFXCertificate(invalid, "password")
+	g.Expect(err).To(MatchError(ContainSubstring("Failed to parse pfx certificate")))
+
+	_, _, err = parsePFXCertificate(filepath.Join(t.TempDir(), "missing.pfx"), "password")
+	g.Expect(err).To(MatchError(ContainSubstring("Failed to read pfx certificate")))
+}
+
+func TestNewClientCertificateCredentialFromPFXExternalTenant(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	credential, err := NewClientCertificateCredentialFromPFXExternalTenant(cloud.AzurePublic, "", "b829b379-ca1f-4f1d-91a2-0d26b244680d", testPFXPath, "password")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(credential).NotTo(BeNil())
+
+	_, err = NewClientCertificateCredentialFromPFXExternalTenant(cloud.AzurePublic, "", "b829b379-ca1f-4f1d-91a2-0d26b244680d", testPFXPath, "wrong")
+	g.Expect(err).To(MatchError(ContainSubstring("Failed to parse pfx certificate")))
+}
+
+func TestNewInteractiveCredentialsExternalTenant(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	options := deviceCodeCredentialOptions(cloud.AzurePublic, adfsTenantID, "")
+	g.Expect(options.TenantID).To(Equal(adfsTenantID))
+	g.Expect(options.ClientID).To(Equal(azureCLIClientID))
+	g.Expect(deviceCodeCredentialOptions(cloud.AzurePublic, adfsTenantID, "b829b379-ca1f-4f1d-91a2-0d26b244680d").ClientID).To(Equal("b829b379-ca1f-4f1d-91a2-0d26b244680d"))
+
+	deviceCode, err := NewDeviceCodeCredentialExternalTenant(cloud.AzurePublic, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deviceCode).NotTo(BeNil())
+
+	azureCLI, err := NewAzureCLICredentialExternalTenant("6dc93fae-9a76-421f-bbe5-cc6460ea81cb")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(azureCLI).NotTo(BeNil())
+}
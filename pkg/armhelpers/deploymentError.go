@@ -54,7 +54,7 @@ func (e *DeploymentValidationError) Error() string {
 
 // DeployTemplateSync deploys the template and returns ArmError
 func DeployTemplateSync(az AKSEngineClient, logger *logrus.Entry, resourceGroupName, deploymentName string, template map[string]interface{}, parameters map[string]interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), az.OperationTimeout())
 	defer cancel()
 	deploymentExtended, err := az.DeployTemplate(ctx, resourceGroupName, deploymentName, template, parameters)
 	if err == nil {
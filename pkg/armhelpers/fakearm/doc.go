@@ -0,0 +1,11 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package fakearm provides a stateful, in-process emulator of the Azure Stack Hub
+// Resource Manager APIs used by AKS Engine, for running commands end to end offline.
+//
+// The deploy, scale (up) and addpool commands run end to end against it. Only the ARM APIs
+// are emulated, so upgrade cannot: it waits for every upgraded node to be Ready through the
+// Kubernetes API server. There is no delete command, a cluster is deleted with its resource
+// group, which the emulator supports.
+package fakearm
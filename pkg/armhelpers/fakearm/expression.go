@@ -0,0 +1,695 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package fakearm
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// evalContext holds what an ARM template expression can refer to
+type evalContext struct {
+	subscriptionID string
+	resourceGroup  string
+	location       string
+	parameters     map[string]interface{}
+	variables      map[string]interface{}
+	resolvedVars   map[string]interface{}
+	resolvingVars  map[string]bool
+	copyIndex      *int
+	// reference resolves the reference() function against the deployed resources
+	reference func(resourceID string, full bool) (interface{}, error)
+}
+
+// withCopyIndex returns a copy of the context evaluating the given copy loop iteration
+func (c *evalContext) withCopyIndex(i int) *evalContext {
+	copied := *c
+	copied.copyIndex = &i
+	return &copied
+}
+
+// evalValue evaluates every template expression found in v
+func (c *evalContext) evalValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return c.evalString(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			e, err := c.evalValue(child)
+			if err != nil {
+				return nil, errors.Wrap(err, k)
+			}
+			out[k] = e
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			e, err := c.evalValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	default:
+		return t, nil
+	}
+}
+
+// evalValueLenient evaluates the template expressions found in v,
+// leaving the expressions that cannot be evaluated unchanged
+func (c *evalContext) evalValueLenient(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		e, err := c.evalString(t)
+		if err != nil {
+			return t
+		}
+		return e
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			out[k] = c.evalValueLenient(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			out[i] = c.evalValueLenient(child)
+		}
+		return out
+	default:
+		return t
+	}
+}
+
+// evalString evaluates s if it is a template expression, e.g. "[concat('a', 'b')]"
+func (c *evalContext) evalString(s string) (interface{}, error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return s, nil
+	}
+	if strings.HasPrefix(s, "[[") {
+		// escaped literal
+		return s[1:], nil
+	}
+	p := &exprParser{input: s[1 : len(s)-1]}
+	v, err := p.parseExpression(c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "evaluating %q", s)
+	}
+	p.skipSpaces()
+	if p.pos != len(p.input) {
+		return nil, errors.Errorf("evaluating %q: unexpected %q at position %d", s, p.input[p.pos:], p.pos)
+	}
+	return v, nil
+}
+
+func (c *evalContext) variable(name string) (interface{}, error) {
+	if v, ok := c.resolvedVars[name]; ok {
+		return v, nil
+	}
+	raw, ok := c.variables[name]
+	if !ok {
+		return nil, errors.Errorf("variable %q is not defined", name)
+	}
+	if c.resolvingVars[name] {
+		return nil, errors.Errorf("variable %q refers to itself", name)
+	}
+	c.resolvingVars[name] = true
+	defer delete(c.resolvingVars, name)
+	// variables never depend on a copy loop
+	root := *c
+	root.copyIndex = nil
+	v, err := root.evalValue(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "variable %q", name)
+	}
+	c.resolvedVars[name] = v
+	return v, nil
+}
+
+func (c *evalContext) parameter(name string) (interface{}, error) {
+	v, ok := c.parameters[name]
+	if !ok {
+		return nil, errors.Errorf("parameter %q is not defined", name)
+	}
+	return v, nil
+}
+
+// exprParser is a recursive descent parser evaluating ARM template expressions as it parses them
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) expect(b byte) error {
+	if p.peek() != b {
+		return errors.Errorf("expected %q at position %d", b, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) parseExpression(c *evalContext) (interface{}, error) {
+	v, err := p.parsePrimary(c)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			name := p.parseIdentifier()
+			if name == "" {
+				return nil, errors.Errorf("expected property name at position %d", p.pos)
+			}
+			if v, err = property(v, name); err != nil {
+				return nil, err
+			}
+		case '[':
+			p.pos++
+			index, err := p.parseExpression(c)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(']'); err != nil {
+				return nil, err
+			}
+			if v, err = indexValue(v, index); err != nil {
+				return nil, err
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary(c *evalContext) (interface{}, error) {
+	switch b := p.peek(); {
+	case b == '\'':
+		return p.parseString()
+	case b == '-' || (b >= '0' && b <= '9'):
+		return p.parseNumber()
+	case b == 0:
+		return nil, errors.New("unexpected end of expression")
+	}
+	name := p.parseIdentifier()
+	if name == "" {
+		return nil, errors.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	if p.peek() != ')' {
+		for {
+			arg, err := p.parseExpression(c)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	return c.call(name, args)
+}
+
+func (p *exprParser) parseIdentifier() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) {
+		r := rune(p.input[p.pos])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *exprParser) parseString() (interface{}, error) {
+	p.pos++ // opening quote
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		p.pos++
+		if ch != '\'' {
+			sb.WriteByte(ch)
+			continue
+		}
+		// '' is an escaped quote
+		if p.pos < len(p.input) && p.input[p.pos] == '\'' {
+			sb.WriteByte('\'')
+			p.pos++
+			continue
+		}
+		return sb.String(), nil
+	}
+	return nil, errors.New("unterminated string literal")
+}
+
+func (p *exprParser) parseNumber() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// call evaluates the template function name
+func (c *evalContext) call(name string, args []interface{}) (interface{}, error) {
+	fn := strings.ToLower(name)
+	switch fn {
+	case "parameters", "variables":
+		if len(args) != 1 {
+			return nil, errors.Errorf("%s() expects 1 argument", name)
+		}
+		if fn == "parameters" {
+			return c.parameter(toString(args[0]))
+		}
+		return c.variable(toString(args[0]))
+	case "copyindex":
+		if c.copyIndex == nil {
+			return nil, errors.New("copyIndex() used outside of a copy loop")
+		}
+		offset := 0
+		for _, a := range args {
+			// copyIndex('loopName') is not supported, only copyIndex(offset)
+			if n, err := toInt(a); err == nil {
+				offset = n
+			}
+		}
+		return *c.copyIndex + offset, nil
+	case "concat":
+		if len(args) > 0 {
+			if _, ok := args[0].([]interface{}); ok {
+				var out []interface{}
+				for _, a := range args {
+					arr, _ := a.([]interface{})
+					out = append(out, arr...)
+				}
+				return out, nil
+			}
+		}
+		var sb strings.Builder
+		for _, a := range args {
+			sb.WriteString(toString(a))
+		}
+		return sb.String(), nil
+	case "add", "sub", "mul", "div", "mod":
+		if len(args) != 2 {
+			return nil, errors.Errorf("%s() expects 2 arguments", name)
+		}
+		a, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		switch fn {
+		case "add":
+			return a + b, nil
+		case "sub":
+			return a - b, nil
+		case "mul":
+			return a * b, nil
+		}
+		if b == 0 {
+			return nil, errors.Errorf("%s() by zero", name)
+		}
+		if fn == "div" {
+			return a / b, nil
+		}
+		return a % b, nil
+	case "int":
+		return toInt(arg(args, 0))
+	case "string":
+		return toString(arg(args, 0)), nil
+	case "bool":
+		return toBool(arg(args, 0)), nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "length":
+		switch t := arg(args, 0).(type) {
+		case []interface{}:
+			return len(t), nil
+		case map[string]interface{}:
+			return len(t), nil
+		default:
+			return len(toString(t)), nil
+		}
+	case "empty":
+		switch t := arg(args, 0).(type) {
+		case nil:
+			return true, nil
+		case []interface{}:
+			return len(t) == 0, nil
+		case map[string]interface{}:
+			return len(t) == 0, nil
+		default:
+			return toString(t) == "", nil
+		}
+	case "if":
+		if len(args) != 3 {
+			return nil, errors.New("if() expects 3 arguments")
+		}
+		if toBool(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	case "equals":
+		return fmt.Sprint(arg(args, 0)) == fmt.Sprint(arg(args, 1)), nil
+	case "not":
+		return !toBool(arg(args, 0)), nil
+	case "and", "or":
+		result := fn == "and"
+		for _, a := range args {
+			if fn == "and" {
+				result = result && toBool(a)
+			} else {
+				result = result || toBool(a)
+			}
+		}
+		return result, nil
+	case "greater", "less", "greaterorequals", "lessorequals":
+		a, err := toInt(arg(args, 0))
+		if err != nil {
+			return nil, err
+		}
+		b, err := toInt(arg(args, 1))
+		if err != nil {
+			return nil, err
+		}
+		switch fn {
+		case "greater":
+			return a > b, nil
+		case "less":
+			return a < b, nil
+		case "greaterorequals":
+			return a >= b, nil
+		}
+		return a <= b, nil
+	case "tolower":
+		return strings.ToLower(toString(arg(args, 0))), nil
+	case "toupper":
+		return strings.ToUpper(toString(arg(args, 0))), nil
+	case "trim":
+		return strings.TrimSpace(toString(arg(args, 0))), nil
+	case "replace":
+		return strings.ReplaceAll(toString(arg(args, 0)), toString(arg(args, 1)), toString(arg(args, 2))), nil
+	case "split":
+		parts := strings.Split(toString(arg(args, 0)), toString(arg(args, 1)))
+		out := make([]interface{}, len(parts))
+		for i := range parts {
+			out[i] = parts[i]
+		}
+		return out, nil
+	case "startswith":
+		return strings.HasPrefix(strings.ToLower(toString(arg(args, 0))), strings.ToLower(toString(arg(args, 1)))), nil
+	case "endswith":
+		return strings.HasSuffix(strings.ToLower(toString(arg(args, 0))), strings.ToLower(toString(arg(args, 1)))), nil
+	case "contains":
+		switch t := arg(args, 0).(type) {
+		case []interface{}:
+			for _, item := range t {
+				if fmt.Sprint(item) == fmt.Sprint(arg(args, 1)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			_, ok := t[toString(arg(args, 1))]
+			return ok, nil
+		default:
+			return strings.Contains(toString(t), toString(arg(args, 1))), nil
+		}
+	case "take", "skip":
+		n, err := toInt(arg(args, 1))
+		if err != nil {
+			return nil, err
+		}
+		switch t := arg(args, 0).(type) {
+		case []interface{}:
+			n = clamp(n, len(t))
+			if fn == "take" {
+				return t[:n], nil
+			}
+			return t[n:], nil
+		default:
+			s := toString(t)
+			n = clamp(n, len(s))
+			if fn == "take" {
+				return s[:n], nil
+			}
+			return s[n:], nil
+		}
+	case "first", "last":
+		switch t := arg(args, 0).(type) {
+		case []interface{}:
+			if len(t) == 0 {
+				return nil, nil
+			}
+			if fn == "first" {
+				return t[0], nil
+			}
+			return t[len(t)-1], nil
+		default:
+			s := toString(t)
+			if s == "" {
+				return "", nil
+			}
+			if fn == "first" {
+				return s[:1], nil
+			}
+			return s[len(s)-1:], nil
+		}
+	case "createarray":
+		return append([]interface{}{}, args...), nil
+	case "format":
+		s := toString(arg(args, 0))
+		for i := 1; i < len(args); i++ {
+			s = strings.ReplaceAll(s, fmt.Sprintf("{%d}", i-1), toString(args[i]))
+		}
+		return s, nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(toString(arg(args, 0)))), nil
+	case "json":
+		var v interface{}
+		if err := json.Unmarshal([]byte(toString(arg(args, 0))), &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "uniquestring":
+		h := sha256.Sum256([]byte(joinArgs(args)))
+		return hex.EncodeToString(h[:])[:13], nil
+	case "guid":
+		return uuid.NewSHA1(uuid.NameSpaceURL, []byte(joinArgs(args))).String(), nil
+	case "resourcegroup":
+		return map[string]interface{}{
+			"id":       fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", c.subscriptionID, c.resourceGroup),
+			"name":     c.resourceGroup,
+			"location": c.location,
+		}, nil
+	case "subscription":
+		return map[string]interface{}{
+			"id":             fmt.Sprintf("/subscriptions/%s", c.subscriptionID),
+			"subscriptionId": c.subscriptionID,
+		}, nil
+	case "resourceid":
+		return c.resourceID(args)
+	case "reference":
+		if c.reference == nil {
+			return nil, errors.New("reference() is not supported")
+		}
+		full := len(args) > 2 && strings.EqualFold(toString(args[2]), "Full")
+		return c.reference(toString(arg(args, 0)), full)
+	default:
+		return nil, errors.Errorf("template function %s() is not supported", name)
+	}
+}
+
+// resourceID evaluates resourceId([subscriptionId], [resourceGroupName], resourceType, resourceName1, ...)
+func (c *evalContext) resourceID(args []interface{}) (interface{}, error) {
+	strs := make([]string, len(args))
+	for i := range args {
+		strs[i] = toString(args[i])
+	}
+	typeIndex := -1
+	for i, s := range strs {
+		if strings.Contains(s, "/") && strings.Contains(s, ".") {
+			typeIndex = i
+			break
+		}
+	}
+	if typeIndex < 0 || typeIndex > 2 {
+		return nil, errors.Errorf("resourceId(%s): cannot find the resource type", strings.Join(strs, ", "))
+	}
+	sub, rg := c.subscriptionID, c.resourceGroup
+	switch typeIndex {
+	case 1:
+		rg = strs[0]
+	case 2:
+		sub, rg = strs[0], strs[1]
+	}
+	typeParts := strings.Split(strs[typeIndex], "/")
+	names := strs[typeIndex+1:]
+	if len(names) != len(typeParts)-1 {
+		return nil, errors.Errorf("resourceId(%s): the number of names does not match the resource type", strings.Join(strs, ", "))
+	}
+	id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s", sub, rg, typeParts[0])
+	for i, name := range names {
+		id += "/" + typeParts[i+1] + "/" + name
+	}
+	return id, nil
+}
+
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func joinArgs(args []interface{}) string {
+	strs := make([]string, len(args))
+	for i := range args {
+		strs[i] = toString(args[i])
+	}
+	return strings.Join(strs, "-")
+}
+
+func clamp(n, max int) int {
+	if n < 0 {
+		return 0
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func property(v interface{}, name string) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("cannot read property %q of a %T", name, v)
+	}
+	if p, ok := m[name]; ok {
+		return p, nil
+	}
+	for k, p := range m {
+		if strings.EqualFold(k, name) {
+			return p, nil
+		}
+	}
+	return nil, errors.Errorf("property %q does not exist", name)
+}
+
+func indexValue(v, index interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case []interface{}:
+		i, err := toInt(index)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(t) {
+			return nil, errors.Errorf("index %d is out of range", i)
+		}
+		return t[i], nil
+	case map[string]interface{}:
+		return property(t, toString(index))
+	default:
+		return nil, errors.Errorf("cannot index a %T", v)
+	}
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(t)
+		return string(b)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func toInt(v interface{}) (int, error) {
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	case float64:
+		return int(t), nil
+	case json.Number:
+		n, err := t.Int64()
+		return int(n), err
+	case string:
+		return strconv.Atoi(strings.TrimSpace(t))
+	default:
+		return 0, errors.Errorf("cannot convert %v to an integer", v)
+	}
+}
+
+func toBool(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case string:
+		b, _ := strconv.ParseBool(t)
+		return b
+	case nil:
+		return false
+	default:
+		n, err := toInt(t)
+		return err == nil && n != 0
+	}
+}
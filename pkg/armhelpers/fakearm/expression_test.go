@@ -0,0 +1,95 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package fakearm
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func newTestEvalContext() *evalContext {
+	return &evalContext{
+		subscriptionID: DefaultSubscriptionID,
+		resourceGroup:  "rg",
+		location:       "local",
+		parameters: map[string]interface{}{
+			"name":  "master",
+			"count": float64(3),
+			"tags":  map[string]interface{}{"Pool": "agentpool"},
+		},
+		variables: map[string]interface{}{
+			"prefix":   "[concat('k8s-', parameters('name'))]",
+			"loop":     "[variables('loop')]",
+			"sizes":    []interface{}{"small", "large"},
+			"nicNames": "[concat(variables('prefix'), '-nic')]",
+		},
+		resolvedVars:  map[string]interface{}{},
+		resolvingVars: map[string]bool{},
+	}
+}
+
+func TestEvalString(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"plain", "plain"},
+		{"[[escaped]", "[escaped]"},
+		{"[concat(variables('prefix'), '-', copyIndex(1))]", "k8s-master-3"},
+		{"[variables('nicNames')]", "k8s-master-nic"},
+		{"[add(parameters('count'), 2)]", 5},
+		{"[if(equals(parameters('count'), 3), 'three', 'other')]", "three"},
+		{"[toLower(parameters('tags').pool)]", "agentpool"},
+		{"[variables('sizes')[1]]", "large"},
+		{"[length(variables('sizes'))]", 2},
+		{"[format('{0}-{1}', 'a', 'b')]", "a-b"},
+		{"[replace('it''s', '''', '')]", "its"},
+		{"[resourceGroup().location]", "local"},
+		{"[resourceId('Microsoft.Compute/virtualMachines/extensions', 'vm', 'cse')]", "/subscriptions/" + DefaultSubscriptionID + "/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm/extensions/cse"},
+		{"[split('a,b', ',')]", []interface{}{"a", "b"}},
+		{"[and(true(), not(false()))]", true},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.expression, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			v, err := newTestEvalContext().withCopyIndex(2).evalString(c.expression)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(v).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestEvalStringErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := newTestEvalContext()
+	_, err := c.evalString("[variables('loop')]")
+	g.Expect(err).To(MatchError(ContainSubstring(`variable "loop" refers to itself`)))
+	_, err = c.evalString("[parameters('missing')]")
+	g.Expect(err).To(MatchError(ContainSubstring(`parameter "missing" is not defined`)))
+	_, err = c.evalString("[copyIndex()]")
+	g.Expect(err).To(HaveOccurred())
+	_, err = c.evalString("[concat('a') extra]")
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(c.evalValueLenient("[unknownFunction()]")).To(Equal("[unknownFunction()]"))
+}
+
+func TestEvalUniqueFunctions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := newTestEvalContext()
+	a, err := c.evalString("[uniqueString(resourceGroup().id)]")
+	g.Expect(err).NotTo(HaveOccurred())
+	b, err := c.evalString("[uniqueString(resourceGroup().id)]")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(a).To(Equal(b))
+	g.Expect(a).To(HaveLen(13))
+
+	guid, err := c.evalString("[guid('a', 'b')]")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(guid).To(HaveLen(36))
+}
@@ -0,0 +1,788 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package fakearm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PowerStateRunning is the power state of a running virtual machine
+	PowerStateRunning = "PowerState/running"
+	// PowerStateStopped is the power state of a stopped virtual machine
+	PowerStateStopped = "PowerState/stopped"
+	// PowerStateDeallocated is the power state of a deallocated virtual machine
+	PowerStateDeallocated = "PowerState/deallocated"
+
+	// DefaultSubscriptionID is the subscription emulated by a Server created without options
+	DefaultSubscriptionID = "cc6b141e-6afc-4786-9bf6-e3b9a5601460"
+	// DefaultTenantID is the tenant emulated by a Server created without options
+	DefaultTenantID = "19590a3f-b1af-4e6b-8f63-f917cbf40711"
+)
+
+// DefaultProviders are the resource providers known to a new Server
+var DefaultProviders = []string{"Microsoft.Compute", "Microsoft.Storage", "Microsoft.Network", "Microsoft.Authorization", "Microsoft.Resources"}
+
+// Server is a stateful, in-process emulator of the Azure Stack Hub Resource Manager APIs used by AKS Engine.
+// It emulates resource groups, template deployments, virtual machines and their power states,
+// managed disks, network interfaces, role assignments, storage account keys and resource providers.
+//
+// Deployed templates are evaluated: copy loops, parameters, variables and the common template
+// functions are resolved into virtual machine, network interface, disk and role assignment records.
+//
+// Virtual machine scale sets are not emulated: Azure Stack Hub clusters only use availability sets,
+// and a deployed scale set is stored as a plain resource without instances.
+type Server struct {
+	subscriptionID        string
+	tenantID              string
+	storageEndpointSuffix string
+	statePath             string
+
+	mu    sync.Mutex
+	state *serverState
+}
+
+// serverState is the emulated ARM state, persisted to disk between CLI invocations if a state file is set
+type serverState struct {
+	Providers       map[string]string                 `json:"providers"`
+	ResourceGroups  map[string]*resourceGroup         `json:"resourceGroups"`
+	RoleAssignments map[string]map[string]interface{} `json:"roleAssignments"`
+	PowerStates     map[string]string                 `json:"powerStates"`
+}
+
+type resourceGroup struct {
+	Name        string                            `json:"name"`
+	Location    string                            `json:"location"`
+	Tags        map[string]interface{}            `json:"tags,omitempty"`
+	ManagedBy   string                            `json:"managedBy,omitempty"`
+	Deployments map[string]*deployment            `json:"deployments"`
+	Resources   map[string]map[string]interface{} `json:"resources"`
+}
+
+type deployment struct {
+	Name       string                 `json:"name"`
+	Template   map[string]interface{} `json:"template"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Outputs    map[string]interface{} `json:"outputs"`
+	Timestamp  string                 `json:"timestamp"`
+	Operations []*deploymentOperation `json:"operations"`
+}
+
+type deploymentOperation struct {
+	OperationID  string `json:"operationId"`
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+}
+
+func newDeploymentOperation(resource map[string]interface{}) *deploymentOperation {
+	return &deploymentOperation{
+		OperationID:  strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", ""))[:16],
+		ResourceID:   toString(resource["id"]),
+		ResourceName: toString(resource["name"]),
+		ResourceType: toString(resource["type"]),
+	}
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithSubscriptionID sets the emulated subscription
+func WithSubscriptionID(subscriptionID string) Option {
+	return func(s *Server) { s.subscriptionID = subscriptionID }
+}
+
+// WithTenantID sets the tenant reported by the emulated subscription
+func WithTenantID(tenantID string) Option {
+	return func(s *Server) { s.tenantID = tenantID }
+}
+
+// WithStorageEndpointSuffix sets the storage endpoint suffix of the emulated storage accounts
+func WithStorageEndpointSuffix(suffix string) Option {
+	return func(s *Server) { s.storageEndpointSuffix = suffix }
+}
+
+// WithStateFile persists the emulated state to path after every change,
+// and loads it from path when the server is created if the file exists.
+func WithStateFile(path string) Option {
+	return func(s *Server) { s.statePath = path }
+}
+
+// NewServer returns a Server with no resource groups
+func NewServer(options ...Option) (*Server, error) {
+	s := &Server{
+		subscriptionID:        DefaultSubscriptionID,
+		tenantID:              DefaultTenantID,
+		storageEndpointSuffix: "local.azurestack.external",
+		state: &serverState{
+			Providers:       map[string]string{},
+			ResourceGroups:  map[string]*resourceGroup{},
+			RoleAssignments: map[string]map[string]interface{}{},
+			PowerStates:     map[string]string{},
+		},
+	}
+	for _, p := range DefaultProviders {
+		s.state.Providers[p] = "Registered"
+	}
+	for _, o := range options {
+		o(s)
+	}
+	if s.statePath != "" {
+		b, err := os.ReadFile(s.statePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "reading fake ARM state %s", s.statePath)
+		}
+		if err == nil {
+			if err = json.Unmarshal(b, s.state); err != nil {
+				return nil, errors.Wrapf(err, "parsing fake ARM state %s", s.statePath)
+			}
+		}
+	}
+	return s, nil
+}
+
+// SubscriptionID returns the emulated subscription
+func (s *Server) SubscriptionID() string {
+	return s.subscriptionID
+}
+
+// SetProviderRegistrationState sets the registration state of a resource provider, e.g. "NotRegistered"
+func (s *Server) SetProviderRegistrationState(namespace, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Providers[namespace] = state
+}
+
+// SetPowerState sets the power state of a virtual machine, e.g. PowerStateStopped
+func (s *Server) SetPowerState(resourceGroupName, vmName, powerState string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := resourceIDFromTypeAndName(s.subscriptionID, resourceGroupName, "Microsoft.Compute/virtualMachines", vmName)
+	s.state.PowerStates[strings.ToLower(id)] = powerState
+}
+
+// Resources returns the resources of the given type deployed in a resource group, sorted by name
+func (s *Server) Resources(resourceGroupName, resourceType string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rg, ok := s.state.ResourceGroups[strings.ToLower(resourceGroupName)]
+	if !ok {
+		return nil
+	}
+	return rg.list(resourceType)
+}
+
+// Transport returns a policy.Transporter-compatible client that serves requests in-process
+func (s *Server) Transport() *Transport {
+	return &Transport{server: s}
+}
+
+// Transport sends requests to a Server without a network listener
+type Transport struct {
+	server *Server
+}
+
+// Do serves the request
+func (t *Transport) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.server.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, body := s.route(r)
+	if status < 300 && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if err := s.save(); err != nil {
+			status, body = errorResponse(http.StatusInternalServerError, "InternalServerError", err.Error())
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("x-ms-request-id", uuid.New().String())
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer authorization_uri="https://login.microsoftonline.com/%s", error="invalid_token"`, s.tenantID))
+	}
+	w.WriteHeader(status)
+	if body != nil && r.Method != http.MethodHead {
+		b, _ := json.Marshal(body)
+		_, _ = w.Write(b)
+	}
+}
+
+func (s *Server) save() error {
+	if s.statePath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.statePath, b, 0600)
+}
+
+// route dispatches a request on its path, e.g.
+// /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.Compute/virtualMachines/{vm}
+func (s *Server) route(r *http.Request) (int, interface{}) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	lower := make([]string, len(segments))
+	for i := range segments {
+		lower[i] = strings.ToLower(segments[i])
+	}
+	if len(lower) < 2 || lower[0] != "subscriptions" {
+		return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+	}
+	if !strings.EqualFold(segments[1], s.subscriptionID) {
+		return errorResponse(http.StatusNotFound, "SubscriptionNotFound", fmt.Sprintf("the subscription %s could not be found", segments[1]))
+	}
+	if r.Header.Get("Authorization") == "" {
+		return errorResponse(http.StatusUnauthorized, "AuthenticationFailed", "authentication failed, the Authorization header is missing")
+	}
+	if i := indexOf(lower, "microsoft.authorization"); i > 0 && lower[i-1] == "providers" {
+		return s.routeRoleAssignments(r, "/"+strings.Join(segments[:i-1], "/"), segments[i+1:])
+	}
+	rest := segments[2:]
+	switch {
+	case len(rest) == 0:
+		return http.StatusOK, map[string]interface{}{
+			"id":             "/subscriptions/" + s.subscriptionID,
+			"subscriptionId": s.subscriptionID,
+			"tenantId":       s.tenantID,
+			"displayName":    "fake Azure Stack Hub subscription",
+			"state":          "Enabled",
+		}
+	case strings.EqualFold(rest[0], "providers"):
+		return s.routeSubscriptionProviders(r, rest[1:])
+	case strings.EqualFold(rest[0], "resourcegroups"):
+		return s.routeResourceGroups(r, rest[1:])
+	}
+	return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+}
+
+func (s *Server) routeSubscriptionProviders(r *http.Request, rest []string) (int, interface{}) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		namespaces := make([]string, 0, len(s.state.Providers))
+		for ns := range s.state.Providers {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+		value := []interface{}{}
+		for _, ns := range namespaces {
+			value = append(value, s.provider(ns))
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		if ns, ok := s.findProvider(rest[0]); ok {
+			return http.StatusOK, s.provider(ns)
+		}
+	case len(rest) == 2 && strings.EqualFold(rest[1], "register") && r.Method == http.MethodPost:
+		ns, ok := s.findProvider(rest[0])
+		if !ok {
+			ns = rest[0]
+		}
+		s.state.Providers[ns] = "Registered"
+		return http.StatusOK, s.provider(ns)
+	case len(rest) >= 2 && strings.EqualFold(rest[0], "Microsoft.Compute") && strings.EqualFold(rest[1], "locations"):
+		return s.routeVirtualMachineImages(r, rest[2:])
+	}
+	return errorResponse(http.StatusNotFound, "InvalidResourceNamespace", fmt.Sprintf("the resource namespace %s is invalid", strings.Join(rest, "/")))
+}
+
+func (s *Server) findProvider(namespace string) (string, bool) {
+	for ns := range s.state.Providers {
+		if strings.EqualFold(ns, namespace) {
+			return ns, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) provider(namespace string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                fmt.Sprintf("/subscriptions/%s/providers/%s", s.subscriptionID, namespace),
+		"namespace":         namespace,
+		"registrationState": s.state.Providers[namespace],
+	}
+}
+
+// routeVirtualMachineImages emulates a marketplace holding every requested image, with a single version
+func (s *Server) routeVirtualMachineImages(r *http.Request, rest []string) (int, interface{}) {
+	// {location}/publishers/{publisher}/artifacttypes/vmimage/offers/{offer}/skus/{sku}/versions[/{version}]
+	if len(rest) < 10 || r.Method != http.MethodGet {
+		return errorResponse(http.StatusNotFound, "NotFound", "the platform image is not supported")
+	}
+	location, publisher, offer, sku := rest[0], rest[2], rest[6], rest[8]
+	version := "1.0.0"
+	if len(rest) == 11 {
+		version = rest[10]
+	}
+	image := map[string]interface{}{
+		"id":       fmt.Sprintf("/Subscriptions/%s/Providers/Microsoft.Compute/Locations/%s/Publishers/%s/ArtifactTypes/VMImage/Offers/%s/Skus/%s/Versions/%s", s.subscriptionID, location, publisher, offer, sku, version),
+		"name":     version,
+		"location": location,
+		"properties": map[string]interface{}{
+			"osImage": map[string]interface{}{"operatingSystem": "Linux"},
+		},
+	}
+	if len(rest) == 11 {
+		return http.StatusOK, image
+	}
+	return http.StatusOK, []interface{}{image}
+}
+
+func (s *Server) routeResourceGroups(r *http.Request, rest []string) (int, interface{}) {
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+		}
+		value := []interface{}{}
+		for _, name := range sortedKeys(s.state.ResourceGroups) {
+			value = append(value, s.resourceGroupRecord(s.state.ResourceGroups[name]))
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	}
+	key := strings.ToLower(rest[0])
+	rg, exists := s.state.ResourceGroups[key]
+	if len(rest) == 1 {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if !exists {
+				return resourceGroupNotFound(rest[0])
+			}
+			return http.StatusOK, s.resourceGroupRecord(rg)
+		case http.MethodPut, http.MethodPatch:
+			body := map[string]interface{}{}
+			if err := decodeBody(r, &body); err != nil {
+				return errorResponse(http.StatusBadRequest, "InvalidRequestContent", err.Error())
+			}
+			status := http.StatusOK
+			if !exists {
+				rg = &resourceGroup{
+					Name:        rest[0],
+					Deployments: map[string]*deployment{},
+					Resources:   map[string]map[string]interface{}{},
+				}
+				s.state.ResourceGroups[key] = rg
+				status = http.StatusCreated
+			}
+			if l := toString(body["location"]); l != "" {
+				rg.Location = l
+			}
+			if tags, ok := body["tags"].(map[string]interface{}); ok {
+				rg.Tags = tags
+			}
+			if m := toString(body["managedBy"]); m != "" {
+				rg.ManagedBy = m
+			}
+			return status, s.resourceGroupRecord(rg)
+		case http.MethodDelete:
+			if !exists {
+				return resourceGroupNotFound(rest[0])
+			}
+			for id := range rg.Resources {
+				delete(s.state.PowerStates, id)
+			}
+			prefix := strings.ToLower(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/", s.subscriptionID, rg.Name))
+			for id := range s.state.RoleAssignments {
+				if strings.HasPrefix(id, prefix) {
+					delete(s.state.RoleAssignments, id)
+				}
+			}
+			delete(s.state.ResourceGroups, key)
+			return http.StatusOK, nil
+		}
+		return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+	if !exists {
+		return resourceGroupNotFound(rest[0])
+	}
+	if !strings.EqualFold(rest[1], "providers") || len(rest) < 4 {
+		return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+	}
+	namespace, resourceType := rest[2], rest[3]
+	if strings.EqualFold(namespace, "Microsoft.Resources") && strings.EqualFold(resourceType, "deployments") {
+		return s.routeDeployments(r, rg, rest[4:])
+	}
+	return s.routeResources(r, rg, namespace+"/"+resourceType, rest[4:])
+}
+
+func (s *Server) resourceGroupRecord(rg *resourceGroup) map[string]interface{} {
+	record := map[string]interface{}{
+		"id":       fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", s.subscriptionID, rg.Name),
+		"name":     rg.Name,
+		"type":     "Microsoft.Resources/resourceGroups",
+		"location": rg.Location,
+		"properties": map[string]interface{}{
+			"provisioningState": "Succeeded",
+		},
+	}
+	if rg.Tags != nil {
+		record["tags"] = rg.Tags
+	}
+	if rg.ManagedBy != "" {
+		record["managedBy"] = rg.ManagedBy
+	}
+	return record
+}
+
+func (s *Server) routeDeployments(r *http.Request, rg *resourceGroup, rest []string) (int, interface{}) {
+	if len(rest) == 0 {
+		value := []interface{}{}
+		for _, name := range sortedKeys(rg.Deployments) {
+			value = append(value, s.deploymentRecord(rg, rg.Deployments[name]))
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	}
+	key := strings.ToLower(rest[0])
+	d, exists := rg.Deployments[key]
+	if len(rest) == 2 && strings.EqualFold(rest[1], "operations") && r.Method == http.MethodGet {
+		if !exists {
+			return deploymentNotFound(rest[0])
+		}
+		value := []interface{}{}
+		for _, op := range d.Operations {
+			value = append(value, map[string]interface{}{
+				"id":          fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Resources/deployments/%s/operations/%s", s.subscriptionID, rg.Name, d.Name, op.OperationID),
+				"operationId": op.OperationID,
+				"properties": map[string]interface{}{
+					"provisioningOperation": "Create",
+					"provisioningState":     "Succeeded",
+					"statusCode":            "OK",
+					"timestamp":             d.Timestamp,
+					"targetResource": map[string]interface{}{
+						"id":           op.ResourceID,
+						"resourceName": op.ResourceName,
+						"resourceType": op.ResourceType,
+					},
+				},
+			})
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	}
+	if len(rest) == 2 && strings.EqualFold(rest[1], "validate") && r.Method == http.MethodPost {
+		body := map[string]interface{}{}
+		if err := decodeBody(r, &body); err != nil {
+			return errorResponse(http.StatusBadRequest, "InvalidRequestContent", err.Error())
+		}
+		template, _ := lookupPath(body, "properties", "template").(map[string]interface{})
+		parameters, _ := lookupPath(body, "properties", "parameters").(map[string]interface{})
+		if _, err := resolveParameters(template, parameters); err != nil {
+			return errorResponse(http.StatusBadRequest, "InvalidTemplate", err.Error())
+		}
+		return http.StatusOK, map[string]interface{}{
+			"id":   fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Resources/deployments/%s", s.subscriptionID, rg.Name, rest[0]),
+			"name": rest[0],
+			"properties": map[string]interface{}{
+				"provisioningState": "Succeeded",
+				"mode":              "Incremental",
+			},
+		}
+	}
+	if len(rest) != 1 {
+		return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			return deploymentNotFound(rest[0])
+		}
+		return http.StatusOK, s.deploymentRecord(rg, d)
+	case http.MethodPut:
+		body := map[string]interface{}{}
+		if err := decodeBody(r, &body); err != nil {
+			return errorResponse(http.StatusBadRequest, "InvalidRequestContent", err.Error())
+		}
+		template, _ := lookupPath(body, "properties", "template").(map[string]interface{})
+		parameters, _ := lookupPath(body, "properties", "parameters").(map[string]interface{})
+		if template == nil {
+			return errorResponse(http.StatusBadRequest, "InvalidTemplate", "the deployment template is missing, template links are not supported")
+		}
+		operations, outputs, err := s.deployTemplate(rg, template, parameters)
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "InvalidTemplate", err.Error())
+		}
+		d = &deployment{
+			Name:       rest[0],
+			Template:   template,
+			Parameters: parameters,
+			Outputs:    outputs,
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+			Operations: operations,
+		}
+		rg.Deployments[key] = d
+		status := http.StatusOK
+		if !exists {
+			status = http.StatusCreated
+		}
+		return status, s.deploymentRecord(rg, d)
+	case http.MethodDelete:
+		if !exists {
+			return deploymentNotFound(rest[0])
+		}
+		delete(rg.Deployments, key)
+		return http.StatusOK, nil
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+}
+
+func (s *Server) deploymentRecord(rg *resourceGroup, d *deployment) map[string]interface{} {
+	outputResources := []interface{}{}
+	for _, op := range d.Operations {
+		outputResources = append(outputResources, map[string]interface{}{"id": op.ResourceID})
+	}
+	return map[string]interface{}{
+		"id":   fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Resources/deployments/%s", s.subscriptionID, rg.Name, d.Name),
+		"name": d.Name,
+		"type": "Microsoft.Resources/deployments",
+		"properties": map[string]interface{}{
+			"provisioningState": "Succeeded",
+			"mode":              "Incremental",
+			"timestamp":         d.Timestamp,
+			"outputs":           d.Outputs,
+			"outputResources":   outputResources,
+		},
+	}
+}
+
+// routeResources serves the resource group resources, e.g. Microsoft.Compute/virtualMachines
+func (s *Server) routeResources(r *http.Request, rg *resourceGroup, resourceType string, rest []string) (int, interface{}) {
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+		}
+		value := []interface{}{}
+		for _, resource := range rg.list(resourceType) {
+			value = append(value, s.resourceView(resource, false))
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	}
+	id := resourceIDFromTypeAndName(s.subscriptionID, rg.Name, resourceType, rest[0])
+	resource, exists := rg.Resources[strings.ToLower(id)]
+	if !exists {
+		return errorResponse(http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("The Resource '%s/%s' under resource group '%s' was not found.", resourceType, rest[0], rg.Name))
+	}
+	if len(rest) == 2 && r.Method == http.MethodPost {
+		return s.resourceAction(rg, resource, strings.ToLower(rest[1]))
+	}
+	if len(rest) != 1 {
+		return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, s.resourceView(resource, strings.EqualFold(r.URL.Query().Get("$expand"), "instanceView"))
+	case http.MethodDelete:
+		if strings.EqualFold(resourceType, "Microsoft.Compute/virtualMachines") {
+			s.detachDisks(rg, resource)
+		}
+		delete(rg.Resources, strings.ToLower(id))
+		delete(s.state.PowerStates, strings.ToLower(id))
+		return http.StatusOK, nil
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+}
+
+// resourceAction serves the POST actions of a resource, e.g. virtualMachines/{vm}/restart
+func (s *Server) resourceAction(rg *resourceGroup, resource map[string]interface{}, action string) (int, interface{}) {
+	id := strings.ToLower(toString(resource["id"]))
+	switch strings.ToLower(toString(resource["type"])) {
+	case "microsoft.compute/virtualmachines":
+		switch action {
+		case "restart", "start":
+			s.state.PowerStates[id] = PowerStateRunning
+			return http.StatusOK, nil
+		case "poweroff":
+			s.state.PowerStates[id] = PowerStateStopped
+			return http.StatusOK, nil
+		case "deallocate":
+			s.state.PowerStates[id] = PowerStateDeallocated
+			return http.StatusOK, nil
+		}
+	case "microsoft.storage/storageaccounts":
+		if action == "listkeys" {
+			return http.StatusOK, map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"keyName": "key1", "value": "ZmFrZWtleTE=", "permissions": "FULL"},
+					map[string]interface{}{"keyName": "key2", "value": "ZmFrZWtleTI=", "permissions": "FULL"},
+				},
+			}
+		}
+	}
+	return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the action %s is not supported on %s", action, resource["type"]))
+}
+
+// detachDisks marks the managed disks of a deleted virtual machine as unattached, like ARM does
+func (s *Server) detachDisks(rg *resourceGroup, vm map[string]interface{}) {
+	for _, d := range rg.list("Microsoft.Compute/disks") {
+		if strings.EqualFold(toString(d["managedBy"]), toString(vm["id"])) {
+			delete(d, "managedBy")
+			if properties, ok := d["properties"].(map[string]interface{}); ok {
+				properties["diskState"] = "Unattached"
+			}
+		}
+	}
+}
+
+// resourceView returns the resource as served by ARM, with the virtual machine instance view if requested
+func (s *Server) resourceView(resource map[string]interface{}, instanceView bool) map[string]interface{} {
+	if !instanceView {
+		return resource
+	}
+	view := make(map[string]interface{}, len(resource))
+	for k, v := range resource {
+		view[k] = v
+	}
+	properties := map[string]interface{}{}
+	if p, ok := resource["properties"].(map[string]interface{}); ok {
+		for k, v := range p {
+			properties[k] = v
+		}
+	}
+	powerState, ok := s.state.PowerStates[strings.ToLower(toString(resource["id"]))]
+	if !ok {
+		powerState = PowerStateRunning
+	}
+	properties["instanceView"] = map[string]interface{}{
+		"statuses": []interface{}{
+			map[string]interface{}{"code": "ProvisioningState/succeeded", "level": "Info"},
+			map[string]interface{}{"code": powerState, "level": "Info"},
+		},
+	}
+	view["properties"] = properties
+	return view
+}
+
+// routeRoleAssignments serves {scope}/providers/Microsoft.Authorization/roleAssignments[/{name}]
+func (s *Server) routeRoleAssignments(r *http.Request, scope string, rest []string) (int, interface{}) {
+	if len(rest) == 0 || !strings.EqualFold(rest[0], "roleAssignments") {
+		return errorResponse(http.StatusNotFound, "InvalidResourceType", fmt.Sprintf("the path %s is not supported", r.URL.Path))
+	}
+	if len(rest) == 1 && r.Method == http.MethodGet {
+		principalID := ""
+		if filter := r.URL.Query().Get("$filter"); strings.HasPrefix(filter, "principalId eq ") {
+			principalID = strings.Trim(strings.TrimPrefix(filter, "principalId eq "), "'")
+		}
+		value := []interface{}{}
+		for _, id := range sortedKeys(s.state.RoleAssignments) {
+			ra := s.state.RoleAssignments[id]
+			if !strings.HasPrefix(strings.ToLower(toString(lookupPath(ra, "properties", "scope"))), strings.ToLower(scope)) {
+				continue
+			}
+			if principalID != "" && !strings.EqualFold(toString(lookupPath(ra, "properties", "principalId")), principalID) {
+				continue
+			}
+			value = append(value, ra)
+		}
+		return http.StatusOK, map[string]interface{}{"value": value}
+	}
+	if len(rest) == 2 {
+		id := strings.ToLower(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignments/%s", scope, rest[1]))
+		ra, exists := s.state.RoleAssignments[id]
+		switch r.Method {
+		case http.MethodGet:
+			if exists {
+				return http.StatusOK, ra
+			}
+		case http.MethodPut:
+			body := map[string]interface{}{}
+			if err := decodeBody(r, &body); err != nil {
+				return errorResponse(http.StatusBadRequest, "InvalidRequestContent", err.Error())
+			}
+			properties, _ := body["properties"].(map[string]interface{})
+			if properties == nil {
+				properties = map[string]interface{}{}
+			}
+			properties["scope"] = scope
+			ra = map[string]interface{}{
+				"id":         fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignments/%s", scope, rest[1]),
+				"name":       rest[1],
+				"type":       "Microsoft.Authorization/roleAssignments",
+				"properties": properties,
+			}
+			s.state.RoleAssignments[id] = ra
+			return http.StatusCreated, ra
+		case http.MethodDelete:
+			if exists {
+				delete(s.state.RoleAssignments, id)
+				return http.StatusOK, ra
+			}
+			return http.StatusNoContent, nil
+		}
+	}
+	return errorResponse(http.StatusNotFound, "RoleAssignmentNotFound", fmt.Sprintf("the role assignment %s/%s does not exist", scope, strings.Join(rest, "/")))
+}
+
+// list returns the resources of the given type, sorted by name
+func (rg *resourceGroup) list(resourceType string) []map[string]interface{} {
+	var list []map[string]interface{}
+	for _, id := range sortedKeys(rg.Resources) {
+		resource := rg.Resources[id]
+		if strings.EqualFold(toString(resource["type"]), resourceType) {
+			list = append(list, resource)
+		}
+	}
+	return list
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func errorResponse(status int, code, message string) (int, interface{}) {
+	return status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
+
+func resourceGroupNotFound(name string) (int, interface{}) {
+	return errorResponse(http.StatusNotFound, "ResourceGroupNotFound", fmt.Sprintf("Resource group '%s' could not be found.", name))
+}
+
+func deploymentNotFound(name string) (int, interface{}) {
+	return errorResponse(http.StatusNotFound, "DeploymentNotFound", fmt.Sprintf("Deployment '%s' could not be found.", name))
+}
+
+func indexOf(list []string, s string) int {
+	for i := range list {
+		if list[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
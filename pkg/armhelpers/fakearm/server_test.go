@@ -0,0 +1,199 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package fakearm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	. "github.com/onsi/gomega"
+)
+
+const testTemplate = `{
+  "parameters": {
+    "clusterName": {"type": "string"},
+    "count": {"type": "int", "defaultValue": 2}
+  },
+  "variables": {
+    "vmPrefix": "[concat('k8s-master-', parameters('clusterName'), '-')]",
+    "storageAccountName": "[concat('sa', uniqueString(resourceGroup().id))]"
+  },
+  "resources": [
+    {
+      "type": "Microsoft.Storage/storageAccounts",
+      "name": "[variables('storageAccountName')]",
+      "properties": {}
+    },
+    {
+      "type": "Microsoft.Network/networkInterfaces",
+      "name": "[concat(variables('vmPrefix'), 'nic-', copyIndex())]",
+      "copy": {"name": "nicLoop", "count": "[parameters('count')]"},
+      "properties": {}
+    },
+    {
+      "type": "Microsoft.Compute/virtualMachines",
+      "name": "[concat(variables('vmPrefix'), copyIndex())]",
+      "copy": {"name": "vmLoop", "count": "[parameters('count')]"},
+      "identity": {"type": "SystemAssigned"},
+      "tags": {"poolName": "master"},
+      "properties": {
+        "networkProfile": {
+          "networkInterfaces": [{"id": "[resourceId('Microsoft.Network/networkInterfaces', concat(variables('vmPrefix'), 'nic-', copyIndex()))]"}]
+        },
+        "storageProfile": {
+          "osDisk": {"createOption": "FromImage", "diskSizeGB": 30}
+        }
+      }
+    },
+    {
+      "type": "Microsoft.Authorization/roleAssignments",
+      "name": "[guid(concat(variables('vmPrefix'), copyIndex()))]",
+      "copy": {"name": "roleLoop", "count": "[parameters('count')]"},
+      "properties": {
+        "principalId": "[reference(concat('Microsoft.Compute/virtualMachines/', variables('vmPrefix'), copyIndex()), '2017-03-30', 'Full').identity.principalId]"
+      }
+    },
+    {
+      "condition": "[equals(parameters('count'), 0)]",
+      "type": "Microsoft.Compute/availabilitySets",
+      "name": "skipped",
+      "properties": {}
+    }
+  ],
+  "outputs": {
+    "storageAccount": {"type": "string", "value": "[variables('storageAccountName')]"}
+  }
+}`
+
+func newTestClient(t *testing.T, s *Server) *armhelpers.AzureClient {
+	t.Helper()
+	client, err := armhelpers.NewAzureClient(s.SubscriptionID(), &fake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: s.Transport()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestServerDeployTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	s, err := NewServer()
+	g.Expect(err).NotTo(HaveOccurred())
+	client := newTestClient(t, s)
+
+	_, err = client.EnsureResourceGroup(ctx, "rg", "local", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	template := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(testTemplate), &template)).To(Succeed())
+	parameters := map[string]interface{}{
+		"clusterName": map[string]interface{}{"value": "12345678"},
+	}
+	deployment, err := client.DeployTemplate(ctx, "rg", "deployment", template, parameters)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*deployment.Properties.ProvisioningState).To(BeEquivalentTo("Succeeded"))
+
+	vms, err := client.ListVirtualMachines(ctx, "rg")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vms).To(HaveLen(2))
+	g.Expect(*vms[0].Name).To(Equal("k8s-master-12345678-0"))
+	g.Expect(*vms[1].Tags["poolName"]).To(Equal("master"))
+	g.Expect(*vms[0].Properties.NetworkProfile.NetworkInterfaces[0].ID).To(HaveSuffix("/networkInterfaces/k8s-master-12345678-nic-0"))
+
+	g.Expect(s.Resources("rg", "Microsoft.Compute/disks")).To(HaveLen(2))
+	g.Expect(s.Resources("rg", "Microsoft.Compute/availabilitySets")).To(BeEmpty())
+	g.Expect(s.Resources("rg", "Microsoft.Storage/storageAccounts")).To(HaveLen(1))
+
+	principalID := *vms[0].Identity.PrincipalID
+	assignments, err := client.ListRoleAssignmentsForPrincipal(ctx, "/subscriptions/"+s.SubscriptionID()+"/resourceGroups/rg", principalID)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(assignments).To(HaveLen(1))
+	g.Expect(*assignments[0].Properties.PrincipalID).To(Equal(principalID))
+
+	state, err := client.GetVirtualMachinePowerState(ctx, "rg", "k8s-master-12345678-1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state).To(Equal(PowerStateRunning))
+	s.SetPowerState("rg", "k8s-master-12345678-1", PowerStateStopped)
+	g.Expect(client.RestartVirtualMachine(ctx, "rg", "k8s-master-12345678-1")).To(Succeed())
+	state, err = client.GetVirtualMachinePowerState(ctx, "rg", "k8s-master-12345678-1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state).To(Equal(PowerStateRunning))
+
+	g.Expect(client.DeleteVirtualMachine(ctx, "rg", "k8s-master-12345678-1")).To(Succeed())
+	vms, err = client.ListVirtualMachines(ctx, "rg")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vms).To(HaveLen(1))
+	disks := s.Resources("rg", "Microsoft.Compute/disks")
+	g.Expect(disks[1]).NotTo(HaveKey("managedBy"))
+	g.Expect(client.DeleteManagedDisk(ctx, "rg", disks[1]["name"].(string))).To(Succeed())
+	g.Expect(s.Resources("rg", "Microsoft.Compute/disks")).To(HaveLen(1))
+}
+
+func TestServerStateFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := NewServer(WithStateFile(path))
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = newTestClient(t, s).EnsureResourceGroup(ctx, "rg", "local", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, err = NewServer(WithStateFile(path))
+	g.Expect(err).NotTo(HaveOccurred())
+	client := newTestClient(t, s)
+	g.Expect(client.DeleteResourceGroup(ctx, "RG")).To(Succeed())
+	_, err = client.EnsureResourceGroup(ctx, "other", "local", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, err = NewServer(WithStateFile(path))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.state.ResourceGroups).To(HaveKey("other"))
+	g.Expect(s.state.ResourceGroups).NotTo(HaveKey("rg"))
+}
+
+func TestServerProviders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := NewServer()
+	g.Expect(err).NotTo(HaveOccurred())
+	s.SetProviderRegistrationState("Microsoft.Compute", "NotRegistered")
+	client := newTestClient(t, s)
+
+	g.Expect(client.EnsureProvidersRegistered(s.SubscriptionID())).To(Succeed())
+	g.Expect(s.state.Providers["Microsoft.Compute"]).To(Equal("Registered"))
+}
+
+func TestServerAuthentication(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := NewServer()
+	g.Expect(err).NotTo(HaveOccurred())
+	req, _ := http.NewRequest(http.MethodGet, "https://management.local.azurestack.external/subscriptions/"+s.SubscriptionID()+"?api-version=2016-06-01", nil)
+	resp, err := s.Transport().Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(resp.Header.Get("WWW-Authenticate")).To(ContainSubstring(DefaultTenantID))
+
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err = s.Transport().Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	req, _ = http.NewRequest(http.MethodGet, "https://management.local.azurestack.external/subscriptions/other?api-version=2016-06-01", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err = s.Transport().Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+}
@@ -0,0 +1,284 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package fakearm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// deployTemplate resolves the resources of an ARM template and adds them to the resource group.
+// The caller must hold the server lock.
+func (s *Server) deployTemplate(rg *resourceGroup, template, parameters map[string]interface{}) ([]*deploymentOperation, map[string]interface{}, error) {
+	params, err := resolveParameters(template, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	variables, _ := template["variables"].(map[string]interface{})
+	ctx := &evalContext{
+		subscriptionID: s.subscriptionID,
+		resourceGroup:  rg.Name,
+		location:       rg.Location,
+		parameters:     params,
+		variables:      variables,
+		resolvedVars:   map[string]interface{}{},
+		resolvingVars:  map[string]bool{},
+	}
+	ctx.reference = func(resourceID string, full bool) (interface{}, error) {
+		return s.reference(rg, resourceID, full)
+	}
+
+	var operations []*deploymentOperation
+	templateResources, _ := template["resources"].([]interface{})
+	for _, r := range templateResources {
+		resource, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cond, ok := resource["condition"]; ok {
+			c, err := ctx.evalValue(cond)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "evaluating resource condition")
+			}
+			if !toBool(c) {
+				continue
+			}
+		}
+		count, loop := 1, false
+		if cp, ok := resource["copy"].(map[string]interface{}); ok {
+			c, err := ctx.evalValue(cp["count"])
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "evaluating copy loop count")
+			}
+			if count, err = toInt(c); err != nil {
+				return nil, nil, errors.Wrap(err, "evaluating copy loop count")
+			}
+			loop = true
+		}
+		for i := 0; i < count; i++ {
+			iteration := ctx
+			if loop {
+				iteration = ctx.withCopyIndex(i)
+			}
+			op, err := s.deployResource(rg, iteration, resource)
+			if err != nil {
+				return nil, nil, err
+			}
+			operations = append(operations, op)
+		}
+	}
+
+	outputs := map[string]interface{}{}
+	if templateOutputs, ok := template["outputs"].(map[string]interface{}); ok {
+		for name, o := range templateOutputs {
+			output, _ := o.(map[string]interface{})
+			outputs[name] = map[string]interface{}{
+				"type":  output["type"],
+				"value": ctx.evalValueLenient(output["value"]),
+			}
+		}
+	}
+	return operations, outputs, nil
+}
+
+// resolveParameters merges the deployment parameter values with the template parameter defaults
+func resolveParameters(template, parameters map[string]interface{}) (map[string]interface{}, error) {
+	resolved := map[string]interface{}{}
+	definitions, _ := template["parameters"].(map[string]interface{})
+	for name, d := range definitions {
+		definition, _ := d.(map[string]interface{})
+		if p, ok := parameters[name].(map[string]interface{}); ok {
+			if v, ok := p["value"]; ok {
+				resolved[name] = v
+				continue
+			}
+		}
+		if v, ok := definition["defaultValue"]; ok {
+			resolved[name] = v
+			continue
+		}
+		return nil, errors.Errorf("the template parameter %q has no value", name)
+	}
+	// parameter defaults may refer to other parameters
+	ctx := &evalContext{parameters: resolved}
+	for name, v := range resolved {
+		if e, err := ctx.evalValue(v); err == nil {
+			resolved[name] = e
+		}
+	}
+	return resolved, nil
+}
+
+// deployResource evaluates a template resource and stores it in the resource group
+func (s *Server) deployResource(rg *resourceGroup, ctx *evalContext, resource map[string]interface{}) (*deploymentOperation, error) {
+	resourceType := toString(resource["type"])
+	n, err := ctx.evalValue(resource["name"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "evaluating the name of a %s resource", resourceType)
+	}
+	name := toString(n)
+
+	evaluated := map[string]interface{}{}
+	for k, v := range resource {
+		switch k {
+		case "copy", "dependsOn", "condition", "apiVersion", "comments":
+			continue
+		}
+		evaluated[k] = ctx.evalValueLenient(v)
+	}
+	evaluated["name"] = name
+	evaluated["type"] = resourceType
+	if _, ok := evaluated["location"]; !ok {
+		evaluated["location"] = rg.Location
+	}
+	id := resourceIDFromTypeAndName(s.subscriptionID, rg.Name, resourceType, name)
+	evaluated["id"] = id
+
+	properties, _ := evaluated["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = map[string]interface{}{}
+		evaluated["properties"] = properties
+	}
+	properties["provisioningState"] = "Succeeded"
+
+	switch strings.ToLower(resourceType) {
+	case "microsoft.authorization/roleassignments":
+		// role assignments are listed by scope rather than by resource group
+		scope := toString(properties["scope"])
+		if scope == "" {
+			scope = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", s.subscriptionID, rg.Name)
+		}
+		properties["scope"] = scope
+		evaluated["id"] = fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignments/%s", scope, name)
+		s.state.RoleAssignments[strings.ToLower(toString(evaluated["id"]))] = evaluated
+		return newDeploymentOperation(evaluated), nil
+	case "microsoft.compute/virtualmachines":
+		s.prepareVirtualMachine(rg, evaluated)
+	case "microsoft.storage/storageaccounts":
+		properties["primaryEndpoints"] = map[string]interface{}{
+			"blob": fmt.Sprintf("https://%s.blob.%s/", name, s.storageEndpointSuffix),
+		}
+	}
+	if identity, ok := evaluated["identity"].(map[string]interface{}); ok && strings.Contains(strings.ToLower(toString(identity["type"])), "systemassigned") {
+		if previous, ok := rg.Resources[strings.ToLower(id)]; ok {
+			identity["principalId"] = lookupPath(previous, "identity", "principalId")
+		}
+		if toString(identity["principalId"]) == "" {
+			identity["principalId"] = uuid.New().String()
+		}
+		identity["tenantId"] = s.tenantID
+	}
+	rg.Resources[strings.ToLower(id)] = evaluated
+	return newDeploymentOperation(evaluated), nil
+}
+
+// prepareVirtualMachine fills in the properties ARM sets when it creates a virtual machine,
+// and creates the managed disks implied by its storage profile
+func (s *Server) prepareVirtualMachine(rg *resourceGroup, vm map[string]interface{}) {
+	name := toString(vm["name"])
+	properties := vm["properties"].(map[string]interface{})
+	properties["vmId"] = uuid.NewSHA1(uuid.NameSpaceURL, []byte(toString(vm["id"]))).String()
+	s.state.PowerStates[strings.ToLower(toString(vm["id"]))] = PowerStateRunning
+
+	storageProfile, _ := properties["storageProfile"].(map[string]interface{})
+	if storageProfile == nil {
+		return
+	}
+	if osDisk, ok := storageProfile["osDisk"].(map[string]interface{}); ok {
+		if toString(osDisk["name"]) == "" {
+			osDisk["name"] = fmt.Sprintf("%s_OsDisk_1_%s", name, strings.ReplaceAll(uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String(), "-", ""))
+		}
+		s.prepareDisk(rg, vm, osDisk)
+	}
+	if dataDisks, ok := storageProfile["dataDisks"].([]interface{}); ok {
+		for i, d := range dataDisks {
+			disk, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if toString(disk["name"]) == "" {
+				disk["name"] = fmt.Sprintf("%s_disk%d_%s", name, i+1, strings.ReplaceAll(uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String(), "-", ""))
+			}
+			s.prepareDisk(rg, vm, disk)
+		}
+	}
+}
+
+// prepareDisk creates the managed disk record of a virtual machine disk that does not use a VHD blob
+func (s *Server) prepareDisk(rg *resourceGroup, vm, disk map[string]interface{}) {
+	if _, ok := disk["vhd"]; ok {
+		return
+	}
+	diskName := toString(disk["name"])
+	diskID := resourceIDFromTypeAndName(s.subscriptionID, rg.Name, "Microsoft.Compute/disks", diskName)
+	managedDisk, _ := disk["managedDisk"].(map[string]interface{})
+	if managedDisk == nil {
+		managedDisk = map[string]interface{}{}
+		disk["managedDisk"] = managedDisk
+	}
+	managedDisk["id"] = diskID
+	rg.Resources[strings.ToLower(diskID)] = map[string]interface{}{
+		"id":        diskID,
+		"name":      diskName,
+		"type":      "Microsoft.Compute/disks",
+		"location":  vm["location"],
+		"tags":      vm["tags"],
+		"managedBy": vm["id"],
+		"properties": map[string]interface{}{
+			"provisioningState": "Succeeded",
+			"diskSizeGB":        disk["diskSizeGB"],
+			"diskState":         "Attached",
+		},
+	}
+}
+
+// reference resolves reference(resourceName or resourceID, [apiVersion], ['Full'])
+func (s *Server) reference(rg *resourceGroup, resourceID string, full bool) (interface{}, error) {
+	id := resourceID
+	if !strings.HasPrefix(id, "/") {
+		// reference('Microsoft.Compute/virtualMachines/name')
+		parts := strings.Split(id, "/")
+		if len(parts) < 3 {
+			return nil, errors.Errorf("reference(%q): unknown resource", resourceID)
+		}
+		id = resourceIDFromTypeAndName(s.subscriptionID, rg.Name, strings.Join(parts[:2], "/"), strings.Join(parts[2:], "/"))
+	}
+	resource, ok := rg.Resources[strings.ToLower(id)]
+	if !ok {
+		return nil, errors.Errorf("reference(%q): the resource has not been deployed", resourceID)
+	}
+	if full {
+		return resource, nil
+	}
+	return resource["properties"], nil
+}
+
+// resourceIDFromTypeAndName returns the ID of a resource group resource.
+// Child resources, such as "Microsoft.Compute/virtualMachines/extensions", are named "parent/child".
+func resourceIDFromTypeAndName(subscriptionID, resourceGroup, resourceType, name string) string {
+	typeParts := strings.Split(resourceType, "/")
+	nameParts := strings.Split(name, "/")
+	id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s", subscriptionID, resourceGroup, typeParts[0])
+	for i := 1; i < len(typeParts); i++ {
+		id += "/" + typeParts[i]
+		if i-1 < len(nameParts) {
+			id += "/" + nameParts[i-1]
+		}
+	}
+	return id
+}
+
+func lookupPath(v interface{}, path ...string) interface{} {
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
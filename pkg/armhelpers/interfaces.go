@@ -80,4 +80,7 @@ type AKSEngineClient interface {
 
 	// ListDeploymentOperations gets all deployments operations for a deployment.
 	ListDeploymentOperations(ctx context.Context, resourceGroupName string, deploymentName string) ([]*resources.DeploymentOperation, error)
+
+	// OperationTimeout returns the timeout of an ARM operation, including the polling of long-running operations
+	OperationTimeout() time.Duration
 }
@@ -579,6 +579,11 @@ func (mc *MockAKSEngineClient) ListDeploymentOperationsNextResults(lastResults r
 	return resources.DeploymentOperationsListResult{}, nil
 }
 
+// OperationTimeout returns the default ARM operation timeout
+func (mc *MockAKSEngineClient) OperationTimeout() time.Duration {
+	return DefaultARMOperationTimeout
+}
+
 // DeleteRoleAssignmentByID deletes a roleAssignment via its unique identifier
 func (mc *MockAKSEngineClient) DeleteRoleAssignmentByID(ctx context.Context, roleAssignmentID string) (authorization.RoleAssignment, error) {
 	if mc.FailDeleteRoleAssignment {
@@ -0,0 +1,288 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armhelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// redactedValue replaces secret material in recorded ARM interactions
+	redactedValue = "REDACTED"
+)
+
+var (
+	// secretKeyPattern matches the JSON keys whose values are scrubbed from recorded ARM interactions
+	secretKeyPattern = regexp.MustCompile(`(?i)(secret|password|token|privatekey|sharedkey|encryptionkey|protectedsettings)`)
+	// secretHeaders are the HTTP headers scrubbed from recorded ARM interactions
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Ms-Authorization-Auxiliary"}
+	// secretQueryParameters are the query string parameters scrubbed from recorded ARM interactions
+	secretQueryParameters = []string{"sig", "code", "client_secret"}
+)
+
+// RecordedInteraction is an ARM request and its response, as stored in a recording directory
+type RecordedInteraction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded ARM request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded ARM response
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// RecordingTransport is a policy.Transporter that writes every ARM request and its response
+// to a directory, with secrets scrubbed, before returning the response to the caller.
+type RecordingTransport struct {
+	dir       string
+	transport policy.Transporter
+	mu        sync.Mutex
+	count     int
+}
+
+// NewRecordingTransport returns a RecordingTransport that sends requests through transport
+// and records them in dir. http.DefaultClient is used if transport is nil.
+func NewRecordingTransport(dir string, transport policy.Transporter) (*RecordingTransport, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "creating ARM recording directory %s", dir)
+	}
+	if transport == nil {
+		transport = http.DefaultClient
+	}
+	return &RecordingTransport{dir: dir, transport: transport}, nil
+}
+
+// Do sends the request and records the interaction
+func (t *RecordingTransport) Do(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := t.transport.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &RecordedInteraction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+			Body:   string(scrubBody(reqBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+			Body:       string(scrubBody(respBody)),
+		},
+	}
+	if err := t.write(interaction); err != nil {
+		// a failure to record must not fail the ARM operation
+		log.Warnf("failed to record ARM interaction %s %s: %s", req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func (t *RecordingTransport) write(interaction *RecordedInteraction) error {
+	b, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.count++
+	name := fmt.Sprintf("%05d_%s.json", t.count, strings.ToLower(interaction.Request.Method))
+	t.mu.Unlock()
+	return os.WriteFile(filepath.Join(t.dir, name), b, 0600)
+}
+
+// ReplayTransport is a policy.Transporter that serves the ARM interactions recorded by a RecordingTransport.
+// Requests are matched by method, path and query string; requests repeated more times than
+// they were recorded, such as long-running operation polls, are served the last recorded response.
+type ReplayTransport struct {
+	mu           sync.Mutex
+	interactions map[string][]*RecordedInteraction
+	served       map[string]int
+}
+
+// NewReplayTransport returns a ReplayTransport serving the interactions recorded in dir
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no recorded ARM interactions found in %s", dir)
+	}
+	sort.Strings(files)
+	t := &ReplayTransport{
+		interactions: map[string][]*RecordedInteraction{},
+		served:       map[string]int{},
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		interaction := &RecordedInteraction{}
+		if err := json.Unmarshal(b, interaction); err != nil {
+			return nil, errors.Wrapf(err, "parsing recorded ARM interaction %s", file)
+		}
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing recorded ARM interaction %s", file)
+		}
+		key := replayKey(interaction.Request.Method, u)
+		t.interactions[key] = append(t.interactions[key], interaction)
+	}
+	return t, nil
+}
+
+// Do returns the recorded response matching the request
+func (t *ReplayTransport) Do(req *http.Request) (*http.Response, error) {
+	key := replayKey(req.Method, req.URL)
+	t.mu.Lock()
+	recorded, ok := t.interactions[key]
+	if !ok {
+		t.mu.Unlock()
+		return nil, errors.Errorf("no recorded ARM response for %s", key)
+	}
+	i := t.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	t.served[key]++
+	t.mu.Unlock()
+
+	r := recorded[i].Response
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
+
+// replayKey identifies a request regardless of the ARM endpoint host
+func replayKey(method string, u *url.URL) string {
+	query := u.Query()
+	for _, p := range secretQueryParameters {
+		if query.Has(p) {
+			query.Set(p, redactedValue)
+		}
+	}
+	key := fmt.Sprintf("%s %s", strings.ToUpper(method), strings.ToLower(u.EscapedPath()))
+	if encoded := query.Encode(); encoded != "" {
+		key = key + "?" + encoded
+	}
+	return key
+}
+
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for _, p := range secretQueryParameters {
+		if query.Has(p) {
+			query.Set(p, redactedValue)
+		}
+	}
+	scrubbed.RawQuery = query.Encode()
+	return scrubbed.String()
+}
+
+func scrubHeader(h http.Header) http.Header {
+	scrubbed := h.Clone()
+	for _, name := range secretHeaders {
+		if scrubbed.Get(name) != "" {
+			scrubbed.Set(name, redactedValue)
+		}
+	}
+	return scrubbed
+}
+
+// scrubBody replaces the secret values of a JSON body.
+// Bodies that are not JSON are returned unchanged.
+func scrubBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	b, err := json.Marshal(scrubValue(v, false))
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+func scrubValue(v interface{}, redact bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			// storage account keys are listed as {"keys": [{"keyName": "key1", "value": "..."}]}
+			t[k] = scrubValue(child, redact || secretKeyPattern.MatchString(k) || strings.EqualFold(k, "keys"))
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = scrubValue(child, redact)
+		}
+		return t
+	case string:
+		if redact {
+			return redactedValue
+		}
+		return t
+	default:
+		return t
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armhelpers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultARMMaxRetries is the default number of times a failed ARM request is retried
+	DefaultARMMaxRetries = 3
+	// DefaultARMRetryDelay is the default delay before the first retry of a failed ARM request,
+	// doubled on every subsequent retry
+	DefaultARMRetryDelay = 4 * time.Second
+	// DefaultARMMaxRetryDelay is the default maximum delay between retries of a failed ARM request
+	DefaultARMMaxRetryDelay = 60 * time.Second
+	// DefaultARMCircuitBreakerCooldown is the default time ARM requests fail fast after the circuit breaker opens
+	DefaultARMCircuitBreakerCooldown = 30 * time.Second
+
+	// correlationRequestIDHeader identifies every try of an ARM request in the ARM activity logs
+	correlationRequestIDHeader = "x-ms-correlation-request-id"
+)
+
+// RetryOptions configures how ARM requests are retried and timed out
+type RetryOptions struct {
+	// MaxRetries is the number of times a throttled (429), timed out or failed (5xx) request is retried.
+	// Zero disables retries.
+	MaxRetries int32
+	// RetryDelay is the delay before the first retry, doubled on every subsequent retry.
+	// The delay requested by a Retry-After response header takes precedence.
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the delay between retries. A request is not retried
+	// if its Retry-After response header asks for a longer delay.
+	MaxRetryDelay time.Duration
+	// TryTimeout is the timeout of each try of a request. Zero disables the timeout.
+	TryTimeout time.Duration
+	// OperationTimeout is the timeout of an ARM operation, including the polling of long-running operations
+	OperationTimeout time.Duration
+	// CircuitBreakerThreshold is the number of consecutive server errors (5xx) or connection failures after which
+	// requests fail fast without reaching ARM. Zero disables the circuit breaker.
+	CircuitBreakerThreshold int
+	// CircuitBreakerCooldown is how long requests fail fast once the circuit breaker opens.
+	// The first request after the cooldown is sent to ARM and closes the circuit breaker if it succeeds.
+	CircuitBreakerCooldown time.Duration
+}
+
+// DefaultRetryOptions returns the retry options used unless configured otherwise
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:             DefaultARMMaxRetries,
+		RetryDelay:             DefaultARMRetryDelay,
+		MaxRetryDelay:          DefaultARMMaxRetryDelay,
+		OperationTimeout:       DefaultARMOperationTimeout,
+		CircuitBreakerCooldown: DefaultARMCircuitBreakerCooldown,
+	}
+}
+
+// GetOperationTimeout returns the timeout of an ARM operation, DefaultARMOperationTimeout unless configured
+func (o RetryOptions) GetOperationTimeout() time.Duration {
+	if o.OperationTimeout <= 0 {
+		return DefaultARMOperationTimeout
+	}
+	return o.OperationTimeout
+}
+
+// Validate returns an error if the retry options are inconsistent
+func (o RetryOptions) Validate() error {
+	if o.MaxRetries < 0 {
+		return errors.New("the maximum number of ARM retries cannot be negative")
+	}
+	if o.RetryDelay < 0 || o.MaxRetryDelay < 0 || o.TryTimeout < 0 || o.OperationTimeout < 0 || o.CircuitBreakerCooldown < 0 {
+		return errors.New("ARM retry delays and timeouts cannot be negative")
+	}
+	if o.MaxRetryDelay > 0 && o.RetryDelay > o.MaxRetryDelay {
+		return errors.Errorf("the ARM retry delay %s exceeds the maximum retry delay %s", o.RetryDelay, o.MaxRetryDelay)
+	}
+	if o.CircuitBreakerThreshold < 0 {
+		return errors.New("the ARM circuit breaker threshold cannot be negative")
+	}
+	return nil
+}
+
+// Apply sets the retry policy of the client options, and adds the policies that
+// log every retry with its correlation ID and implement the circuit breaker
+func (o RetryOptions) Apply(options *azcore.ClientOptions) {
+	maxRetries := o.MaxRetries
+	if maxRetries == 0 {
+		// the SDK uses its default for zero and disables retries for negative values
+		maxRetries = -1
+	}
+	options.Retry = policy.RetryOptions{
+		MaxRetries:    maxRetries,
+		RetryDelay:    o.RetryDelay,
+		MaxRetryDelay: o.MaxRetryDelay,
+		TryTimeout:    o.TryTimeout,
+	}
+	options.PerCallPolicies = append(options.PerCallPolicies, &correlationPolicy{})
+	retryPolicy := &retryLoggingPolicy{maxRetries: o.MaxRetries}
+	if o.CircuitBreakerThreshold > 0 {
+		retryPolicy.breaker = newCircuitBreaker(o.CircuitBreakerThreshold, o.CircuitBreakerCooldown)
+	}
+	options.PerRetryPolicies = append(options.PerRetryPolicies, retryPolicy)
+}
+
+// tryState tracks the tries of an ARM request across the retry policy
+type tryState struct {
+	correlationID string
+	tries         int32
+	lastStatus    string
+}
+
+// correlationPolicy gives every ARM request a correlation ID shared by all its tries
+type correlationPolicy struct{}
+
+func (p *correlationPolicy) Do(req *policy.Request) (*http.Response, error) {
+	correlationID := req.Raw().Header.Get(correlationRequestIDHeader)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+		req.Raw().Header.Set(correlationRequestIDHeader, correlationID)
+	}
+	req.SetOperationValue(&tryState{correlationID: correlationID})
+	return req.Next()
+}
+
+// retryLoggingPolicy runs before every try of an ARM request
+type retryLoggingPolicy struct {
+	maxRetries int32
+	breaker    *circuitBreaker
+}
+
+func (p *retryLoggingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	state := &tryState{}
+	if !req.OperationValue(&state) {
+		state = &tryState{correlationID: req.Raw().Header.Get(correlationRequestIDHeader)}
+	}
+	state.tries++
+	if state.tries > 1 {
+		log.Warnf("Retrying ARM request %s %s (retry %d of %d) after %s, correlation ID %s",
+			req.Raw().Method, req.Raw().URL.Path, state.tries-1, p.maxRetries, state.lastStatus, state.correlationID)
+	}
+	if p.breaker != nil {
+		if err := p.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := req.Next()
+	if p.breaker != nil {
+		p.breaker.record(resp, err)
+	}
+	switch {
+	case err != nil:
+		state.lastStatus = err.Error()
+	case resp.StatusCode == http.StatusTooManyRequests:
+		state.lastStatus = fmt.Sprintf("throttling response %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	default:
+		state.lastStatus = fmt.Sprintf("response %d", resp.StatusCode)
+	}
+	return resp, err
+}
+
+// CircuitOpenError is returned for the ARM requests not sent while the circuit breaker is open
+type CircuitOpenError struct {
+	ConsecutiveFailures int
+	RetryAfter          time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("ARM requests are suspended after %d consecutive server errors, retry in %s", e.ConsecutiveFailures, e.RetryAfter.Round(time.Second))
+}
+
+// NonRetriable prevents the SDK retry policy from retrying the request
+func (e *CircuitOpenError) NonRetriable() {}
+
+// circuitBreaker suspends ARM requests after repeated server errors
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	threshold           int
+	cooldown            time.Duration
+	consecutiveFailures int
+	openedAt            time.Time
+	now                 func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns a CircuitOpenError if the circuit breaker is open
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if elapsed := b.now().Sub(b.openedAt); elapsed < b.cooldown {
+		return &CircuitOpenError{ConsecutiveFailures: b.consecutiveFailures, RetryAfter: b.cooldown - elapsed}
+	}
+	// half-open: let a request through, the next failure opens the circuit breaker again
+	b.openedAt = time.Time{}
+	b.consecutiveFailures = b.threshold - 1
+	return nil
+}
+
+// record counts the consecutive server errors and opens the circuit breaker when they reach the threshold
+func (b *circuitBreaker) record(resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		b.consecutiveFailures = 0
+		return
+	}
+	b.consecutiveFailures++
+	if b.consecutiveFailures >= b.threshold && b.openedAt.IsZero() {
+		log.Warnf("Suspending ARM requests for %s after %d consecutive server errors", b.cooldown, b.consecutiveFailures)
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package armhelpers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	. "github.com/onsi/gomega"
+)
+
+type transportFunc func(req *http.Request) (*http.Response, error)
+
+func (f transportFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newStatusResponse(req *http.Request, status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"name":"vm"}`)),
+		Request:    req,
+	}
+}
+
+func newRetryTestClient(t *testing.T, o RetryOptions, transport transportFunc) *AzureClient {
+	t.Helper()
+	options := &arm.ClientOptions{ClientOptions: azcore.ClientOptions{Transport: transport}}
+	o.Apply(&options.ClientOptions)
+	client, err := NewAzureClient("cc6b141e-6afc-4786-9bf6-e3b9a5601460", &fake.TokenCredential{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRetryOptionsRetriesWithCorrelationID(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var correlationIDs []string
+	client := newRetryTestClient(t, RetryOptions{MaxRetries: 2, RetryDelay: time.Millisecond, MaxRetryDelay: time.Second}, func(req *http.Request) (*http.Response, error) {
+		correlationIDs = append(correlationIDs, req.Header.Get(correlationRequestIDHeader))
+		if len(correlationIDs) == 1 {
+			return newStatusResponse(req, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0"}}), nil
+		}
+		if len(correlationIDs) == 2 {
+			return newStatusResponse(req, http.StatusServiceUnavailable, nil), nil
+		}
+		return newStatusResponse(req, http.StatusOK, nil), nil
+	})
+
+	_, err := client.GetVirtualMachine(context.Background(), "rg", "vm")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(correlationIDs).To(HaveLen(3))
+	g.Expect(correlationIDs[0]).NotTo(BeEmpty())
+	g.Expect(correlationIDs[1]).To(Equal(correlationIDs[0]))
+	g.Expect(correlationIDs[2]).To(Equal(correlationIDs[0]))
+}
+
+func TestRetryOptionsNoRetries(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tries := 0
+	client := newRetryTestClient(t, RetryOptions{}, func(req *http.Request) (*http.Response, error) {
+		tries++
+		return newStatusResponse(req, http.StatusServiceUnavailable, nil), nil
+	})
+
+	_, err := client.GetVirtualMachine(context.Background(), "rg", "vm")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(tries).To(Equal(1))
+}
+
+func TestRetryOptionsCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tries := 0
+	client := newRetryTestClient(t, RetryOptions{MaxRetries: 5, RetryDelay: time.Millisecond, CircuitBreakerThreshold: 2, CircuitBreakerCooldown: time.Hour}, func(req *http.Request) (*http.Response, error) {
+		tries++
+		return newStatusResponse(req, http.StatusInternalServerError, nil), nil
+	})
+
+	_, err := client.GetVirtualMachine(context.Background(), "rg", "vm")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("ARM requests are suspended after 2 consecutive server errors"))
+	g.Expect(tries).To(Equal(2))
+
+	_, err = client.GetVirtualMachine(context.Background(), "rg", "vm")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(tries).To(Equal(2))
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := &http.Response{StatusCode: http.StatusBadGateway}
+	success := &http.Response{StatusCode: http.StatusOK}
+
+	b.record(failure, nil)
+	g.Expect(b.allow()).To(Succeed())
+	b.record(failure, nil)
+	g.Expect(b.allow()).To(BeAssignableToTypeOf(&CircuitOpenError{}))
+
+	now = now.Add(time.Minute)
+	g.Expect(b.allow()).To(Succeed())
+	b.record(failure, nil)
+	g.Expect(b.allow()).To(HaveOccurred())
+
+	now = now.Add(time.Minute)
+	g.Expect(b.allow()).To(Succeed())
+	b.record(success, nil)
+	b.record(failure, nil)
+	g.Expect(b.allow()).To(Succeed())
+}
+
+func TestRetryOptionsValidate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(DefaultRetryOptions().Validate()).To(Succeed())
+	g.Expect(RetryOptions{MaxRetries: -1}.Validate()).To(MatchError("the maximum number of ARM retries cannot be negative"))
+	g.Expect(RetryOptions{TryTimeout: -time.Second}.Validate()).To(MatchError("ARM retry delays and timeouts cannot be negative"))
+	g.Expect(RetryOptions{RetryDelay: time.Minute, MaxRetryDelay: time.Second}.Validate()).To(MatchError("the ARM retry delay 1m0s exceeds the maximum retry delay 1s"))
+	g.Expect(RetryOptions{CircuitBreakerThreshold: -1}.Validate()).To(MatchError("the ARM circuit breaker threshold cannot be negative"))
+}
+
+func TestOperationTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(RetryOptions{}.GetOperationTimeout()).To(Equal(DefaultARMOperationTimeout))
+	g.Expect(RetryOptions{OperationTimeout: time.Minute}.GetOperationTimeout()).To(Equal(time.Minute))
+
+	az := &AzureClient{operationTimeout: DefaultARMOperationTimeout}
+	az.SetOperationTimeout(time.Minute)
+	g.Expect(az.OperationTimeout()).To(Equal(time.Minute))
+	az.SetOperationTimeout(0)
+	g.Expect(az.OperationTimeout()).To(Equal(DefaultARMOperationTimeout))
+}
@@ -150,6 +150,11 @@ func kubernetesAddonSettingsInit(p *api.Properties) map[string]kubernetesCompone
 			base64Data:      k.GetAddonScript(common.NodeProblemDetectorAddonName),
 			destinationFile: nodeProblemDetectorAddonDestinationFilename,
 		},
+		common.KubeletCSRApproverAddonName: {
+			sourceFile:      kubeletCSRApproverAddonSourceFilename,
+			base64Data:      k.GetAddonScript(common.KubeletCSRApproverAddonName),
+			destinationFile: kubeletCSRApproverAddonDestinationFilename,
+		},
 		common.KubeDNSAddonName: {
 			sourceFile:      kubeDNSAddonSourceFilename,
 			base64Data:      k.GetAddonScript(common.KubeDNSAddonName),
@@ -174,6 +174,8 @@ const (
 	cloudNodeManagerAddonDestinationFilename      string = "cloud-node-manager.yaml"
 	nodeProblemDetectorAddonSourceFilename        string = "node-problem-detector.yaml"
 	nodeProblemDetectorAddonDestinationFilename   string = "node-problem-detector.yaml"
+	kubeletCSRApproverAddonSourceFilename         string = "kubelet-csr-approver.yaml"
+	kubeletCSRApproverAddonDestinationFilename    string = "kubelet-csr-approver.yaml"
 	kubeDNSAddonSourceFilename                    string = "kube-dns.yaml"
 	kubeDNSAddonDestinationFilename               string = "kube-dns.yaml"
 	corednsAddonSourceFilename                    string = "coredns.yaml"
@@ -44,13 +44,51 @@ func GenerateKubeConfig(properties *api.Properties, location string) (string, er
 	if properties.CertificateProfile == nil {
 		return "", errors.New("CertificateProfile property may not be nil in GenerateKubeConfig")
 	}
+	var authInfo string
+	if properties.AADProfile == nil {
+		authInfo = clientCertificateAuthInfo(properties.CertificateProfile.KubeConfigCertificate, properties.CertificateProfile.KubeConfigPrivateKey)
+	} else {
+		tenantID := properties.AADProfile.TenantID
+		if len(tenantID) == 0 {
+			tenantID = "common"
+		}
+
+		authInfo = fmt.Sprintf("{\"auth-provider\":{\"name\":\"azure\",\"config\":{\"environment\":\"%v\",\"tenant-id\":\"%v\",\"apiserver-id\":\"%v\",\"client-id\":\"%v\"}}}",
+			helpers.GetTargetEnv(location, properties.GetCustomCloudName()),
+			tenantID,
+			properties.AADProfile.ServerAppID,
+			properties.AADProfile.ClientAppID)
+	}
+	return generateKubeConfig(properties, location, fmt.Sprintf("%s-admin", properties.MasterProfile.DNSPrefix), authInfo)
+}
+
+// GenerateClientKubeConfig returns a JSON string representing the KubeConfig of user,
+// authenticated by the client certificate and private key
+func GenerateClientKubeConfig(properties *api.Properties, location, user, certificatePem, privateKeyPem string) (string, error) {
+	if properties == nil {
+		return "", errors.New("Properties nil in GenerateClientKubeConfig")
+	}
+	if properties.CertificateProfile == nil {
+		return "", errors.New("CertificateProfile property may not be nil in GenerateClientKubeConfig")
+	}
+	return generateKubeConfig(properties, location, fmt.Sprintf("%s-%s", properties.MasterProfile.DNSPrefix, user), clientCertificateAuthInfo(certificatePem, privateKeyPem))
+}
+
+func clientCertificateAuthInfo(certificatePem, privateKeyPem string) string {
+	return fmt.Sprintf("{\"client-certificate-data\":\"%v\",\"client-key-data\":\"%v\"}",
+		base64.StdEncoding.EncodeToString([]byte(certificatePem)),
+		base64.StdEncoding.EncodeToString([]byte(privateKeyPem)))
+}
+
+func generateKubeConfig(properties *api.Properties, location, userName, authInfo string) (string, error) {
 	b, err := Asset(kubeConfigJSON)
 	if err != nil {
 		return "", errors.Wrapf(err, "error reading kube config template file %s", kubeConfigJSON)
 	}
 	kubeconfig := string(b)
 	// variable replacement
-	kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVerbatim \"parameters('caCertificate')\"}}", base64.StdEncoding.EncodeToString([]byte(properties.CertificateProfile.CaCertificate)), -1)
+	kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVariable \"resourceGroup\"}}-admin", userName, -1)
+	kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVerbatim \"parameters('caCertificate')\"}}", base64.StdEncoding.EncodeToString([]byte(properties.CertificateProfile.GetCACertificateBundle())), -1)
 	if properties.OrchestratorProfile != nil &&
 		properties.OrchestratorProfile.KubernetesConfig != nil &&
 		properties.OrchestratorProfile.KubernetesConfig.PrivateCluster != nil &&
@@ -71,24 +109,6 @@ func GenerateKubeConfig(properties *api.Properties, location string) (string, er
 		kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVerbatim \"reference(concat('Microsoft.Network/publicIPAddresses/', variables('masterPublicIPAddressName'))).dnsSettings.fqdn\"}}", api.FormatProdFQDNByLocation(properties.MasterProfile.DNSPrefix, location, properties.GetCustomCloudName()), -1)
 	}
 	kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVariable \"resourceGroup\"}}", properties.MasterProfile.DNSPrefix, -1)
-
-	var authInfo string
-	if properties.AADProfile == nil {
-		authInfo = fmt.Sprintf("{\"client-certificate-data\":\"%v\",\"client-key-data\":\"%v\"}",
-			base64.StdEncoding.EncodeToString([]byte(properties.CertificateProfile.KubeConfigCertificate)),
-			base64.StdEncoding.EncodeToString([]byte(properties.CertificateProfile.KubeConfigPrivateKey)))
-	} else {
-		tenantID := properties.AADProfile.TenantID
-		if len(tenantID) == 0 {
-			tenantID = "common"
-		}
-
-		authInfo = fmt.Sprintf("{\"auth-provider\":{\"name\":\"azure\",\"config\":{\"environment\":\"%v\",\"tenant-id\":\"%v\",\"apiserver-id\":\"%v\",\"client-id\":\"%v\"}}}",
-			helpers.GetTargetEnv(location, properties.GetCustomCloudName()),
-			tenantID,
-			properties.AADProfile.ServerAppID,
-			properties.AADProfile.ClientAppID)
-	}
 	kubeconfig = strings.Replace(kubeconfig, "{{authInfo}}", authInfo, -1)
 
 	return kubeconfig, nil
@@ -645,6 +665,12 @@ func getAddonFuncMap(addon api.KubernetesAddon, cs *api.ContainerService) templa
 		"GetAADPodIdentityTaintKey": func() string {
 			return common.AADPodIdentityTaintKey
 		},
+		"GetNodeNameRegexp": func() string {
+			return cs.Properties.GetNodeNameRegexp()
+		},
+		"IsKubeletServerTLSBootstrapEnabled": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.IsKubeletServerTLSBootstrapEnabled()
+		},
 		"GetMode": func() string {
 			return addon.Mode
 		},
@@ -5,6 +5,7 @@ package engine
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/leonelquinteros/gotext"
 	"github.com/pkg/errors"
@@ -603,6 +605,58 @@ func TestGenerateKubeConfig(t *testing.T) {
 	}
 }
 
+func TestGenerateClientKubeConfig(t *testing.T) {
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	if err := i18n.Initialize(locale); err != nil {
+		t.Error(err)
+	}
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	containerService, _, err := apiloader.LoadContainerServiceFromFile("./testdata/simple/kubernetes.json", true, false, nil)
+	if err != nil {
+		t.Fatalf("Failed to load container service from file: %v", err)
+	}
+	// the client certificate is used even if the cluster authenticates the admin with AAD
+	containerService.Properties.AADProfile = &api.AADProfile{ClientAppID: "fooClientAppID", ServerAppID: "fooServerAppID"}
+
+	kubeConfig, err := GenerateClientKubeConfig(containerService.Properties, "westus2", "alice", "alice-certificate", "alice-key")
+	if err != nil {
+		t.Fatalf("Failed to call GenerateClientKubeConfig: %v", err)
+	}
+	var config struct {
+		Contexts []struct {
+			Context struct {
+				User string `json:"user"`
+			} `json:"context"`
+		} `json:"contexts"`
+		Users []struct {
+			Name string `json:"name"`
+			User struct {
+				ClientCertificateData string `json:"client-certificate-data"`
+				ClientKeyData         string `json:"client-key-data"`
+			} `json:"user"`
+		} `json:"users"`
+	}
+	if err = json.Unmarshal([]byte(kubeConfig), &config); err != nil {
+		t.Fatalf("Failed to parse the kubeconfig: %v", err)
+	}
+	user := fmt.Sprintf("%s-alice", containerService.Properties.MasterProfile.DNSPrefix)
+	if len(config.Users) != 1 || config.Users[0].Name != user || config.Contexts[0].Context.User != user {
+		t.Errorf("expected the kubeconfig user to be %s, got %v", user, config.Users)
+	}
+	if config.Users[0].User.ClientCertificateData != base64.StdEncoding.EncodeToString([]byte("alice-certificate")) ||
+		config.Users[0].User.ClientKeyData != base64.StdEncoding.EncodeToString([]byte("alice-key")) {
+		t.Errorf("expected the kubeconfig to embed the client certificate and key")
+	}
+
+	if _, err = GenerateClientKubeConfig(&api.Properties{}, "westus2", "alice", "", ""); err == nil {
+		t.Errorf("Expected an error result from nil Properties child properties")
+	}
+}
+
 func TestMakeMasterExtensionScriptCommands(t *testing.T) {
 	cs := &api.ContainerService{
 		Properties: &api.Properties{
@@ -3128,6 +3182,62 @@ func TestGetAddonFuncMap(t *testing.T) {
 	}
 }
 
+func TestKubeletServerTLSBootstrapAddons(t *testing.T) {
+	render := func(cs *api.ContainerService, addon api.KubernetesAddon, file string) string {
+		b, err := Asset("k8s/addons/" + file)
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %s", file, err)
+		}
+		templ, err := template.New("addon resolver template").Funcs(getAddonFuncMap(addon, cs)).Parse(string(b))
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %s", file, err)
+		}
+		var buffer bytes.Buffer
+		if err = templ.Execute(&buffer, addon); err != nil {
+			t.Fatalf("unexpected error executing %s: %s", file, err)
+		}
+		return buffer.String()
+	}
+	metricsServer := api.KubernetesAddon{
+		Name:       common.MetricsServerAddonName,
+		Containers: []api.KubernetesContainerSpec{{Name: common.MetricsServerAddonName}},
+	}
+	approver := api.KubernetesAddon{
+		Name: common.KubeletCSRApproverAddonName,
+		Config: map[string]string{
+			"providerIPPrefixes":  "10.239.0.0/16",
+			"bypassDNSResolution": "true",
+		},
+		Containers: []api.KubernetesContainerSpec{{Name: common.KubeletCSRApproverAddonName}},
+	}
+
+	cs := api.CreateMockContainerService("testcluster", "", 1, 1, false)
+	cs.Properties.ClusterID = "99117399"
+	if !strings.Contains(render(cs, metricsServer, metricsServerAddonSourceFilename), "--kubelet-insecure-tls") {
+		t.Errorf("expected metrics-server to skip the verification of self-signed kubelet serving certificates")
+	}
+
+	cs.Properties.OrchestratorProfile.KubernetesConfig.EnableKubeletServerTLSBootstrap = to.BoolPtr(true)
+	if strings.Contains(render(cs, metricsServer, metricsServerAddonSourceFilename), "--kubelet-insecure-tls") {
+		t.Errorf("expected metrics-server to verify the kubelet serving certificates signed by the cluster CA")
+	}
+	manifest := render(cs, approver, kubeletCSRApproverAddonSourceFilename)
+	for _, expected := range []string{
+		"value: '^(k8s-master-99117399-|k8s-agentpool1-99117399-)[0-9a-z]+$'",
+		"value: \"10.239.0.0/16\"",
+		"- kubernetes.io/kubelet-serving",
+	} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("expected kubelet-csr-approver manifest to contain %s", expected)
+		}
+	}
+
+	approver.Config["providerRegex"] = "^k8s-.*$"
+	if !strings.Contains(render(cs, approver, kubeletCSRApproverAddonSourceFilename), "value: '^k8s-.*$'") {
+		t.Errorf("expected kubelet-csr-approver manifest to use the providerRegex config")
+	}
+}
+
 func TestGetComponentFuncMap(t *testing.T) {
 	specConfig := api.AzureCloudSpecEnvMap["AzurePublicCloud"].KubernetesSpecConfig
 	k8sComponentsByVersionMap := api.GetK8sComponentsByVersionMap(&api.KubernetesConfig{KubernetesImageBaseType: common.KubernetesImageBaseTypeGCR})
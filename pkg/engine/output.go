@@ -14,9 +14,22 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// APIModelFormatJSON writes the API model to apimodel.json
+	APIModelFormatJSON = "json"
+	// APIModelFormatYAML writes the API model to apimodel.yaml
+	APIModelFormatYAML = "yaml"
+)
+
 // ArtifactWriter represents the object that writes artifacts
 type ArtifactWriter struct {
 	Translator *i18n.Translator
+	// APIModelFormat is the format of the API model artifact, APIModelFormatJSON unless set
+	APIModelFormat string
+	// OriginalAPIModel is the YAML API model the artifacts are generated from, whose comments are preserved where possible
+	OriginalAPIModel []byte
+	// EncryptionKey encrypts the API model, the parameters, the kubeconfigs and the certificates and keys if set
+	EncryptionKey *helpers.EncryptionKey
 }
 
 // WriteTLSArtifacts saves TLS certificates and keys to the server filesystem
@@ -27,7 +40,8 @@ func (w *ArtifactWriter) WriteTLSArtifacts(containerService *api.ContainerServic
 	}
 
 	f := &helpers.FileSaver{
-		Translator: w.Translator,
+		Translator:    w.Translator,
+		EncryptionKey: w.EncryptionKey,
 	}
 
 	// convert back the API object, and write it
@@ -37,17 +51,27 @@ func (w *ArtifactWriter) WriteTLSArtifacts(containerService *api.ContainerServic
 		apiloader := &api.Apiloader{
 			Translator: w.Translator,
 		}
-		b, err = apiloader.SerializeContainerService(containerService, apiVersion)
+		apiModelFile := "apimodel.json"
+		if w.APIModelFormat == APIModelFormatYAML {
+			apiModelFile = "apimodel.yaml"
+			b, err = apiloader.SerializeContainerServiceYAML(containerService, apiVersion, w.OriginalAPIModel)
+		} else {
+			b, err = apiloader.SerializeContainerService(containerService, apiVersion)
+		}
 
 		if err != nil {
 			return err
 		}
 
-		if e := f.SaveFile(artifactsDir, "apimodel.json", b); e != nil {
+		if e := f.SaveFile(artifactsDir, apiModelFile, b); e != nil {
 			return e
 		}
 
-		if e := f.SaveFileString(artifactsDir, "azuredeploy.json", template); e != nil {
+		// the template has no secret, it is not encrypted
+		t := &helpers.FileSaver{
+			Translator: w.Translator,
+		}
+		if e := t.SaveFileString(artifactsDir, "azuredeploy.json", template); e != nil {
 			return e
 		}
 	}
@@ -82,7 +106,7 @@ func (w *ArtifactWriter) WriteTLSArtifacts(containerService *api.ContainerServic
 	if e := f.SaveFileString(artifactsDir, "ca.key", properties.CertificateProfile.CaPrivateKey); e != nil {
 		return e
 	}
-	if e := f.SaveFileString(artifactsDir, "ca.crt", properties.CertificateProfile.CaCertificate); e != nil {
+	if e := f.SaveFileString(artifactsDir, "ca.crt", properties.CertificateProfile.GetCACertificateBundle()); e != nil {
 		return e
 	}
 	if e := f.SaveFileString(artifactsDir, "apiserver.key", properties.CertificateProfile.APIServerPrivateKey); e != nil {
@@ -128,6 +152,20 @@ func (w *ArtifactWriter) WriteTLSArtifacts(containerService *api.ContainerServic
 			return e
 		}
 	}
+	if properties.CertificateProfile.HasSeparateCAs() {
+		for _, file := range []struct{ name, content string }{
+			{"etcd-ca.key", properties.CertificateProfile.EtcdCaPrivateKey},
+			{"etcd-ca.crt", properties.CertificateProfile.EtcdCaCertificate},
+			{"proxy-ca.key", properties.CertificateProfile.FrontProxyCaPrivateKey},
+			{"proxy-ca.crt", properties.CertificateProfile.FrontProxyCaCertificate},
+			{"proxy.key", properties.CertificateProfile.FrontProxyClientPrivateKey},
+			{"proxy.crt", properties.CertificateProfile.FrontProxyClientCertificate},
+		} {
+			if e := f.SaveFileString(artifactsDir, file.name, file.content); e != nil {
+				return e
+			}
+		}
+	}
 
 	return nil
 }
@@ -146,6 +146,10 @@ func assignKubernetesParameters(properties *api.Properties, parametersMap params
 	 - etcdServerPrivateKey
 	 - etcdPeerCertificates
 	 - etcdPeerPrivateKeys
+	 - etcdCaCertificate
+	 - frontProxyCaCertificate
+	 - frontProxyClientCertificate
+	 - frontProxyClientPrivateKey
 
 	 To refer to a keyvault secret, the value of the parameter in the api model file should be formatted as:
 
@@ -172,7 +176,7 @@ func assignKubernetesParameters(properties *api.Properties, parametersMap params
 	if certificateProfile != nil {
 		addSecret(parametersMap, "apiServerCertificate", certificateProfile.APIServerCertificate, true)
 		addSecret(parametersMap, "apiServerPrivateKey", certificateProfile.APIServerPrivateKey, true)
-		addSecret(parametersMap, "caCertificate", certificateProfile.CaCertificate, true)
+		addSecret(parametersMap, "caCertificate", certificateProfile.GetCACertificateBundle(), true)
 		addSecret(parametersMap, "caPrivateKey", certificateProfile.CaPrivateKey, true)
 		addSecret(parametersMap, "clientCertificate", certificateProfile.ClientCertificate, true)
 		addSecret(parametersMap, "clientPrivateKey", certificateProfile.ClientPrivateKey, true)
@@ -189,6 +193,13 @@ func assignKubernetesParameters(properties *api.Properties, parametersMap params
 			for i, pk := range certificateProfile.EtcdPeerPrivateKeys {
 				addSecret(parametersMap, "etcdPeerPrivateKey"+strconv.Itoa(i), pk, true)
 			}
+			// the private keys of the separate CAs stay in the apimodel, the nodes do not sign certificates with them
+			if certificateProfile.HasSeparateCAs() {
+				addSecret(parametersMap, "etcdCaCertificate", certificateProfile.EtcdCaCertificate, true)
+				addSecret(parametersMap, "frontProxyCaCertificate", certificateProfile.FrontProxyCaCertificate, true)
+				addSecret(parametersMap, "frontProxyClientCertificate", certificateProfile.FrontProxyClientCertificate, true)
+				addSecret(parametersMap, "frontProxyClientPrivateKey", certificateProfile.FrontProxyClientPrivateKey, true)
+			}
 		}
 	}
 
@@ -673,6 +673,9 @@ version = 2
 		"IsDashboardAddonEnabled": func() bool {
 			return cs.Properties.OrchestratorProfile.KubernetesConfig.IsAddonEnabled(common.DashboardAddonName)
 		},
+		"HasSeparateCAs": func() bool {
+			return cs.Properties.CertificateProfile.HasSeparateCAs()
+		},
 		"IsPodSecurityPolicyAddonEnabled": func() bool {
 			return cs.Properties.OrchestratorProfile.KubernetesConfig.IsAddonEnabled(common.PodSecurityPolicyAddonName)
 		},
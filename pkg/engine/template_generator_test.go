@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"text/template"
 
@@ -38,6 +39,48 @@ func TestGenerateTemplateV2(t *testing.T) {
 	}
 }
 
+func TestGenerateTemplateV2SeparateCAs(t *testing.T) {
+	tg, _ := InitializeTemplateGenerator(Context{})
+
+	cs := &api.ContainerService{}
+	if err := json.Unmarshal([]byte(getAPIModelString()), &cs); err != nil {
+		t.Fatalf("unexpected error while unmarshalling the apiModel JSON: %s", err.Error())
+	}
+	cs.Properties.CertificateProfile.SeparateCAs = to.BoolPtr(true)
+	cs.Properties.CertificateProfile.EtcdCaCertificate = "etcdCaCertificate"
+	cs.Properties.CertificateProfile.FrontProxyCaCertificate = "frontProxyCaCertificate"
+	cs.Properties.CertificateProfile.FrontProxyClientCertificate = "frontProxyClientCertificate"
+	cs.Properties.CertificateProfile.FrontProxyClientPrivateKey = "frontProxyClientPrivateKey"
+
+	template, parameters, err := tg.GenerateTemplateV2(cs, DefaultGeneratorCode, TestAKSEngineVersion)
+	if err != nil {
+		t.Fatalf("unexpected error while generating the template: %s", err.Error())
+	}
+	var armTemplate struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if err = json.Unmarshal([]byte(template), &armTemplate); err != nil {
+		t.Fatalf("unexpected error while unmarshalling the template: %s", err.Error())
+	}
+	if _, ok := armTemplate.Parameters["etcdCaCertificate"]; !ok {
+		t.Errorf("expected the template to declare the etcdCaCertificate parameter")
+	}
+	for _, expected := range []string{"ETCD_CA_CERTIFICATE=", "FRONT_PROXY_CLIENT_PRIVATE_KEY=", "/etc/kubernetes/certs/etcd-ca.crt"} {
+		if !strings.Contains(template, expected) {
+			t.Errorf("expected the template to contain %s", expected)
+		}
+	}
+	for _, expected := range []string{"etcdCaCertificate", "frontProxyCaCertificate", "frontProxyClientCertificate", "frontProxyClientPrivateKey"} {
+		if !strings.Contains(parameters, `"`+expected+`"`) {
+			t.Errorf("expected the parameters to contain %s", expected)
+		}
+	}
+	// the private keys of the separate CAs are not sent to the nodes
+	if strings.Contains(parameters, "etcdCaPrivateKey") || strings.Contains(parameters, "frontProxyCaPrivateKey") {
+		t.Errorf("expected the parameters not to contain the private keys of the separate CAs")
+	}
+}
+
 func TestGetTemplateFuncMap(t *testing.T) {
 	funcmap, err := getFuncMap(getAPIModelString())
 	if err != nil {
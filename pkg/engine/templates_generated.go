@@ -13156,6 +13156,163 @@ func k8sAddonsKubeProxyYaml() (*asset, error) {
 	return a, nil
 }
 
+var _k8sAddonsKubeletCsrApproverYaml = []byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kubelet-csr-approver
+  namespace: kube-system
+  labels:
+    k8s-app: kubelet-csr-approver
+    addonmanager.kubernetes.io/mode: {{GetMode}}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:kubelet-csr-approver
+  labels:
+    k8s-app: kubelet-csr-approver
+    addonmanager.kubernetes.io/mode: {{GetMode}}
+rules:
+- apiGroups:
+  - certificates.k8s.io
+  resources:
+  - certificatesigningrequests
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - certificates.k8s.io
+  resources:
+  - certificatesigningrequests/approval
+  verbs:
+  - update
+- apiGroups:
+  - certificates.k8s.io
+  resources:
+  - signers
+  resourceNames:
+  - kubernetes.io/kubelet-serving
+  verbs:
+  - approve
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - create
+  - patch
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: system:kubelet-csr-approver
+  labels:
+    k8s-app: kubelet-csr-approver
+    addonmanager.kubernetes.io/mode: {{GetMode}}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:kubelet-csr-approver
+subjects:
+- kind: ServiceAccount
+  name: kubelet-csr-approver
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kubelet-csr-approver
+  namespace: kube-system
+  labels:
+    k8s-app: kubelet-csr-approver
+    addonmanager.kubernetes.io/mode: {{GetMode}}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      k8s-app: kubelet-csr-approver
+  template:
+    metadata:
+      labels:
+        k8s-app: kubelet-csr-approver
+    spec:
+      containers:
+      - name: kubelet-csr-approver
+        image: {{ContainerImage "kubelet-csr-approver"}}
+        imagePullPolicy: IfNotPresent
+        args:
+        - -metrics-bind-address
+        - ":8080"
+        - -health-probe-bind-address
+        - ":8081"
+        env:
+        - name: PROVIDER_REGEX
+          value: '{{if ContainerConfig "providerRegex"}}{{ContainerConfig "providerRegex"}}{{else}}{{GetNodeNameRegexp}}{{end}}'
+        - name: PROVIDER_IP_PREFIXES
+          value: "{{ContainerConfig "providerIPPrefixes"}}"
+        - name: BYPASS_DNS_RESOLUTION
+          value: "{{ContainerConfig "bypassDNSResolution"}}"
+        ports:
+        - containerPort: 8080
+          name: metrics
+          protocol: TCP
+        livenessProbe:
+          httpGet:
+            path: /healthz
+            port: 8081
+          initialDelaySeconds: 15
+          periodSeconds: 20
+        readinessProbe:
+          httpGet:
+            path: /readyz
+            port: 8081
+          periodSeconds: 10
+        resources:
+          limits:
+            cpu: {{ContainerCPULimits "kubelet-csr-approver"}}
+            memory: {{ContainerMemLimits "kubelet-csr-approver"}}
+          requests:
+            cpu: {{ContainerCPUReqs "kubelet-csr-approver"}}
+            memory: {{ContainerMemReqs "kubelet-csr-approver"}}
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop:
+            - ALL
+          readOnlyRootFilesystem: true
+          runAsNonRoot: true
+          runAsUser: 65532
+      nodeSelector:
+        kubernetes.io/os: linux
+      priorityClassName: system-cluster-critical
+      serviceAccountName: kubelet-csr-approver
+      tolerations:
+      - key: node-role.kubernetes.io/master
+        effect: NoSchedule
+        operator: "Exists"
+      - key: node-role.kubernetes.io/control-plane
+        effect: NoSchedule
+        operator: "Exists"
+      - key: CriticalAddonsOnly
+        operator: "Exists"
+`)
+
+func k8sAddonsKubeletCsrApproverYamlBytes() ([]byte, error) {
+	return _k8sAddonsKubeletCsrApproverYaml, nil
+}
+
+func k8sAddonsKubeletCsrApproverYaml() (*asset, error) {
+	bytes, err := k8sAddonsKubeletCsrApproverYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "k8s/addons/kubelet-csr-approver.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _k8sAddonsKubernetesDashboardYaml = []byte(`{{- /* Note: dashboard addon is deprecated */}}
 apiVersion: v1
 kind: Namespace
@@ -13618,7 +13775,9 @@ spec:
         - --kubelet-preferred-address-types=InternalIP,ExternalIP,Hostname
         - --kubelet-use-node-status-port
         - --metric-resolution=15s
+{{- if not IsKubeletServerTLSBootstrapEnabled}}
         - --kubelet-insecure-tls
+{{- end}}
         image: {{ContainerImage "metrics-server"}}
         imagePullPolicy: IfNotPresent
         livenessProbe:
@@ -15575,6 +15734,13 @@ configureSecrets(){
   echo "${ETCD_SERVER_CERTIFICATE}" | base64 --decode >"${etcdserver_crt}"
   echo "${ETCD_CLIENT_CERTIFICATE}" | base64 --decode >"${etcdclient_crt}"
   echo "${ETCD_PEER_CERT}" | base64 --decode >"${etcdpeer_crt}"
+{{- if HasSeparateCAs}}
+  local etcdca_crt="/etc/kubernetes/certs/etcd-ca.crt"
+  touch "${etcdca_crt}"
+  chmod 0644 "${etcdca_crt}"
+  chown root:root "${etcdca_crt}"
+  echo "${ETCD_CA_CERTIFICATE}" | base64 --decode >"${etcdca_crt}"
+{{- end}}
 }
 configureEtcd() {
   set -x
@@ -15646,9 +15812,21 @@ ensureCron() {
   fi
 }
 generateAggregatedAPICerts() {
+{{- if HasSeparateCAs}}
+  {{- /* The front-proxy certificates are signed by the front-proxy CA of the apimodel */}}
+  local proxyca_crt="/etc/kubernetes/certs/proxy-ca.crt" proxy_crt="/etc/kubernetes/certs/proxy.crt" proxy_key="/etc/kubernetes/certs/proxy.key"
+  touch "${proxyca_crt}" "${proxy_crt}" "${proxy_key}"
+  chmod 0644 "${proxyca_crt}" "${proxy_crt}"
+  chmod 0600 "${proxy_key}"
+  chown root:root "${proxyca_crt}" "${proxy_crt}" "${proxy_key}"
+  echo "${FRONT_PROXY_CA_CERTIFICATE}" | base64 --decode >"${proxyca_crt}"
+  echo "${FRONT_PROXY_CLIENT_CERTIFICATE}" | base64 --decode >"${proxy_crt}"
+  echo "${FRONT_PROXY_CLIENT_PRIVATE_KEY}" | base64 --decode >"${proxy_key}"
+{{- else}}
   local f=/etc/kubernetes/generate-proxy-certs.sh
   wait_for_file 1200 1 $f || exit {{GetCSEErrorCode "ERR_FILE_WATCH_TIMEOUT"}}
   $f
+{{- end}}
 }
 configureKubeletServerCert() {
   local kubeletserver_key="/etc/kubernetes/certs/kubeletserver.key" kubeletserver_crt="/etc/kubernetes/certs/kubeletserver.crt"
@@ -16055,7 +16233,7 @@ ensureLabelExclusionForAzurePolicyAddon() {
 {{end}}
 ensureEtcd() {
   local etcd_client_url="https://${PRIVATE_IP}:2379"
-  retrycmd 120 5 25 curl --cacert /etc/kubernetes/certs/ca.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${etcd_client_url}/v2/machines || exit {{GetCSEErrorCode "ERR_ETCD_RUNNING_TIMEOUT"}}
+  retrycmd 120 5 25 curl --cacert /etc/kubernetes/certs/{{if HasSeparateCAs}}etcd-ca{{else}}ca{{end}}.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${etcd_client_url}/v2/machines || exit {{GetCSEErrorCode "ERR_ETCD_RUNNING_TIMEOUT"}}
   wait_for_file 1200 1 /etc/systemd/system/etcd-monitor.service || exit {{GetCSEErrorCode "ERR_FILE_WATCH_TIMEOUT"}}
   systemctlEnableAndStart etcd-monitor || exit {{GetCSEErrorCode "ERR_SYSTEMCTL_START_FAIL"}}
 }
@@ -17964,7 +18142,7 @@ etcd_monitoring() {
   sleep 300 {{/* Wait for 5 minutes for etcd to be functional/stable */}}
   local max_seconds=10 output=""
   local endpoint="https://${PRIVATE_IP}:2379"
-  local monitor_cmd="curl -s -S -m ${max_seconds} --cacert /etc/kubernetes/certs/ca.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${endpoint}/v2/machines"
+  local monitor_cmd="curl -s -S -m ${max_seconds} --cacert /etc/kubernetes/certs/{{if HasSeparateCAs}}etcd-ca{{else}}ca{{end}}.crt --cert /etc/kubernetes/certs/etcdclient.crt --key /etc/kubernetes/certs/etcdclient.key ${endpoint}/v2/machines"
   while true; do
     if ! output=$(${monitor_cmd}); then
       echo $output
@@ -20322,6 +20500,32 @@ var _k8sKubernetesparamsT = []byte(`    "etcdServerCertificate": {
         },
       {{end}}
     {{end}}
+    {{if HasSeparateCAs}}
+    "etcdCaCertificate": {
+      "metadata": {
+        "description": "The base 64 certificate authority certificate of etcd"
+      },
+      "type": "string"
+    },
+    "frontProxyCaCertificate": {
+      "metadata": {
+        "description": "The base 64 certificate authority certificate of the aggregation layer"
+      },
+      "type": "string"
+    },
+    "frontProxyClientCertificate": {
+      "metadata": {
+        "description": "The base 64 client certificate used by the apiserver to proxy requests to the aggregated apiservers"
+      },
+      "type": "string"
+    },
+    "frontProxyClientPrivateKey": {
+      "metadata": {
+        "description": "The base 64 client private key used by the apiserver to proxy requests to the aggregated apiservers"
+      },
+      "type": "securestring"
+    },
+    {{end}}
     "apiServerCertificate": {
       "metadata": {
         "description": "The base 64 server certificate used on the master"
@@ -22153,11 +22357,35 @@ cp_certs() {
   cp -p ${NEW_CERTS_DIR}/client.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/apiserver.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/kubeconfig /home/$(logname)/.kube/config
+  cp_etcd_ca
+  if [ -f ${NEW_CERTS_DIR}/proxy-ca.crt ]; then
+    cp -p ${NEW_CERTS_DIR}/proxy-ca.crt ${NEW_CERTS_DIR}/proxy.* /etc/kubernetes/certs/
+  fi
 
   rm -f /var/lib/kubelet/pki/kubelet-client-current.pem
 }
 
+# etcd-ca.crt is only distributed when etcd has its own CA,
+# etcd and its clients trust ca.crt otherwise
+cp_etcd_ca() {
+  local ca_file=/etc/kubernetes/certs/ca.crt
+  if [ -f ${NEW_CERTS_DIR}/etcd-ca.crt ]; then
+    cp -p ${NEW_CERTS_DIR}/etcd-ca.crt /etc/kubernetes/certs/
+    ca_file=/etc/kubernetes/certs/etcd-ca.crt
+  fi
+  if [ -f /etc/default/etcd ]; then
+    sed -i -E "s#(--peer-trusted-ca-file=|--trusted-ca-file=)/etc/kubernetes/certs/(etcd-)?ca.crt#\1${ca_file}#g" /etc/default/etcd
+    sed -i -E "s#^ETCDCTL_CA_FILE=.*#ETCDCTL_CA_FILE=${ca_file}#" /etc/environment
+    sed -i -E "s#--etcd-cafile=/etc/kubernetes/certs/(etcd-)?ca.crt#--etcd-cafile=${ca_file}#" /etc/kubernetes/manifests/kube-apiserver.yaml
+    sed -i -E "s#--cacert /etc/kubernetes/certs/(etcd-)?ca.crt --cert /etc/kubernetes/certs/etcdclient.crt#--cacert ${ca_file} --cert /etc/kubernetes/certs/etcdclient.crt#" /usr/local/bin/health-monitor.sh
+  fi
+}
+
 cp_proxy() {
+  # the front-proxy certificates signed by the front-proxy CA are copied by cp_certs
+  if [ -f ${NEW_CERTS_DIR}/proxy-ca.crt ]; then
+    return
+  fi
   source /etc/environment
   local NODE_INDEX
   NODE_INDEX=$(hostname | tail -c 2)
@@ -22167,6 +22395,22 @@ cp_proxy() {
   /etc/kubernetes/generate-proxy-certs.sh
 }
 
+# restarts etcd and the control plane static pods so they load the new certificates signed by the current CA,
+# the kubelet recreates the static pods once their manifests are moved back
+restart_control_plane() {
+  if [ -f /etc/default/etcd ]; then
+    systemctl_restart 10 5 60 etcd
+  fi
+  mkdir -p ${WD}/manifests
+  # the manifests are already moved out if a previous run stopped halfway
+  if compgen -G "/etc/kubernetes/manifests/*.yaml" >/dev/null; then
+    mv /etc/kubernetes/manifests/*.yaml ${WD}/manifests/
+    sleep 30
+  fi
+  mv ${WD}/manifests/*.yaml /etc/kubernetes/manifests/
+  systemctl_restart 10 5 10 kubelet
+}
+
 agent_certs() {
   cp -p ${NEW_CERTS_DIR}/ca.* /etc/kubernetes/certs/
   cp -p ${NEW_CERTS_DIR}/client.* /etc/kubernetes/certs/
@@ -24017,6 +24261,7 @@ var _bindata = map[string]func() (*asset, error){
 	"k8s/addons/ip-masq-agent.yaml":                                      k8sAddonsIpMasqAgentYaml,
 	"k8s/addons/kube-dns.yaml":                                           k8sAddonsKubeDnsYaml,
 	"k8s/addons/kube-proxy.yaml":                                         k8sAddonsKubeProxyYaml,
+	"k8s/addons/kubelet-csr-approver.yaml":                               k8sAddonsKubeletCsrApproverYaml,
 	"k8s/addons/kubernetes-dashboard.yaml":                               k8sAddonsKubernetesDashboardYaml,
 	"k8s/addons/metrics-server.yaml":                                     k8sAddonsMetricsServerYaml,
 	"k8s/addons/node-problem-detector.yaml":                              k8sAddonsNodeProblemDetectorYaml,
@@ -24169,6 +24414,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"ip-masq-agent.yaml":                    {k8sAddonsIpMasqAgentYaml, map[string]*bintree{}},
 			"kube-dns.yaml":                         {k8sAddonsKubeDnsYaml, map[string]*bintree{}},
 			"kube-proxy.yaml":                       {k8sAddonsKubeProxyYaml, map[string]*bintree{}},
+			"kubelet-csr-approver.yaml":             {k8sAddonsKubeletCsrApproverYaml, map[string]*bintree{}},
 			"kubernetes-dashboard.yaml":             {k8sAddonsKubernetesDashboardYaml, map[string]*bintree{}},
 			"metrics-server.yaml":                   {k8sAddonsMetricsServerYaml, map[string]*bintree{}},
 			"node-problem-detector.yaml":            {k8sAddonsNodeProblemDetectorYaml, map[string]*bintree{}},
@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+// MergeAPIModelLayers takes the paths to a base ApiModel JSON or YAML file and to its overlays,
+// deep-merges the overlays into the base in order and writes the result to a temp file
+func MergeAPIModelLayers(apiModelPaths []string) (string, error) {
+	if len(apiModelPaths) == 0 {
+		return "", errors.New("no API model to merge")
+	}
+	merged, err := helpers.ReadDecryptedFile(apiModelPaths[0])
+	if err != nil {
+		return "", err
+	}
+	if merged, err = api.ConvertYAMLToJSON(merged); err != nil {
+		return "", errors.Wrapf(err, "parsing API model %s", apiModelPaths[0])
+	}
+	for _, overlayPath := range apiModelPaths[1:] {
+		overlay, err := helpers.ReadDecryptedFile(overlayPath)
+		if err != nil {
+			return "", err
+		}
+		if merged, err = OverlayAPIModel(merged, overlay); err != nil {
+			return "", errors.Wrapf(err, "merging API model overlay %s", overlayPath)
+		}
+	}
+	return writeMergedAPIModel(merged)
+}
+
+// OverlayAPIModel deep-merges the API model JSON or YAML document overlay into the API model JSON document base.
+// Objects are merged key by key, regardless of the case of the keys, and null removes a key. Arrays of objects
+// that all have a name, like agentPoolProfiles or addons, are merged element by element matching the names,
+// the elements with a new name are appended. Other values, including other arrays, replace the base values.
+func OverlayAPIModel(base, overlay []byte) ([]byte, error) {
+	overlay, err := api.ConvertYAMLToJSON(overlay)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeJSON(base)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the API model")
+	}
+	o, err := decodeJSON(overlay)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the API model overlay")
+	}
+	bo, ok := b.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the API model is not a JSON object")
+	}
+	oo, ok := o.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the API model overlay is not a JSON object")
+	}
+	if bv, ov := bo[lookupKey(bo, "apiVersion")], oo[lookupKey(oo, "apiVersion")]; bv != nil && ov != nil && bv != ov {
+		return nil, errors.Errorf("the API model overlay apiVersion %v does not match the API model apiVersion %v", ov, bv)
+	}
+	return json.Marshal(overlayJSON(bo, oo))
+}
+
+// overlayJSON deep-merges the JSON value overlay into base and returns the result, see OverlayAPIModel
+func overlayJSON(base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			b = map[string]interface{}{}
+		}
+		for k, v := range o {
+			key := lookupKey(b, k)
+			if v == nil {
+				delete(b, key)
+				continue
+			}
+			b[key] = overlayJSON(b[key], v)
+		}
+		return b
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok || !allNamed(b) || !allNamed(o) {
+			return o
+		}
+		for _, element := range o {
+			name := elementName(element)
+			matched := false
+			for i := range b {
+				if elementName(b[i]) == name {
+					b[i] = overlayJSON(b[i], element)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				b = append(b, element)
+			}
+		}
+		return b
+	default:
+		return overlay
+	}
+}
+
+// allNamed returns true if a is not empty and all its elements are objects with a name
+func allNamed(a []interface{}) bool {
+	for _, element := range a {
+		if elementName(element) == "" {
+			return false
+		}
+	}
+	return len(a) > 0
+}
+
+// elementName returns the name of the array element e, or an empty string if it is not an object with a name
+func elementName(e interface{}) string {
+	o, ok := e.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for k, v := range o {
+		if strings.EqualFold(k, "name") {
+			name, _ := v.(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// writeMergedAPIModel writes the merged API model to a new temp file and returns its path.
+// The file holds the decrypted API model, the caller removes it once loaded.
+func writeMergedAPIModel(merged []byte) (string, error) {
+	tmpFile, err := os.CreateTemp("", "mergedApiModel")
+	if err != nil {
+		return "", err
+	}
+
+	tmpFileName := tmpFile.Name()
+	err = os.WriteFile(tmpFileName, merged, os.ModeAppend)
+	if err != nil {
+		_ = os.Remove(tmpFileName)
+		return "", err
+	}
+
+	return tmpFileName, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOverlayAPIModel(t *testing.T) {
+	RegisterTestingT(t)
+
+	base := `{"apiVersion":"vlabs","location":"local","properties":{
+		"masterProfile":{"count":1,"dnsPrefix":"base","vnetSubnetId":"/subnets/base"},
+		"agentPoolProfiles":[{"name":"linuxpool","count":3},{"name":"windowspool","count":2,"osType":"Windows"}],
+		"orchestratorProfile":{"kubernetesConfig":{"addons":[{"name":"coredns","enabled":true}],"kubeletConfig":{"--max-pods":"30"}}},
+		"linuxProfile":{"ssh":{"publicKeys":[{"keyData":"ssh-rsa BASE"}]}}}}`
+	cases := []struct {
+		name, overlay, expected, err string
+	}{
+		{
+			name:    "objects are merged regardless of the case of the keys",
+			overlay: `{"location":"stamp3","properties":{"MasterProfile":{"count":3,"vnetSubnetId":null}}}`,
+			expected: `{"apiVersion":"vlabs","location":"stamp3","properties":{
+				"masterProfile":{"count":3,"dnsPrefix":"base"},
+				"agentPoolProfiles":[{"name":"linuxpool","count":3},{"name":"windowspool","count":2,"osType":"Windows"}],
+				"orchestratorProfile":{"kubernetesConfig":{"addons":[{"name":"coredns","enabled":true}],"kubeletConfig":{"--max-pods":"30"}}},
+				"linuxProfile":{"ssh":{"publicKeys":[{"keyData":"ssh-rsa BASE"}]}}}}`,
+		},
+		{
+			name: "named array elements are matched by name",
+			overlay: `properties:
+  agentPoolProfiles:
+  - name: windowspool
+    count: 0
+  - name: gpupool
+    count: 1
+  orchestratorProfile:
+    kubernetesConfig:
+      addons:
+      - name: coredns
+        enabled: false
+      kubeletConfig:
+        --node-status-update-frequency: 1m
+  linuxProfile:
+    ssh:
+      publicKeys:
+      - keyData: ssh-rsa STAMP3
+`,
+			expected: `{"apiVersion":"vlabs","location":"local","properties":{
+				"masterProfile":{"count":1,"dnsPrefix":"base","vnetSubnetId":"/subnets/base"},
+				"agentPoolProfiles":[{"name":"linuxpool","count":3},{"name":"windowspool","count":0,"osType":"Windows"},{"name":"gpupool","count":1}],
+				"orchestratorProfile":{"kubernetesConfig":{"addons":[{"name":"coredns","enabled":false}],"kubeletConfig":{"--max-pods":"30","--node-status-update-frequency":"1m"}}},
+				"linuxProfile":{"ssh":{"publicKeys":[{"keyData":"ssh-rsa STAMP3"}]}}}}`,
+		},
+		{
+			name:    "apiVersion must match",
+			overlay: `{"apiVersion":"2017-07-01"}`,
+			err:     "the API model overlay apiVersion 2017-07-01 does not match the API model apiVersion vlabs",
+		},
+		{
+			name:    "overlay must be an object",
+			overlay: `[]`,
+			err:     "the API model overlay is not a JSON object",
+		},
+	}
+	for _, c := range cases {
+		merged, err := OverlayAPIModel([]byte(base), []byte(c.overlay))
+		if c.err != "" {
+			Expect(err).To(MatchError(c.err), c.name)
+			continue
+		}
+		Expect(err).NotTo(HaveOccurred(), c.name)
+		Expect(merged).To(MatchJSON(c.expected), c.name)
+	}
+}
+
+func TestMergeAPIModelLayers(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir := t.TempDir()
+	overlay1 := filepath.Join(dir, "stamp.yaml")
+	Expect(os.WriteFile(overlay1, []byte("properties:\n  masterProfile:\n    count: 3\n"), 0600)).To(Succeed())
+	overlay2 := filepath.Join(dir, "stamp-override.json")
+	Expect(os.WriteFile(overlay2, []byte(`{"properties":{"masterProfile":{"count":5},"agentPoolProfiles":[{"name":"agentpool2","count":1}]}}`), 0600)).To(Succeed())
+
+	tmpFile, err := MergeAPIModelLayers([]string{"../testdata/simple/kubernetes.json", overlay1, overlay2})
+	Expect(err).NotTo(HaveOccurred())
+	merged, err := os.ReadFile(tmpFile)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(merged)).To(ContainSubstring(`"masterProfile":{"count":5,"dnsPrefix":"masterdns1","vmSize":"Standard_D2_v2"}`))
+	Expect(string(merged)).To(ContainSubstring(`{"availabilityProfile":"AvailabilitySet","count":1,"name":"agentpool2","vmSize":"Standard_D2_v2"}`))
+
+	_, err = MergeAPIModelLayers([]string{"../testdata/simple/kubernetes.json", filepath.Join(dir, "missing.json")})
+	Expect(err).To(HaveOccurred())
+	_, err = MergeAPIModelLayers(nil)
+	Expect(err).To(MatchError("no API model to merge"))
+}
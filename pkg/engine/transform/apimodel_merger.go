@@ -4,187 +4,406 @@
 package transform
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
-	"github.com/Jeffail/gabs"
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/helpers"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 // APIModelValue represents a value in the APIModel JSON file
 type APIModelValue struct {
-	value         interface{}
-	arrayValue    bool
-	arrayIndex    int
-	arrayProperty string
-	arrayName     string
+	value interface{}
+	// path is the path of the value in the properties of the API model
+	path []pathElement
+	// order is the position of the value on the command line, values are merged in that order
+	order int
 }
 
-// MapValues converts an arraw of rwa ApiModel values (like ["masterProfile.count=4","linuxProfile.adminUsername=admin"]) to a map
+// pathElement is a key of an object, an index of an array, or the end of an array to append to
+type pathElement struct {
+	key    string
+	index  int
+	isKey  bool
+	append bool
+	// quoted is true for keys quoted in brackets, the keys of string maps like kubeletConfig
+	quoted bool
+}
+
+func (e pathElement) String() string {
+	switch {
+	case e.isKey:
+		return e.key
+	case e.append:
+		return "[-]"
+	default:
+		return fmt.Sprintf("[%d]", e.index)
+	}
+}
+
+// MapValues converts an arraw of rwa ApiModel values (like ["masterProfile.count=4","linuxProfile.adminUsername=admin"]) to a map.
+// Keys are paths in the properties of the API model: objects keys are separated by dots, and can be quoted in brackets
+// when they contain dots or brackets (kubeletConfig["--max-pods"]), arrays are indexed in brackets (agentPoolProfiles[0])
+// and [-] appends to an array. Values are integers, booleans or strings, quoted values and the values of keys
+// quoted in brackets are always strings, as the API model maps with such keys only hold strings.
 func MapValues(m map[string]APIModelValue, setFlagValues []string) {
 	if len(setFlagValues) == 0 {
 		return
 	}
 
-	// regex to find array[index].property pattern in the key, like linuxProfile.ssh.publicKeys[0].keyData
-	re := regexp.MustCompile(`(.*?)\[(.*?)\](?:\.(.*?))?$`)
-
 	for _, setFlagValue := range setFlagValues {
-		kvpMap := parseKeyValuePairs(setFlagValue)
-		for key, keyValue := range kvpMap {
-			flagValue := APIModelValue{}
+		for _, kv := range parseKeyValuePairs(setFlagValue) {
+			path, err := parsePath(kv.key)
+			if err != nil {
+				log.Warnln(fmt.Sprintf("ignoring --set value for property %s: %s", kv.key, err))
+				continue
+			}
+			flagValue := APIModelValue{path: path, order: nextOrder(m)}
 			// try to parse the value as integer, bool or fallback to string
-			if keyValueAsInteger, err := strconv.ParseInt(keyValue, 10, 64); err == nil {
+			if kv.quoted || path[len(path)-1].quoted {
+				flagValue.value = kv.value
+			} else if keyValueAsInteger, err := strconv.ParseInt(kv.value, 10, 64); err == nil {
 				flagValue.value = keyValueAsInteger
-			} else if keyValueAsBool, err := strconv.ParseBool(keyValue); err == nil {
+			} else if keyValueAsBool, err := strconv.ParseBool(kv.value); err == nil {
 				flagValue.value = keyValueAsBool
 			} else {
-				flagValue.value = keyValue
+				flagValue.value = kv.value
 			}
+			m[kv.key] = flagValue
+		}
+	}
+}
 
-			// check if the key is an array property
-			keyArrayMatch := re.FindStringSubmatch(key)
-
-			// it's an array
-			if keyArrayMatch != nil {
-				i, err := strconv.ParseInt(keyArrayMatch[2], 10, 32)
-				if err != nil {
-					log.Warnln(fmt.Sprintf("array index is not specified for property %s", key))
-				} else {
-					arrayIndex := int(i)
-					flagValue.arrayValue = true
-					flagValue.arrayName = keyArrayMatch[1]
-					flagValue.arrayIndex = arrayIndex
-					flagValue.arrayProperty = keyArrayMatch[3]
-					m[key] = flagValue
-				}
-			} else {
-				m[key] = flagValue
-			}
+// MapJSONValues converts an array of ApiModel values whose values are JSON documents
+// (like [`agentPoolProfiles[-]={"name":"pool2","count":3,"vmSize":"Standard_D2_v2"}`]) to a map, see MapValues for the keys
+func MapJSONValues(m map[string]APIModelValue, setJSONFlagValues []string) error {
+	for _, setJSONFlagValue := range setJSONFlagValues {
+		key, value, err := splitKeyValue(setJSONFlagValue)
+		if err != nil {
+			return errors.Wrapf(err, "parsing --set-json value %s", setJSONFlagValue)
+		}
+		path, err := parsePath(key)
+		if err != nil {
+			return errors.Wrapf(err, "parsing --set-json key %s", key)
 		}
+		d := json.NewDecoder(strings.NewReader(value))
+		d.UseNumber()
+		var v interface{}
+		if err = d.Decode(&v); err != nil {
+			return errors.Wrapf(err, "parsing the JSON value of --set-json key %s", key)
+		}
+		m[key] = APIModelValue{value: v, path: path, order: nextOrder(m)}
 	}
+	return nil
+}
+
+// nextOrder returns the order of the next value added to m
+func nextOrder(m map[string]APIModelValue) int {
+	order := 0
+	for _, v := range m {
+		if v.order >= order {
+			order = v.order + 1
+		}
+	}
+	return order
 }
 
 // MergeValuesWithAPIModel takes the path to an ApiModel JSON file, loads it and merges it with the values in the map to another temp file
 func MergeValuesWithAPIModel(apiModelPath string, m map[string]APIModelValue) (string, error) {
+	return MergeAPIModel(apiModelPath, nil, m)
+}
+
+// MergeAPIModel takes the path to an ApiModel JSON or YAML file, loads it, applies the JSON Patch or JSON Merge Patch
+// files at patchPaths in order, then merges it with the values in the map to another temp file
+func MergeAPIModel(apiModelPath string, patchPaths []string, m map[string]APIModelValue) (string, error) {
 	// load the apiModel file from path
-	fileContent, err := os.ReadFile(apiModelPath)
+	fileContent, err := helpers.ReadDecryptedFile(apiModelPath)
 	if err != nil {
 		return "", err
 	}
 
-	// parse the json from file content
-	jsonObj, err := gabs.ParseJSON(fileContent)
+	// YAML API models are merged as JSON
+	if fileContent, err = api.ConvertYAMLToJSON(fileContent); err != nil {
+		return "", err
+	}
+
+	for _, patchPath := range patchPaths {
+		patch, err := os.ReadFile(patchPath)
+		if err != nil {
+			return "", errors.Wrapf(err, "reading patch file %s", patchPath)
+		}
+		if fileContent, err = PatchAPIModel(fileContent, patch); err != nil {
+			return "", errors.Wrapf(err, "applying patch file %s", patchPath)
+		}
+	}
+
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(fileContent))
+	d.UseNumber()
+	if err = d.Decode(&doc); err != nil {
+		return "", err
+	}
+
+	// update api model definition with each value in the map, in the order of the command line
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return m[keys[i]].order < m[keys[j]].order })
+	for _, key := range keys {
+		flagValue := m[key]
+		log.Debugln(fmt.Sprintf("--set flag value detected. Path: %s", formatPath(flagValue.path)))
+		path := append([]pathElement{{key: "properties", isKey: true}}, flagValue.path...)
+		if doc, err = setPath(doc, path, flagValue.value); err != nil {
+			return "", errors.Wrapf(err, "setting %s", key)
+		}
+	}
+
+	merged, err := json.Marshal(doc)
 	if err != nil {
 		return "", err
 	}
 
-	// update api model definition with each value in the map
-	for key, flagValue := range m {
-		// working on an array
-		if flagValue.arrayValue {
-			log.Debugln(fmt.Sprintf("--set flag array value detected. Name: %s, Index: %d, PropertyName: %s", flagValue.arrayName, flagValue.arrayIndex, flagValue.arrayProperty))
-			arrayPath := fmt.Sprint("properties.", flagValue.arrayName)
-			arrayValue := jsonObj.Path(arrayPath)
-			if flagValue.arrayProperty != "" {
-				c := arrayValue.Index(flagValue.arrayIndex)
-				if _, err = c.SetP(flagValue.value, flagValue.arrayProperty); err != nil {
-					return "", err
+	// generate a new file
+	return writeMergedAPIModel(merged)
+}
+
+// setPath sets value at path in node, creating the missing objects and array elements, and returns the updated node
+func setPath(node interface{}, path []pathElement, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	e, rest := path[0], path[1:]
+	if e.isKey {
+		if node == nil {
+			node = map[string]interface{}{}
+		}
+		o, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("cannot set key %s of a %s", e.key, jsonKind(node))
+		}
+		key := lookupKey(o, e.key)
+		child, err := setPath(o[key], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		o[key] = child
+		return o, nil
+	}
+	if node == nil {
+		node = []interface{}{}
+	}
+	a, ok := node.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("cannot set element %s of a %s", e, jsonKind(node))
+	}
+	index := e.index
+	if e.append {
+		index = len(a)
+	}
+	// missing elements are added as null
+	for len(a) <= index {
+		a = append(a, nil)
+	}
+	child, err := setPath(a[index], rest, value)
+	if err != nil {
+		return nil, err
+	}
+	a[index] = child
+	return a, nil
+}
+
+// lookupKey returns the key of o matching key regardless of case, as the keys of the API model
+// are not case sensitive, or key if there is none
+func lookupKey(o map[string]interface{}, key string) string {
+	if _, ok := o[key]; ok {
+		return key
+	}
+	for k := range o {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// parsePath parses the path of a value in the properties of the API model,
+// like agentPoolProfiles[0].kubernetesConfig.kubeletConfig["--max-pods"]
+func parsePath(literal string) ([]pathElement, error) {
+	var path []pathElement
+	key := ""
+	inKey := false
+	pushKey := func() {
+		if inKey {
+			path = append(path, pathElement{key: key, isKey: true})
+		}
+		key, inKey = "", false
+	}
+	for i := 0; i < len(literal); i++ {
+		switch c := literal[i]; c {
+		case '.':
+			if !inKey && (i == 0 || literal[i-1] != ']') {
+				return nil, errors.Errorf("empty key at position %d", i)
+			}
+			pushKey()
+		case '[':
+			pushKey()
+			end := i + 1
+			if end < len(literal) && (literal[end] == '"' || literal[end] == '\'') {
+				quote := literal[end]
+				closing := strings.IndexByte(literal[end+1:], quote)
+				if closing < 0 || end+closing+2 >= len(literal) || literal[end+closing+2] != ']' {
+					return nil, errors.Errorf("unterminated quoted key at position %d", i)
 				}
+				path = append(path, pathElement{key: literal[end+1 : end+1+closing], isKey: true, quoted: true})
+				i = end + closing + 2
+				continue
+			}
+			closing := strings.IndexByte(literal[end:], ']')
+			if closing < 0 {
+				return nil, errors.Errorf("unterminated index at position %d", i)
+			}
+			index := literal[end : end+closing]
+			if index == "-" {
+				path = append(path, pathElement{append: true})
 			} else {
-				count, _ := arrayValue.ArrayCount()
-				for i := count; i <= flagValue.arrayIndex; i++ {
-					if err = jsonObj.ArrayAppendP(nil, arrayPath); err != nil {
-						return "", err
-					}
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, errors.Errorf("array index %q is not a positive integer or -", index)
 				}
-				arrayValue = jsonObj.Path(arrayPath)
-				if _, err = arrayValue.SetIndex(flagValue.value, flagValue.arrayIndex); err != nil {
-					return "", err
-				}
-			}
-		} else {
-			if _, err = jsonObj.SetP(flagValue.value, fmt.Sprint("properties.", key)); err != nil {
-				return "", err
+				path = append(path, pathElement{index: n})
 			}
+			i = end + closing
+		default:
+			key += string(c)
+			inKey = true
 		}
 	}
-
-	// generate a new file
-	tmpFile, err := os.CreateTemp("", "mergedApiModel")
-	if err != nil {
-		return "", err
+	pushKey()
+	if len(path) == 0 {
+		return nil, errors.New("empty key")
 	}
+	return path, nil
+}
 
-	tmpFileName := tmpFile.Name()
-	err = os.WriteFile(tmpFileName, []byte(jsonObj.String()), os.ModeAppend)
-	if err != nil {
-		return "", err
+func formatPath(path []pathElement) string {
+	var b strings.Builder
+	for i, e := range path {
+		switch {
+		case e.isKey && strings.ContainsAny(e.key, ".[]"):
+			fmt.Fprintf(&b, "[%q]", e.key)
+		case e.isKey:
+			if i > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(e.key)
+		default:
+			b.WriteString(e.String())
+		}
 	}
+	return b.String()
+}
+
+// keyValuePair is a key and its value on the command line
+type keyValuePair struct {
+	key, value string
+	// quoted is true if the value, or a part of it, was quoted
+	quoted bool
+}
 
-	return tmpFileName, nil
+// splitKeyValue splits literal at its first = that is not part of a quoted key
+func splitKeyValue(literal string) (string, string, error) {
+	var quote rune
+	for i, c := range literal {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return literal[:i], literal[i+1:], nil
+		}
+	}
+	return "", "", errors.New("expected key=value")
 }
 
-func parseKeyValuePairs(literal string) map[string]string {
+func parseKeyValuePairs(literal string) []keyValuePair {
 	log.Debugln(fmt.Sprintf("parsing --set flag key/value pairs from %s", literal))
-	inQuoteLiteral := false
-	inDblQuoteLiteral := false
+	var quote rune
 	inKey := true
-	kvpMap := map[string]string{}
+	var kvps []keyValuePair
 
-	currentKey := ""
-	currentValue := ""
+	current := keyValuePair{}
+	push := func() {
+		log.Debugln(fmt.Sprintf("new key/value parsed: %s = %s", current.key, current.value))
+		kvps = append(kvps, current)
+		current = keyValuePair{}
+		inKey = true
+	}
 
 	for _, literalChar := range literal {
-		switch literalChar {
-		case '\'': // if we hit a ' char
-			if !inQuoteLiteral && !inDblQuoteLiteral { // and we are not already in a literal
-				inQuoteLiteral = true // start a new ' delimited literal value
-				inKey = false
-			} else if inQuoteLiteral { // we already are in a ' delimited literal value
-				inQuoteLiteral = false // stop it
-				inKey = true
-			}
-		case '"': // if we hit a " char
-			if !inDblQuoteLiteral && !inQuoteLiteral { // and we are not already in a literal
-				inDblQuoteLiteral = true // start a new " delimited literal value
-				inKey = false
-			} else if inDblQuoteLiteral { // we already are in a " delimited literal value
-				inDblQuoteLiteral = false // stop it
-				inKey = true
-			}
-		case ',': // if we hit a , char
-			if inQuoteLiteral || inDblQuoteLiteral { // we are in a literal
-				currentValue += string(literalChar)
+		switch {
+		case quote != 0: // we are in a literal
+			if literalChar == quote { // that ends here
+				quote = 0
+				if inKey {
+					current.key += string(literalChar)
+				}
+			} else if inKey {
+				current.key += string(literalChar)
 			} else {
-				log.Debugln(fmt.Sprintf("new key/value parsed: %s = %s", currentKey, currentValue))
-				kvpMap[currentKey] = currentValue
-				currentKey = ""
-				currentValue = ""
-				inKey = true
+				current.value += string(literalChar)
 			}
-		case '=': // if we hit a = char
-			if inQuoteLiteral || inDblQuoteLiteral || !inKey { // we are in a literal / value
-				currentValue += string(literalChar)
+		case literalChar == '\'' || literalChar == '"': // we start a new literal
+			quote = literalChar
+			if inKey { // quoted keys keep their quotes, see parsePath
+				current.key += string(literalChar)
 			} else {
-				inKey = false
+				current.quoted = true
 			}
+		case literalChar == ',':
+			push()
+		case literalChar == '=' && inKey:
+			inKey = false
 		default: // we hit any other char
 			if inKey {
-				currentKey += string(literalChar)
+				current.key += string(literalChar)
 			} else {
-				currentValue += string(literalChar)
+				current.value += string(literalChar)
 			}
 		}
 	}
 
 	// push latest literal
-	if currentKey != "" {
-		log.Debugln(fmt.Sprintf("new key/value parsed: %s = %s", currentKey, currentValue))
-		kvpMap[currentKey] = currentValue
+	if current.key != "" {
+		push()
 	}
 
-	return kvpMap
+	return kvps
 }
@@ -4,9 +4,13 @@
 package transform
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
 	"github.com/Jeffail/gabs"
 	. "github.com/onsi/gomega"
 )
@@ -25,18 +29,12 @@ func TestAPIModelMergerMapValues(t *testing.T) {
 
 	MapValues(m, values)
 	Expect(m["masterProfile.count"].value).To(BeIdenticalTo(int64(5)))
-	Expect(m["agentPoolProfiles[0].name"].arrayValue).To(BeTrue())
-	Expect(m["agentPoolProfiles[0].name"].arrayIndex).To(BeIdenticalTo(0))
-	Expect(m["agentPoolProfiles[0].name"].arrayProperty).To(BeIdenticalTo("name"))
-	Expect(m["agentPoolProfiles[0].name"].arrayName).To(BeIdenticalTo("agentPoolProfiles"))
+	Expect(m["agentPoolProfiles[0].name"].path).To(Equal([]pathElement{{key: "agentPoolProfiles", isKey: true}, {index: 0}, {key: "name", isKey: true}}))
 	Expect(m["agentPoolProfiles[0].name"].value).To(BeIdenticalTo("agentpool1"))
 	Expect(m["linuxProfile.adminUsername"].value).To(BeIdenticalTo("admin"))
 	Expect(m["servicePrincipalProfile.secret"].value).To(BeIdenticalTo("=!,Test$^="))
 	Expect(m["servicePrincipalProfile.clientId"].value).To(BeIdenticalTo("123a1238-c6eb-4b61-9d6f-7db6f1e14123"))
-	Expect(m["certificateProfile.etcdPeerCertificates[0]"].arrayValue).To(BeTrue())
-	Expect(m["certificateProfile.etcdPeerCertificates[0]"].arrayIndex).To(BeIdenticalTo(0))
-	Expect(m["certificateProfile.etcdPeerCertificates[0]"].arrayProperty).To(BeEmpty())
-	Expect(m["certificateProfile.etcdPeerCertificates[0]"].arrayName).To(BeIdenticalTo("certificateProfile.etcdPeerCertificates"))
+	Expect(m["certificateProfile.etcdPeerCertificates[0]"].path).To(Equal([]pathElement{{key: "certificateProfile", isKey: true}, {key: "etcdPeerCertificates", isKey: true}, {index: 0}}))
 	Expect(m["certificateProfile.etcdPeerCertificates[0]"].value).To(BeIdenticalTo("certificate-value"))
 }
 
@@ -72,3 +70,149 @@ func TestMergeValuesWithAPIModel(t *testing.T) {
 	etcdPeerCertificates := jsonAPIModel.Path("properties.certificateProfile.etcdPeerCertificates").Index(0).Data()
 	Expect(etcdPeerCertificates).To(BeIdenticalTo("certificate-value"))
 }
+
+func TestMergeValuesWithAPIModelLoad(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := make(map[string]APIModelValue)
+	MapValues(m, []string{
+		"masterProfile.count=3",
+		`orchestratorProfile.kubernetesConfig.kubeletConfig["--max-pods"]=50`,
+		`agentPoolProfiles[0].kubernetesConfig.kubeletConfig['--node-labels']=a=b`,
+	})
+	tmpFile, err := MergeValuesWithAPIModel("../testdata/simple/kubernetes.json", m)
+	Expect(err).NotTo(HaveOccurred())
+	defer os.Remove(tmpFile)
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{},
+	}
+	cs, _, err := apiloader.LoadContainerServiceFromFile(tmpFile, true, false, nil)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cs.Properties.MasterProfile.Count).To(Equal(3))
+	Expect(cs.Properties.OrchestratorProfile.KubernetesConfig.KubeletConfig).To(HaveKeyWithValue("--max-pods", "50"))
+	Expect(cs.Properties.AgentPoolProfiles[0].KubernetesConfig.KubeletConfig).To(HaveKeyWithValue("--node-labels", "a=b"))
+}
+
+func TestAPIModelMergerMapValuesPaths(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := make(map[string]APIModelValue)
+	values := []string{
+		`agentPoolProfiles[0].kubernetesConfig.kubeletConfig["--max-pods"]=50`,
+		`orchestratorProfile.kubernetesConfig.kubeletConfig['--node-labels']="a=b,c=d"`,
+		"agentPoolProfiles[-].name=pool3",
+		"masterProfile.dnsPrefix='true'",
+		"linuxProfile.ssh.publicKeys[0].keyData[1]=nested",
+	}
+
+	MapValues(m, values)
+	Expect(m[`agentPoolProfiles[0].kubernetesConfig.kubeletConfig["--max-pods"]`].path).To(Equal([]pathElement{
+		{key: "agentPoolProfiles", isKey: true}, {index: 0}, {key: "kubernetesConfig", isKey: true}, {key: "kubeletConfig", isKey: true}, {key: "--max-pods", isKey: true, quoted: true},
+	}))
+	Expect(m[`agentPoolProfiles[0].kubernetesConfig.kubeletConfig["--max-pods"]`].value).To(BeIdenticalTo("50"))
+	Expect(m[`orchestratorProfile.kubernetesConfig.kubeletConfig['--node-labels']`].value).To(BeIdenticalTo("a=b,c=d"))
+	Expect(m["agentPoolProfiles[-].name"].path).To(Equal([]pathElement{{key: "agentPoolProfiles", isKey: true}, {append: true}, {key: "name", isKey: true}}))
+	Expect(m["masterProfile.dnsPrefix"].value).To(BeIdenticalTo("true"))
+	Expect(m["linuxProfile.ssh.publicKeys[0].keyData[1]"].path).To(HaveLen(6))
+	for i, key := range []string{`agentPoolProfiles[0].kubernetesConfig.kubeletConfig["--max-pods"]`, `orchestratorProfile.kubernetesConfig.kubeletConfig['--node-labels']`, "agentPoolProfiles[-].name", "masterProfile.dnsPrefix"} {
+		Expect(m[key].order).To(Equal(i))
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	RegisterTestingT(t)
+
+	cases := []struct {
+		literal  string
+		expected []pathElement
+		err      string
+	}{
+		{"masterProfile.count", []pathElement{{key: "masterProfile", isKey: true}, {key: "count", isKey: true}}, ""},
+		{"a[1][2].b", []pathElement{{key: "a", isKey: true}, {index: 1}, {index: 2}, {key: "b", isKey: true}}, ""},
+		{`a['b.c'][-]`, []pathElement{{key: "a", isKey: true}, {key: "b.c", isKey: true, quoted: true}, {append: true}}, ""},
+		{"", nil, "empty key"},
+		{"a..b", nil, "empty key at position 2"},
+		{"a[x]", nil, `array index "x" is not a positive integer or -`},
+		{"a[1", nil, "unterminated index at position 1"},
+		{`a["b]`, nil, "unterminated quoted key at position 1"},
+	}
+	for _, c := range cases {
+		path, err := parsePath(c.literal)
+		if c.err != "" {
+			Expect(err).To(MatchError(c.err), c.literal)
+			continue
+		}
+		Expect(err).NotTo(HaveOccurred(), c.literal)
+		Expect(path).To(Equal(c.expected), c.literal)
+	}
+}
+
+func TestAPIModelMergerMapJSONValues(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := make(map[string]APIModelValue)
+	err := MapJSONValues(m, []string{
+		`agentPoolProfiles[-]={"name":"pool3","count":2,"vmSize":"Standard_D2_v2"}`,
+		`orchestratorProfile.kubernetesConfig.kubeletConfig={"--max-pods":"50"}`,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(m["agentPoolProfiles[-]"].value).To(Equal(map[string]interface{}{"name": "pool3", "count": json.Number("2"), "vmSize": "Standard_D2_v2"}))
+	Expect(m["orchestratorProfile.kubernetesConfig.kubeletConfig"].order).To(Equal(1))
+
+	err = MapJSONValues(m, []string{`masterProfile.count={`})
+	Expect(err).To(MatchError(ContainSubstring("parsing the JSON value of --set-json key masterProfile.count")))
+	err = MapJSONValues(m, []string{`masterProfile.count`})
+	Expect(err).To(MatchError("parsing --set-json value masterProfile.count: expected key=value"))
+}
+
+func TestMergeAPIModel(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir := t.TempDir()
+	jsonPatch := filepath.Join(dir, "patch.json")
+	Expect(os.WriteFile(jsonPatch, []byte(`[
+		{"op": "test", "path": "/properties/masterProfile/count", "value": 1},
+		{"op": "add", "path": "/properties/agentPoolProfiles/-", "value": {"name": "agentpool3", "count": 1}},
+		{"op": "remove", "path": "/properties/certificateProfile"}
+	]`), 0600)).To(Succeed())
+	mergePatch := filepath.Join(dir, "patch.yaml")
+	Expect(os.WriteFile(mergePatch, []byte(`properties:
+  masterprofile:
+    count: 3
+  servicePrincipalProfile: null
+`), 0600)).To(Succeed())
+
+	m := make(map[string]APIModelValue)
+	MapValues(m, []string{
+		`agentPoolProfiles[2].kubernetesConfig.kubeletConfig["--max-pods"]=50`,
+		"agentPoolProfiles[-].name=agentpool4",
+	})
+	Expect(MapJSONValues(m, []string{`linuxProfile.ssh.publicKeys[-]={"keyData":"ssh-rsa KEY2"}`})).To(Succeed())
+	tmpFile, err := MergeAPIModel("../testdata/simple/kubernetes.json", []string{jsonPatch, mergePatch}, m)
+	Expect(err).NotTo(HaveOccurred())
+
+	jsonFileContent, err := os.ReadFile(tmpFile)
+	Expect(err).To(BeNil())
+	jsonAPIModel, err := gabs.ParseJSON(jsonFileContent)
+	Expect(err).To(BeNil())
+
+	Expect(jsonAPIModel.Path("properties.masterProfile.count").Data()).To(BeIdenticalTo(float64(3)))
+	Expect(jsonAPIModel.Exists("properties", "masterprofile")).To(BeFalse())
+	Expect(jsonAPIModel.Exists("properties", "servicePrincipalProfile")).To(BeFalse())
+	Expect(jsonAPIModel.Exists("properties", "certificateProfile")).To(BeFalse())
+	pools, err := jsonAPIModel.Path("properties.agentPoolProfiles").Children()
+	Expect(err).To(BeNil())
+	Expect(pools).To(HaveLen(4))
+	Expect(pools[2].Path("name").Data()).To(Equal("agentpool3"))
+	Expect(pools[2].Search("kubernetesConfig", "kubeletConfig", "--max-pods").Data()).To(BeIdenticalTo("50"))
+	Expect(pools[3].Path("name").Data()).To(Equal("agentpool4"))
+	keys, err := jsonAPIModel.Path("properties.linuxProfile.ssh.publicKeys").Children()
+	Expect(err).To(BeNil())
+	Expect(keys).To(HaveLen(2))
+
+	m = make(map[string]APIModelValue)
+	MapValues(m, []string{"masterProfile.count.value=1"})
+	_, err = MergeAPIModel("../testdata/simple/kubernetes.json", nil, m)
+	Expect(err).To(MatchError("setting masterProfile.count.value: cannot set key value of a number"))
+}
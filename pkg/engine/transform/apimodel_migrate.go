@@ -0,0 +1,258 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/Azure/aks-engine-azurestack/pkg/api/common"
+	"github.com/pkg/errors"
+)
+
+// APIModelChange is a change MigrateAPIModel makes to migrate a deprecated value of the API model
+type APIModelChange struct {
+	// Path is the JSON path of the value, e.g. properties.masterProfile.distro
+	Path string
+	// From and To describe the value before and after the change, To is empty if the value is removed
+	From, To string
+	// Description explains the change
+	Description string
+	// RequiresNodeReplacement is true if the nodes must be replaced to apply the change, e.g. to use a new OS image
+	RequiresNodeReplacement bool
+	// Skipped is true if the change requires node replacement and was not made
+	Skipped bool
+}
+
+// deprecatedAddons are the addons MigrateAPIModel removes
+var deprecatedAddons = []string{
+	common.TillerAddonName,
+	common.DashboardAddonName,
+	common.ACIConnectorAddonName,
+	common.ReschedulerAddonName,
+	common.ContainerMonitoringAddonName,
+}
+
+// apimodelMigration collects the changes of MigrateAPIModel
+type apimodelMigration struct {
+	allowNodeReplacement bool
+	changes              []APIModelChange
+}
+
+// MigrateAPIModel rewrites the deprecated values of the API model JSON or YAML document apimodel to their current
+// equivalents and returns the migrated JSON document and the changes. The changes that require node replacement
+// are only made if allowNodeReplacement is set, otherwise they are returned as skipped.
+func MigrateAPIModel(apimodel []byte, allowNodeReplacement bool) ([]byte, []APIModelChange, error) {
+	apimodel, err := api.ConvertYAMLToJSON(apimodel)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := decodeJSON(apimodel)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing the API model")
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("the API model is not a JSON object")
+	}
+	m := &apimodelMigration{allowNodeReplacement: allowNodeReplacement}
+	if properties := objectAt(root, "properties"); properties != nil {
+		m.migrateProperties(properties)
+	}
+	b, err := json.Marshal(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, m.changes, nil
+}
+
+// apply records the change c and returns true if it must be made
+func (m *apimodelMigration) apply(c APIModelChange) bool {
+	c.Skipped = c.RequiresNodeReplacement && !m.allowNodeReplacement
+	m.changes = append(m.changes, c)
+	return !c.Skipped
+}
+
+func (m *apimodelMigration) migrateProperties(properties map[string]interface{}) {
+	azureStack := isAzureStackCloud(properties)
+	if masterProfile := objectAt(properties, "masterProfile"); masterProfile != nil {
+		m.migrateDistro("properties.masterProfile", masterProfile, azureStack)
+	}
+	if pools, ok := properties[lookupKey(properties, "agentPoolProfiles")].([]interface{}); ok {
+		for i, p := range pools {
+			pool, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := fmt.Sprintf("properties.agentPoolProfiles[%d]", i)
+			m.migrateDistro(path, pool, azureStack)
+			m.remove(path, pool, "windowsNameVersion", "windowsNameVersion is no longer used, the Windows image is selected by windowsProfile")
+		}
+	}
+	if kubernetesConfig := objectAt(objectAt(properties, "orchestratorProfile"), "kubernetesConfig"); kubernetesConfig != nil {
+		m.migrateKubernetesConfig("properties.orchestratorProfile.kubernetesConfig", kubernetesConfig)
+	}
+}
+
+// migrateDistro replaces the deprecated distro aliases by the distros with the same image,
+// then the Ubuntu 16.04-LTS distros, which are End of Life, by the distros new clusters use
+func (m *apimodelMigration) migrateDistro(path string, profile map[string]interface{}, azureStack bool) {
+	key := lookupKey(profile, "distro")
+	distro, _ := profile[key].(string)
+	path += "." + key
+	switch api.Distro(distro) {
+	case api.AKS1604Deprecated, api.AKSDockerEngine:
+		if m.apply(APIModelChange{Path: path, From: distro, To: string(api.AKSUbuntu1604), Description: fmt.Sprintf("the %s distro is deprecated, %s uses the same image", distro, api.AKSUbuntu1604)}) {
+			distro = string(api.AKSUbuntu1604)
+			profile[key] = distro
+		}
+	case api.AKS1804Deprecated:
+		if m.apply(APIModelChange{Path: path, From: distro, To: string(api.AKSUbuntu1804), Description: fmt.Sprintf("the %s distro is deprecated, %s uses the same image", distro, api.AKSUbuntu1804)}) {
+			distro = string(api.AKSUbuntu1804)
+			profile[key] = distro
+		}
+	}
+
+	// an image reference or a Windows pool does not use the image of the distro
+	if profile[lookupKey(profile, "imageReference")] != nil {
+		return
+	}
+	if osType, _ := profile[lookupKey(profile, "osType")].(string); strings.EqualFold(osType, string(api.Windows)) {
+		return
+	}
+	var to api.Distro
+	switch api.Distro(distro) {
+	case api.AKSUbuntu1604:
+		to = api.AKSUbuntu1804
+		if azureStack {
+			to = api.AKSUbuntu2204
+		}
+	case api.Ubuntu:
+		to = api.Ubuntu1804
+		if azureStack {
+			to = api.Ubuntu2204
+		}
+	default:
+		return
+	}
+	if m.apply(APIModelChange{Path: path, From: distro, To: string(to), RequiresNodeReplacement: true,
+		Description: fmt.Sprintf("the %s distro uses Ubuntu 16.04-LTS, which is End of Life, the nodes must be replaced to use the %s image", distro, to)}) {
+		profile[key] = string(to)
+	}
+}
+
+func (m *apimodelMigration) migrateKubernetesConfig(path string, kubernetesConfig map[string]interface{}) {
+	m.remove(path, kubernetesConfig, "dockerEngineVersion", "docker-engine is replaced by moby, dockerEngineVersion is ignored")
+
+	addonsKey := lookupKey(kubernetesConfig, "addons")
+	addons, _ := kubernetesConfig[addonsKey].([]interface{})
+	pspKey := lookupKey(kubernetesConfig, "podSecurityPolicyConfig")
+	if psp, ok := kubernetesConfig[pspKey].(map[string]interface{}); ok {
+		description := fmt.Sprintf("podSecurityPolicyConfig is ignored, the PodSecurityPolicy manifest is the data of the %s addon", common.PodSecurityPolicyAddonName)
+		data, _ := psp[lookupKey(psp, "data")].(string)
+		if data != "" {
+			addon, i := namedElement(addons, common.PodSecurityPolicyAddonName)
+			if addon == nil {
+				addon = map[string]interface{}{"name": common.PodSecurityPolicyAddonName}
+				addons = append(addons, addon)
+				i = len(addons) - 1
+				kubernetesConfig[addonsKey] = addons
+			}
+			dataKey := lookupKey(addon, "data")
+			if current, _ := addon[dataKey].(string); current == "" {
+				m.apply(APIModelChange{Path: fmt.Sprintf("%s.%s[%d].%s", path, addonsKey, i, dataKey), To: describeJSON(data), Description: "the PodSecurityPolicy manifest of podSecurityPolicyConfig is moved to the addon"})
+				addon[dataKey] = data
+			}
+		}
+		m.remove(path, kubernetesConfig, pspKey, description)
+	}
+
+	if addons == nil {
+		return
+	}
+	var kept []interface{}
+	for i, a := range addons {
+		name := elementName(a)
+		if !isDeprecatedAddon(name) {
+			kept = append(kept, a)
+			continue
+		}
+		m.apply(APIModelChange{Path: fmt.Sprintf("%s.%s[%d]", path, addonsKey, i), From: name + " addon",
+			Description: fmt.Sprintf("the %s addon is deprecated, it is no longer managed by aks-engine-azurestack", name)})
+	}
+	if len(kept) == len(addons) {
+		return
+	}
+	if len(kept) == 0 {
+		delete(kubernetesConfig, addonsKey)
+		return
+	}
+	kubernetesConfig[addonsKey] = kept
+}
+
+// remove removes the deprecated key of o, if it is set
+func (m *apimodelMigration) remove(path string, o map[string]interface{}, key, description string) {
+	key = lookupKey(o, key)
+	v, ok := o[key]
+	if !ok {
+		return
+	}
+	if m.apply(APIModelChange{Path: path + "." + key, From: describeJSON(v), Description: description}) {
+		delete(o, key)
+	}
+}
+
+func isDeprecatedAddon(name string) bool {
+	for _, addon := range deprecatedAddons {
+		if name == addon {
+			return true
+		}
+	}
+	return false
+}
+
+// isAzureStackCloud returns true if the properties of the API model have a custom cloud profile
+// for Azure Stack Hub, see api.Properties.IsAzureStackCloud
+func isAzureStackCloud(properties map[string]interface{}) bool {
+	customCloudProfile, ok := properties[lookupKey(properties, "customCloudProfile")].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	environment := objectAt(customCloudProfile, "environment")
+	name, _ := environment[lookupKey(environment, "name")].(string)
+	return name == "" || strings.EqualFold(name, api.AzureStackCloud)
+}
+
+// objectAt returns the object at key of o regardless of the case of key, or nil if there is none
+func objectAt(o map[string]interface{}, key string) map[string]interface{} {
+	if o == nil {
+		return nil
+	}
+	v, _ := o[lookupKey(o, key)].(map[string]interface{})
+	return v
+}
+
+// namedElement returns the object of a named name and its index, or nil if there is none
+func namedElement(a []interface{}, name string) (map[string]interface{}, int) {
+	for i, e := range a {
+		if elementName(e) == name {
+			return e.(map[string]interface{}), i
+		}
+	}
+	return nil, -1
+}
+
+// describeJSON returns the JSON encoding of v, shortened to be printed
+func describeJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if s := string(b); len(s) <= 60 {
+		return s
+	}
+	return string(b[:57]) + "..."
+}
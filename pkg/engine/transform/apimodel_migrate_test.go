@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMigrateAPIModel(t *testing.T) {
+	RegisterTestingT(t)
+
+	apimodel := `{"apiVersion":"vlabs","properties":{
+		"masterProfile":{"count":1,"distro":"aks-1804"},
+		"agentPoolProfiles":[
+			{"name":"linuxpool","distro":"aks"},
+			{"name":"ubuntupool","distro":"ubuntu"},
+			{"name":"imagepool","distro":"ubuntu","imageReference":{"name":"custom"}},
+			{"name":"windowspool","osType":"Windows","windowsNameVersion":"v2"}],
+		"orchestratorProfile":{"kubernetesConfig":{
+			"dockerEngineVersion":"17.05.*",
+			"podSecurityPolicyConfig":{"data":"cHNw"},
+			"addons":[{"name":"tiller","enabled":true},{"name":"coredns","enabled":true},{"name":"kubernetes-dashboard"}]}}}}`
+
+	cases := []struct {
+		name                 string
+		allowNodeReplacement bool
+		expected             string
+		changes              []APIModelChange
+	}{
+		{
+			name: "changes that require node replacement are skipped",
+			expected: `{"apiVersion":"vlabs","properties":{
+				"masterProfile":{"count":1,"distro":"aks-ubuntu-18.04"},
+				"agentPoolProfiles":[
+					{"name":"linuxpool","distro":"aks-ubuntu-16.04"},
+					{"name":"ubuntupool","distro":"ubuntu"},
+					{"name":"imagepool","distro":"ubuntu","imageReference":{"name":"custom"}},
+					{"name":"windowspool","osType":"Windows"}],
+				"orchestratorProfile":{"kubernetesConfig":{
+					"addons":[{"name":"coredns","enabled":true},{"name":"pod-security-policy","data":"cHNw"}]}}}}`,
+			changes: []APIModelChange{
+				{Path: "properties.masterProfile.distro", From: "aks-1804", To: "aks-ubuntu-18.04"},
+				{Path: "properties.agentPoolProfiles[0].distro", From: "aks", To: "aks-ubuntu-16.04"},
+				{Path: "properties.agentPoolProfiles[0].distro", From: "aks-ubuntu-16.04", To: "aks-ubuntu-18.04", RequiresNodeReplacement: true, Skipped: true},
+				{Path: "properties.agentPoolProfiles[1].distro", From: "ubuntu", To: "ubuntu-18.04", RequiresNodeReplacement: true, Skipped: true},
+				{Path: "properties.agentPoolProfiles[3].windowsNameVersion", From: `"v2"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.dockerEngineVersion", From: `"17.05.*"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[3].data", To: `"cHNw"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.podSecurityPolicyConfig", From: `{"data":"cHNw"}`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[0]", From: "tiller addon"},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[2]", From: "kubernetes-dashboard addon"},
+			},
+		},
+		{
+			name:                 "changes that require node replacement are made when allowed",
+			allowNodeReplacement: true,
+			expected: `{"apiVersion":"vlabs","properties":{
+				"masterProfile":{"count":1,"distro":"aks-ubuntu-18.04"},
+				"agentPoolProfiles":[
+					{"name":"linuxpool","distro":"aks-ubuntu-18.04"},
+					{"name":"ubuntupool","distro":"ubuntu-18.04"},
+					{"name":"imagepool","distro":"ubuntu","imageReference":{"name":"custom"}},
+					{"name":"windowspool","osType":"Windows"}],
+				"orchestratorProfile":{"kubernetesConfig":{
+					"addons":[{"name":"coredns","enabled":true},{"name":"pod-security-policy","data":"cHNw"}]}}}}`,
+			changes: []APIModelChange{
+				{Path: "properties.masterProfile.distro", From: "aks-1804", To: "aks-ubuntu-18.04"},
+				{Path: "properties.agentPoolProfiles[0].distro", From: "aks", To: "aks-ubuntu-16.04"},
+				{Path: "properties.agentPoolProfiles[0].distro", From: "aks-ubuntu-16.04", To: "aks-ubuntu-18.04", RequiresNodeReplacement: true},
+				{Path: "properties.agentPoolProfiles[1].distro", From: "ubuntu", To: "ubuntu-18.04", RequiresNodeReplacement: true},
+				{Path: "properties.agentPoolProfiles[3].windowsNameVersion", From: `"v2"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.dockerEngineVersion", From: `"17.05.*"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[3].data", To: `"cHNw"`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.podSecurityPolicyConfig", From: `{"data":"cHNw"}`},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[0]", From: "tiller addon"},
+				{Path: "properties.orchestratorProfile.kubernetesConfig.addons[2]", From: "kubernetes-dashboard addon"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		migrated, changes, err := MigrateAPIModel([]byte(apimodel), c.allowNodeReplacement)
+		Expect(err).NotTo(HaveOccurred(), c.name)
+		Expect(string(migrated)).To(MatchJSON(c.expected), c.name)
+		Expect(changes).To(HaveLen(len(c.changes)), c.name)
+		for i, change := range changes {
+			Expect(change.Description).NotTo(BeEmpty(), c.name)
+			change.Description = ""
+			Expect(change).To(Equal(c.changes[i]), c.name)
+		}
+	}
+}
+
+func TestMigrateAPIModelAzureStack(t *testing.T) {
+	RegisterTestingT(t)
+
+	apimodel := `apiVersion: vlabs
+properties:
+  customCloudProfile:
+    portalURL: https://portal.local.azurestack.external/
+  masterProfile:
+    distro: aks-ubuntu-16.04
+  agentPoolProfiles:
+  - name: pool
+    distro: ubuntu
+`
+	migrated, changes, err := MigrateAPIModel([]byte(apimodel), true)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(migrated)).To(MatchJSON(`{"apiVersion":"vlabs","properties":{
+		"customCloudProfile":{"portalURL":"https://portal.local.azurestack.external/"},
+		"masterProfile":{"distro":"aks-ubuntu-22.04"},
+		"agentPoolProfiles":[{"name":"pool","distro":"ubuntu-22.04"}]}}`))
+	Expect(changes).To(HaveLen(2))
+
+	_, changes, err = MigrateAPIModel([]byte(`{"apiVersion":"vlabs","properties":{"masterProfile":{"distro":"ubuntu-22.04"}}}`), false)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(changes).To(BeEmpty())
+
+	_, _, err = MigrateAPIModel([]byte(`[]`), false)
+	Expect(err).To(MatchError("the API model is not a JSON object"))
+}
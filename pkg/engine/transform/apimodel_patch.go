@@ -0,0 +1,386 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine-azurestack/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// jsonPatchOperation is an operation of a RFC 6902 JSON Patch
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// PatchAPIModel applies patch to the API model JSON document apimodel. The patch is a JSON or YAML document,
+// either a RFC 6902 JSON Patch (an array of operations) or a RFC 7386 JSON Merge Patch (an object).
+// As the keys of the API model are not case sensitive, the keys of patch match the keys of apimodel regardless of case.
+func PatchAPIModel(apimodel, patch []byte) ([]byte, error) {
+	patch, err := api.ConvertYAMLToJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSON(apimodel)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the API model")
+	}
+	if t := bytes.TrimSpace(patch); len(t) > 0 && t[0] == '[' {
+		var ops []jsonPatchOperation
+		if err = json.Unmarshal(patch, &ops); err != nil {
+			return nil, errors.Wrap(err, "parsing the JSON Patch")
+		}
+		if doc, err = applyJSONPatch(doc, ops); err != nil {
+			return nil, err
+		}
+	} else {
+		p, err := decodeJSON(patch)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing the JSON Merge Patch")
+		}
+		if _, ok := p.(map[string]interface{}); !ok {
+			return nil, errors.New("a patch must be a JSON Patch array or a JSON Merge Patch object")
+		}
+		doc = mergePatch(doc, p)
+	}
+	return json.Marshal(doc)
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// mergePatch applies the RFC 7386 JSON Merge Patch patch to target and returns the result
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		key := lookupKey(t, k)
+		if v == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], v)
+	}
+	return t
+}
+
+// applyJSONPatch applies the RFC 6902 JSON Patch operations ops to doc in order and returns the result
+func applyJSONPatch(doc interface{}, ops []jsonPatchOperation) (interface{}, error) {
+	for i, op := range ops {
+		var err error
+		if doc, err = applyJSONPatchOperation(doc, op); err != nil {
+			return nil, errors.Wrapf(err, "JSON Patch operation %d (%s)", i, op.Op)
+		}
+	}
+	return doc, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, op jsonPatchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, errors.New("missing path")
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (interface{}, error) {
+		if len(op.Value) == 0 {
+			return nil, errors.New("missing value")
+		}
+		return decodeJSON(op.Value)
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, errors.New("missing from")
+		}
+		return parseJSONPointer(*op.From)
+	}
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return addPointer(doc, path, v)
+	case "remove":
+		doc, _, err = removePointer(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = removePointer(doc, path); err != nil {
+			return nil, err
+		}
+		return addPointer(doc, path, v)
+	case "move":
+		f, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(f) && isPointerPrefix(f, path) {
+			return nil, errors.Errorf("cannot move %s into one of its children", *op.From)
+		}
+		doc, v, err := removePointer(doc, f)
+		if err != nil {
+			return nil, err
+		}
+		return addPointer(doc, path, v)
+	case "copy":
+		f, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := getPointer(doc, f)
+		if err != nil {
+			return nil, err
+		}
+		return addPointer(doc, path, deepCopyJSON(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := getPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(actual, v) {
+			return nil, errors.Errorf("test failed, the value at %s is not the expected value", *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, errors.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// parseJSONPointer returns the reference tokens of the RFC 6901 JSON Pointer pointer
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.Errorf("JSON Pointer %q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func isPointerPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if !strings.EqualFold(prefix[i], path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex returns the index token of an array of length n, allowing n itself if end is true
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.Errorf("invalid array index %q", token)
+	}
+	if i > n || (i == n && !end) {
+		return 0, errors.Errorf("array index %d out of bounds", i)
+	}
+	return i, nil
+}
+
+func getPointer(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[lookupKey(node, token)]
+			if !ok {
+				return nil, errors.Errorf("key %q not found", token)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, errors.Errorf("cannot get %q of a %s", token, jsonKind(doc))
+		}
+	}
+	return doc, nil
+}
+
+// addPointer adds value at path in doc: it sets the key of an object, and inserts into an array
+func addPointer(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		key := lookupKey(node, token)
+		if len(rest) == 0 {
+			node[key] = value
+			return node, nil
+		}
+		child, ok := node[key]
+		if !ok {
+			return nil, errors.Errorf("key %q not found", token)
+		}
+		child, err := addPointer(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[key] = child
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(node), len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		child, err := addPointer(node[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	default:
+		return nil, errors.Errorf("cannot add %q to a %s", token, jsonKind(doc))
+	}
+}
+
+// removePointer removes the value at path from doc, and returns doc and the removed value
+func removePointer(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		key := lookupKey(node, token)
+		child, ok := node[key]
+		if !ok {
+			return nil, nil, errors.Errorf("key %q not found", token)
+		}
+		if len(rest) == 0 {
+			delete(node, key)
+			return node, child, nil
+		}
+		child, removed, err := removePointer(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[key] = child
+		return node, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+		child, removed, err := removePointer(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = child
+		return node, removed, nil
+	default:
+		return nil, nil, errors.Errorf("cannot remove %q from a %s", token, jsonKind(doc))
+	}
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, child := range node {
+			c[k] = deepCopyJSON(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, child := range node {
+			c[i] = deepCopyJSON(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// equalJSON compares two JSON values, numbers are equal when their values are
+func equalJSON(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, erra := na.Float64()
+		fb, errb := nb.Float64()
+		return erra == nil && errb == nil && fa == fb
+	}
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, child := range va {
+			other, ok := vb[k]
+			if !ok || !equalJSON(child, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !equalJSON(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
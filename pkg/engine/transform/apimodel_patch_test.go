@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package transform
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPatchAPIModelJSONPatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	doc := `{"a":{"b":[1,2,3],"c":"x","d~e/f":true},"g":[]}`
+	cases := []struct {
+		patch, expected, err string
+	}{
+		{`[{"op":"add","path":"/a/b/1","value":9}]`, `{"a":{"b":[1,9,2,3],"c":"x","d~e/f":true},"g":[]}`, ""},
+		{`[{"op":"add","path":"/g/-","value":{"h":1}}]`, `{"a":{"b":[1,2,3],"c":"x","d~e/f":true},"g":[{"h":1}]}`, ""},
+		{`[{"op":"add","path":"/A/new","value":null}]`, `{"a":{"b":[1,2,3],"c":"x","d~e/f":true,"new":null},"g":[]}`, ""},
+		{`[{"op":"remove","path":"/a/d~0e~1f"}]`, `{"a":{"b":[1,2,3],"c":"x"},"g":[]}`, ""},
+		{`[{"op":"replace","path":"/a/b/0","value":"one"}]`, `{"a":{"b":["one",2,3],"c":"x","d~e/f":true},"g":[]}`, ""},
+		{`[{"op":"move","from":"/a/c","path":"/g/0"}]`, `{"a":{"b":[1,2,3],"d~e/f":true},"g":["x"]}`, ""},
+		{`[{"op":"copy","from":"/a/b","path":"/g"}]`, `{"a":{"b":[1,2,3],"c":"x","d~e/f":true},"g":[1,2,3]}`, ""},
+		{`[{"op":"test","path":"/a/b","value":[1,2.0,3]}]`, doc, ""},
+		{`[{"op":"test","path":"/a/c","value":"y"}]`, "", "JSON Patch operation 0 (test): test failed, the value at /a/c is not the expected value"},
+		{`[{"op":"replace","path":"/a/missing","value":1}]`, "", `JSON Patch operation 0 (replace): key "missing" not found`},
+		{`[{"op":"add","path":"/a/b/4","value":1}]`, "", "JSON Patch operation 0 (add): array index 4 out of bounds"},
+		{`[{"op":"move","from":"/a","path":"/a/c"}]`, "", "JSON Patch operation 0 (move): cannot move /a into one of its children"},
+		{`[{"op":"add","path":"a","value":1}]`, "", `JSON Patch operation 0 (add): JSON Pointer "a" does not start with /`},
+		{`[{"op":"replace","path":"/d"}]`, "", "JSON Patch operation 0 (replace): missing value"},
+		{`[{"op":"merge","path":"/a"}]`, "", `JSON Patch operation 0 (merge): unknown operation "merge"`},
+		{`- op: remove
+  path: /g`, `{"a":{"b":[1,2,3],"c":"x","d~e/f":true}}`, ""},
+	}
+	for _, c := range cases {
+		patched, err := PatchAPIModel([]byte(doc), []byte(c.patch))
+		if c.err != "" {
+			Expect(err).To(MatchError(c.err), c.patch)
+			continue
+		}
+		Expect(err).NotTo(HaveOccurred(), c.patch)
+		Expect(patched).To(MatchJSON(c.expected), c.patch)
+	}
+}
+
+func TestPatchAPIModelMergePatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	doc := `{"a":{"b":[1,2,3],"c":"x"},"d":1}`
+	cases := []struct {
+		patch, expected, err string
+	}{
+		{`{"a":{"c":null,"e":{"f":"g"}}}`, `{"a":{"b":[1,2,3],"e":{"f":"g"}},"d":1}`, ""},
+		{`{"A":{"B":[4]},"d":{"x":1}}`, `{"a":{"b":[4],"c":"x"},"d":{"x":1}}`, ""},
+		{"a:\n  c: z\n", `{"a":{"b":[1,2,3],"c":"z"},"d":1}`, ""},
+		{`"a"`, "", "a patch must be a JSON Patch array or a JSON Merge Patch object"},
+	}
+	for _, c := range cases {
+		patched, err := PatchAPIModel([]byte(doc), []byte(c.patch))
+		if c.err != "" {
+			Expect(err).To(MatchError(c.err), c.patch)
+			continue
+		}
+		Expect(err).NotTo(HaveOccurred(), c.patch)
+		Expect(patched).To(MatchJSON(c.expected), c.patch)
+	}
+}
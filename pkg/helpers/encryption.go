@@ -0,0 +1,322 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// EncryptionKeyFileEnvVar is the environment variable with the path to the key file encrypting the files at rest
+	EncryptionKeyFileEnvVar = "AKSE_ENCRYPTION_KEY_FILE"
+	// EncryptionPassphraseEnvVar is the environment variable with the passphrase the key encrypting the files at rest is derived from
+	EncryptionPassphraseEnvVar = "AKSE_ENCRYPTION_PASSPHRASE"
+)
+
+const (
+	encryptedFileType    = "AKS ENGINE ENCRYPTED FILE"
+	encryptedFileVersion = "1"
+	encryptionKeySize    = 32
+	keySourceKeyFile     = "key-file"
+	keySourcePassphrase  = "passphrase"
+	passphraseKDF        = "PBKDF2-SHA256"
+	// passphraseIterations is the PBKDF2 iteration count of the passphrase-derived keys
+	passphraseIterations = 600000
+	// maxPassphraseIterations caps the iteration count read from an encrypted file, so that
+	// a crafted file cannot make the key derivation run for hours
+	maxPassphraseIterations = 10 * passphraseIterations
+)
+
+// authenticatedHeaders are the headers identifying the key encryption key of an encrypted file,
+// authenticated as the additional data of the encryption so that they cannot be altered
+var authenticatedHeaders = []string{"Version", "Key-Source", "Key-Id", "KDF", "Iterations", "Salt"}
+
+// encryptedFilePrefix starts every encrypted file
+var encryptedFilePrefix = []byte("-----BEGIN " + encryptedFileType + "-----")
+
+// EncryptionKey encrypts files at rest with envelope encryption: every file is encrypted with AES-256-GCM
+// by a random data key, which is encrypted by the key encryption key read from a key file or derived from a passphrase
+type EncryptionKey struct {
+	// key is the key encryption key read from a key file, nil for a passphrase
+	key        []byte
+	passphrase []byte
+
+	lock sync.Mutex
+	// salt is the salt of the key encryption key derived to encrypt files
+	salt []byte
+	// derived caches the key encryption keys derived from the passphrase, by salt and iteration count
+	derived map[string][]byte
+}
+
+// NewPassphraseEncryptionKey returns the EncryptionKey deriving the key encryption key from passphrase
+func NewPassphraseEncryptionKey(passphrase string) (*EncryptionKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("the encryption passphrase is empty")
+	}
+	return &EncryptionKey{passphrase: []byte(passphrase), derived: map[string][]byte{}}, nil
+}
+
+// LoadEncryptionKeyFile returns the EncryptionKey whose key encryption key is in the file at path,
+// 32 random bytes either raw or base64-encoded, e.g. created by openssl rand -out path 32
+func LoadEncryptionKeyFile(path string) (*EncryptionKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the encryption key file")
+	}
+	if len(b) != encryptionKeySize {
+		decoded, decodeErr := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+		if decodeErr != nil || len(decoded) != encryptionKeySize {
+			return nil, errors.Errorf("the encryption key file %s must contain %d bytes, raw or base64-encoded", path, encryptionKeySize)
+		}
+		b = decoded
+	}
+	return &EncryptionKey{key: b}, nil
+}
+
+// EncryptionKeyFromEnvironment returns the EncryptionKey of the key file at $AKSE_ENCRYPTION_KEY_FILE, or
+// derived from $AKSE_ENCRYPTION_PASSPHRASE, or nil if neither is set
+func EncryptionKeyFromEnvironment() (*EncryptionKey, error) {
+	if path := os.Getenv(EncryptionKeyFileEnvVar); path != "" {
+		return LoadEncryptionKeyFile(path)
+	}
+	if passphrase := os.Getenv(EncryptionPassphraseEnvVar); passphrase != "" {
+		return NewPassphraseEncryptionKey(passphrase)
+	}
+	return nil, nil
+}
+
+// IsEncrypted returns true if data is a file encrypted by an EncryptionKey
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), encryptedFilePrefix)
+}
+
+// IsEncryptedFile returns true if the file at path exists and is encrypted by an EncryptionKey
+func IsEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+	b := make([]byte, 512)
+	n, err := f.Read(b)
+	if err != nil && n == 0 {
+		return false, nil
+	}
+	return IsEncrypted(b[:n]), nil
+}
+
+// ReadDecryptedFile reads the file at path and decrypts it if it is encrypted,
+// with the EncryptionKey set in the environment, see EncryptionKeyFromEnvironment
+func ReadDecryptedFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil || !IsEncrypted(b) {
+		return b, err
+	}
+	key, err := EncryptionKeyFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.Errorf("%s is encrypted, set %s or %s to decrypt it", path, EncryptionKeyFileEnvVar, EncryptionPassphraseEnvVar)
+	}
+	if b, err = key.Decrypt(b); err != nil {
+		return nil, errors.Wrapf(err, "decrypting %s", path)
+	}
+	return b, nil
+}
+
+// Encrypt encrypts plaintext with a new data key and returns the encrypted file
+func (k *EncryptionKey) Encrypt(plaintext []byte) ([]byte, error) {
+	headers := map[string]string{"Version": encryptedFileVersion, "Cipher": "AES-256-GCM"}
+	kek, err := k.encryptionKEK(headers)
+	if err != nil {
+		return nil, err
+	}
+	dataKey := make([]byte, encryptionKeySize)
+	if _, err = rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	additionalData := serializeHeaders(headers)
+	wrappedKey, err := seal(kek, dataKey, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	headers["Wrapped-Key"] = base64.StdEncoding.EncodeToString(wrappedKey)
+	ciphertext, err := seal(dataKey, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: encryptedFileType, Headers: headers, Bytes: ciphertext}), nil
+}
+
+// Decrypt decrypts the file data encrypted by Encrypt
+func (k *EncryptionKey) Decrypt(data []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedFileType {
+		return nil, errors.New("not an encrypted file")
+	}
+	if v := block.Headers["Version"]; v != encryptedFileVersion {
+		return nil, errors.Errorf("unsupported encrypted file version %q", v)
+	}
+	kek, err := k.decryptionKEK(block.Headers)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(block.Headers["Wrapped-Key"])
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding the wrapped data key")
+	}
+	additionalData := serializeHeaders(block.Headers)
+	dataKey, err := open(kek, wrappedKey, additionalData)
+	if err != nil {
+		if k.key == nil {
+			return nil, errors.New("unwrapping the data key: wrong passphrase or corrupted file")
+		}
+		return nil, errors.New("unwrapping the data key: corrupted file")
+	}
+	plaintext, err := open(dataKey, block.Bytes, additionalData)
+	if err != nil {
+		return nil, errors.New("decrypting the file: corrupted file")
+	}
+	return plaintext, nil
+}
+
+// encryptionKEK returns the key encryption key of a new file and adds the headers identifying it to headers
+func (k *EncryptionKey) encryptionKEK(headers map[string]string) ([]byte, error) {
+	if k.key != nil {
+		headers["Key-Source"] = keySourceKeyFile
+		headers["Key-Id"] = k.keyID()
+		return k.key, nil
+	}
+	k.lock.Lock()
+	if k.salt == nil {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			k.lock.Unlock()
+			return nil, err
+		}
+		k.salt = salt
+	}
+	salt := k.salt
+	k.lock.Unlock()
+	headers["Key-Source"] = keySourcePassphrase
+	headers["KDF"] = passphraseKDF
+	headers["Iterations"] = strconv.Itoa(passphraseIterations)
+	headers["Salt"] = base64.StdEncoding.EncodeToString(salt)
+	return k.deriveKEK(salt, passphraseIterations), nil
+}
+
+// decryptionKEK returns the key encryption key identified by the headers of an encrypted file
+func (k *EncryptionKey) decryptionKEK(headers map[string]string) ([]byte, error) {
+	switch headers["Key-Source"] {
+	case keySourceKeyFile:
+		if k.key == nil {
+			return nil, errors.Errorf("the file is encrypted with a key file, set %s", EncryptionKeyFileEnvVar)
+		}
+		if id := headers["Key-Id"]; id != k.keyID() {
+			return nil, errors.Errorf("the file is encrypted with the key %s, not with the key %s", id, k.keyID())
+		}
+		return k.key, nil
+	case keySourcePassphrase:
+		if k.passphrase == nil {
+			return nil, errors.Errorf("the file is encrypted with a passphrase, set %s", EncryptionPassphraseEnvVar)
+		}
+		if kdf := headers["KDF"]; kdf != passphraseKDF {
+			return nil, errors.Errorf("unsupported key derivation function %q", kdf)
+		}
+		iterations, err := strconv.Atoi(headers["Iterations"])
+		if err != nil || iterations <= 0 || iterations > maxPassphraseIterations {
+			return nil, errors.Errorf("invalid key derivation iteration count %q", headers["Iterations"])
+		}
+		salt, err := base64.StdEncoding.DecodeString(headers["Salt"])
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding the key derivation salt")
+		}
+		return k.deriveKEK(salt, iterations), nil
+	default:
+		return nil, errors.Errorf("unsupported key source %q", headers["Key-Source"])
+	}
+}
+
+// keyID identifies the key of a key file without disclosing it
+func (k *EncryptionKey) keyID() string {
+	sum := sha256.Sum256(k.key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// deriveKEK returns the key encryption key derived from the passphrase, the files encrypted
+// by the same EncryptionKey share a salt so that the key is derived once
+func (k *EncryptionKey) deriveKEK(salt []byte, iterations int) []byte {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	id := string(salt) + "/" + strconv.Itoa(iterations)
+	if kek, ok := k.derived[id]; ok {
+		return kek
+	}
+	kek := pbkdf2.Key(k.passphrase, salt, iterations, encryptionKeySize, sha256.New)
+	k.derived[id] = kek
+	return kek
+}
+
+// serializeHeaders returns the authenticated headers of an encrypted file, in order
+func serializeHeaders(headers map[string]string) []byte {
+	var b bytes.Buffer
+	for _, name := range authenticatedHeaders {
+		if value, ok := headers[name]; ok {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	return b.Bytes()
+}
+
+// seal encrypts plaintext and authenticates additionalData with AES-256-GCM
+// and returns the nonce followed by the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal
+func open(key, data, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestKeyFile(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEncryptionKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	raw := bytes.Repeat([]byte{7}, 32)
+	key, err := LoadEncryptionKeyFile(writeTestKeyFile(t, dir, "raw.key", raw))
+	if err != nil {
+		t.Fatalf("unexpected error loading a raw key file: %s", err)
+	}
+	encoded, err := LoadEncryptionKeyFile(writeTestKeyFile(t, dir, "base64.key", []byte(base64.StdEncoding.EncodeToString(raw)+"\n")))
+	if err != nil {
+		t.Fatalf("unexpected error loading a base64 key file: %s", err)
+	}
+	if _, err = LoadEncryptionKeyFile(writeTestKeyFile(t, dir, "short.key", []byte("too short"))); err == nil {
+		t.Fatal("expected an error loading a key file of the wrong size")
+	}
+
+	plaintext := []byte(`{"apiVersion":"vlabs"}`)
+	encrypted, err := key.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	if !IsEncrypted(encrypted) || IsEncrypted(plaintext) {
+		t.Fatal("IsEncrypted does not tell the encrypted file from the plaintext")
+	}
+	if bytes.Contains(encrypted, plaintext) {
+		t.Fatal("the encrypted file contains the plaintext")
+	}
+	decrypted, err := encoded.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %s", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("expected %s, got %s", plaintext, decrypted)
+	}
+
+	other, err := LoadEncryptionKeyFile(writeTestKeyFile(t, dir, "other.key", bytes.Repeat([]byte{8}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), "is encrypted with the key") {
+		t.Fatalf("expected a key mismatch error, got %v", err)
+	}
+
+	tampered := bytes.Replace(encrypted, []byte("\n\n"), []byte("\n\nAA"), 1)
+	if _, err = key.Decrypt(tampered); err == nil {
+		t.Fatal("expected an error decrypting a tampered file")
+	}
+}
+
+func TestPassphraseEncryptionKey(t *testing.T) {
+	if _, err := NewPassphraseEncryptionKey(""); err == nil {
+		t.Fatal("expected an error for an empty passphrase")
+	}
+	key, err := NewPassphraseEncryptionKey("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := key.Encrypt([]byte("ca.key"))
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	second, err := key.Encrypt([]byte("ca.crt"))
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	if len(key.derived) != 1 {
+		t.Fatalf("expected the files encrypted by a key to share a derived key, got %d derived keys", len(key.derived))
+	}
+
+	// a new key derives the key encryption key from the salt of the file
+	same, _ := NewPassphraseEncryptionKey("correct horse battery staple")
+	for _, c := range []struct {
+		encrypted []byte
+		expected  string
+	}{{first, "ca.key"}, {second, "ca.crt"}} {
+		decrypted, err := same.Decrypt(c.encrypted)
+		if err != nil {
+			t.Fatalf("unexpected error decrypting: %s", err)
+		}
+		if string(decrypted) != c.expected {
+			t.Fatalf("expected %s, got %s", c.expected, decrypted)
+		}
+	}
+
+	wrong, _ := NewPassphraseEncryptionKey("wrong")
+	if _, err = wrong.Decrypt(first); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("expected a wrong passphrase error, got %v", err)
+	}
+	keyFile, err := LoadEncryptionKeyFile(writeTestKeyFile(t, t.TempDir(), "k", bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = keyFile.Decrypt(first); err == nil || !strings.Contains(err.Error(), EncryptionPassphraseEnvVar) {
+		t.Fatalf("expected an error asking for the passphrase, got %v", err)
+	}
+}
+
+func TestEncryptedFileHeaders(t *testing.T) {
+	key, err := LoadEncryptionKeyFile(writeTestKeyFile(t, t.TempDir(), "k", bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := key.Encrypt([]byte("ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the headers are authenticated, even those not used to find the key encryption key
+	tampered := bytes.Replace(encrypted, []byte("Key-Source: key-file\n"), []byte("KDF: PBKDF2-SHA256\nKey-Source: key-file\n"), 1)
+	if bytes.Equal(tampered, encrypted) {
+		t.Fatal("expected the encrypted file to have a Key-Source header")
+	}
+	if _, err = key.Decrypt(tampered); err == nil || !strings.Contains(err.Error(), "corrupted file") {
+		t.Fatalf("expected an error decrypting a file with tampered headers, got %v", err)
+	}
+
+	passphrase, _ := NewPassphraseEncryptionKey("correct horse battery staple")
+	for _, iterations := range []string{"0", "x", "6000001"} {
+		_, err = passphrase.decryptionKEK(map[string]string{"Key-Source": keySourcePassphrase, "KDF": passphraseKDF, "Iterations": iterations, "Salt": "c2FsdA=="})
+		if err == nil || !strings.Contains(err.Error(), "invalid key derivation iteration count") {
+			t.Fatalf("expected an invalid iteration count error for %s, got %v", iterations, err)
+		}
+	}
+}
+
+func TestFileSaverEncryption(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writeTestKeyFile(t, dir, "output.key", bytes.Repeat([]byte{3}, 32))
+	key, err := LoadEncryptionKeyFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EncryptionKeyFileEnvVar, "")
+	t.Setenv(EncryptionPassphraseEnvVar, "")
+
+	f := &FileSaver{EncryptionKey: key}
+	if err = f.SaveFileString(dir, "apimodel.json", "secret"); err != nil {
+		t.Fatalf("unexpected error saving an encrypted file: %s", err)
+	}
+	path := filepath.Join(dir, "apimodel.json")
+	if encrypted, _ := IsEncryptedFile(path); !encrypted {
+		t.Fatal("expected the file to be encrypted")
+	}
+	if _, err = ReadDecryptedFile(path); err == nil || !strings.Contains(err.Error(), EncryptionKeyFileEnvVar) {
+		t.Fatalf("expected an error asking for the key, got %v", err)
+	}
+
+	// an encrypted file is encrypted again when it is saved without a key
+	plain := &FileSaver{}
+	if err = plain.SaveFileString(dir, "apimodel.json", "updated"); err == nil {
+		t.Fatal("expected an error saving an encrypted file without the key")
+	}
+	t.Setenv(EncryptionKeyFileEnvVar, keyPath)
+	if err = plain.SaveFileString(dir, "apimodel.json", "updated"); err != nil {
+		t.Fatalf("unexpected error saving an encrypted file: %s", err)
+	}
+	b, err := ReadDecryptedFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading the encrypted file: %s", err)
+	}
+	if string(b) != "updated" {
+		t.Fatalf("expected updated, got %s", b)
+	}
+	if encrypted, _ := IsEncryptedFile(path); !encrypted {
+		t.Fatal("expected the file to be encrypted again")
+	}
+
+	if err = plain.SaveFileString(dir, "azuredeploy.json", "{}"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = os.ReadFile(filepath.Join(dir, "azuredeploy.json")); string(b) != "{}" {
+		t.Fatalf("expected a plaintext file, got %s", b)
+	}
+}
@@ -8,12 +8,16 @@ import (
 	"path"
 
 	"github.com/Azure/aks-engine-azurestack/pkg/i18n"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 // FileSaver represents the object that save string or byte data to file
 type FileSaver struct {
 	Translator *i18n.Translator
+	// EncryptionKey encrypts the saved files if set. A file that is already encrypted
+	// is encrypted again with the key set in the environment, see EncryptionKeyFromEnvironment
+	EncryptionKey *EncryptionKey
 }
 
 // SaveFileString saves string to file
@@ -33,6 +37,10 @@ func (f *FileSaver) SaveFile(dir string, file string, data []byte) error {
 	}
 
 	path := path.Join(dir, file)
+	data, err := f.encrypt(path, data)
+	if err != nil {
+		return err
+	}
 	if err := os.WriteFile(path, data, 0600); err != nil {
 		return err
 	}
@@ -41,3 +49,25 @@ func (f *FileSaver) SaveFile(dir string, file string, data []byte) error {
 
 	return nil
 }
+
+// encrypt returns data encrypted if it is saved to an encrypted file, see EncryptionKey
+func (f *FileSaver) encrypt(path string, data []byte) ([]byte, error) {
+	key := f.EncryptionKey
+	if key == nil {
+		encrypted, err := IsEncryptedFile(path)
+		if err != nil || !encrypted {
+			return data, err
+		}
+		if key, err = EncryptionKeyFromEnvironment(); err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, errors.Errorf("%s is encrypted, set %s or %s to encrypt it again", path, EncryptionKeyFileEnvVar, EncryptionPassphraseEnvVar)
+		}
+	}
+	b, err := key.Encrypt(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encrypting %s", path)
+	}
+	return b, nil
+}
@@ -5,8 +5,13 @@ package helpers
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -26,6 +31,21 @@ const (
 	ValidityDuration = time.Hour * 24 * 365 * 30
 )
 
+// Key algorithms of the PKI private keys
+const (
+	// PkiKeyAlgorithmRSA generates RSA keys of PkiKeySize bits, the default
+	PkiKeyAlgorithmRSA = "RSA"
+	// PkiKeyAlgorithmECDSAP256 generates ECDSA keys on the NIST P-256 curve
+	PkiKeyAlgorithmECDSAP256 = "ECDSA-P256"
+	// PkiKeyAlgorithmECDSAP384 generates ECDSA keys on the NIST P-384 curve
+	PkiKeyAlgorithmECDSAP384 = "ECDSA-P384"
+	// PkiKeyAlgorithmEd25519 generates Ed25519 keys
+	PkiKeyAlgorithmEd25519 = "Ed25519"
+)
+
+// PkiKeyAlgorithms are the supported key algorithms of the PKI private keys
+var PkiKeyAlgorithms = []string{PkiKeyAlgorithmRSA, PkiKeyAlgorithmECDSAP256, PkiKeyAlgorithmECDSAP384, PkiKeyAlgorithmEd25519}
+
 // PkiParams is used when we create Pki
 type PkiParams struct {
 	ExtraFQDNs    []string
@@ -34,12 +54,52 @@ type PkiParams struct {
 	CaPair        *PkiKeyCertPair
 	MasterCount   int
 	PkiKeySize    int
+	// PkiKeyAlgorithm is the algorithm of the generated keys, RSA if empty
+	PkiKeyAlgorithm string
+	// CaCertificateChain is the PEM chain of the issuers of an intermediate CA, the generated certificates
+	// are bundled with the CA and the intermediate CAs of the chain
+	CaCertificateChain string
+	// APIServerSettings are the settings of the apiserver certificate
+	APIServerSettings PkiCertificateSettings
+	// ClientSettings are the settings of the kubelet client certificate
+	ClientSettings PkiCertificateSettings
+	// KubeConfigSettings are the settings of the kubectl client certificate
+	KubeConfigSettings PkiCertificateSettings
+	// EtcdSettings are the settings of the etcd server, client and peer certificates
+	EtcdSettings PkiCertificateSettings
+	// EtcdCaPair is the CA signing the etcd server, client and peer certificates, CaPair if nil
+	EtcdCaPair *PkiKeyCertPair
+	// FrontProxyCaPair is the CA signing the front-proxy client certificate, which is only created if set
+	FrontProxyCaPair *PkiKeyCertPair
+	// APIServerPrivateKeyPem is the PEM private key of the apiserver certificate, a new key is generated if empty.
+	// Reusing the current key keeps valid the service account tokens it signed
+	APIServerPrivateKeyPem string
 }
 
 // PkiKeyCertPairParams is the params when we create the pki key cert pair.
 type PkiKeyCertPairParams struct {
 	CommonName string
 	PkiKeySize int
+	// PkiKeyAlgorithm is the algorithm of the generated key, RSA if empty
+	PkiKeyAlgorithm string
+	// Settings are the validity and subject settings of the certificate, its SANs are ignored
+	Settings PkiCertificateSettings
+}
+
+// PkiCertificateSettings are the optional settings of a generated certificate
+type PkiCertificateSettings struct {
+	// ValidityDuration is the duration the certificate is valid, ValidityDuration if zero
+	ValidityDuration time.Duration
+	// Organization is added to the organizations of the certificate subject
+	Organization []string
+	// OrganizationalUnit is the organizational units of the certificate subject
+	OrganizationalUnit []string
+	// Country is the countries of the certificate subject
+	Country []string
+	// ExtraFQDNs are added to the DNS names of the certificate
+	ExtraFQDNs []string
+	// ExtraIPs are added to the IP addresses of the certificate
+	ExtraIPs []net.IP
 }
 
 // PkiKeyCertPair represents an PKI public and private cert pair
@@ -60,6 +120,8 @@ func CreatePkiKeyCertPair(params PkiKeyCertPairParams) (*PkiKeyCertPair, error)
 		extraIPs:      nil,
 		organization:  nil,
 		keySize:       params.PkiKeySize,
+		keyAlgorithm:  params.PkiKeyAlgorithm,
+		settings:      params.Settings,
 	}
 	caCertificate, caPrivateKey, err := createCertificate(certPram)
 	if err != nil {
@@ -69,8 +131,59 @@ func CreatePkiKeyCertPair(params PkiKeyCertPairParams) (*PkiKeyCertPair, error)
 	return caPair, nil
 }
 
-// CreatePki creates PKI certificates
-func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, []*PkiKeyCertPair, error) {
+// PkiClientCertificateParams are the params of a client certificate signed by a CA
+type PkiClientCertificateParams struct {
+	// CommonName is the Kubernetes user name of the certificate
+	CommonName string
+	// Organizations are the Kubernetes groups of the certificate
+	Organizations []string
+	CaPair        *PkiKeyCertPair
+	// CaCertificateChain is the PEM chain of the issuers of an intermediate CA, the certificate is bundled with
+	// the CA and the intermediate CAs of the chain
+	CaCertificateChain string
+	PkiKeySize         int
+	// PkiKeyAlgorithm is the algorithm of the generated key, RSA if empty
+	PkiKeyAlgorithm string
+	// ValidityDuration is the duration the certificate is valid, ValidityDuration if zero
+	ValidityDuration time.Duration
+}
+
+// CreateClientCertificate generates a client certificate and its private key signed by the CA
+func CreateClientCertificate(params PkiClientCertificateParams) (*PkiKeyCertPair, error) {
+	if params.CaPair == nil {
+		return nil, errors.New("the CA is required to sign a client certificate")
+	}
+	caCertificate, err := pemToCertificate(params.CaPair.CertificatePem)
+	if err != nil {
+		return nil, err
+	}
+	caPrivateKey, err := pemToKey(params.CaPair.PrivateKeyPem)
+	if err != nil {
+		return nil, err
+	}
+	var bundle string
+	if params.CaCertificateChain != "" {
+		if bundle, err = intermediateCertificatesBundle(params.CaPair.CertificatePem + "\n" + params.CaCertificateChain); err != nil {
+			return nil, err
+		}
+	}
+	certificate, privateKey, err := createCertificate(certParams{
+		commonName:    params.CommonName,
+		caCertificate: caCertificate,
+		caPrivateKey:  caPrivateKey,
+		organization:  params.Organizations,
+		keySize:       params.PkiKeySize,
+		keyAlgorithm:  params.PkiKeyAlgorithm,
+		settings:      PkiCertificateSettings{ValidityDuration: params.ValidityDuration},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PkiKeyCertPair{CertificatePem: string(certificateToPem(certificate.Raw)) + bundle, PrivateKeyPem: string(privateKeyToPem(privateKey))}, nil
+}
+
+// CreatePki creates PKI certificates, the front-proxy client pair is nil if PkiParams.FrontProxyCaPair is not set
+func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, []*PkiKeyCertPair, *PkiKeyCertPair, error) {
 	start := time.Now()
 	defer func(s time.Time) {
 		log.Debugf("pki: PKI asset creation took %s", time.Since(s))
@@ -85,29 +198,59 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 
 	var (
 		caCertificate         *x509.Certificate
-		caPrivateKey          *rsa.PrivateKey
+		caPrivateKey          crypto.Signer
 		apiServerCertificate  *x509.Certificate
-		apiServerPrivateKey   *rsa.PrivateKey
+		apiServerPrivateKey   crypto.Signer
 		clientCertificate     *x509.Certificate
-		clientPrivateKey      *rsa.PrivateKey
+		clientPrivateKey      crypto.Signer
 		kubeConfigCertificate *x509.Certificate
-		kubeConfigPrivateKey  *rsa.PrivateKey
+		kubeConfigPrivateKey  crypto.Signer
 		etcdServerCertificate *x509.Certificate
-		etcdServerPrivateKey  *rsa.PrivateKey
+		etcdServerPrivateKey  crypto.Signer
 		etcdClientCertificate *x509.Certificate
-		etcdClientPrivateKey  *rsa.PrivateKey
+		etcdClientPrivateKey  crypto.Signer
 		etcdPeerCertPairs     []*PkiKeyCertPair
+		frontProxyClientPair  *PkiKeyCertPair
 	)
 	var group errgroup.Group
 
 	var err error
 	caCertificate, err = pemToCertificate(pkiParams.CaPair.CertificatePem)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, err
 	}
 	caPrivateKey, err = pemToKey(pkiParams.CaPair.PrivateKeyPem)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, err
+	}
+	var bundle string
+	if pkiParams.CaCertificateChain != "" {
+		if bundle, err = intermediateCertificatesBundle(pkiParams.CaPair.CertificatePem + "\n" + pkiParams.CaCertificateChain); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, err
+		}
+	}
+	// the etcd certificates are only bundled with the chain of the CA when the CA signs them
+	etcdCaCertificate, etcdCaPrivateKey, etcdBundle := caCertificate, caPrivateKey, bundle
+	if pkiParams.EtcdCaPair != nil {
+		if etcdCaCertificate, err = pemToCertificate(pkiParams.EtcdCaPair.CertificatePem); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, err
+		}
+		if etcdCaPrivateKey, err = pemToKey(pkiParams.EtcdCaPair.PrivateKeyPem); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, err
+		}
+		etcdBundle = ""
+	}
+	var apiServerReusedKey crypto.Signer
+	if pkiParams.APIServerPrivateKeyPem != "" {
+		if apiServerReusedKey, err = pemToKey(pkiParams.APIServerPrivateKeyPem); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, err
+		}
+	}
+
+	// the apiserver key also signs the service account tokens, which Kubernetes cannot sign with Ed25519
+	apiServerKeyAlgorithm := pkiParams.PkiKeyAlgorithm
+	if apiServerKeyAlgorithm == PkiKeyAlgorithmEd25519 {
+		apiServerKeyAlgorithm = PkiKeyAlgorithmECDSAP256
 	}
 
 	group.Go(func() (err error) {
@@ -121,6 +264,9 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 			extraIPs:      pkiParams.ExtraIPs,
 			organization:  nil,
 			keySize:       pkiParams.PkiKeySize,
+			keyAlgorithm:  apiServerKeyAlgorithm,
+			settings:      pkiParams.APIServerSettings,
+			privateKey:    apiServerReusedKey,
 		}
 		apiServerCertificate, apiServerPrivateKey, err = createCertificate(certPram)
 		return err
@@ -139,6 +285,8 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 			extraIPs:      nil,
 			organization:  organization,
 			keySize:       pkiParams.PkiKeySize,
+			keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+			settings:      pkiParams.ClientSettings,
 		}
 		clientCertificate, clientPrivateKey, err = createCertificate(certPram)
 		return err
@@ -158,6 +306,8 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 			extraIPs:      nil,
 			organization:  organization,
 			keySize:       pkiParams.PkiKeySize,
+			keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+			settings:      pkiParams.KubeConfigSettings,
 		}
 
 		kubeConfigCertificate, kubeConfigPrivateKey, err = createCertificate(certPram)
@@ -167,14 +317,16 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 	group.Go(func() (err error) {
 		certPram := certParams{
 			commonName:    "etcdserver",
-			caCertificate: caCertificate,
-			caPrivateKey:  caPrivateKey,
+			caCertificate: etcdCaCertificate,
+			caPrivateKey:  etcdCaPrivateKey,
 			isEtcd:        true,
 			isServer:      true,
 			extraFQDNs:    nil,
 			extraIPs:      pkiParams.ExtraIPs,
 			organization:  nil,
 			keySize:       pkiParams.PkiKeySize,
+			keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+			settings:      pkiParams.EtcdSettings,
 		}
 		etcdServerCertificate, etcdServerPrivateKey, err = createCertificate(certPram)
 		return err
@@ -183,14 +335,16 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 	group.Go(func() (err error) {
 		certPram := certParams{
 			commonName:    "etcdclient",
-			caCertificate: caCertificate,
-			caPrivateKey:  caPrivateKey,
+			caCertificate: etcdCaCertificate,
+			caPrivateKey:  etcdCaPrivateKey,
 			isEtcd:        true,
 			isServer:      false,
 			extraFQDNs:    nil,
 			extraIPs:      pkiParams.ExtraIPs,
 			organization:  nil,
 			keySize:       pkiParams.PkiKeySize,
+			keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+			settings:      pkiParams.EtcdSettings,
 		}
 		etcdClientCertificate, etcdClientPrivateKey, err = createCertificate(certPram)
 		return err
@@ -202,64 +356,125 @@ func CreatePki(pkiParams PkiParams) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCe
 		group.Go(func() (err error) {
 			certPram := certParams{
 				commonName:    "etcdpeer",
-				caCertificate: caCertificate,
-				caPrivateKey:  caPrivateKey,
+				caCertificate: etcdCaCertificate,
+				caPrivateKey:  etcdCaPrivateKey,
 				isEtcd:        true,
 				isServer:      false,
 				extraFQDNs:    nil,
 				extraIPs:      pkiParams.ExtraIPs,
 				organization:  nil,
 				keySize:       pkiParams.PkiKeySize,
+				keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+				settings:      pkiParams.EtcdSettings,
 			}
 			etcdPeerCertificate, etcdPeerPrivateKey, err := createCertificate(certPram)
-			etcdPeerCertPairs[i] = &PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdPeerCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(etcdPeerPrivateKey))}
+			if err != nil {
+				return err
+			}
+			etcdPeerCertPairs[i] = &PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdPeerCertificate.Raw)) + etcdBundle, PrivateKeyPem: string(privateKeyToPem(etcdPeerPrivateKey))}
 			return err
 		})
 	}
 
+	if pkiParams.FrontProxyCaPair != nil {
+		group.Go(func() (err error) {
+			frontProxyCaCertificate, err := pemToCertificate(pkiParams.FrontProxyCaPair.CertificatePem)
+			if err != nil {
+				return err
+			}
+			frontProxyCaPrivateKey, err := pemToKey(pkiParams.FrontProxyCaPair.PrivateKeyPem)
+			if err != nil {
+				return err
+			}
+			certPram := certParams{
+				commonName:    "aggregator",
+				caCertificate: frontProxyCaCertificate,
+				caPrivateKey:  frontProxyCaPrivateKey,
+				isEtcd:        false,
+				isServer:      false,
+				extraFQDNs:    nil,
+				extraIPs:      nil,
+				organization:  []string{"system:masters"},
+				keySize:       pkiParams.PkiKeySize,
+				keyAlgorithm:  pkiParams.PkiKeyAlgorithm,
+				settings:      pkiParams.ClientSettings,
+			}
+			frontProxyClientCertificate, frontProxyClientPrivateKey, err := createCertificate(certPram)
+			if err != nil {
+				return err
+			}
+			frontProxyClientPair = &PkiKeyCertPair{CertificatePem: string(certificateToPem(frontProxyClientCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(frontProxyClientPrivateKey))}
+			return nil
+		})
+	}
+
 	if err := group.Wait(); err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, err
 	}
 
-	return &PkiKeyCertPair{CertificatePem: string(certificateToPem(apiServerCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(apiServerPrivateKey))},
-		&PkiKeyCertPair{CertificatePem: string(certificateToPem(clientCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(clientPrivateKey))},
-		&PkiKeyCertPair{CertificatePem: string(certificateToPem(kubeConfigCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(kubeConfigPrivateKey))},
-		&PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdServerCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(etcdServerPrivateKey))},
-		&PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdClientCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(etcdClientPrivateKey))},
+	return &PkiKeyCertPair{CertificatePem: string(certificateToPem(apiServerCertificate.Raw)) + bundle, PrivateKeyPem: string(privateKeyToPem(apiServerPrivateKey))},
+		&PkiKeyCertPair{CertificatePem: string(certificateToPem(clientCertificate.Raw)) + bundle, PrivateKeyPem: string(privateKeyToPem(clientPrivateKey))},
+		&PkiKeyCertPair{CertificatePem: string(certificateToPem(kubeConfigCertificate.Raw)) + bundle, PrivateKeyPem: string(privateKeyToPem(kubeConfigPrivateKey))},
+		&PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdServerCertificate.Raw)) + etcdBundle, PrivateKeyPem: string(privateKeyToPem(etcdServerPrivateKey))},
+		&PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdClientCertificate.Raw)) + etcdBundle, PrivateKeyPem: string(privateKeyToPem(etcdClientPrivateKey))},
 		etcdPeerCertPairs,
+		frontProxyClientPair,
 		nil
 }
 
 type certParams struct {
 	commonName    string
 	caCertificate *x509.Certificate
-	caPrivateKey  *rsa.PrivateKey
+	caPrivateKey  crypto.Signer
 	isEtcd        bool
 	isServer      bool
 	extraFQDNs    []string
 	extraIPs      []net.IP
 	organization  []string
 	keySize       int
+	keyAlgorithm  string
+	settings      PkiCertificateSettings
+	// privateKey is the key of the certificate, a new key is generated if nil
+	privateKey crypto.Signer
 }
 
-func createCertificate(options certParams) (*x509.Certificate, *rsa.PrivateKey, error) {
+func createCertificate(options certParams) (*x509.Certificate, crypto.Signer, error) {
 	var err error
 
 	isCA := (options.caCertificate == nil)
 
 	now := time.Now()
+	validity := ValidityDuration
+	if options.settings.ValidityDuration > 0 {
+		validity = options.settings.ValidityDuration
+	}
 
 	template := x509.Certificate{
-		Subject:   pkix.Name{CommonName: options.commonName},
+		Subject: pkix.Name{
+			CommonName:         options.commonName,
+			OrganizationalUnit: options.settings.OrganizationalUnit,
+			Country:            options.settings.Country,
+		},
 		NotBefore: now,
-		NotAfter:  now.Add(ValidityDuration),
+		NotAfter:  now.Add(validity),
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		BasicConstraintsValid: true,
 	}
 
-	if options.organization != nil {
-		template.Subject.Organization = options.organization
+	privateKey := options.privateKey
+	if privateKey == nil {
+		if privateKey, err = generatePrivateKey(options.keyAlgorithm, options.keySize); err != nil {
+			return nil, nil, err
+		}
+	}
+	// only RSA keys encipher the TLS key exchange
+	if _, ok := privateKey.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	if options.organization != nil || options.settings.Organization != nil {
+		template.Subject.Organization = append(append([]string{}, options.organization...), options.settings.Organization...)
 	}
 
 	if isCA {
@@ -285,15 +500,21 @@ func createCertificate(options certParams) (*x509.Certificate, *rsa.PrivateKey,
 		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
 	}
 
+	// the extra SANs are appended to copies, the DNS names and IP addresses are shared by the certificates
+	if !isCA && len(options.settings.ExtraFQDNs) > 0 {
+		template.DNSNames = append(append([]string{}, template.DNSNames...), options.settings.ExtraFQDNs...)
+	}
+	if !isCA && len(options.settings.ExtraIPs) > 0 {
+		template.IPAddresses = append(append([]net.IP{}, template.IPAddresses...), options.settings.ExtraIPs...)
+	}
+
 	snMax := new(big.Int).Lsh(big.NewInt(1), 128)
 	template.SerialNumber, err = rand.Int(rand.Reader, snMax)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	privateKey, _ := rsa.GenerateKey(rand.Reader, options.keySize)
-
-	var privateKeyToUse *rsa.PrivateKey
+	var privateKeyToUse crypto.Signer
 	var certificateToUse *x509.Certificate
 	if !isCA {
 		privateKeyToUse = options.caPrivateKey
@@ -303,7 +524,7 @@ func createCertificate(options certParams) (*x509.Certificate, *rsa.PrivateKey,
 		certificateToUse = &template
 	}
 
-	certDerBytes, err := x509.CreateCertificate(rand.Reader, &template, certificateToUse, &privateKey.PublicKey, privateKeyToUse)
+	certDerBytes, err := x509.CreateCertificate(rand.Reader, &template, certificateToUse, privateKey.Public(), privateKeyToUse)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -327,10 +548,35 @@ func certificateToPem(derBytes []byte) []byte {
 	return pemBuffer.Bytes()
 }
 
-func privateKeyToPem(privateKey *rsa.PrivateKey) []byte {
-	pemBlock := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+// generatePrivateKey generates a private key with the key algorithm, keySize is the size of the RSA keys
+func generatePrivateKey(algorithm string, keySize int) (crypto.Signer, error) {
+	switch algorithm {
+	case "", PkiKeyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, keySize)
+	case PkiKeyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case PkiKeyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case PkiKeyAlgorithmEd25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q, the supported key algorithms are %v", algorithm, PkiKeyAlgorithms)
+	}
+}
+
+// privateKeyToPem encodes the RSA keys as PKCS #1, the ECDSA keys as SEC 1 and the Ed25519 keys as PKCS #8
+func privateKeyToPem(privateKey crypto.Signer) []byte {
+	var pemBlock *pem.Block
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		pemBlock = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, _ := x509.MarshalECPrivateKey(k)
+		pemBlock = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	default:
+		der, _ := x509.MarshalPKCS8PrivateKey(k)
+		pemBlock = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
 	}
 	pemBuffer := bytes.Buffer{}
 	_ = pem.Encode(&pemBuffer, pemBlock)
@@ -338,6 +584,89 @@ func privateKeyToPem(privateKey *rsa.PrivateKey) []byte {
 	return pemBuffer.Bytes()
 }
 
+// SplitCertificateChain splits a PEM bundle into its first certificate and the chain of the other ones
+func SplitCertificateChain(raw string) (string, string, error) {
+	certificates, err := pemToCertificates(raw)
+	if err != nil {
+		return "", "", err
+	}
+	var chain []byte
+	for _, c := range certificates[1:] {
+		chain = append(chain, certificateToPem(c.Raw)...)
+	}
+	return string(certificateToPem(certificates[0].Raw)), string(chain), nil
+}
+
+// VerifyCertificateChain verifies the CA certificate is issued by the chain, which must end with a root CA
+func VerifyCertificateChain(caCertificatePem, chainPem string) error {
+	caCertificate, err := pemToCertificate(caCertificatePem)
+	if err != nil {
+		return err
+	}
+	chain, err := pemToCertificates(chainPem)
+	if err != nil {
+		return err
+	}
+	if !caCertificate.IsCA {
+		return errors.New("the CA certificate is not a CA")
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, c := range chain {
+		if !c.IsCA {
+			return fmt.Errorf("the certificate %s of the chain is not a CA", c.Subject)
+		}
+		if isSelfSigned(c) {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = caCertificate.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	return err
+}
+
+// intermediateCertificatesBundle returns the PEM bundle of the certificates which are not self-signed
+func intermediateCertificatesBundle(raw string) (string, error) {
+	certificates, err := pemToCertificates(raw)
+	if err != nil {
+		return "", err
+	}
+	var bundle []byte
+	for _, c := range certificates {
+		if !isSelfSigned(c) {
+			bundle = append(bundle, certificateToPem(c.Raw)...)
+		}
+	}
+	return string(bundle), nil
+}
+
+func isSelfSigned(c *x509.Certificate) bool {
+	return bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil
+}
+
+func pemToCertificates(raw string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := []byte(raw)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, c)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("The raw pem has no valid PEM formatted certificate")
+	}
+	return certificates, nil
+}
+
 func pemToCertificate(raw string) (*x509.Certificate, error) {
 	cpb, _ := pem.Decode([]byte(raw))
 	if cpb == nil {
@@ -346,10 +675,59 @@ func pemToCertificate(raw string) (*x509.Certificate, error) {
 	return x509.ParseCertificate(cpb.Bytes)
 }
 
-func pemToKey(raw string) (*rsa.PrivateKey, error) {
+func pemToKey(raw string) (crypto.Signer, error) {
 	kpb, _ := pem.Decode([]byte(raw))
 	if kpb == nil {
 		return nil, errors.New("The raw pem is not a valid PEM formatted block")
 	}
-	return x509.ParsePKCS1PrivateKey(kpb.Bytes)
+	switch kpb.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(kpb.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(kpb.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(kpb.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// CertificateDetails describes the first certificate of a PEM bundle
+type CertificateDetails struct {
+	Subject           string    `json:"subject"`
+	SANs              []string  `json:"sans,omitempty"`
+	Issuer            string    `json:"issuer"`
+	SerialNumber      string    `json:"serialNumber"`
+	NotAfter          time.Time `json:"notAfter"`
+	SHA256Fingerprint string    `json:"sha256Fingerprint"`
+}
+
+// GetCertificateDetails parses the first certificate of a PEM bundle
+func GetCertificateDetails(raw string) (*CertificateDetails, error) {
+	certificates, err := pemToCertificates(raw)
+	if err != nil {
+		return nil, err
+	}
+	c := certificates[0]
+	sans := append([]string{}, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, c.EmailAddresses...)
+	for _, uri := range c.URIs {
+		sans = append(sans, uri.String())
+	}
+	return &CertificateDetails{
+		Subject:           c.Subject.String(),
+		SANs:              sans,
+		Issuer:            c.Issuer.String(),
+		SerialNumber:      c.SerialNumber.Text(16),
+		NotAfter:          c.NotAfter.UTC(),
+		SHA256Fingerprint: fmt.Sprintf("%x", sha256.Sum256(c.Raw)),
+	}, nil
 }
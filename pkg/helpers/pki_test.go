@@ -4,11 +4,16 @@
 package helpers
 
 import (
-	"crypto/rsa"
+	"crypto"
+	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
+	"math/big"
 	"net"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCreateCertificateWithOrganisation(t *testing.T) {
@@ -17,7 +22,7 @@ func TestCreateCertificateWithOrganisation(t *testing.T) {
 
 	var (
 		caCertificate   *x509.Certificate
-		caPrivateKey    *rsa.PrivateKey
+		caPrivateKey    crypto.Signer
 		testCertificate *x509.Certificate
 	)
 	certParam := certParams{
@@ -77,7 +82,7 @@ func TestCreateCertificateWithoutOrganisation(t *testing.T) {
 
 	var (
 		caCertificate   *x509.Certificate
-		caPrivateKey    *rsa.PrivateKey
+		caPrivateKey    crypto.Signer
 		testCertificate *x509.Certificate
 	)
 	certParam := certParams{
@@ -222,7 +227,7 @@ func TestSubjectAltNameInCert(t *testing.T) {
 		MasterCount:   1,
 		PkiKeySize:    DefaultPkiKeySize,
 	}
-	apiServerPair, _, _, _, _, _, err := CreatePki(pkiParams)
+	apiServerPair, _, _, _, _, _, _, err := CreatePki(pkiParams)
 
 	if err != nil {
 		t.Fatalf("failed to generate certificates: %s.", err)
@@ -266,7 +271,7 @@ func TestSubjectAltNameInCert(t *testing.T) {
 		MasterCount:   1,
 		PkiKeySize:    DefaultPkiKeySize,
 	}
-	apiServerPair, _, _, _, _, _, err = CreatePki(pkiParams)
+	apiServerPair, _, _, _, _, _, _, err = CreatePki(pkiParams)
 
 	if err != nil {
 		t.Fatalf("failed to generate certificates: %s.", err)
@@ -309,3 +314,407 @@ func TestCreatePkiKeyCertPair(t *testing.T) {
 		t.Errorf("unexpected error thrown while executing CreatePkiKeyCertPair : %s", err.Error())
 	}
 }
+
+func TestCreatePkiKeyAlgorithms(t *testing.T) {
+	expectedKeyTypes := map[string]string{
+		"":                       "RSA PRIVATE KEY",
+		PkiKeyAlgorithmRSA:       "RSA PRIVATE KEY",
+		PkiKeyAlgorithmECDSAP256: "EC PRIVATE KEY",
+		PkiKeyAlgorithmECDSAP384: "EC PRIVATE KEY",
+		PkiKeyAlgorithmEd25519:   "PRIVATE KEY",
+	}
+	// an RSA CA keeps signing the certificates of the other key algorithms
+	rsaCaPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeySize: DefaultPkiKeySize})
+	if err != nil {
+		t.Fatalf("failed to generate the RSA CA: %s", err)
+	}
+	for algorithm, keyType := range expectedKeyTypes {
+		caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeySize: DefaultPkiKeySize, PkiKeyAlgorithm: algorithm})
+		if err != nil {
+			t.Fatalf("failed to generate the %q CA: %s", algorithm, err)
+		}
+		if block, _ := pem.Decode([]byte(caPair.PrivateKeyPem)); block == nil || block.Type != keyType {
+			t.Fatalf("expected a %s for the %q CA", keyType, algorithm)
+		}
+		for _, ca := range []*PkiKeyCertPair{caPair, rsaCaPair} {
+			apiServerPair, clientPair, _, _, _, etcdPeerPairs, _, err := CreatePki(PkiParams{CaPair: ca, ClusterDomain: "cluster.local", MasterCount: 1, PkiKeySize: DefaultPkiKeySize, PkiKeyAlgorithm: algorithm})
+			if err != nil {
+				t.Fatalf("failed to generate the %q PKI: %s", algorithm, err)
+			}
+			roots := x509.NewCertPool()
+			roots.AppendCertsFromPEM([]byte(ca.CertificatePem))
+			for _, pair := range []*PkiKeyCertPair{apiServerPair, clientPair, etcdPeerPairs[0]} {
+				cert, err := pemToCertificate(pair.CertificatePem)
+				if err != nil {
+					t.Fatalf("failed to parse the %q certificate: %s", algorithm, err)
+				}
+				if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+					t.Fatalf("failed to verify the %q certificate %s: %s", algorithm, cert.Subject.CommonName, err)
+				}
+				key, err := pemToKey(pair.PrivateKeyPem)
+				if err != nil {
+					t.Fatalf("failed to parse the %q private key: %s", algorithm, err)
+				}
+				if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(cert.PublicKey) {
+					t.Fatalf("the %q private key does not match the certificate %s", algorithm, cert.Subject.CommonName)
+				}
+			}
+			// Kubernetes cannot sign the service account tokens with the Ed25519 apiserver key
+			if block, _ := pem.Decode([]byte(apiServerPair.PrivateKeyPem)); algorithm == PkiKeyAlgorithmEd25519 && block.Type != "EC PRIVATE KEY" {
+				t.Fatalf("expected an ECDSA apiserver key for the Ed25519 PKI, got a %s", block.Type)
+			}
+		}
+	}
+
+	if _, err = CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: "DSA"}); err == nil {
+		t.Fatalf("expected an error for an unsupported key algorithm")
+	}
+}
+
+func TestCreatePkiCertificateSettings(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{
+		CommonName: "ca",
+		PkiKeySize: DefaultPkiKeySize,
+		Settings:   PkiCertificateSettings{ValidityDuration: 24 * time.Hour * 3650, Organization: []string{"Contoso"}, Country: []string{"US"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	caCertificate, err := pemToCertificate(caPair.CertificatePem)
+	if err != nil {
+		t.Fatalf("failed to parse the CA certificate: %s", err)
+	}
+	if validity := caCertificate.NotAfter.Sub(caCertificate.NotBefore); validity != 24*time.Hour*3650 {
+		t.Errorf("expected the CA certificate to be valid 3650 days, got %s", validity)
+	}
+	if !reflect.DeepEqual(caCertificate.Subject.Organization, []string{"Contoso"}) || !reflect.DeepEqual(caCertificate.Subject.Country, []string{"US"}) {
+		t.Errorf("unexpected CA certificate subject %s", caCertificate.Subject)
+	}
+
+	extraIPs := []net.IP{net.ParseIP("10.0.0.4")}
+	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, _, _, _, err := CreatePki(PkiParams{
+		CaPair:             caPair,
+		ClusterDomain:      "cluster.local",
+		MasterCount:        1,
+		PkiKeySize:         DefaultPkiKeySize,
+		ExtraIPs:           extraIPs,
+		APIServerSettings:  PkiCertificateSettings{ValidityDuration: 24 * time.Hour * 365, ExtraFQDNs: []string{"api.contoso.com"}, ExtraIPs: []net.IP{net.ParseIP("192.168.0.1")}},
+		ClientSettings:     PkiCertificateSettings{Organization: []string{"Contoso"}, OrganizationalUnit: []string{"Platform"}},
+		KubeConfigSettings: PkiCertificateSettings{ValidityDuration: 24 * time.Hour * 30},
+		EtcdSettings:       PkiCertificateSettings{ExtraFQDNs: []string{"etcd.contoso.com"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+	certificates := map[string]*x509.Certificate{}
+	for name, pair := range map[string]*PkiKeyCertPair{"apiserver": apiServerPair, "client": clientPair, "kubeconfig": kubeConfigPair, "etcdserver": etcdServerPair} {
+		if certificates[name], err = pemToCertificate(pair.CertificatePem); err != nil {
+			t.Fatalf("failed to parse the %s certificate: %s", name, err)
+		}
+	}
+
+	apiServer := certificates["apiserver"]
+	if validity := apiServer.NotAfter.Sub(apiServer.NotBefore); validity != 24*time.Hour*365 {
+		t.Errorf("expected the apiserver certificate to be valid 365 days, got %s", validity)
+	}
+	if apiServer.DNSNames[len(apiServer.DNSNames)-1] != "api.contoso.com" || !apiServer.IPAddresses[len(apiServer.IPAddresses)-1].Equal(net.ParseIP("192.168.0.1")) {
+		t.Errorf("expected the extra SANs in the apiserver certificate, got %v %v", apiServer.DNSNames, apiServer.IPAddresses)
+	}
+	// the organizations are a DER set, parsed in their encoding order
+	if client := certificates["client"]; !reflect.DeepEqual(client.Subject.Organization, []string{"Contoso", "system:masters"}) || !reflect.DeepEqual(client.Subject.OrganizationalUnit, []string{"Platform"}) {
+		t.Errorf("unexpected client certificate subject %s", client.Subject)
+	}
+	if kubeConfig := certificates["kubeconfig"]; kubeConfig.NotAfter.Sub(kubeConfig.NotBefore) != 24*time.Hour*30 {
+		t.Errorf("expected the kubeconfig certificate to be valid 30 days, got %s", kubeConfig.NotAfter.Sub(kubeConfig.NotBefore))
+	}
+	if etcdServer := certificates["etcdserver"]; !reflect.DeepEqual(etcdServer.DNSNames, []string{"etcd.contoso.com"}) || len(etcdServer.IPAddresses) != 1 {
+		t.Errorf("expected the extra SANs in the etcd server certificate, got %v %v", etcdServer.DNSNames, etcdServer.IPAddresses)
+	}
+	if len(extraIPs) != 1 {
+		t.Errorf("expected the extra IPs of the PKI to be left unchanged, got %v", extraIPs)
+	}
+}
+
+// createIntermediateCA returns an intermediate CA issued by the CA pair
+func createIntermediateCA(t *testing.T, caPair *PkiKeyCertPair) *PkiKeyCertPair {
+	caCertificate, err := pemToCertificate(caPair.CertificatePem)
+	if err != nil {
+		t.Fatalf("failed to parse the CA certificate: %s", err)
+	}
+	caPrivateKey, err := pemToKey(caPair.PrivateKeyPem)
+	if err != nil {
+		t.Fatalf("failed to parse the CA private key: %s", err)
+	}
+	privateKey, err := generatePrivateKey(PkiKeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("failed to generate the intermediate CA private key: %s", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, caCertificate, privateKey.Public(), caPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to create the intermediate CA certificate: %s", err)
+	}
+	return &PkiKeyCertPair{CertificatePem: string(certificateToPem(der)), PrivateKeyPem: string(privateKeyToPem(privateKey))}
+}
+
+func TestCreatePkiIntermediateCA(t *testing.T) {
+	rootPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "root", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the root CA: %s", err)
+	}
+	intermediatePair := createIntermediateCA(t, rootPair)
+
+	caCertificate, chain, err := SplitCertificateChain(intermediatePair.CertificatePem + rootPair.CertificatePem)
+	if err != nil {
+		t.Fatalf("unexpected error splitting the CA bundle: %s", err)
+	}
+	if caCertificate != intermediatePair.CertificatePem || chain != rootPair.CertificatePem {
+		t.Fatalf("expected the bundle to be split into the intermediate CA and the root CA")
+	}
+	if _, _, err = SplitCertificateChain("not a certificate"); err == nil {
+		t.Errorf("expected an error splitting an invalid bundle")
+	}
+
+	if err = VerifyCertificateChain(intermediatePair.CertificatePem, rootPair.CertificatePem); err != nil {
+		t.Errorf("unexpected error verifying the chain: %s", err)
+	}
+	otherRootPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "root", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the other root CA: %s", err)
+	}
+	if err = VerifyCertificateChain(intermediatePair.CertificatePem, otherRootPair.CertificatePem); err == nil {
+		t.Errorf("expected an error verifying the intermediate CA with another root CA")
+	}
+
+	apiServerPair, clientPair, _, _, _, etcdPeerPairs, _, err := CreatePki(PkiParams{
+		CaPair:             intermediatePair,
+		CaCertificateChain: rootPair.CertificatePem,
+		ClusterDomain:      "cluster.local",
+		MasterCount:        1,
+		PkiKeyAlgorithm:    PkiKeyAlgorithmECDSAP256,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(mustParseCertificate(t, rootPair.CertificatePem))
+	for _, pair := range []*PkiKeyCertPair{apiServerPair, clientPair, etcdPeerPairs[0]} {
+		certificates, err := pemToCertificates(pair.CertificatePem)
+		if err != nil {
+			t.Fatalf("failed to parse the certificate bundle: %s", err)
+		}
+		// the leaf certificate and the intermediate CA, the root CA is left out
+		if len(certificates) != 2 {
+			t.Fatalf("expected the certificate to be bundled with the intermediate CA, got %d certificates", len(certificates))
+		}
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(certificates[1])
+		if _, err = certificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			t.Errorf("failed to verify the certificate %s with its bundle: %s", certificates[0].Subject, err)
+		}
+	}
+}
+
+func TestCreatePkiWithSeparateCAs(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	etcdCaPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "etcd-ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the etcd CA: %s", err)
+	}
+	frontProxyCaPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "front-proxy-ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the front-proxy CA: %s", err)
+	}
+
+	_, clientPair, _, etcdServerPair, etcdClientPair, etcdPeerPairs, frontProxyClientPair, err := CreatePki(PkiParams{
+		CaPair:           caPair,
+		EtcdCaPair:       etcdCaPair,
+		FrontProxyCaPair: frontProxyCaPair,
+		ClusterDomain:    "cluster.local",
+		MasterCount:      1,
+		PkiKeyAlgorithm:  PkiKeyAlgorithmECDSAP256,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+
+	pool := func(pair *PkiKeyCertPair) *x509.CertPool {
+		p := x509.NewCertPool()
+		p.AddCert(mustParseCertificate(t, pair.CertificatePem))
+		return p
+	}
+	verify := func(pair *PkiKeyCertPair, roots *x509.CertPool) error {
+		_, err := mustParseCertificate(t, pair.CertificatePem).Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		return err
+	}
+	for _, pair := range []*PkiKeyCertPair{etcdServerPair, etcdClientPair, etcdPeerPairs[0]} {
+		if err = verify(pair, pool(etcdCaPair)); err != nil {
+			t.Errorf("expected the etcd certificate to be signed by the etcd CA: %s", err)
+		}
+		if err = verify(pair, pool(caPair)); err == nil {
+			t.Errorf("expected the etcd certificate not to be signed by the CA")
+		}
+	}
+	if err = verify(clientPair, pool(etcdCaPair)); err == nil {
+		t.Errorf("expected the client certificate not to be trusted by etcd")
+	}
+
+	if frontProxyClientPair == nil {
+		t.Fatalf("expected the front-proxy client certificate to be created")
+	}
+	if err = verify(frontProxyClientPair, pool(frontProxyCaPair)); err != nil {
+		t.Errorf("expected the front-proxy client certificate to be signed by the front-proxy CA: %s", err)
+	}
+	frontProxyClient := mustParseCertificate(t, frontProxyClientPair.CertificatePem)
+	if frontProxyClient.Subject.CommonName != "aggregator" {
+		t.Errorf("expected the front-proxy client common name to be aggregator, got %s", frontProxyClient.Subject.CommonName)
+	}
+}
+
+func TestCreatePkiReusesAPIServerKey(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	params := PkiParams{
+		CaPair:          caPair,
+		ClusterDomain:   "cluster.local",
+		MasterCount:     1,
+		PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256,
+	}
+	current, _, _, _, _, _, _, err := CreatePki(params)
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+
+	params.APIServerPrivateKeyPem = current.PrivateKeyPem
+	rotated, _, _, _, _, _, _, err := CreatePki(params)
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+	if rotated.PrivateKeyPem != current.PrivateKeyPem {
+		t.Errorf("expected the apiserver private key to be reused")
+	}
+	if rotated.CertificatePem == current.CertificatePem {
+		t.Errorf("expected a new apiserver certificate")
+	}
+
+	params.APIServerPrivateKeyPem = "invalid"
+	if _, _, _, _, _, _, _, err = CreatePki(params); err == nil {
+		t.Errorf("expected an invalid apiserver private key to fail")
+	}
+}
+
+func TestCreateClientCertificate(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	pair, err := CreateClientCertificate(PkiClientCertificateParams{
+		CommonName:       "alice",
+		Organizations:    []string{"dev-team"},
+		CaPair:           caPair,
+		PkiKeyAlgorithm:  PkiKeyAlgorithmECDSAP256,
+		ValidityDuration: 720 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the client certificate: %s", err)
+	}
+	c := mustParseCertificate(t, pair.CertificatePem)
+	if c.Subject.CommonName != "alice" || len(c.Subject.Organization) != 1 || c.Subject.Organization[0] != "dev-team" {
+		t.Errorf("unexpected client certificate subject %s", c.Subject)
+	}
+	if len(c.ExtKeyUsage) != 1 || c.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("expected a client authentication certificate, got %v", c.ExtKeyUsage)
+	}
+	if validity := c.NotAfter.Sub(c.NotBefore); validity != 720*time.Hour {
+		t.Errorf("expected the client certificate to be valid 720h, got %s", validity)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(mustParseCertificate(t, caPair.CertificatePem))
+	if _, err = c.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("expected the client certificate to be signed by the CA: %s", err)
+	}
+	if _, err = pemToKey(pair.PrivateKeyPem); err != nil {
+		t.Errorf("failed to parse the client private key: %s", err)
+	}
+
+	if _, err = CreateClientCertificate(PkiClientCertificateParams{CommonName: "alice"}); err == nil {
+		t.Errorf("expected a client certificate without CA to fail")
+	}
+}
+
+func mustParseCertificate(t *testing.T, raw string) *x509.Certificate {
+	c, err := pemToCertificate(raw)
+	if err != nil {
+		t.Fatalf("failed to parse the certificate: %s", err)
+	}
+	return c
+}
+
+func TestGetCertificateDetails(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair(PkiKeyCertPairParams{CommonName: "ca", PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %s", err)
+	}
+	apiServerPair, _, _, _, _, _, _, err := CreatePki(PkiParams{
+		CaPair:          caPair,
+		ClusterDomain:   "cluster.local",
+		MasterCount:     1,
+		PkiKeyAlgorithm: PkiKeyAlgorithmECDSAP256,
+		ExtraFQDNs:      []string{"santest.contoso.com"},
+		ExtraIPs:        []net.IP{net.ParseIP("10.0.0.4")},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the PKI: %s", err)
+	}
+
+	details, err := GetCertificateDetails(apiServerPair.CertificatePem + caPair.CertificatePem)
+	if err != nil {
+		t.Fatalf("failed to get the certificate details: %s", err)
+	}
+	apiServer := mustParseCertificate(t, apiServerPair.CertificatePem)
+	if details.Subject != "CN=apiserver" || details.Issuer != "CN=ca" {
+		t.Errorf("unexpected subject %s or issuer %s", details.Subject, details.Issuer)
+	}
+	if details.SerialNumber != apiServer.SerialNumber.Text(16) {
+		t.Errorf("expected serial number %s, got %s", apiServer.SerialNumber.Text(16), details.SerialNumber)
+	}
+	if !details.NotAfter.Equal(apiServer.NotAfter) {
+		t.Errorf("expected NotAfter %s, got %s", apiServer.NotAfter, details.NotAfter)
+	}
+	if !containsString(details.SANs, "santest.contoso.com") || !containsString(details.SANs, "10.0.0.4") {
+		t.Errorf("expected the extra SANs in %v", details.SANs)
+	}
+	caDetails, err := GetCertificateDetails(caPair.CertificatePem)
+	if err != nil {
+		t.Fatalf("failed to get the CA certificate details: %s", err)
+	}
+	if caDetails.SHA256Fingerprint == details.SHA256Fingerprint {
+		t.Errorf("expected the fingerprints of different certificates to differ")
+	}
+
+	if _, err = GetCertificateDetails("not a certificate"); err == nil {
+		t.Errorf("expected an error parsing an invalid PEM")
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -21,7 +21,8 @@ import (
 	"k8s.io/client-go/util/retry"
 )
 
-// ExecuteRemote executes a script in a remote host.
+// ExecuteRemote executes a script in a remote host
+// and returns the combined stdout and stderr of the script.
 //
 // Context ctx is only enforced during the process that stablishes
 // the SSH connection and creates the SSH client.
@@ -36,10 +37,11 @@ func ExecuteRemote(ctx context.Context, host *RemoteHost, script string) (combin
 		return "", errors.Wrap(err, "creating SSH session")
 	}
 	defer s.Close()
-	if co, err := s.CombinedOutput(script); err != nil {
+	co, err := s.CombinedOutput(script)
+	if err != nil {
 		return string(co), errors.Wrapf(err, "executing script")
 	}
-	return "", nil
+	return string(co), nil
 }
 
 // PublicKeyAuth returns an AuthMethod that uses a ssh key pair
@@ -174,6 +174,11 @@ func (c *ClientSetClient) DeleteClusterRole(role *rbacv1.ClusterRole) error {
 	return c.clientset.RbacV1().ClusterRoles().Delete(context.TODO(), role.Name, metav1.DeleteOptions{})
 }
 
+// CreateRoleBinding creates the passed in role binding.
+func (c *ClientSetClient) CreateRoleBinding(binding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+	return c.clientset.RbacV1().RoleBindings(binding.Namespace).Create(context.TODO(), binding, metav1.CreateOptions{})
+}
+
 // DeleteDaemonSet deletes the passed in daemonset.
 func (c *ClientSetClient) DeleteDaemonSet(daemonset *appsv1.DaemonSet) error {
 	return c.clientset.AppsV1().DaemonSets(daemonset.Namespace).Delete(context.TODO(), daemonset.Name, metav1.DeleteOptions{})
@@ -64,3 +64,9 @@ type Client interface {
 type NodeLister interface {
 	ListNodes() (*v1.NodeList, error)
 }
+
+// RoleBindingCreator is an interface implemented by Kubernetes clients
+// that are able to create role bindings
+type RoleBindingCreator interface {
+	CreateRoleBinding(binding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error)
+}
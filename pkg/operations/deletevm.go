@@ -20,7 +20,7 @@ const (
 
 // CleanDeleteVirtualMachine deletes a VM and any associated OS disk
 func CleanDeleteVirtualMachine(az armhelpers.AKSEngineClient, logger *log.Entry, subscriptionID, resourceGroup, name string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), az.OperationTimeout())
 	defer cancel()
 	logger.Debugf("fetching VM %s in resource group %s", name, resourceGroup)
 	vm, err := az.GetVirtualMachine(ctx, resourceGroup, name)
@@ -19,6 +19,7 @@ set -x
 GENERATED_FILES=(
 	"pkg/i18n/translations_generated.go"
 	"pkg/engine/templates_generated.go"
+	"pkg/api/schema/descriptions_generated.go"
 )
 
 T="$(mktemp -d)"